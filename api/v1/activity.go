@@ -1,6 +1,19 @@
 package v1
 
-import "github.com/usememos/memos/server/profile"
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/server/profile"
+	"github.com/usememos/memos/store"
+)
 
 // ActivityType is the type for an activity.
 type ActivityType string
@@ -18,6 +31,22 @@ const (
 	ActivityUserAuthSignIn ActivityType = "user.auth.signin"
 	// ActivityUserAuthSignUp is the type for user signup.
 	ActivityUserAuthSignUp ActivityType = "user.auth.signup"
+	// ActivityUserAuthSignInFailed is the type for a failed user signin.
+	ActivityUserAuthSignInFailed ActivityType = "user.auth.signin.failed"
+	// ActivityUserAuthTwoFactorEnable is the type for enabling two-factor authentication.
+	ActivityUserAuthTwoFactorEnable ActivityType = "user.auth.2fa.enable"
+	// ActivityUserAuthTwoFactorDisable is the type for disabling two-factor authentication.
+	ActivityUserAuthTwoFactorDisable ActivityType = "user.auth.2fa.disable"
+	// ActivityUserAuthTwoFactorReset is the type for resetting two-factor authentication of a user by the host.
+	ActivityUserAuthTwoFactorReset ActivityType = "user.auth.2fa.reset"
+	// ActivityUserAuthTwoFactorFailed is the type for failed two-factor code verification.
+	ActivityUserAuthTwoFactorFailed ActivityType = "user.auth.2fa.failed"
+	// ActivityUserAuthTwoFactorRecoveryCodeUse is the type for using a two-factor recovery code.
+	ActivityUserAuthTwoFactorRecoveryCodeUse ActivityType = "user.auth.2fa.recovery-code.use"
+	// ActivityUserAuthImpersonationStart is the type for issuing an impersonation token of a user to the host.
+	ActivityUserAuthImpersonationStart ActivityType = "user.auth.impersonation.start"
+	// ActivityUserAuthImpersonationRequest is the type for a request made by the host under impersonation of a user.
+	ActivityUserAuthImpersonationRequest ActivityType = "user.auth.impersonation.request"
 	// ActivityUserSettingUpdate is the type for updating user settings.
 	ActivityUserSettingUpdate ActivityType = "user.setting.update"
 
@@ -57,6 +86,8 @@ const (
 
 	// ActivityServerStart is the type for starting server.
 	ActivityServerStart ActivityType = "server.start"
+	// ActivityServerBackup is the type for the auto backups of the database.
+	ActivityServerBackup ActivityType = "server.backup"
 )
 
 func (t ActivityType) String() string {
@@ -85,11 +116,55 @@ type ActivityUserCreatePayload struct {
 	Role     Role   `json:"role"`
 }
 
+type ActivityUserUpdatePayload struct {
+	UserID   int    `json:"userId"`
+	Username string `json:"username"`
+	// Fields are the names of the updated fields, the values are not recorded.
+	Fields []string `json:"fields"`
+}
+
+type ActivityUserDeletePayload struct {
+	UserID           int    `json:"userId"`
+	Username         string `json:"username"`
+	Mode             string `json:"mode"`
+	TransferToUserID int    `json:"transferToUserId,omitempty"`
+	MemoCount        int    `json:"memoCount"`
+	ResourceCount    int    `json:"resourceCount"`
+}
+
+type ActivityUserSettingUpdatePayload struct {
+	Key string `json:"key"`
+}
+
 type ActivityUserAuthSignInPayload struct {
 	UserID int    `json:"userId"`
 	IP     string `json:"ip"`
 }
 
+type ActivityUserAuthSignInFailedPayload struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+type ActivityUserAuthTwoFactorPayload struct {
+	UserID int    `json:"userId"`
+	IP     string `json:"ip"`
+}
+
+type ActivityUserAuthImpersonationStartPayload struct {
+	ImpersonatorID int    `json:"impersonatorId"`
+	AllowWrite     bool   `json:"allowWrite"`
+	ExpiresTs      int64  `json:"expiresTs"`
+	IP             string `json:"ip"`
+}
+
+type ActivityUserAuthImpersonationRequestPayload struct {
+	ImpersonatorID int    `json:"impersonatorId"`
+	Method         string `json:"method"`
+	Path           string `json:"path"`
+	IP             string `json:"ip"`
+}
+
 type ActivityUserAuthSignUpPayload struct {
 	Username string `json:"username"`
 	IP       string `json:"ip"`
@@ -100,26 +175,63 @@ type ActivityMemoCreatePayload struct {
 	Visibility string `json:"visibility"`
 }
 
+// ActivityMemoUpdatePayload leaves out the content, as the activities are visible to the admins who may not read the memo.
+type ActivityMemoUpdatePayload struct {
+	MemoID     int    `json:"memoId"`
+	Visibility string `json:"visibility"`
+}
+
+type ActivityMemoDeletePayload struct {
+	MemoID     int    `json:"memoId"`
+	Visibility string `json:"visibility"`
+}
+
 type ActivityShortcutCreatePayload struct {
 	Title   string `json:"title"`
 	Payload string `json:"payload"`
 }
 
+type ActivityShortcutUpdatePayload struct {
+	ShortcutID int    `json:"shortcutId"`
+	Title      string `json:"title"`
+	Payload    string `json:"payload"`
+}
+
+type ActivityShortcutDeletePayload struct {
+	ShortcutID int `json:"shortcutId"`
+}
+
 type ActivityResourceCreatePayload struct {
 	Filename string `json:"filename"`
 	Type     string `json:"type"`
 	Size     int64  `json:"size"`
 }
 
+type ActivityResourceDeletePayload struct {
+	ResourceID int    `json:"resourceId"`
+	Filename   string `json:"filename"`
+}
+
 type ActivityTagCreatePayload struct {
 	TagName string `json:"tagName"`
 }
 
+type ActivityTagDeletePayload struct {
+	TagName string `json:"tagName"`
+}
+
 type ActivityServerStartPayload struct {
 	ServerID string           `json:"serverId"`
 	Profile  *profile.Profile `json:"profile"`
 }
 
+type ActivityServerBackupPayload struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	// Error is the error of the failed backup, empty if succeeded.
+	Error string `json:"error,omitempty"`
+}
+
 type Activity struct {
 	ID int `json:"id"`
 
@@ -143,3 +255,283 @@ type ActivityCreate struct {
 	Level   ActivityLevel
 	Payload string `json:"payload"`
 }
+
+type ListActivitiesResponse struct {
+	Activities []*Activity `json:"activities"`
+	// NextCursor is the cursor of the next page, 0 if there is no more activities.
+	NextCursor int `json:"nextCursor"`
+}
+
+const (
+	// defaultActivityPageLimit is the number of activities of a page if not specified.
+	defaultActivityPageLimit = 50
+	// maxActivityPageLimit is the max number of activities of a page.
+	maxActivityPageLimit = 1000
+	// activityExportBatchSize is the number of activities loaded at once when exporting.
+	activityExportBatchSize = 500
+)
+
+func (s *APIV1Service) registerActivityRoutes(g *echo.Group) {
+	// GET /activity?type=memo.create&level=INFO&creatorId=1&createdTsAfter=0&createdTsBefore=0&cursor=0&limit=50
+	g.GET("/activity", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		find, err := s.parseFindActivity(c, user)
+		if err != nil {
+			return err
+		}
+
+		limit := defaultActivityPageLimit
+		if v := c.QueryParam("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxActivityPageLimit {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid limit: %s, should be between 1 and %d", v, maxActivityPageLimit))
+			}
+		}
+		if v := c.QueryParam("cursor"); v != "" {
+			cursor, err := strconv.Atoi(v)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", v)).SetInternal(err)
+			}
+			// The cursor 0 is the first page.
+			if cursor > 0 {
+				find.IDBefore = &cursor
+			}
+		}
+		// Load one more activity to know whether there is a next page.
+		findLimit := limit + 1
+		find.Limit = &findLimit
+		list, err := s.Store.ListActivities(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find activity list").SetInternal(err)
+		}
+
+		response := &ListActivitiesResponse{
+			Activities: []*Activity{},
+		}
+		if len(list) > limit {
+			list = list[:limit]
+			response.NextCursor = list[limit-1].ID
+		}
+		for _, activity := range list {
+			response.Activities = append(response.Activities, convertActivityFromStore(activity, user.ID))
+		}
+		return c.JSON(http.StatusOK, response)
+	})
+
+	// GET /activity/export?format=csv - Export all the activities matching the filters of the list as CSV or JSON.
+	g.GET("/activity/export", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		find, err := s.parseFindActivity(c, user)
+		if err != nil {
+			return err
+		}
+		format := c.QueryParam("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid export format: %s", format))
+		}
+
+		// The activities are loaded in batches, the first batch is checked before writing the response.
+		batchSize := activityExportBatchSize
+		find.Limit = &batchSize
+		list, err := s.Store.ListActivities(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find activity list").SetInternal(err)
+		}
+
+		response := c.Response()
+		if format == "csv" {
+			response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		} else {
+			response.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		}
+		response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"activities.%s\"", format))
+		response.WriteHeader(http.StatusOK)
+
+		csvWriter := csv.NewWriter(response)
+		if format == "csv" {
+			if err := csvWriter.Write([]string{"id", "creatorId", "createdTs", "type", "level", "payload"}); err != nil {
+				return err
+			}
+		} else if _, err := response.Write([]byte("[")); err != nil {
+			return err
+		}
+		count := 0
+		for len(list) > 0 {
+			for _, activity := range list {
+				if format == "csv" {
+					if err := csvWriter.Write([]string{
+						strconv.Itoa(activity.ID),
+						strconv.Itoa(activity.CreatorID),
+						strconv.FormatInt(activity.CreatedTs, 10),
+						activity.Type,
+						activity.Level,
+						redactActivityPayload(activity, user.ID),
+					}); err != nil {
+						return err
+					}
+					continue
+				}
+
+				activityBytes, err := json.Marshal(convertActivityFromStore(activity, user.ID))
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					activityBytes = append([]byte(","), activityBytes...)
+				}
+				if _, err := response.Write(activityBytes); err != nil {
+					return err
+				}
+				count++
+			}
+			if len(list) < batchSize {
+				break
+			}
+
+			find.IDBefore = &list[len(list)-1].ID
+			if list, err = s.Store.ListActivities(ctx, find); err != nil {
+				// The status is already sent, so the export is truncated.
+				return err
+			}
+		}
+		if format == "csv" {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+		_, err = response.Write([]byte("]"))
+		return err
+	})
+}
+
+// parseFindActivity parses the filters of the activities from the query, the users not granted to view the activities can only find their own.
+func (s *APIV1Service) parseFindActivity(c echo.Context, user *store.User) (*store.FindActivity, error) {
+	find := &store.FindActivity{}
+	for _, value := range c.QueryParams()["type"] {
+		for _, activityType := range strings.Split(value, ",") {
+			if activityType = strings.TrimSpace(activityType); activityType != "" {
+				find.TypeList = append(find.TypeList, activityType)
+			}
+		}
+	}
+	if v := c.QueryParam("level"); v != "" {
+		level := ActivityLevel(v)
+		if level != ActivityInfo && level != ActivityWarn && level != ActivityError {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid activity level: %s", v))
+		}
+		find.Level = &v
+	}
+	if v := c.QueryParam("creatorId"); v != "" {
+		creatorID, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid creator ID: %s", v)).SetInternal(err)
+		}
+		find.CreatorID = &creatorID
+	}
+	canViewAll, err := hasPermission(c.Request().Context(), s.Store, user, PermissionActivityView)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+	}
+	if !canViewAll {
+		if find.CreatorID != nil && *find.CreatorID != user.ID {
+			return nil, echo.NewHTTPError(http.StatusForbidden, "Unauthorized to find the activities of other users")
+		}
+		find.CreatorID = &user.ID
+	}
+	if v := c.QueryParam("createdTsAfter"); v != "" {
+		createdTsAfter, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid createdTsAfter: %s", v)).SetInternal(err)
+		}
+		find.CreatedTsAfter = &createdTsAfter
+	}
+	if v := c.QueryParam("createdTsBefore"); v != "" {
+		createdTsBefore, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid createdTsBefore: %s", v)).SetInternal(err)
+		}
+		find.CreatedTsBefore = &createdTsBefore
+	}
+	return find, nil
+}
+
+// createActivity records the activity of the creator with the payload marshaled as JSON.
+func (s *APIV1Service) createActivity(ctx context.Context, creatorID int, activityType ActivityType, level ActivityLevel, payload any) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	if _, err := s.Store.CreateActivity(ctx, &store.Activity{
+		CreatorID: creatorID,
+		Type:      activityType.String(),
+		Level:     level.String(),
+		Payload:   string(payloadBytes),
+	}); err != nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return nil
+}
+
+// PruneActivities deletes the activities older than the retention days setting, returning the number of deleted ones.
+func PruneActivities(ctx context.Context, s *store.Store, currentTs int64) (int64, error) {
+	systemSetting, err := s.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingActivityRetentionDaysName.String(),
+	})
+	if err != nil || systemSetting == nil {
+		return 0, err
+	}
+	retentionDays := 0
+	if err := json.Unmarshal([]byte(systemSetting.Value), &retentionDays); err != nil {
+		return 0, errors.Wrap(err, "failed to unmarshal activity retention days")
+	}
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	createdTsBefore := currentTs - int64(retentionDays)*24*60*60
+	return s.DeleteActivities(ctx, &store.DeleteActivity{
+		CreatedTsBefore: &createdTsBefore,
+	})
+}
+
+// convertActivityFromStore converts the activity viewed by the user, see redactActivityPayload.
+func convertActivityFromStore(activity *store.Activity, viewerID int) *Activity {
+	return &Activity{
+		ID:        activity.ID,
+		CreatorID: activity.CreatorID,
+		CreatedTs: activity.CreatedTs,
+		Type:      ActivityType(activity.Type),
+		Level:     ActivityLevel(activity.Level),
+		Payload:   redactActivityPayload(activity, viewerID),
+	}
+}
+
+// redactActivityPayload removes the memo content from the payloads of the memo activities of other users than the viewer,
+// as the viewer may not be allowed to read the memo, such as a private one.
+func redactActivityPayload(activity *store.Activity, viewerID int) string {
+	if activity.CreatorID == viewerID || (activity.Type != ActivityMemoCreate.String() && activity.Type != ActivityMemoUpdate.String()) {
+		return activity.Payload
+	}
+	payload := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(activity.Payload), &payload); err != nil {
+		return "{}"
+	}
+	if _, ok := payload["content"]; !ok {
+		return activity.Payload
+	}
+	delete(payload, "content")
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "{}"
+	}
+	return string(payloadBytes)
+}
@@ -0,0 +1,309 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	echosse "github.com/CorrectRoadH/echo-sse"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/ai"
+	"github.com/usememos/memos/plugin/ai/ollama"
+	"github.com/usememos/memos/plugin/ai/openai"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+// aiRateLimitWindowSeconds is the window of the per user rate limit.
+const aiRateLimitWindowSeconds = 60 * 60
+
+var (
+	errAINotConfigured     = errors.New("AI provider not configured")
+	errAIRateLimitExceeded = errors.New("AI rate limit exceeded")
+)
+
+type AIUsageSummary struct {
+	UserID           int `json:"userId"`
+	RequestCount     int `json:"requestCount"`
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	// RequestCountInWindow is the number of requests in the last hour which counts towards the rate limit.
+	RequestCountInWindow int `json:"requestCountInWindow"`
+	RateLimitPerHour     int `json:"rateLimitPerHour"`
+}
+
+func (s *APIV1Service) registerAIRoutes(g *echo.Group) {
+	chatCompletion := func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		messages := []ai.Message{}
+		if err := json.NewDecoder(c.Request().Body).Decode(&messages); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post chat completion request").SetInternal(err)
+		}
+		if len(messages) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "No messages provided")
+		}
+
+		response, err := ChatWithAI(ctx, s.Store, userID, messages, nil)
+		if err != nil {
+			return convertAIError(err)
+		}
+		return c.JSON(http.StatusOK, response.Content)
+	}
+
+	chatStreaming := func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		messages := []ai.Message{}
+		if err := json.NewDecoder(c.Request().Body).Decode(&messages); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post chat completion request").SetInternal(err)
+		}
+		if len(messages) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "No messages provided")
+		}
+
+		sse := echosse.NewSSEClint(c)
+		_, err := ChatWithAI(ctx, s.Store, userID, messages, func(delta string) error {
+			// _ is for to pass the golangci-lint check
+			_ = sse.SendEvent(delta)
+
+			// the delay is a very good way to make the chatbot more comfortable
+			// otherwise the chatbot will reply too fast. Believe me it is not good.🤔
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			return convertAIError(err)
+		}
+		return nil
+	}
+
+	enabled := func(c echo.Context) error {
+		ctx := c.Request().Context()
+		aiConfig, err := getAIConfig(ctx, s.Store)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find AI config").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, aiConfig != nil)
+	}
+
+	g.POST("/ai/chat-completion", chatCompletion)
+	g.POST("/ai/chat-streaming", chatStreaming)
+	g.GET("/ai/enabled", enabled)
+	// The OpenAI routes are kept for the compatibility of existing clients.
+	g.POST("/openai/chat-completion", chatCompletion)
+	g.POST("/openai/chat-streaming", chatStreaming)
+	g.GET("/openai/enabled", enabled)
+
+	g.GET("/ai/usage", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUserID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		userID := currentUserID
+		if userIDStr := c.QueryParam("userId"); userIDStr != "" {
+			id, err := strconv.Atoi(userIDStr)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User ID is not a number: %s", userIDStr)).SetInternal(err)
+			}
+			if id != currentUserID {
+				if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+					return err
+				}
+			}
+			userID = id
+		}
+
+		find := &store.FindAIUsage{
+			UserID: &userID,
+		}
+		if sinceStr := c.QueryParam("since"); sinceStr != "" {
+			since, err := strconv.ParseInt(sinceStr, 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Since is not a number: %s", sinceStr)).SetInternal(err)
+			}
+			find.CreatedTsAfter = &since
+		}
+		list, err := s.Store.ListAIUsages(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find AI usage list").SetInternal(err)
+		}
+		aiConfig, err := getAIConfig(ctx, s.Store)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find AI config").SetInternal(err)
+		}
+
+		windowStartTs := time.Now().Unix() - aiRateLimitWindowSeconds
+		summary := &AIUsageSummary{
+			UserID: userID,
+		}
+		for _, aiUsage := range list {
+			summary.RequestCount++
+			summary.PromptTokens += aiUsage.PromptTokens
+			summary.CompletionTokens += aiUsage.CompletionTokens
+			if aiUsage.CreatedTs >= windowStartTs {
+				summary.RequestCountInWindow++
+			}
+		}
+		if aiConfig != nil {
+			summary.RateLimitPerHour = aiConfig.RateLimitPerHour
+		}
+		return c.JSON(http.StatusOK, summary)
+	})
+}
+
+// ChatWithAI sends the messages to the configured AI provider on behalf of the user.
+// The reply is streamed to onDelta if it's not nil. The rate limit of the user is checked
+// before the request and the usage is recorded after it.
+func ChatWithAI(ctx context.Context, s *store.Store, userID int, messages []ai.Message, onDelta func(delta string) error) (*ai.ChatResponse, error) {
+	aiConfig, err := getAIConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if aiConfig == nil {
+		return nil, errAINotConfigured
+	}
+	if err := checkAIRateLimit(ctx, s, userID, aiConfig); err != nil {
+		return nil, err
+	}
+
+	provider, err := newAIProvider(aiConfig)
+	if err != nil {
+		return nil, err
+	}
+	request := &ai.ChatRequest{
+		Model:       aiConfig.Model,
+		Messages:    messages,
+		Temperature: aiConfig.Temperature,
+		MaxTokens:   aiConfig.MaxTokens,
+	}
+	var response *ai.ChatResponse
+	if onDelta == nil {
+		response, err = provider.Chat(ctx, request)
+	} else {
+		response, err = provider.ChatStream(ctx, request, onDelta)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recordAIUsage(ctx, s, userID, aiConfig, response.Model, response.Usage)
+	return response, nil
+}
+
+func recordAIUsage(ctx context.Context, s *store.Store, userID int, aiConfig *AIConfig, model string, usage ai.Usage) {
+	if model == "" {
+		model = aiConfig.Model
+	}
+	if _, err := s.CreateAIUsage(ctx, &store.AIUsage{
+		UserID:           userID,
+		Provider:         aiConfig.Provider.String(),
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	}); err != nil {
+		log.WithContext(ctx).Error("failed to create AI usage", zap.Error(err))
+	}
+}
+
+// getAIConfig returns the AI config, or nil if no provider is configured.
+// The legacy OpenAI config is used if the AI config is not set.
+func getAIConfig(ctx context.Context, s *store.Store) (*AIConfig, error) {
+	aiConfigSetting, err := s.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingAIConfigName.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aiConfigSetting != nil {
+		aiConfig := &AIConfig{}
+		if err := json.Unmarshal([]byte(aiConfigSetting.Value), aiConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal AI system setting value")
+		}
+		return aiConfig, nil
+	}
+
+	openAIConfigSetting, err := s.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingOpenAIConfigName.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if openAIConfigSetting == nil {
+		return nil, nil
+	}
+	openAIConfig := OpenAIConfig{}
+	if err := json.Unmarshal([]byte(openAIConfigSetting.Value), &openAIConfig); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal openai system setting value")
+	}
+	if openAIConfig.Key == "" {
+		return nil, nil
+	}
+	return &AIConfig{
+		Provider: AIProviderOpenAI,
+		Host:     openAIConfig.Host,
+		Key:      openAIConfig.Key,
+		Model:    openai.DefaultModel,
+	}, nil
+}
+
+func newAIProvider(aiConfig *AIConfig) (ai.Provider, error) {
+	switch aiConfig.Provider {
+	case AIProviderOpenAI:
+		return openai.NewProvider(&openai.Config{
+			Host: aiConfig.Host,
+			Key:  aiConfig.Key,
+		})
+	case AIProviderOllama:
+		return ollama.NewProvider(&ollama.Config{
+			Host: aiConfig.Host,
+		})
+	default:
+		return nil, errors.Errorf("unsupported AI provider %s", aiConfig.Provider)
+	}
+}
+
+func checkAIRateLimit(ctx context.Context, s *store.Store, userID int, aiConfig *AIConfig) error {
+	if aiConfig.RateLimitPerHour <= 0 {
+		return nil
+	}
+
+	windowStartTs := time.Now().Unix() - aiRateLimitWindowSeconds
+	list, err := s.ListAIUsages(ctx, &store.FindAIUsage{
+		UserID:         &userID,
+		CreatedTsAfter: &windowStartTs,
+	})
+	if err != nil {
+		return err
+	}
+	if len(list) >= aiConfig.RateLimitPerHour {
+		return errAIRateLimitExceeded
+	}
+	return nil
+}
+
+func convertAIError(err error) error {
+	switch {
+	case errors.Is(err, errAINotConfigured):
+		return echo.NewHTTPError(http.StatusBadRequest, "AI provider not configured")
+	case errors.Is(err, errAIRateLimitExceeded):
+		return echo.NewHTTPError(http.StatusTooManyRequests, "AI rate limit exceeded, please try again later")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to chat with AI").SetInternal(err)
+	}
+}
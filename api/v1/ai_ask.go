@@ -0,0 +1,318 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/ai"
+	"github.com/usememos/memos/plugin/ai/openai"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAIAskTopK = 5
+	maxAIAskTopK     = 20
+	// memoEmbeddingBatchSize is the number of memos embedded in a single request.
+	memoEmbeddingBatchSize = 16
+)
+
+var memoCitationRegexp = regexp.MustCompile(`\[memo:(\d+)\]`)
+
+type AIAskRequest struct {
+	Question string `json:"question"`
+	// TopK is the number of relevant memos retrieved as context.
+	TopK int `json:"topK"`
+}
+
+type AIAskSource struct {
+	MemoID int     `json:"memoId"`
+	Score  float64 `json:"score"`
+}
+
+type AIAskResponse struct {
+	Content string `json:"content"`
+	// Sources are the retrieved memos injected as context, the most relevant first.
+	Sources []*AIAskSource `json:"sources"`
+	// Citations are the IDs of the source memos cited in the answer.
+	Citations []int `json:"citations"`
+}
+
+func (s *APIV1Service) registerAIAskRoutes(g *echo.Group) {
+	g.POST("/ai/ask", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		request := &AIAskRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post ask request").SetInternal(err)
+		}
+		request.Question = strings.TrimSpace(request.Question)
+		if request.Question == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "No question provided")
+		}
+		if request.TopK <= 0 {
+			request.TopK = defaultAIAskTopK
+		}
+		if request.TopK > maxAIAskTopK {
+			request.TopK = maxAIAskTopK
+		}
+
+		response, err := AskAI(ctx, s.Store, userID, request)
+		if err != nil {
+			return convertAIError(err)
+		}
+		return c.JSON(http.StatusOK, response)
+	})
+}
+
+// AskAI answers the question of the user with the most relevant memos of the user as context.
+func AskAI(ctx context.Context, s *store.Store, userID int, request *AIAskRequest) (*AIAskResponse, error) {
+	aiConfig, err := getAIConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if aiConfig == nil {
+		return nil, errAINotConfigured
+	}
+	if err := checkAIRateLimit(ctx, s, userID, aiConfig); err != nil {
+		return nil, err
+	}
+	provider, err := newAIProvider(aiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	normalStatus := store.Normal
+	memoList, err := s.ListMemos(ctx, &store.FindMemo{
+		CreatorID: &userID,
+		RowStatus: &normalStatus,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list memos")
+	}
+	usage, _, err := refreshMemoEmbeddings(ctx, s, aiConfig, provider, memoList)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to refresh memo embeddings")
+	}
+
+	embeddingModel := getAIEmbeddingModel(aiConfig)
+	questionEmbedding, err := provider.Embed(ctx, &ai.EmbeddingRequest{
+		Model: embeddingModel,
+		Input: []string{request.Question},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to embed question")
+	}
+	usage.PromptTokens += questionEmbedding.Usage.PromptTokens
+
+	memoEmbeddingList, err := s.ListMemoEmbeddings(ctx, &store.FindMemoEmbedding{
+		CreatorID: &userID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list memo embeddings")
+	}
+	memoMap := map[int]*store.Memo{}
+	for _, memo := range memoList {
+		memoMap[memo.ID] = memo
+	}
+	sources := []*AIAskSource{}
+	for _, memoEmbedding := range memoEmbeddingList {
+		if memoMap[memoEmbedding.MemoID] == nil || memoEmbedding.Model != embeddingModel {
+			continue
+		}
+		sources = append(sources, &AIAskSource{
+			MemoID: memoEmbedding.MemoID,
+			Score:  ai.CosineSimilarity(questionEmbedding.Embeddings[0], memoEmbedding.Embedding),
+		})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Score > sources[j].Score
+	})
+	if len(sources) > request.TopK {
+		sources = sources[:request.TopK]
+	}
+
+	contextList := []string{}
+	for _, source := range sources {
+		contextList = append(contextList, fmt.Sprintf("[memo:%d]\n%s", source.MemoID, memoMap[source.MemoID].Content))
+	}
+	messages := []ai.Message{
+		{
+			Role: "system",
+			Content: "You answer questions using the user's memos below. " +
+				"Cite every memo you use with its tag like [memo:1]. " +
+				"If the memos do not contain the answer, say that you don't know.\n\n" +
+				strings.Join(contextList, "\n\n"),
+		},
+		{
+			Role:    "user",
+			Content: request.Question,
+		},
+	}
+	chatResponse, err := provider.Chat(ctx, &ai.ChatRequest{
+		Model:       aiConfig.Model,
+		Messages:    messages,
+		Temperature: aiConfig.Temperature,
+		MaxTokens:   aiConfig.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	usage.PromptTokens += chatResponse.Usage.PromptTokens
+	usage.CompletionTokens += chatResponse.Usage.CompletionTokens
+	recordAIUsage(ctx, s, userID, aiConfig, chatResponse.Model, usage)
+
+	return &AIAskResponse{
+		Content:   chatResponse.Content,
+		Sources:   sources,
+		Citations: findMemoCitations(chatResponse.Content, sources),
+	}, nil
+}
+
+// refreshMemoEmbeddings embeds the memos whose embedding is missing or outdated, it returns the usage and the number of embedding requests.
+func refreshMemoEmbeddings(ctx context.Context, s *store.Store, aiConfig *AIConfig, provider ai.Provider, memoList []*store.Memo) (ai.Usage, int, error) {
+	usage, requestCount := ai.Usage{}, 0
+	embeddingModel := getAIEmbeddingModel(aiConfig)
+	memoEmbeddingMap := map[int]*store.MemoEmbedding{}
+	listedCreatorIDs := map[int]bool{}
+	for _, memo := range memoList {
+		creatorID := memo.CreatorID
+		if listedCreatorIDs[creatorID] {
+			continue
+		}
+		listedCreatorIDs[creatorID] = true
+		memoEmbeddingList, err := s.ListMemoEmbeddings(ctx, &store.FindMemoEmbedding{
+			CreatorID: &creatorID,
+		})
+		if err != nil {
+			return usage, requestCount, err
+		}
+		for _, memoEmbedding := range memoEmbeddingList {
+			memoEmbeddingMap[memoEmbedding.MemoID] = memoEmbedding
+		}
+	}
+	staleMemoList := []*store.Memo{}
+	for _, memo := range memoList {
+		memoEmbedding := memoEmbeddingMap[memo.ID]
+		if memoEmbedding == nil || memoEmbedding.Model != embeddingModel || memoEmbedding.ContentHash != getMemoContentHash(memo.Content) {
+			staleMemoList = append(staleMemoList, memo)
+		}
+	}
+
+	for start := 0; start < len(staleMemoList); start += memoEmbeddingBatchSize {
+		end := start + memoEmbeddingBatchSize
+		if end > len(staleMemoList) {
+			end = len(staleMemoList)
+		}
+		batch := staleMemoList[start:end]
+		input := []string{}
+		for _, memo := range batch {
+			input = append(input, memo.Content)
+		}
+		response, err := provider.Embed(ctx, &ai.EmbeddingRequest{
+			Model: embeddingModel,
+			Input: input,
+		})
+		if err != nil {
+			return usage, requestCount, err
+		}
+		usage.PromptTokens += response.Usage.PromptTokens
+		requestCount++
+
+		for i, memo := range batch {
+			if _, err := s.UpsertMemoEmbedding(ctx, &store.MemoEmbedding{
+				MemoID:      memo.ID,
+				Model:       embeddingModel,
+				ContentHash: getMemoContentHash(memo.Content),
+				Embedding:   response.Embeddings[i],
+			}); err != nil {
+				return usage, requestCount, err
+			}
+		}
+	}
+	return usage, requestCount, nil
+}
+
+// refreshMemoEmbeddingAsync refreshes the embedding of the memo in background if its creator opted in and an AI provider is configured.
+// Otherwise the memo is embedded the next time its creator asks.
+func (s *APIV1Service) refreshMemoEmbeddingAsync(memo *store.Memo) {
+	go func() {
+		ctx := context.Background()
+		userSetting, err := s.Store.GetUserSetting(ctx, &store.FindUserSetting{
+			UserID: &memo.CreatorID,
+			Key:    UserSettingAIMemoEmbeddingKey.String(),
+		})
+		if err != nil || userSetting == nil || userSetting.Value != "true" {
+			return
+		}
+		aiConfig, err := getAIConfig(ctx, s.Store)
+		if err != nil || aiConfig == nil {
+			return
+		}
+		if err := checkAIRateLimit(ctx, s.Store, memo.CreatorID, aiConfig); err != nil {
+			return
+		}
+		provider, err := newAIProvider(aiConfig)
+		if err != nil {
+			return
+		}
+		usage, requestCount, err := refreshMemoEmbeddings(ctx, s.Store, aiConfig, provider, []*store.Memo{memo})
+		if err != nil {
+			log.Warn(fmt.Sprintf("failed to refresh embedding of memo %d", memo.ID), zap.Error(err))
+			return
+		}
+		if requestCount == 0 {
+			return
+		}
+		recordAIUsage(ctx, s.Store, memo.CreatorID, aiConfig, getAIEmbeddingModel(aiConfig), usage)
+	}()
+}
+
+func getAIEmbeddingModel(aiConfig *AIConfig) string {
+	if aiConfig.EmbeddingModel != "" {
+		return aiConfig.EmbeddingModel
+	}
+	if aiConfig.Provider == AIProviderOpenAI {
+		return openai.DefaultEmbeddingModel
+	}
+	return aiConfig.Model
+}
+
+func getMemoContentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// findMemoCitations returns the IDs of the source memos cited in the content.
+func findMemoCitations(content string, sources []*AIAskSource) []int {
+	sourceIDs := map[int]bool{}
+	for _, source := range sources {
+		sourceIDs[source.MemoID] = true
+	}
+	citations := []int{}
+	cited := map[int]bool{}
+	for _, match := range memoCitationRegexp.FindAllStringSubmatch(content, -1) {
+		memoID, err := strconv.Atoi(match[1])
+		if err != nil || !sourceIDs[memoID] || cited[memoID] {
+			continue
+		}
+		cited[memoID] = true
+		citations = append(citations, memoID)
+	}
+	return citations
+}
@@ -1,18 +1,24 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"github.com/usememos/memos/api/v1/auth"
+	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/common/util"
 	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/plugin/idp/ldap"
 	"github.com/usememos/memos/plugin/idp/oauth2"
+	"github.com/usememos/memos/plugin/idp/oidc"
 	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,11 +31,17 @@ type SSOSignIn struct {
 	IdentityProviderID int    `json:"identityProviderId"`
 	Code               string `json:"code"`
 	RedirectURI        string `json:"redirectUri"`
+	// State is the state of the authorization request of an OIDC identity provider, which the nonce of the request is bound to.
+	State string `json:"state"`
 }
 
 type SignUp struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Email is optional, a verification email is sent to it if SMTP is configured.
+	Email string `json:"email"`
+	// InviteCode is the code of an invitation, required if the signup mode is INVITE_ONLY.
+	InviteCode string `json:"inviteCode"`
 }
 
 func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
@@ -40,6 +52,9 @@ func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
 		if err := json.NewDecoder(c.Request().Body).Decode(signin); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted signin request").SetInternal(err)
 		}
+		if err := s.checkSignInLockout(c, signin.Username); err != nil {
+			return err
+		}
 
 		user, err := s.Store.GetUser(ctx, &store.FindUser{
 			Username: &signin.Username,
@@ -47,19 +62,41 @@ func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Incorrect login credentials, please try again")
 		}
-		if user == nil {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Incorrect login credentials, please try again")
-		} else if user.RowStatus == store.Archived {
+		if user != nil && user.RowStatus == store.Archived {
 			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", signin.Username))
 		}
 
 		// Compare the stored hashed password, with the hashed version of the password that was received.
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(signin.Password)); err != nil {
-			// If the two passwords don't match, return a 401 status.
-			return echo.NewHTTPError(http.StatusUnauthorized, "Incorrect login credentials, please try again")
+		if user == nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(signin.Password)) != nil {
+			userID := UnknownID
+			if user != nil {
+				userID = user.ID
+			}
+			// Fall back to the LDAP directories before rejecting the credentials.
+			user, err = s.signInWithLDAP(ctx, signin.Username, signin.Password)
+			if err != nil {
+				return err
+			}
+			if user == nil {
+				if err := s.recordSignInFailure(c, userID, signin.Username); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized, "Incorrect login credentials, please try again")
+			}
 		}
 
-		if err := auth.GenerateTokensAndSetCookies(c, user, s.Secret); err != nil {
+		challenge, err := s.getTwoFactorChallenge(ctx, user)
+		if err != nil {
+			return err
+		}
+		if challenge != nil {
+			return c.JSON(http.StatusOK, challenge)
+		}
+
+		if err := s.clearSignInFailures(ctx, signin.Username); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to clear sign-in failures").SetInternal(err)
+		}
+		if err := s.createUserSessionAndSetCookies(c, user); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
 		}
 		if err := s.createAuthSignInActivity(c, user); err != nil {
@@ -86,67 +123,17 @@ func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, "Identity provider not found")
 		}
 
-		var userInfo *idp.IdentityProviderUserInfo
-		if identityProvider.Type == store.IdentityProviderOAuth2Type {
-			oauth2IdentityProvider, err := oauth2.NewIdentityProvider(identityProvider.Config.OAuth2Config)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create identity provider instance").SetInternal(err)
-			}
-			token, err := oauth2IdentityProvider.ExchangeToken(ctx, signin.RedirectURI, signin.Code)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to exchange token").SetInternal(err)
-			}
-			userInfo, err = oauth2IdentityProvider.UserInfo(token)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user info").SetInternal(err)
-			}
-		}
-
-		identifierFilter := identityProvider.IdentifierFilter
-		if identifierFilter != "" {
-			identifierFilterRegex, err := regexp.Compile(identifierFilter)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compile identifier filter").SetInternal(err)
-			}
-			if !identifierFilterRegex.MatchString(userInfo.Identifier) {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Access denied, identifier does not match the filter.").SetInternal(err)
-			}
+		userInfo, err := s.getSSOUserInfo(c, identityProvider, signin)
+		if err != nil {
+			return err
 		}
 
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			Username: &userInfo.Identifier,
-		})
+		user, err := s.findOrCreateIdentityProviderUser(ctx, identityProvider, userInfo)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Incorrect login credentials, please try again")
-		}
-		if user == nil {
-			userCreate := &store.User{
-				Username: userInfo.Identifier,
-				// The new signup user should be normal user by default.
-				Role:     store.RoleUser,
-				Nickname: userInfo.DisplayName,
-				Email:    userInfo.Email,
-				OpenID:   util.GenUUID(),
-			}
-			password, err := util.RandomString(20)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate random password").SetInternal(err)
-			}
-			passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
-			}
-			userCreate.PasswordHash = string(passwordHash)
-			user, err = s.Store.CreateUser(ctx, userCreate)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
-			}
-		}
-		if user.RowStatus == store.Archived {
-			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", userInfo.Identifier))
+			return err
 		}
 
-		if err := auth.GenerateTokensAndSetCookies(c, user, s.Secret); err != nil {
+		if err := s.createUserSessionAndSetCookies(c, user); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
 		}
 		if err := s.createAuthSignInActivity(c, user); err != nil {
@@ -162,6 +149,12 @@ func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
 		if err := json.NewDecoder(c.Request().Body).Decode(signup); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted signup request").SetInternal(err)
 		}
+		if signup.Email != "" && !util.ValidateEmail(signup.Email) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid email format")
+		}
+		if err := s.checkSignInLockout(c, ""); err != nil {
+			return err
+		}
 
 		hostUserType := store.RoleHost
 		existedHostUsers, err := s.Store.ListUsers(ctx, &store.FindUser{
@@ -176,58 +169,265 @@ func (s *APIV1Service) registerAuthRoutes(g *echo.Group) {
 			// The new signup user should be normal user by default.
 			Role:     store.RoleUser,
 			Nickname: signup.Username,
+			Email:    signup.Email,
 			OpenID:   util.GenUUID(),
 		}
+		var invitation *store.Invitation
 		if len(existedHostUsers) == 0 {
 			// Change the default role to host if there is no host user.
 			userCreate.Role = store.RoleHost
 		} else {
-			allowSignUpSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
-				Name: SystemSettingAllowSignUpName.String(),
-			})
+			signUpMode, err := s.getSignUpMode(ctx)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find system setting").SetInternal(err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get signup mode").SetInternal(err)
 			}
-
-			allowSignUpSettingValue := false
-			if allowSignUpSetting != nil {
-				err = json.Unmarshal([]byte(allowSignUpSetting.Value), &allowSignUpSettingValue)
-				if err != nil {
-					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unmarshal system setting allow signup").SetInternal(err)
+			if signUpMode == SignUpModeDisabled || (signUpMode == SignUpModeInviteOnly && signup.InviteCode == "") {
+				if err := s.recordSignInFailure(c, UnknownID, ""); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
 				}
+				return echo.NewHTTPError(http.StatusUnauthorized, "signup is disabled")
 			}
-			if !allowSignUpSettingValue {
-				return echo.NewHTTPError(http.StatusUnauthorized, "signup is disabled").SetInternal(err)
+			if signup.InviteCode != "" {
+				invitation, err = s.findSignUpInvitation(ctx, signup.InviteCode, signup.Email)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find invitation").SetInternal(err)
+				}
+				if invitation == nil {
+					if err := s.recordSignInFailure(c, UnknownID, ""); err != nil {
+						return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
+					}
+					return echo.NewHTTPError(http.StatusUnauthorized, "Invalid invitation code")
+				}
+				userCreate.Role = invitation.Role
 			}
 		}
 
+		existedUser, err := s.Store.GetUser(ctx, &store.FindUser{
+			Username: &userCreate.Username,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if existedUser != nil {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Username %s already exists", userCreate.Username))
+		}
+
 		passwordHash, err := bcrypt.GenerateFromPassword([]byte(signup.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
 		}
 
 		userCreate.PasswordHash = string(passwordHash)
-		user, err := s.Store.CreateUser(ctx, userCreate)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
+		var user *store.User
+		if invitation != nil {
+			user, err = s.Store.CreateInvitedUser(ctx, userCreate, invitation.ID, time.Now().Unix())
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
+			}
+			if user == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid invitation code")
+			}
+		} else {
+			user, err = s.Store.CreateUser(ctx, userCreate)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
+			}
 		}
-		if err := auth.GenerateTokensAndSetCookies(c, user, s.Secret); err != nil {
+		if err := s.createUserSessionAndSetCookies(c, user); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
 		}
 		if err := s.createAuthSignUpActivity(c, user); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
 		}
+		if user.Email != "" {
+			// The signup succeeds without the verification email, it can be sent again later.
+			sender, err := s.getMailSender(ctx)
+			if err != nil {
+				log.WithContext(ctx).Warn("failed to get mail sender", zap.Error(err))
+			} else if sender != nil {
+				if err := s.sendVerificationMail(ctx, sender, user, store.VerificationTokenEmailVerification); err != nil {
+					log.WithContext(ctx).Warn(fmt.Sprintf("failed to send verification email to user %d", user.ID), zap.Error(err))
+				}
+			}
+		}
 
 		return c.JSON(http.StatusOK, user)
 	})
 
 	// POST /auth/signout - Sign out.
 	g.POST("/auth/signout", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if sessionID := findSessionID(c, s.Secret); sessionID != "" {
+			if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+				ID: &sessionID,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke session").SetInternal(err)
+			}
+		}
 		auth.RemoveTokensAndCookies(c)
 		return c.JSON(http.StatusOK, true)
 	})
 }
 
+// getSSOUserInfo exchanges the authorization code of the OAuth2 or OIDC identity provider for the user info.
+func (*APIV1Service) getSSOUserInfo(c echo.Context, identityProvider *store.IdentityProvider, signin *SSOSignIn) (*idp.IdentityProviderUserInfo, error) {
+	ctx := c.Request().Context()
+	switch identityProvider.Type {
+	case store.IdentityProviderOAuth2Type:
+		oauth2IdentityProvider, err := oauth2.NewIdentityProvider(identityProvider.Config.OAuth2Config)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to create identity provider instance").SetInternal(err)
+		}
+		token, err := oauth2IdentityProvider.ExchangeToken(ctx, signin.RedirectURI, signin.Code)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to exchange token").SetInternal(err)
+		}
+		userInfo, err := oauth2IdentityProvider.UserInfo(token)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user info").SetInternal(err)
+		}
+		return userInfo, nil
+	case store.IdentityProviderOIDCType:
+		nonce, err := popOIDCNonce(c, signin.State)
+		if err != nil {
+			return nil, err
+		}
+		oidcIdentityProvider, err := oidc.NewIdentityProvider(identityProvider.Config.OIDCConfig)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to create identity provider instance").SetInternal(err)
+		}
+		userInfo, err := oidcIdentityProvider.UserInfo(ctx, signin.RedirectURI, signin.Code, nonce)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "Failed to verify identity").SetInternal(err)
+		}
+		return userInfo, nil
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported identity provider type %s", identityProvider.Type))
+	}
+}
+
+// findOrCreateIdentityProviderUser resolves the user of the identity by its linked identity first,
+// then by the identity link policy, creating a new user linked to the identity if none is found.
+func (s *APIV1Service) findOrCreateIdentityProviderUser(ctx context.Context, identityProvider *store.IdentityProvider, userInfo *idp.IdentityProviderUserInfo) (*store.User, error) {
+	if err := checkIdentifierFilter(identityProvider, userInfo); err != nil {
+		return nil, err
+	}
+
+	user, err := s.findIdentityProviderUser(ctx, identityProvider, userInfo)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		userCreate := &store.User{
+			Username: userInfo.Identifier,
+			// The new signup user should be normal user by default.
+			Role:     store.RoleUser,
+			Nickname: userInfo.DisplayName,
+			Email:    userInfo.Email,
+			OpenID:   util.GenUUID(),
+			// The email is verified if the identity provider asserts it.
+			EmailVerified: userInfo.Email != "" && userInfo.EmailVerified,
+		}
+		existedUser, err := s.Store.GetUser(ctx, &store.FindUser{
+			Username: &userCreate.Username,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if existedUser != nil {
+			return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Username %s already exists, sign in and link the identity provider from the settings", userCreate.Username))
+		}
+		password, err := util.RandomString(20)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate random password").SetInternal(err)
+		}
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
+		}
+		userCreate.PasswordHash = string(passwordHash)
+		user, err = s.Store.CreateUser(ctx, userCreate)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
+		}
+	}
+	if user.RowStatus == store.Archived {
+		return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", user.Username))
+	}
+	if err := s.linkUserIdentity(ctx, user, identityProvider, userInfo); err != nil {
+		return nil, err
+	}
+	if err := s.syncIdentityProviderGroups(ctx, user, identityProvider, userInfo); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func checkIdentifierFilter(identityProvider *store.IdentityProvider, userInfo *idp.IdentityProviderUserInfo) error {
+	identifierFilter := identityProvider.IdentifierFilter
+	if identifierFilter == "" {
+		return nil
+	}
+	identifierFilterRegex, err := regexp.Compile(identifierFilter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compile identifier filter").SetInternal(err)
+	}
+	if !identifierFilterRegex.MatchString(userInfo.Identifier) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Access denied, identifier does not match the filter.")
+	}
+	return nil
+}
+
+// signInWithLDAP authenticates the username and password against the LDAP identity providers.
+// It returns nil user if no directory accepts the credentials.
+func (s *APIV1Service) signInWithLDAP(ctx context.Context, username, password string) (*store.User, error) {
+	identityProviders, err := s.Store.ListIdentityProviders(ctx, &store.FindIdentityProvider{})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity provider list").SetInternal(err)
+	}
+
+	for _, identityProvider := range identityProviders {
+		if identityProvider.Type != store.IdentityProviderLDAPType {
+			continue
+		}
+		ldapIdentityProvider, err := ldap.NewIdentityProvider(identityProvider.Config.LDAPConfig)
+		if err != nil {
+			log.WithContext(ctx).Warn(fmt.Sprintf("invalid ldap identity provider %d", identityProvider.ID), zap.Error(err))
+			continue
+		}
+		userInfo, err := ldapIdentityProvider.Authenticate(username, password)
+		if err != nil {
+			if !errors.Is(err, ldap.ErrInvalidCredentials) {
+				log.WithContext(ctx).Warn(fmt.Sprintf("failed to authenticate with ldap identity provider %d", identityProvider.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		user, err := s.findOrCreateIdentityProviderUser(ctx, identityProvider, userInfo)
+		if err != nil {
+			return nil, err
+		}
+		// Sync the role from the directory groups, the host is never demoted.
+		if len(identityProvider.Config.LDAPConfig.AdminGroups) > 0 && user.Role != store.RoleHost {
+			role := store.RoleUser
+			if ldapIdentityProvider.IsAdmin(userInfo.Groups) {
+				role = store.RoleAdmin
+			}
+			if user.Role != role {
+				user, err = s.Store.UpdateUser(ctx, &store.UpdateUser{
+					ID:   user.ID,
+					Role: &role,
+				})
+				if err != nil {
+					return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to update user role").SetInternal(err)
+				}
+			}
+		}
+		return user, nil
+	}
+	return nil, nil
+}
+
 func (s *APIV1Service) createAuthSignInActivity(c echo.Context, user *store.User) error {
 	ctx := c.Request().Context()
 	payload := ActivityUserAuthSignInPayload{
@@ -20,18 +20,27 @@ const (
 	AccessTokenAudienceName = "user.access-token"
 	// RefreshTokenAudienceName is the audience name of the refresh token.
 	RefreshTokenAudienceName = "user.refresh-token"
-	apiTokenDuration         = 2 * time.Hour
-	accessTokenDuration      = 24 * time.Hour
-	refreshTokenDuration     = 7 * 24 * time.Hour
+	// TwoFactorChallengeTokenAudienceName is the audience name of the token between the password and the two-factor sign-in steps.
+	TwoFactorChallengeTokenAudienceName = "user.two-factor-challenge-token"
+	apiTokenDuration                    = 2 * time.Hour
+	accessTokenDuration                 = 24 * time.Hour
+	// RefreshTokenDuration is also the lifetime of a session without activity.
+	RefreshTokenDuration            = 7 * 24 * time.Hour
+	twoFactorChallengeTokenDuration = 5 * time.Minute
 	// RefreshThresholdDuration is the threshold duration for refreshing token.
 	RefreshThresholdDuration = 1 * time.Hour
 
+	// ImpersonationTokenAudienceName is the audience name of the token issued to the host to act as another user.
+	ImpersonationTokenAudienceName = "user.impersonation-token"
+	// MaxImpersonationTokenDuration is the max lifetime of an impersonation token, it is never refreshed.
+	MaxImpersonationTokenDuration = 1 * time.Hour
+
 	// CookieExpDuration expires slightly earlier than the jwt expiration. Client would be logged out if the user
 	// cookie expires, thus the client would always logout first before attempting to make a request with the expired jwt.
 	// Suppose we have a valid refresh token, we will refresh the token in 2 cases:
 	// 1. The access token is about to expire in <<refreshThresholdDuration>>
 	// 2. The access token has already expired, we refresh the token so that the ongoing request can pass through.
-	CookieExpDuration = refreshTokenDuration - 1*time.Minute
+	CookieExpDuration = RefreshTokenDuration - 1*time.Minute
 	// AccessTokenCookieName is the cookie name of access token.
 	AccessTokenCookieName = "memos.access-token"
 	// RefreshTokenCookieName is the cookie name of refresh token.
@@ -40,30 +49,48 @@ const (
 
 type claimsMessage struct {
 	Name string `json:"name"`
+	// ImpersonatorID is the host acting as the user of the impersonation token.
+	ImpersonatorID int `json:"impersonatorId,omitempty"`
+	// AllowWrite is whether the impersonation token is allowed to make write requests.
+	AllowWrite bool `json:"allowWrite,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateAPIToken generates an API token.
-func GenerateAPIToken(userName string, userID int, secret string) (string, error) {
+// GenerateAPIToken generates an API token of the session.
+func GenerateAPIToken(userName string, userID int, sessionID string, secret string) (string, error) {
 	expirationTime := time.Now().Add(apiTokenDuration)
-	return generateToken(userName, userID, AccessTokenAudienceName, expirationTime, []byte(secret))
+	return generateToken(userName, userID, sessionID, AccessTokenAudienceName, expirationTime, []byte(secret))
 }
 
-// GenerateAccessToken generates an access token for web.
-func GenerateAccessToken(userName string, userID int, secret string) (string, error) {
+// GenerateAccessToken generates an access token of the session for web.
+func GenerateAccessToken(userName string, userID int, sessionID string, secret string) (string, error) {
 	expirationTime := time.Now().Add(accessTokenDuration)
-	return generateToken(userName, userID, AccessTokenAudienceName, expirationTime, []byte(secret))
+	return generateToken(userName, userID, sessionID, AccessTokenAudienceName, expirationTime, []byte(secret))
+}
+
+// GenerateRefreshToken generates a refresh token of the session for web.
+func GenerateRefreshToken(userName string, userID int, sessionID string, secret string) (string, error) {
+	expirationTime := time.Now().Add(RefreshTokenDuration)
+	return generateToken(userName, userID, sessionID, RefreshTokenAudienceName, expirationTime, []byte(secret))
+}
+
+// GenerateTwoFactorChallengeToken generates a short-lived token proving the password step of the sign-in.
+func GenerateTwoFactorChallengeToken(userName string, userID int, secret string) (string, error) {
+	expirationTime := time.Now().Add(twoFactorChallengeTokenDuration)
+	return generateToken(userName, userID, "", TwoFactorChallengeTokenAudienceName, expirationTime, []byte(secret))
 }
 
-// GenerateRefreshToken generates a refresh token for web.
-func GenerateRefreshToken(userName string, userID int, secret string) (string, error) {
-	expirationTime := time.Now().Add(refreshTokenDuration)
-	return generateToken(userName, userID, RefreshTokenAudienceName, expirationTime, []byte(secret))
+// GenerateImpersonationToken generates a token for the impersonator to act as the user, the token id is the session of the impersonator.
+func GenerateImpersonationToken(userName string, userID int, impersonatorID int, sessionID string, allowWrite bool, expirationTime time.Time, secret string) (string, error) {
+	claims := newClaimsMessage(userName, userID, sessionID, ImpersonationTokenAudienceName, expirationTime)
+	claims.ImpersonatorID = impersonatorID
+	claims.AllowWrite = allowWrite
+	return signToken(claims, []byte(secret))
 }
 
-// GenerateTokensAndSetCookies generates jwt token and saves it to the http-only cookie.
-func GenerateTokensAndSetCookies(c echo.Context, user *store.User, secret string) error {
-	accessToken, err := GenerateAccessToken(user.Username, user.ID, secret)
+// GenerateTokensAndSetCookies generates jwt token of the session and saves it to the http-only cookie.
+func GenerateTokensAndSetCookies(c echo.Context, user *store.User, sessionID string, secret string) error {
+	accessToken, err := GenerateAccessToken(user.Username, user.ID, sessionID, secret)
 	if err != nil {
 		return errors.Wrap(err, "failed to generate access token")
 	}
@@ -72,7 +99,7 @@ func GenerateTokensAndSetCookies(c echo.Context, user *store.User, secret string
 	setTokenCookie(c, AccessTokenCookieName, accessToken, cookieExp)
 
 	// We generate here a new refresh token and saving it to the cookie.
-	refreshToken, err := GenerateRefreshToken(user.Username, user.ID, secret)
+	refreshToken, err := GenerateRefreshToken(user.Username, user.ID, sessionID, secret)
 	if err != nil {
 		return errors.Wrap(err, "failed to generate refresh token")
 	}
@@ -102,10 +129,14 @@ func setTokenCookie(c echo.Context, name, token string, expiration time.Time) {
 	c.SetCookie(cookie)
 }
 
-// generateToken generates a jwt token.
-func generateToken(username string, userID int, aud string, expirationTime time.Time, secret []byte) (string, error) {
+// generateToken generates a jwt token, the token id is the id of the session it belongs to.
+func generateToken(username string, userID int, sessionID string, aud string, expirationTime time.Time, secret []byte) (string, error) {
+	return signToken(newClaimsMessage(username, userID, sessionID, aud, expirationTime), secret)
+}
+
+func newClaimsMessage(username string, userID int, sessionID string, aud string, expirationTime time.Time) *claimsMessage {
 	// Create the JWT claims, which includes the username and expiry time.
-	claims := &claimsMessage{
+	return &claimsMessage{
 		Name: username,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Audience: jwt.ClaimStrings{aud},
@@ -114,9 +145,13 @@ func generateToken(username string, userID int, aud string, expirationTime time.
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    issuer,
 			Subject:   strconv.Itoa(userID),
+			ID:        sessionID,
 		},
 	}
+}
 
+// signToken signs the claims with the HS256 algorithm.
+func signToken(claims *claimsMessage, secret []byte) (string, error) {
 	// Declare the token with the HS256 algorithm used for signing, and the claims.
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	token.Header["kid"] = keyID
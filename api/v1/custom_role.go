@@ -0,0 +1,385 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/store"
+)
+
+type CustomRole struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type CreateCustomRoleRequest struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func (create CreateCustomRoleRequest) Validate() error {
+	if err := validateCustomRoleName(create.Name); err != nil {
+		return err
+	}
+	if len(create.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return validatePermissions(create.Permissions)
+}
+
+type UpdateCustomRoleRequest struct {
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func (update UpdateCustomRoleRequest) Validate() error {
+	if update.Name != nil {
+		if err := validateCustomRoleName(*update.Name); err != nil {
+			return err
+		}
+	}
+	if update.Description != nil && len(*update.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return validatePermissions(update.Permissions)
+}
+
+type SetUserCustomRolesRequest struct {
+	RoleIDList []int `json:"roleIdList"`
+}
+
+func validateCustomRoleName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("name is too long, maximum length is 64")
+	}
+	switch Role(strings.ToUpper(name)) {
+	case RoleHost, RoleAdmin, RoleUser:
+		return fmt.Errorf("name %s is reserved by the built-in role", name)
+	}
+	return nil
+}
+
+func validatePermissions(permissions []Permission) error {
+	for _, permission := range permissions {
+		if !isValidPermission(permission) {
+			return fmt.Errorf("invalid permission %s", permission)
+		}
+	}
+	return nil
+}
+
+func (s *APIV1Service) registerCustomRoleRoutes(g *echo.Group) {
+	// GET /permission - List all the permissions which can be granted.
+	g.GET("/permission", func(c echo.Context) error {
+		if _, err := s.getCurrentUser(c); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, permissionList)
+	})
+
+	g.GET("/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionRoleManage); err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListCustomRoles(ctx, &store.FindCustomRole{})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+		}
+		customRoleList := []*CustomRole{}
+		for _, customRole := range list {
+			customRoleList = append(customRoleList, convertCustomRoleFromStore(customRole))
+		}
+		return c.JSON(http.StatusOK, customRoleList)
+	})
+
+	g.POST("/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionRoleManage)
+		if err != nil {
+			return err
+		}
+
+		request := &CreateCustomRoleRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post custom role request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid custom role request").SetInternal(err)
+		}
+		if err := s.checkPermissionsGrantable(c, currentUser, request.Permissions); err != nil {
+			return err
+		}
+		if err := s.checkCustomRoleNameAvailable(c, request.Name, UnknownID); err != nil {
+			return err
+		}
+
+		customRole, err := s.Store.CreateCustomRole(ctx, &store.CustomRole{
+			CreatorID:   currentUser.ID,
+			Name:        request.Name,
+			Description: request.Description,
+			Permissions: convertPermissionsToStore(request.Permissions),
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create custom role").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertCustomRoleFromStore(customRole))
+	})
+
+	g.PATCH("/role/:roleId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionRoleManage)
+		if err != nil {
+			return err
+		}
+		customRole, err := s.findCustomRoleByParam(c)
+		if err != nil {
+			return err
+		}
+		// Changing the role changes the permissions of its users, so both the old and new permissions have to be grantable.
+		if err := s.checkPermissionsGrantable(c, currentUser, convertPermissionsFromStore(customRole.Permissions)); err != nil {
+			return err
+		}
+
+		request := &UpdateCustomRoleRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch custom role request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid custom role request").SetInternal(err)
+		}
+		if err := s.checkPermissionsGrantable(c, currentUser, request.Permissions); err != nil {
+			return err
+		}
+		if request.Name != nil {
+			if err := s.checkCustomRoleNameAvailable(c, *request.Name, customRole.ID); err != nil {
+				return err
+			}
+		}
+
+		currentTs := time.Now().Unix()
+		update := &store.UpdateCustomRole{
+			ID:          customRole.ID,
+			UpdatedTs:   &currentTs,
+			Name:        request.Name,
+			Description: request.Description,
+		}
+		if request.Permissions != nil {
+			update.Permissions = convertPermissionsToStore(request.Permissions)
+		}
+		customRole, err = s.Store.UpdateCustomRole(ctx, update)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch custom role").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertCustomRoleFromStore(customRole))
+	})
+
+	g.DELETE("/role/:roleId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionRoleManage)
+		if err != nil {
+			return err
+		}
+		customRole, err := s.findCustomRoleByParam(c)
+		if err != nil {
+			return err
+		}
+		if err := s.checkPermissionsGrantable(c, currentUser, convertPermissionsFromStore(customRole.Permissions)); err != nil {
+			return err
+		}
+
+		if err := s.Store.DeleteCustomRole(ctx, &store.DeleteCustomRole{
+			ID: customRole.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete custom role").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	// GET /user/:id/role - List the custom roles assigned to the user.
+	g.GET("/user/:id/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		userID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
+		}
+		if userID != currentUser.ID {
+			if _, err := s.checkPermission(c, PermissionRoleManage); err != nil {
+				return err
+			}
+		}
+
+		list, err := s.Store.ListCustomRoles(ctx, &store.FindCustomRole{
+			UserID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+		}
+		customRoleList := []*CustomRole{}
+		for _, customRole := range list {
+			customRoleList = append(customRoleList, convertCustomRoleFromStore(customRole))
+		}
+		return c.JSON(http.StatusOK, customRoleList)
+	})
+
+	// PUT /user/:id/role - Replace the custom roles assigned to the user.
+	g.PUT("/user/:id/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionRoleManage)
+		if err != nil {
+			return err
+		}
+		userID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User not found with ID: %d", userID))
+		}
+		if err := s.checkUserManageable(c, currentUser, user); err != nil {
+			return err
+		}
+
+		request := &SetUserCustomRolesRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted put user custom roles request").SetInternal(err)
+		}
+
+		// Both the unassigned and assigned roles have to be grantable by the current user.
+		assignedList, err := s.Store.ListCustomRoles(ctx, &store.FindCustomRole{
+			UserID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+		}
+		permissions := []Permission{}
+		for _, customRole := range assignedList {
+			permissions = append(permissions, convertPermissionsFromStore(customRole.Permissions)...)
+		}
+		customRoleList := []*CustomRole{}
+		for _, roleID := range request.RoleIDList {
+			roleID := roleID
+			customRole, err := s.Store.GetCustomRole(ctx, &store.FindCustomRole{
+				ID: &roleID,
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role").SetInternal(err)
+			}
+			if customRole == nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Custom role not found: %d", roleID))
+			}
+			permissions = append(permissions, convertPermissionsFromStore(customRole.Permissions)...)
+			customRoleList = append(customRoleList, convertCustomRoleFromStore(customRole))
+		}
+		if err := s.checkPermissionsGrantable(c, currentUser, permissions); err != nil {
+			return err
+		}
+
+		if err := s.Store.SetUserCustomRoles(ctx, userID, request.RoleIDList); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set user custom roles").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, customRoleList)
+	})
+}
+
+func (s *APIV1Service) findCustomRoleByParam(c echo.Context) (*store.CustomRole, error) {
+	roleID, err := strconv.Atoi(c.Param("roleId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("roleId"))).SetInternal(err)
+	}
+	customRole, err := s.Store.GetCustomRole(c.Request().Context(), &store.FindCustomRole{
+		ID: &roleID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role").SetInternal(err)
+	}
+	if customRole == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Custom role not found: %d", roleID))
+	}
+	return customRole, nil
+}
+
+// checkPermissionsGrantable rejects granting the permissions not granted to the current user, preventing privilege escalation.
+func (s *APIV1Service) checkPermissionsGrantable(c echo.Context, currentUser *store.User, permissions []Permission) error {
+	currentPermissions, err := getUserPermissions(c.Request().Context(), s.Store, currentUser)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+	}
+	for _, permission := range permissions {
+		if !containsPermission(currentPermissions, permission) {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Permission %s is not granted to the current user", permission))
+		}
+	}
+	return nil
+}
+
+func (s *APIV1Service) checkCustomRoleNameAvailable(c echo.Context, name string, roleID int) error {
+	customRole, err := s.Store.GetCustomRole(c.Request().Context(), &store.FindCustomRole{
+		Name: &name,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role").SetInternal(err)
+	}
+	if customRole != nil && customRole.ID != roleID {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Custom role %s already exists", name))
+	}
+	return nil
+}
+
+func convertCustomRoleFromStore(customRole *store.CustomRole) *CustomRole {
+	return &CustomRole{
+		ID:          customRole.ID,
+		CreatorID:   customRole.CreatorID,
+		CreatedTs:   customRole.CreatedTs,
+		UpdatedTs:   customRole.UpdatedTs,
+		Name:        customRole.Name,
+		Description: customRole.Description,
+		Permissions: convertPermissionsFromStore(customRole.Permissions),
+	}
+}
+
+func convertPermissionsFromStore(permissions []string) []Permission {
+	list := []Permission{}
+	for _, permission := range permissions {
+		list = append(list, Permission(permission))
+	}
+	return list
+}
+
+func convertPermissionsToStore(permissions []Permission) []string {
+	list := []string{}
+	for _, permission := range permissions {
+		list = append(list, permission.String())
+	}
+	return list
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/store"
+)
+
+type EmailVerificationRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *APIV1Service) registerEmailVerificationRoutes(g *echo.Group) {
+	// POST /auth/email-verification - Verify the email with the token of the verification email.
+	g.POST("/auth/email-verification", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		request := &EmailVerificationRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted email verification request").SetInternal(err)
+		}
+
+		verificationToken, err := s.findVerificationToken(ctx, store.VerificationTokenEmailVerification, request.Token)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find verification token").SetInternal(err)
+		}
+		if verificationToken == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired verification token")
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &verificationToken.UserID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		// The token is only valid for the email it was sent to.
+		if user == nil || user.Email != verificationToken.Email {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired verification token")
+		}
+
+		currentTs := time.Now().Unix()
+		emailVerified := true
+		user, err = s.Store.UpdateUser(ctx, &store.UpdateUser{
+			ID:            user.ID,
+			UpdatedTs:     &currentTs,
+			EmailVerified: &emailVerified,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch user").SetInternal(err)
+		}
+		if err := s.Store.DeleteVerificationToken(ctx, &store.DeleteVerificationToken{
+			ID: &verificationToken.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete verification token").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertUserFromStore(user))
+	})
+
+	// POST /user/me/email-verification - Send the verification email again.
+	g.POST("/user/me/email-verification", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		if user.Email == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Email is not set")
+		}
+		if user.EmailVerified {
+			return echo.NewHTTPError(http.StatusBadRequest, "Email is already verified")
+		}
+
+		sender, err := s.getMailSender(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get mail sender").SetInternal(err)
+		}
+		if sender == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "SMTP is not configured")
+		}
+		throttled, err := s.isVerificationMailThrottled(ctx, user.ID, store.VerificationTokenEmailVerification)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find verification token").SetInternal(err)
+		}
+		if throttled {
+			return echo.NewHTTPError(http.StatusTooManyRequests, "Verification email was sent recently, please try again later")
+		}
+		if err := s.sendVerificationMail(ctx, sender, user, store.VerificationTokenEmailVerification); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to send verification email").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
@@ -0,0 +1,427 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/common/log"
+	getter "github.com/usememos/memos/plugin/http-getter"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultFeedSubscriptionPollInterval is the default poll interval in seconds.
+	defaultFeedSubscriptionPollInterval = 60 * 60
+	// minFeedSubscriptionPollInterval is the minimum poll interval in seconds.
+	minFeedSubscriptionPollInterval = 5 * 60
+)
+
+type FeedSubscription struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	RowStatus RowStatus `json:"rowStatus"`
+	CreatorID int       `json:"creatorId"`
+	CreatedTs int64     `json:"createdTs"`
+	UpdatedTs int64     `json:"updatedTs"`
+
+	// Domain specific fields
+	URL          string     `json:"url"`
+	PollInterval int        `json:"pollInterval"`
+	Visibility   Visibility `json:"visibility"`
+	Tags         []string   `json:"tags"`
+	LastPolledTs int64      `json:"lastPolledTs"`
+}
+
+type CreateFeedSubscriptionRequest struct {
+	URL          string     `json:"url"`
+	PollInterval int        `json:"pollInterval"`
+	Visibility   Visibility `json:"visibility"`
+	Tags         []string   `json:"tags"`
+}
+
+func (create *CreateFeedSubscriptionRequest) Validate() error {
+	feedURL, err := url.Parse(create.URL)
+	if err != nil {
+		return fmt.Errorf("invalid feed url")
+	}
+	if feedURL.Scheme != "http" && feedURL.Scheme != "https" {
+		return fmt.Errorf("invalid feed url scheme")
+	}
+	if create.PollInterval == 0 {
+		create.PollInterval = defaultFeedSubscriptionPollInterval
+	}
+	if create.PollInterval < minFeedSubscriptionPollInterval {
+		return fmt.Errorf("poll interval is too short, minimum is %d seconds", minFeedSubscriptionPollInterval)
+	}
+	if create.Visibility == "" {
+		create.Visibility = Private
+	}
+	if create.Visibility != Public && create.Visibility != Protected && create.Visibility != Private {
+		return fmt.Errorf("invalid visibility")
+	}
+	return validateFeedSubscriptionTags(create.Tags)
+}
+
+type UpdateFeedSubscriptionRequest struct {
+	RowStatus    *RowStatus  `json:"rowStatus"`
+	PollInterval *int        `json:"pollInterval"`
+	Visibility   *Visibility `json:"visibility"`
+	Tags         *[]string   `json:"tags"`
+}
+
+func (update UpdateFeedSubscriptionRequest) Validate() error {
+	if update.PollInterval != nil && *update.PollInterval < minFeedSubscriptionPollInterval {
+		return fmt.Errorf("poll interval is too short, minimum is %d seconds", minFeedSubscriptionPollInterval)
+	}
+	if v := update.Visibility; v != nil && *v != Public && *v != Protected && *v != Private {
+		return fmt.Errorf("invalid visibility")
+	}
+	if update.Tags != nil {
+		return validateFeedSubscriptionTags(*update.Tags)
+	}
+	return nil
+}
+
+func validateFeedSubscriptionTags(tags []string) error {
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, " ,#") {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+	return nil
+}
+
+func (s *APIV1Service) registerFeedSubscriptionRoutes(g *echo.Group) {
+	g.POST("/feed-subscription", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		request := &CreateFeedSubscriptionRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post feed subscription request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid feed subscription request").SetInternal(err)
+		}
+
+		existed, err := s.Store.GetFeedSubscription(ctx, &store.FindFeedSubscription{
+			CreatorID: &userID,
+			URL:       &request.URL,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find feed subscription").SetInternal(err)
+		}
+		if existed != nil {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Feed subscription already exists: %s", request.URL))
+		}
+
+		feedSubscription, err := s.Store.CreateFeedSubscription(ctx, &store.FeedSubscription{
+			CreatorID:    userID,
+			URL:          request.URL,
+			PollInterval: request.PollInterval,
+			Visibility:   store.Visibility(request.Visibility),
+			Tags:         request.Tags,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create feed subscription").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertFeedSubscriptionFromStore(feedSubscription))
+	})
+
+	g.GET("/feed-subscription", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		list, err := s.Store.ListFeedSubscriptions(ctx, &store.FindFeedSubscription{
+			CreatorID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find feed subscription list").SetInternal(err)
+		}
+		feedSubscriptionList := []*FeedSubscription{}
+		for _, feedSubscription := range list {
+			feedSubscriptionList = append(feedSubscriptionList, convertFeedSubscriptionFromStore(feedSubscription))
+		}
+		return c.JSON(http.StatusOK, feedSubscriptionList)
+	})
+
+	g.PATCH("/feed-subscription/:feedSubscriptionId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		feedSubscription, err := s.findFeedSubscriptionByParam(c, userID)
+		if err != nil {
+			return err
+		}
+
+		request := &UpdateFeedSubscriptionRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch feed subscription request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid feed subscription request").SetInternal(err)
+		}
+
+		currentTs := time.Now().Unix()
+		update := &store.UpdateFeedSubscription{
+			ID:           feedSubscription.ID,
+			UpdatedTs:    &currentTs,
+			PollInterval: request.PollInterval,
+			Tags:         request.Tags,
+		}
+		if request.RowStatus != nil {
+			rowStatus := store.RowStatus(request.RowStatus.String())
+			update.RowStatus = &rowStatus
+		}
+		if request.Visibility != nil {
+			visibility := store.Visibility(request.Visibility.String())
+			update.Visibility = &visibility
+		}
+
+		feedSubscription, err = s.Store.UpdateFeedSubscription(ctx, update)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch feed subscription").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertFeedSubscriptionFromStore(feedSubscription))
+	})
+
+	g.POST("/feed-subscription/:feedSubscriptionId/poll", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		feedSubscription, err := s.findFeedSubscriptionByParam(c, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := PollFeedSubscription(ctx, s.Store, feedSubscription); err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("Failed to poll feed: %s", feedSubscription.URL)).SetInternal(err)
+		}
+
+		feedSubscription, err = s.Store.GetFeedSubscription(ctx, &store.FindFeedSubscription{
+			ID: &feedSubscription.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find feed subscription").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertFeedSubscriptionFromStore(feedSubscription))
+	})
+
+	g.DELETE("/feed-subscription/:feedSubscriptionId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		feedSubscription, err := s.findFeedSubscriptionByParam(c, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.Store.DeleteFeedSubscription(ctx, &store.DeleteFeedSubscription{
+			ID:        feedSubscription.ID,
+			CreatorID: &userID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete feed subscription").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *APIV1Service) findFeedSubscriptionByParam(c echo.Context, userID int) (*store.FeedSubscription, error) {
+	ctx := c.Request().Context()
+	feedSubscriptionID, err := strconv.Atoi(c.Param("feedSubscriptionId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("feedSubscriptionId"))).SetInternal(err)
+	}
+
+	feedSubscription, err := s.Store.GetFeedSubscription(ctx, &store.FindFeedSubscription{
+		ID:        &feedSubscriptionID,
+		CreatorID: &userID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find feed subscription").SetInternal(err)
+	}
+	if feedSubscription == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Feed subscription not found: %d", feedSubscriptionID))
+	}
+	return feedSubscription, nil
+}
+
+// PollFeedSubscription fetches the feed of the subscription and imports the entries not seen before as memos.
+// It returns the number of imported entries.
+func PollFeedSubscription(ctx context.Context, s *store.Store, feedSubscription *store.FeedSubscription) (int, error) {
+	feed, err := getter.GetFeed(ctx, feedSubscription.URL)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get feed")
+	}
+
+	entryList, err := s.ListFeedSubscriptionEntries(ctx, &store.FindFeedSubscriptionEntry{
+		SubscriptionID: &feedSubscription.ID,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list feed subscription entries")
+	}
+	importedGUIDs := map[string]bool{}
+	for _, entry := range entryList {
+		importedGUIDs[entry.GUID] = true
+	}
+
+	visibility := feedSubscription.Visibility
+	disablePublicMemos := s.GetSystemSettingValueWithDefault(&ctx, SystemSettingDisablePublicMemosName.String(), "false") == "true"
+	if disablePublicMemos {
+		creator, err := s.GetUser(ctx, &store.FindUser{
+			ID: &feedSubscription.CreatorID,
+		})
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to find user")
+		}
+		// Enforce the users except moderators to create private memo if public memos are disabled.
+		isModerator := false
+		if creator != nil {
+			if isModerator, err = hasPermission(ctx, s, creator, PermissionMemoModerate); err != nil {
+				return 0, errors.Wrap(err, "failed to find user permissions")
+			}
+		}
+		if !isModerator {
+			visibility = store.Private
+		}
+	}
+
+	importedCount := 0
+	// Feeds list the newest entries first, so import them in reverse to keep the memo order.
+	for i := len(feed.Entries) - 1; i >= 0; i-- {
+		entry := feed.Entries[i]
+		if entry.GUID == "" || importedGUIDs[entry.GUID] {
+			continue
+		}
+
+		create := &store.Memo{
+			CreatorID:  feedSubscription.CreatorID,
+			CreatedTs:  entry.PublishedTs,
+			Content:    getFeedEntryMemoContent(entry, feedSubscription.Tags),
+			Visibility: visibility,
+		}
+		memo, err := s.CreateMemo(ctx, create)
+		if err != nil {
+			return importedCount, errors.Wrap(err, "failed to create memo")
+		}
+		if entry.Link != "" {
+			if err := saveFeedEntryImage(ctx, s, memo, entry.Link); err != nil {
+				log.WithContext(ctx).Warn(fmt.Sprintf("failed to save og:image of %s", entry.Link), zap.Error(err))
+			}
+		}
+		if _, err := s.CreateFeedSubscriptionEntry(ctx, &store.FeedSubscriptionEntry{
+			SubscriptionID: feedSubscription.ID,
+			GUID:           entry.GUID,
+			MemoID:         memo.ID,
+		}); err != nil {
+			return importedCount, errors.Wrap(err, "failed to create feed subscription entry")
+		}
+		importedGUIDs[entry.GUID] = true
+		importedCount++
+	}
+
+	lastPolledTs := time.Now().Unix()
+	if _, err := s.UpdateFeedSubscription(ctx, &store.UpdateFeedSubscription{
+		ID:           feedSubscription.ID,
+		LastPolledTs: &lastPolledTs,
+	}); err != nil {
+		return importedCount, errors.Wrap(err, "failed to update feed subscription")
+	}
+	return importedCount, nil
+}
+
+func saveFeedEntryImage(ctx context.Context, s *store.Store, memo *store.Memo, link string) error {
+	image, err := getter.GetOpenGraphImage(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	filename := "og-image"
+	if extensions, _ := mime.ExtensionsByType(image.Mediatype); len(extensions) > 0 {
+		filename += extensions[0]
+	}
+	if linkURL, err := url.Parse(link); err == nil && path.Base(linkURL.Path) != "" && path.Base(linkURL.Path) != "/" {
+		filename = path.Base(linkURL.Path) + "-" + filename
+	}
+
+	create := &store.Resource{
+		CreatorID: memo.CreatorID,
+		Filename:  filename,
+		Type:      image.Mediatype,
+		Size:      int64(len(image.Blob)),
+	}
+	if err := SaveResourceBlob(ctx, s, create, bytes.NewReader(image.Blob)); err != nil {
+		return err
+	}
+	resource, err := s.CreateResource(ctx, create)
+	if err != nil {
+		return err
+	}
+	_, err = s.UpsertMemoResource(ctx, &store.UpsertMemoResource{
+		MemoID:     memo.ID,
+		ResourceID: resource.ID,
+	})
+	return err
+}
+
+func getFeedEntryMemoContent(entry *getter.FeedEntry, tags []string) string {
+	lines := []string{}
+	if entry.Title != "" {
+		lines = append(lines, "# "+entry.Title, "")
+	}
+	if entry.Summary != "" {
+		lines = append(lines, entry.Summary, "")
+	}
+	if entry.Link != "" {
+		lines = append(lines, fmt.Sprintf("[%s](%s)", entry.Link, entry.Link), "")
+	}
+	if len(tags) > 0 {
+		tagList := []string{}
+		for _, tag := range tags {
+			tagList = append(tagList, "#"+tag)
+		}
+		lines = append(lines, strings.Join(tagList, " ")+" ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func convertFeedSubscriptionFromStore(feedSubscription *store.FeedSubscription) *FeedSubscription {
+	return &FeedSubscription{
+		ID:           feedSubscription.ID,
+		RowStatus:    RowStatus(feedSubscription.RowStatus.String()),
+		CreatorID:    feedSubscription.CreatorID,
+		CreatedTs:    feedSubscription.CreatedTs,
+		UpdatedTs:    feedSubscription.UpdatedTs,
+		URL:          feedSubscription.URL,
+		PollInterval: feedSubscription.PollInterval,
+		Visibility:   Visibility(feedSubscription.Visibility.String()),
+		Tags:         feedSubscription.Tags,
+		LastPolledTs: feedSubscription.LastPolledTs,
+	}
+}
@@ -0,0 +1,555 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/plugin/idp/ldap"
+	"github.com/usememos/memos/store"
+)
+
+type Group struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// IdentityProviderGroup is the group of the identity providers whose users are synced into the group on sign-in.
+	IdentityProviderGroup string `json:"identityProviderGroup"`
+}
+
+type CreateGroupRequest struct {
+	Name                  string `json:"name"`
+	Description           string `json:"description"`
+	IdentityProviderGroup string `json:"identityProviderGroup"`
+}
+
+func (create CreateGroupRequest) Validate() error {
+	if err := validateGroupName(create.Name); err != nil {
+		return err
+	}
+	if len(create.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	if len(create.IdentityProviderGroup) > 256 {
+		return fmt.Errorf("identity provider group is too long, maximum length is 256")
+	}
+	return nil
+}
+
+type UpdateGroupRequest struct {
+	Name                  *string `json:"name"`
+	Description           *string `json:"description"`
+	IdentityProviderGroup *string `json:"identityProviderGroup"`
+}
+
+func (update UpdateGroupRequest) Validate() error {
+	if update.Name != nil {
+		if err := validateGroupName(*update.Name); err != nil {
+			return err
+		}
+	}
+	if update.Description != nil && len(*update.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	if update.IdentityProviderGroup != nil && len(*update.IdentityProviderGroup) > 256 {
+		return fmt.Errorf("identity provider group is too long, maximum length is 256")
+	}
+	return nil
+}
+
+type GroupMember struct {
+	GroupID int `json:"groupId"`
+	UserID  int `json:"userId"`
+	// IdentityProviderID is the identity provider which synced the member, 0 means added manually.
+	IdentityProviderID int   `json:"identityProviderId"`
+	CreatedTs          int64 `json:"createdTs"`
+}
+
+type CreateGroupMemberRequest struct {
+	UserID int `json:"userId"`
+}
+
+type SetGroupCustomRolesRequest struct {
+	RoleIDList []int `json:"roleIdList"`
+}
+
+func validateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("name is too long, maximum length is 64")
+	}
+	return nil
+}
+
+func (s *APIV1Service) registerGroupRoutes(g *echo.Group) {
+	// GET /group - List the groups, which all users can share their memos with.
+	g.GET("/group", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.getCurrentUser(c); err != nil {
+			return err
+		}
+		find := &store.FindUserGroup{}
+		if v := c.QueryParam("userId"); v != "" {
+			userID, err := strconv.Atoi(v)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User ID is not a number: %s", v)).SetInternal(err)
+			}
+			find.MemberID = &userID
+		}
+
+		list, err := s.Store.ListUserGroups(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group list").SetInternal(err)
+		}
+		groupList := []*Group{}
+		for _, userGroup := range list {
+			groupList = append(groupList, convertGroupFromStore(userGroup))
+		}
+		return c.JSON(http.StatusOK, groupList)
+	})
+
+	g.POST("/group", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionGroupManage)
+		if err != nil {
+			return err
+		}
+
+		request := &CreateGroupRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post group request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid group request").SetInternal(err)
+		}
+		if err := s.checkGroupNameAvailable(c, request.Name, UnknownID); err != nil {
+			return err
+		}
+
+		userGroup, err := s.Store.CreateUserGroup(ctx, &store.UserGroup{
+			CreatorID:             currentUser.ID,
+			Name:                  request.Name,
+			Description:           request.Description,
+			IdentityProviderGroup: request.IdentityProviderGroup,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create group").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertGroupFromStore(userGroup))
+	})
+
+	g.GET("/group/:groupId", func(c echo.Context) error {
+		if _, err := s.getCurrentUser(c); err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, convertGroupFromStore(userGroup))
+	})
+
+	g.PATCH("/group/:groupId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionGroupManage)
+		if err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+		if err := s.checkGroupManageable(c, currentUser, userGroup); err != nil {
+			return err
+		}
+
+		request := &UpdateGroupRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch group request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid group request").SetInternal(err)
+		}
+		if request.Name != nil {
+			if err := s.checkGroupNameAvailable(c, *request.Name, userGroup.ID); err != nil {
+				return err
+			}
+		}
+
+		currentTs := time.Now().Unix()
+		userGroup, err = s.Store.UpdateUserGroup(ctx, &store.UpdateUserGroup{
+			ID:                    userGroup.ID,
+			UpdatedTs:             &currentTs,
+			Name:                  request.Name,
+			Description:           request.Description,
+			IdentityProviderGroup: request.IdentityProviderGroup,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch group").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertGroupFromStore(userGroup))
+	})
+
+	g.DELETE("/group/:groupId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionGroupManage)
+		if err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+		if err := s.checkGroupManageable(c, currentUser, userGroup); err != nil {
+			return err
+		}
+
+		if err := s.Store.DeleteUserGroup(ctx, &store.DeleteUserGroup{
+			ID: userGroup.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete group").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	g.GET("/group/:groupId/member", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.getCurrentUser(c); err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListUserGroupMembers(ctx, &store.FindUserGroupMember{
+			GroupID: &userGroup.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group member list").SetInternal(err)
+		}
+		groupMemberList := []*GroupMember{}
+		for _, userGroupMember := range list {
+			groupMemberList = append(groupMemberList, convertGroupMemberFromStore(userGroupMember))
+		}
+		return c.JSON(http.StatusOK, groupMemberList)
+	})
+
+	g.POST("/group/:groupId/member", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionGroupManage)
+		if err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+		if err := s.checkGroupManageable(c, currentUser, userGroup); err != nil {
+			return err
+		}
+
+		request := &CreateGroupMemberRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post group member request").SetInternal(err)
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &request.UserID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User not found with ID: %d", request.UserID))
+		}
+
+		// The members added manually are kept on the sign-in sync.
+		userGroupMember, err := s.Store.UpsertUserGroupMember(ctx, &store.UserGroupMember{
+			GroupID: userGroup.ID,
+			UserID:  user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert group member").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertGroupMemberFromStore(userGroupMember))
+	})
+
+	g.DELETE("/group/:groupId/member/:userId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionGroupManage)
+		if err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+		if err := s.checkGroupManageable(c, currentUser, userGroup); err != nil {
+			return err
+		}
+		userID, err := strconv.Atoi(c.Param("userId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("userId"))).SetInternal(err)
+		}
+
+		if err := s.Store.DeleteUserGroupMember(ctx, &store.DeleteUserGroupMember{
+			GroupID: userGroup.ID,
+			UserID:  userID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete group member").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	// GET /group/:groupId/role - List the custom roles assigned to the group.
+	g.GET("/group/:groupId/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.getCurrentUser(c); err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListCustomRoles(ctx, &store.FindCustomRole{
+			GroupID: &userGroup.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+		}
+		customRoleList := []*CustomRole{}
+		for _, customRole := range list {
+			customRoleList = append(customRoleList, convertCustomRoleFromStore(customRole))
+		}
+		return c.JSON(http.StatusOK, customRoleList)
+	})
+
+	// PUT /group/:groupId/role - Replace the custom roles assigned to the group, granting them to its members.
+	g.PUT("/group/:groupId/role", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionRoleManage)
+		if err != nil {
+			return err
+		}
+		userGroup, err := s.findGroupByParam(c)
+		if err != nil {
+			return err
+		}
+
+		request := &SetGroupCustomRolesRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted put group custom roles request").SetInternal(err)
+		}
+
+		// Both the unassigned and assigned roles have to be grantable by the current user.
+		permissions, err := s.getGroupPermissions(ctx, userGroup)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+		}
+		customRoleList := []*CustomRole{}
+		for _, roleID := range request.RoleIDList {
+			roleID := roleID
+			customRole, err := s.Store.GetCustomRole(ctx, &store.FindCustomRole{
+				ID: &roleID,
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role").SetInternal(err)
+			}
+			if customRole == nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Custom role not found: %d", roleID))
+			}
+			permissions = append(permissions, convertPermissionsFromStore(customRole.Permissions)...)
+			customRoleList = append(customRoleList, convertCustomRoleFromStore(customRole))
+		}
+		if err := s.checkPermissionsGrantable(c, currentUser, permissions); err != nil {
+			return err
+		}
+
+		if err := s.Store.SetUserGroupCustomRoles(ctx, userGroup.ID, request.RoleIDList); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set group custom roles").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, customRoleList)
+	})
+}
+
+func (s *APIV1Service) findGroupByParam(c echo.Context) (*store.UserGroup, error) {
+	groupID, err := strconv.Atoi(c.Param("groupId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("groupId"))).SetInternal(err)
+	}
+	userGroup, err := s.Store.GetUserGroup(c.Request().Context(), &store.FindUserGroup{
+		ID: &groupID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group").SetInternal(err)
+	}
+	if userGroup == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Group not found: %d", groupID))
+	}
+	return userGroup, nil
+}
+
+func (s *APIV1Service) checkGroupNameAvailable(c echo.Context, name string, groupID int) error {
+	userGroup, err := s.Store.GetUserGroup(c.Request().Context(), &store.FindUserGroup{
+		Name: &name,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group").SetInternal(err)
+	}
+	if userGroup != nil && userGroup.ID != groupID {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Group %s already exists", name))
+	}
+	return nil
+}
+
+// checkGroupsExist rejects sharing a memo with the groups not found.
+func (s *APIV1Service) checkGroupsExist(ctx context.Context, groupIDList []int) error {
+	for _, groupID := range groupIDList {
+		groupID := groupID
+		userGroup, err := s.Store.GetUserGroup(ctx, &store.FindUserGroup{
+			ID: &groupID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group").SetInternal(err)
+		}
+		if userGroup == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Group not found: %d", groupID))
+		}
+	}
+	return nil
+}
+
+// checkGroupManageable rejects managing the group by the current user if the custom roles of the group grant
+// permissions not granted to the current user, as changing its members changes who is granted them.
+func (s *APIV1Service) checkGroupManageable(c echo.Context, currentUser *store.User, userGroup *store.UserGroup) error {
+	permissions, err := s.getGroupPermissions(c.Request().Context(), userGroup)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find custom role list").SetInternal(err)
+	}
+	return s.checkPermissionsGrantable(c, currentUser, permissions)
+}
+
+// getGroupPermissions returns the permissions of the custom roles assigned to the group.
+func (s *APIV1Service) getGroupPermissions(ctx context.Context, userGroup *store.UserGroup) ([]Permission, error) {
+	customRoleList, err := s.Store.ListCustomRoles(ctx, &store.FindCustomRole{
+		GroupID: &userGroup.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	permissions := []Permission{}
+	for _, customRole := range customRoleList {
+		permissions = append(permissions, convertPermissionsFromStore(customRole.Permissions)...)
+	}
+	return permissions, nil
+}
+
+// syncIdentityProviderGroups adds the user to the groups mapped to their identity provider groups,
+// and removes them from the ones previously synced by the identity provider they are no longer in.
+// The members added manually are kept.
+func (s *APIV1Service) syncIdentityProviderGroups(ctx context.Context, user *store.User, identityProvider *store.IdentityProvider, userInfo *idp.IdentityProviderUserInfo) error {
+	if !identityProviderProvidesGroups(identityProvider) {
+		return nil
+	}
+	userGroupList, err := s.Store.ListUserGroups(ctx, &store.FindUserGroup{})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group list").SetInternal(err)
+	}
+	memberList, err := s.Store.ListUserGroupMembers(ctx, &store.FindUserGroupMember{
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find group member list").SetInternal(err)
+	}
+	memberMap := map[int]*store.UserGroupMember{}
+	for _, member := range memberList {
+		memberMap[member.GroupID] = member
+	}
+
+	for _, userGroup := range userGroupList {
+		if userGroup.IdentityProviderGroup == "" {
+			continue
+		}
+		member := memberMap[userGroup.ID]
+		if containsIdentityProviderGroup(userInfo.Groups, userGroup.IdentityProviderGroup) {
+			if member != nil {
+				continue
+			}
+			if _, err := s.Store.UpsertUserGroupMember(ctx, &store.UserGroupMember{
+				GroupID:            userGroup.ID,
+				UserID:             user.ID,
+				IdentityProviderID: identityProvider.ID,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert group member").SetInternal(err)
+			}
+		} else if member != nil && member.IdentityProviderID == identityProvider.ID {
+			if err := s.Store.DeleteUserGroupMember(ctx, &store.DeleteUserGroupMember{
+				GroupID: userGroup.ID,
+				UserID:  user.ID,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete group member").SetInternal(err)
+			}
+		}
+	}
+	return nil
+}
+
+// identityProviderProvidesGroups returns whether the identity provider is configured to provide the groups of its users.
+func identityProviderProvidesGroups(identityProvider *store.IdentityProvider) bool {
+	switch identityProvider.Type {
+	case store.IdentityProviderOIDCType:
+		return identityProvider.Config.OIDCConfig != nil && identityProvider.Config.OIDCConfig.GroupsClaim != ""
+	case store.IdentityProviderLDAPType:
+		return identityProvider.Config.LDAPConfig != nil
+	default:
+		return false
+	}
+}
+
+// containsIdentityProviderGroup returns whether any of the groups matches the group, by its full value
+// or, for the directory groups, by the value of its first RDN.
+func containsIdentityProviderGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if strings.EqualFold(g, group) || strings.EqualFold(ldap.GroupName(g), group) {
+			return true
+		}
+	}
+	return false
+}
+
+func convertGroupFromStore(userGroup *store.UserGroup) *Group {
+	return &Group{
+		ID:                    userGroup.ID,
+		CreatorID:             userGroup.CreatorID,
+		CreatedTs:             userGroup.CreatedTs,
+		UpdatedTs:             userGroup.UpdatedTs,
+		Name:                  userGroup.Name,
+		Description:           userGroup.Description,
+		IdentityProviderGroup: userGroup.IdentityProviderGroup,
+	}
+}
+
+func convertGroupMemberFromStore(userGroupMember *store.UserGroupMember) *GroupMember {
+	return &GroupMember{
+		GroupID:            userGroupMember.GroupID,
+		UserID:             userGroupMember.UserID,
+		IdentityProviderID: userGroupMember.IdentityProviderID,
+		CreatedTs:          userGroupMember.CreatedTs,
+	}
+}
@@ -20,7 +20,7 @@ func (*APIV1Service) registerGetterPublicRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Wrong url").SetInternal(err)
 		}
 
-		htmlMeta, err := getter.GetHTMLMeta(urlStr)
+		htmlMeta, err := getter.GetHTMLMeta(c.Request().Context(), urlStr)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusNotAcceptable, fmt.Sprintf("Failed to get website meta with url: %s", urlStr)).SetInternal(err)
 		}
@@ -37,7 +37,7 @@ func (*APIV1Service) registerGetterPublicRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Wrong url").SetInternal(err)
 		}
 
-		image, err := getter.GetImage(urlStr)
+		image, err := getter.GetImage(c.Request().Context(), urlStr)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Failed to get image url: %s", urlStr)).SetInternal(err)
 		}
@@ -1,19 +1,35 @@
 package v1
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/plugin/idp/ldap"
+	"github.com/usememos/memos/plugin/idp/oidc"
 	"github.com/usememos/memos/store"
 )
 
+const (
+	// oidcNonceCookieName is the name of the cookie keeping the nonce of the pending OIDC authorization request.
+	oidcNonceCookieName = "memos.oidc-nonce"
+	// oidcNonceDuration is the time the user has to complete the OIDC authorization request.
+	oidcNonceDuration = 10 * time.Minute
+)
+
 type IdentityProviderType string
 
 const (
 	IdentityProviderOAuth2Type IdentityProviderType = "OAUTH2"
+	IdentityProviderOIDCType   IdentityProviderType = "OIDC"
+	IdentityProviderLDAPType   IdentityProviderType = "LDAP"
 )
 
 func (t IdentityProviderType) String() string {
@@ -21,7 +37,9 @@ func (t IdentityProviderType) String() string {
 }
 
 type IdentityProviderConfig struct {
-	OAuth2Config *IdentityProviderOAuth2Config `json:"oauth2Config"`
+	OAuth2Config *IdentityProviderOAuth2Config `json:"oauth2Config,omitempty"`
+	OIDCConfig   *IdentityProviderOIDCConfig   `json:"oidcConfig,omitempty"`
+	LDAPConfig   *IdentityProviderLDAPConfig   `json:"ldapConfig,omitempty"`
 }
 
 type IdentityProviderOAuth2Config struct {
@@ -34,6 +52,28 @@ type IdentityProviderOAuth2Config struct {
 	FieldMapping *FieldMapping `json:"fieldMapping"`
 }
 
+type IdentityProviderOIDCConfig struct {
+	Issuer       string        `json:"issuer"`
+	ClientID     string        `json:"clientId"`
+	ClientSecret string        `json:"clientSecret"`
+	Scopes       []string      `json:"scopes"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+	GroupsClaim  string        `json:"groupsClaim"`
+}
+
+type IdentityProviderLDAPConfig struct {
+	URL                string        `json:"url"`
+	StartTLS           bool          `json:"startTls"`
+	InsecureSkipVerify bool          `json:"insecureSkipVerify"`
+	BindDN             string        `json:"bindDn"`
+	BindPassword       string        `json:"bindPassword"`
+	BaseDN             string        `json:"baseDn"`
+	UserFilter         string        `json:"userFilter"`
+	FieldMapping       *FieldMapping `json:"fieldMapping"`
+	GroupAttribute     string        `json:"groupAttribute"`
+	AdminGroups        []string      `json:"adminGroups"`
+}
+
 type FieldMapping struct {
 	Identifier  string `json:"identifier"`
 	DisplayName string `json:"displayName"`
@@ -48,6 +88,10 @@ type IdentityProvider struct {
 	Config           *IdentityProviderConfig `json:"config"`
 }
 
+type IdentityProviderAuthorizationURL struct {
+	URL string `json:"url"`
+}
+
 type CreateIdentityProviderRequest struct {
 	Name             string                  `json:"name"`
 	Type             IdentityProviderType    `json:"type"`
@@ -66,19 +110,8 @@ type UpdateIdentityProviderRequest struct {
 func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 	g.POST("/idp", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionIdentityProviderManage); err != nil {
+			return err
 		}
 
 		identityProviderCreate := &CreateIdentityProviderRequest{}
@@ -100,19 +133,8 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 
 	g.PATCH("/idp/:idpId", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionIdentityProviderManage); err != nil {
+			return err
 		}
 
 		identityProviderID, err := strconv.Atoi(c.Param("idpId"))
@@ -148,7 +170,7 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 		}
 
 		userID, ok := c.Get(getUserIDContextKey()).(int)
-		isHostUser := false
+		isManager := false
 		if ok {
 			user, err := s.Store.GetUser(ctx, &store.FindUser{
 				ID: &userID,
@@ -156,8 +178,11 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 			if err != nil {
 				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
 			}
-			if user == nil || user.Role == store.RoleHost {
-				isHostUser = true
+			if user != nil {
+				isManager, err = hasPermission(ctx, s.Store, user, PermissionIdentityProviderManage)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+				}
 			}
 		}
 
@@ -165,8 +190,16 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 		for _, item := range list {
 			identityProvider := convertIdentityProviderFromStore(item)
 			// data desensitize
-			if !isHostUser {
-				identityProvider.Config.OAuth2Config.ClientSecret = ""
+			if !isManager {
+				if identityProvider.Config.OAuth2Config != nil {
+					identityProvider.Config.OAuth2Config.ClientSecret = ""
+				}
+				if identityProvider.Config.OIDCConfig != nil {
+					identityProvider.Config.OIDCConfig.ClientSecret = ""
+				}
+				if identityProvider.Config.LDAPConfig != nil {
+					identityProvider.Config.LDAPConfig.BindPassword = ""
+				}
 			}
 			identityProviderList = append(identityProviderList, identityProvider)
 		}
@@ -175,21 +208,30 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 
 	g.GET("/idp/:idpId", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		if _, err := s.checkPermission(c, PermissionIdentityProviderManage); err != nil {
+			return err
 		}
 
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
+		identityProviderID, err := strconv.Atoi(c.Param("idpId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("idpId"))).SetInternal(err)
+		}
+		identityProvider, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProvider{
+			ID: &identityProviderID,
 		})
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get identity provider").SetInternal(err)
 		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if identityProvider == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Identity provider not found")
 		}
 
+		return c.JSON(http.StatusOK, convertIdentityProviderFromStore(identityProvider))
+	})
+
+	// GET /idp/:idpId/authorization-url - Get the URL of the authorization endpoint of an OIDC identity provider.
+	g.GET("/idp/:idpId/authorization-url", func(c echo.Context) error {
+		ctx := c.Request().Context()
 		identityProviderID, err := strconv.Atoi(c.Param("idpId"))
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("idpId"))).SetInternal(err)
@@ -203,25 +245,34 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 		if identityProvider == nil {
 			return echo.NewHTTPError(http.StatusNotFound, "Identity provider not found")
 		}
+		if identityProvider.Type != store.IdentityProviderOIDCType {
+			return echo.NewHTTPError(http.StatusBadRequest, "Identity provider is not an OIDC provider")
+		}
 
-		return c.JSON(http.StatusOK, convertIdentityProviderFromStore(identityProvider))
+		oidcIdentityProvider, err := oidc.NewIdentityProvider(identityProvider.Config.OIDCConfig)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create identity provider instance").SetInternal(err)
+		}
+		state := c.QueryParam("state")
+		if state == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Missing state")
+		}
+		// The nonce is generated by the server rather than the client, so that the ID token can't be replayed.
+		nonce := util.GenUUID()
+		authorizationURL, err := oidcIdentityProvider.AuthorizationURL(ctx, c.QueryParam("redirectUri"), state, nonce)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to discover identity provider").SetInternal(err)
+		}
+		setOIDCNonceCookie(c, state, nonce, time.Now().Add(oidcNonceDuration))
+		return c.JSON(http.StatusOK, &IdentityProviderAuthorizationURL{
+			URL: authorizationURL,
+		})
 	})
 
 	g.DELETE("/idp/:idpId", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionIdentityProviderManage); err != nil {
+			return err
 		}
 
 		identityProviderID, err := strconv.Atoi(c.Param("idpId"))
@@ -236,6 +287,36 @@ func (s *APIV1Service) registerIdentityProviderRoutes(g *echo.Group) {
 	})
 }
 
+// setOIDCNonceCookie keeps the nonce of the authorization request bound to its state in the http-only cookie.
+func setOIDCNonceCookie(c echo.Context, state, nonce string, expiration time.Time) {
+	cookie := new(http.Cookie)
+	cookie.Name = oidcNonceCookieName
+	cookie.Value = ""
+	if nonce != "" {
+		// The nonce is an UUID, which never contains the separator.
+		cookie.Value = nonce + ":" + url.QueryEscape(state)
+	}
+	cookie.Expires = expiration
+	cookie.Path = "/"
+	cookie.HttpOnly = true
+	cookie.SameSite = http.SameSiteStrictMode
+	c.SetCookie(cookie)
+}
+
+// popOIDCNonce returns the nonce of the authorization request with the state and removes it, so that it is used once.
+func popOIDCNonce(c echo.Context, state string) (string, error) {
+	cookie, err := c.Cookie(oidcNonceCookieName)
+	if err != nil || cookie.Value == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Missing OIDC authorization request")
+	}
+	setOIDCNonceCookie(c, "", "", time.Now().Add(-1*time.Hour))
+	nonce, cookieState, ok := strings.Cut(cookie.Value, ":")
+	if !ok || state == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(url.QueryEscape(state))) != 1 {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Invalid OIDC authorization state")
+	}
+	return nonce, nil
+}
+
 func convertIdentityProviderFromStore(identityProvider *store.IdentityProvider) *IdentityProvider {
 	return &IdentityProvider{
 		ID:               identityProvider.ID,
@@ -247,37 +328,137 @@ func convertIdentityProviderFromStore(identityProvider *store.IdentityProvider)
 }
 
 func convertIdentityProviderConfigFromStore(config *store.IdentityProviderConfig) *IdentityProviderConfig {
-	return &IdentityProviderConfig{
-		OAuth2Config: &IdentityProviderOAuth2Config{
-			ClientID:     config.OAuth2Config.ClientID,
-			ClientSecret: config.OAuth2Config.ClientSecret,
-			AuthURL:      config.OAuth2Config.AuthURL,
-			TokenURL:     config.OAuth2Config.TokenURL,
-			UserInfoURL:  config.OAuth2Config.UserInfoURL,
-			Scopes:       config.OAuth2Config.Scopes,
-			FieldMapping: &FieldMapping{
-				Identifier:  config.OAuth2Config.FieldMapping.Identifier,
-				DisplayName: config.OAuth2Config.FieldMapping.DisplayName,
-				Email:       config.OAuth2Config.FieldMapping.Email,
-			},
-		},
+	identityProviderConfig := &IdentityProviderConfig{}
+	if v := config.OAuth2Config; v != nil {
+		identityProviderConfig.OAuth2Config = &IdentityProviderOAuth2Config{
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			AuthURL:      v.AuthURL,
+			TokenURL:     v.TokenURL,
+			UserInfoURL:  v.UserInfoURL,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingFromStore(v.FieldMapping),
+		}
+	}
+	if v := config.OIDCConfig; v != nil {
+		identityProviderConfig.OIDCConfig = &IdentityProviderOIDCConfig{
+			Issuer:       v.Issuer,
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingFromStore(v.FieldMapping),
+			GroupsClaim:  v.GroupsClaim,
+		}
 	}
+	if v := config.LDAPConfig; v != nil {
+		identityProviderConfig.LDAPConfig = &IdentityProviderLDAPConfig{
+			URL:                v.URL,
+			StartTLS:           v.StartTLS,
+			InsecureSkipVerify: v.InsecureSkipVerify,
+			BindDN:             v.BindDN,
+			BindPassword:       v.BindPassword,
+			BaseDN:             v.BaseDN,
+			UserFilter:         v.UserFilter,
+			FieldMapping:       convertFieldMappingFromStore(v.FieldMapping),
+			GroupAttribute:     v.GroupAttribute,
+			AdminGroups:        v.AdminGroups,
+		}
+	}
+	return identityProviderConfig
 }
 
 func convertIdentityProviderConfigToStore(config *IdentityProviderConfig) *store.IdentityProviderConfig {
-	return &store.IdentityProviderConfig{
-		OAuth2Config: &store.IdentityProviderOAuth2Config{
-			ClientID:     config.OAuth2Config.ClientID,
-			ClientSecret: config.OAuth2Config.ClientSecret,
-			AuthURL:      config.OAuth2Config.AuthURL,
-			TokenURL:     config.OAuth2Config.TokenURL,
-			UserInfoURL:  config.OAuth2Config.UserInfoURL,
-			Scopes:       config.OAuth2Config.Scopes,
-			FieldMapping: &store.FieldMapping{
-				Identifier:  config.OAuth2Config.FieldMapping.Identifier,
-				DisplayName: config.OAuth2Config.FieldMapping.DisplayName,
-				Email:       config.OAuth2Config.FieldMapping.Email,
-			},
-		},
+	if config == nil {
+		return nil
+	}
+
+	identityProviderConfig := &store.IdentityProviderConfig{}
+	if v := config.OAuth2Config; v != nil {
+		identityProviderConfig.OAuth2Config = &store.IdentityProviderOAuth2Config{
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			AuthURL:      v.AuthURL,
+			TokenURL:     v.TokenURL,
+			UserInfoURL:  v.UserInfoURL,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingToStore(v.FieldMapping),
+		}
+	}
+	if v := config.OIDCConfig; v != nil {
+		identityProviderConfig.OIDCConfig = &store.IdentityProviderOIDCConfig{
+			Issuer:       v.Issuer,
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingToStore(v.FieldMapping),
+			GroupsClaim:  v.GroupsClaim,
+		}
+	}
+	if v := config.LDAPConfig; v != nil {
+		identityProviderConfig.LDAPConfig = &store.IdentityProviderLDAPConfig{
+			URL:                v.URL,
+			StartTLS:           v.StartTLS,
+			InsecureSkipVerify: v.InsecureSkipVerify,
+			BindDN:             v.BindDN,
+			BindPassword:       v.BindPassword,
+			BaseDN:             v.BaseDN,
+			UserFilter:         v.UserFilter,
+			FieldMapping:       convertFieldMappingToStore(v.FieldMapping),
+			GroupAttribute:     v.GroupAttribute,
+			AdminGroups:        v.AdminGroups,
+		}
+	}
+	return identityProviderConfig
+}
+
+func convertFieldMappingFromStore(fieldMapping *store.FieldMapping) *FieldMapping {
+	if fieldMapping == nil {
+		return &FieldMapping{}
+	}
+	return &FieldMapping{
+		Identifier:  fieldMapping.Identifier,
+		DisplayName: fieldMapping.DisplayName,
+		Email:       fieldMapping.Email,
+	}
+}
+
+func convertFieldMappingToStore(fieldMapping *FieldMapping) *store.FieldMapping {
+	if fieldMapping == nil {
+		return nil
+	}
+	return &store.FieldMapping{
+		Identifier:  fieldMapping.Identifier,
+		DisplayName: fieldMapping.DisplayName,
+		Email:       fieldMapping.Email,
+	}
+}
+
+func validateIdentityProviderConfig(identityProviderType IdentityProviderType, config *IdentityProviderConfig) error {
+	if config == nil {
+		return fmt.Errorf("missing config")
+	}
+
+	switch identityProviderType {
+	case IdentityProviderOAuth2Type:
+		if config.OAuth2Config == nil {
+			return fmt.Errorf("missing oauth2 config")
+		}
+	case IdentityProviderOIDCType:
+		if config.OIDCConfig == nil {
+			return fmt.Errorf("missing oidc config")
+		}
+		if _, err := oidc.NewIdentityProvider(convertIdentityProviderConfigToStore(config).OIDCConfig); err != nil {
+			return err
+		}
+	case IdentityProviderLDAPType:
+		if config.LDAPConfig == nil {
+			return fmt.Errorf("missing ldap config")
+		}
+		if _, err := ldap.NewIdentityProvider(convertIdentityProviderConfigToStore(config).LDAPConfig); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported identity provider type %s", identityProviderType)
 	}
+	return nil
 }
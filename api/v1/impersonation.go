@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/api/v1/auth"
+	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/store"
+)
+
+// impersonatorHeaderName is the response header flagging the requests made under impersonation.
+const impersonatorHeaderName = "X-Memos-Impersonator-Id"
+
+type ImpersonateRequest struct {
+	UserID int `json:"userId"`
+	// AllowWrite allows the write requests under impersonation, they are blocked by default.
+	AllowWrite bool `json:"allowWrite"`
+	// ExpiresIn is the lifetime of the token in seconds, it defaults to and is capped at one hour.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+type ImpersonationToken struct {
+	// AccessToken is sent as the bearer token of the requests made as the user.
+	AccessToken    string `json:"accessToken"`
+	UserID         int    `json:"userId"`
+	ImpersonatorID int    `json:"impersonatorId"`
+	AllowWrite     bool   `json:"allowWrite"`
+	ExpiresTs      int64  `json:"expiresTs"`
+}
+
+func (s *APIV1Service) registerAuthImpersonationRoutes(g *echo.Group) {
+	// POST /auth/impersonate - Issue a token for the host to act as a user.
+	g.POST("/auth/impersonate", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		if currentUser.Role != store.RoleHost {
+			return echo.NewHTTPError(http.StatusForbidden, "Only the host can impersonate users")
+		}
+		// The token is bound to the session of the host, signing out ends the impersonation.
+		sessionID, _ := c.Get(getSessionIDContextKey()).(string)
+		if sessionID == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Impersonation requires a signed-in session")
+		}
+
+		request := &ImpersonateRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted impersonate request").SetInternal(err)
+		}
+		duration := auth.MaxImpersonationTokenDuration
+		if request.ExpiresIn != 0 {
+			duration = time.Duration(request.ExpiresIn) * time.Second
+			if duration <= 0 || duration > auth.MaxImpersonationTokenDuration {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid expires in: %d, should be between 1 and %d seconds", request.ExpiresIn, int(auth.MaxImpersonationTokenDuration.Seconds())))
+			}
+		}
+
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &request.UserID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User not found: %d", request.UserID))
+		}
+		if user.Role == store.RoleHost {
+			return echo.NewHTTPError(http.StatusBadRequest, "The host can not be impersonated")
+		}
+		if user.RowStatus == store.Archived {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User has been archived with username %s", user.Username))
+		}
+
+		expirationTime := time.Now().Add(duration)
+		accessToken, err := auth.GenerateImpersonationToken(user.Username, user.ID, currentUser.ID, sessionID, request.AllowWrite, expirationTime, s.Secret)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate impersonation token").SetInternal(err)
+		}
+		// The activity belongs to the impersonated user so that they can see it.
+		if err := s.createActivity(ctx, user.ID, ActivityUserAuthImpersonationStart, ActivityWarn, ActivityUserAuthImpersonationStartPayload{
+			ImpersonatorID: currentUser.ID,
+			AllowWrite:     request.AllowWrite,
+			ExpiresTs:      expirationTime.Unix(),
+			IP:             echo.ExtractIPFromRealIPHeader()(c.Request()),
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, &ImpersonationToken{
+			AccessToken:    accessToken,
+			UserID:         user.ID,
+			ImpersonatorID: currentUser.ID,
+			AllowWrite:     request.AllowWrite,
+			ExpiresTs:      expirationTime.Unix(),
+		})
+	})
+}
+
+// serveImpersonation serves the request of an impersonation token as its user, recording it as an activity of the user.
+// The write requests are blocked unless allowed by the token, and the account changes are always blocked.
+func (s *APIV1Service) serveImpersonation(c echo.Context, next echo.HandlerFunc, claims *Claims) error {
+	ctx := c.Request().Context()
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Malformed ID in the token.")
+	}
+
+	// The impersonator must still be the host with the session the token was issued for.
+	impersonator, err := s.Store.GetUser(ctx, &store.FindUser{
+		ID: &claims.ImpersonatorID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to find user ID: %d", claims.ImpersonatorID)).SetInternal(err)
+	}
+	if impersonator == nil || impersonator.RowStatus == store.Archived || impersonator.Role != store.RoleHost {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Impersonator is not the host anymore.")
+	}
+	userSession, err := s.Store.GetUserSession(ctx, &store.FindUserSession{
+		ID: &claims.ID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to find session of user ID: %d", impersonator.ID)).SetInternal(err)
+	}
+	if claims.ID == "" || userSession == nil || userSession.UserID != impersonator.ID || time.Since(time.Unix(userSession.UpdatedTs, 0)) > auth.RefreshTokenDuration {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Session of the impersonator has been revoked or expired.")
+	}
+
+	user, err := s.Store.GetUser(ctx, &store.FindUser{
+		ID: &userID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to find user ID: %d", userID)).SetInternal(err)
+	}
+	if user == nil || user.RowStatus == store.Archived || user.Role == store.RoleHost {
+		return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("User ID %d can not be impersonated.", userID))
+	}
+
+	// Every request is recorded before being served, including the blocked ones.
+	request := c.Request()
+	isWrite := request.Method != http.MethodGet && request.Method != http.MethodHead && request.Method != http.MethodOptions
+	level := ActivityInfo
+	if isWrite {
+		level = ActivityWarn
+	}
+	if err := s.createActivity(ctx, user.ID, ActivityUserAuthImpersonationRequest, level, ActivityUserAuthImpersonationRequestPayload{
+		ImpersonatorID: impersonator.ID,
+		Method:         request.Method,
+		Path:           request.URL.Path,
+		IP:             echo.ExtractIPFromRealIPHeader()(request),
+	}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+	}
+	if isWrite {
+		if !claims.AllowWrite {
+			return echo.NewHTTPError(http.StatusForbidden, "Write requests are blocked under impersonation")
+		}
+		if util.HasPrefixes(request.URL.Path, "/api/v1/auth", "/api/v1/user") && !util.HasPrefixes(request.URL.Path, "/api/v1/user/setting") {
+			return echo.NewHTTPError(http.StatusForbidden, "Account changes are blocked under impersonation")
+		}
+	}
+
+	c.Set(getUserIDContextKey(), user.ID)
+	c.Set(getImpersonatorIDContextKey(), impersonator.ID)
+	c.Response().Header().Set(impersonatorHeaderName, strconv.Itoa(impersonator.ID))
+	return next(c)
+}
@@ -0,0 +1,247 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/store"
+)
+
+type InvitationStatus string
+
+const (
+	// InvitationPending is the status of the invitations which can still be used.
+	InvitationPending InvitationStatus = "PENDING"
+	// InvitationUsed is the status of the invitations used up to their max uses.
+	InvitationUsed InvitationStatus = "USED"
+	// InvitationExpired is the status of the invitations expired before used up.
+	InvitationExpired InvitationStatus = "EXPIRED"
+)
+
+func (status InvitationStatus) String() string {
+	return string(status)
+}
+
+type Invitation struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Code      string           `json:"code"`
+	Email     string           `json:"email"`
+	Role      Role             `json:"role"`
+	MaxUses   int              `json:"maxUses"`
+	UsedCount int              `json:"usedCount"`
+	ExpiredTs int64            `json:"expiredTs"`
+	Status    InvitationStatus `json:"status"`
+}
+
+type CreateInvitationRequest struct {
+	// Email binds the invitation to the email of the signup, empty means any.
+	Email string `json:"email"`
+	// Role is the role of the users signing up with the invitation, default is USER.
+	Role Role `json:"role"`
+	// MaxUses is the number of signups allowed, 0 means unlimited, default is 1.
+	MaxUses *int `json:"maxUses"`
+	// ExpiredTs is the time after which the invitation can not be used, 0 means never.
+	ExpiredTs int64 `json:"expiredTs"`
+}
+
+func (create CreateInvitationRequest) Validate() error {
+	if create.Role != RoleUser && create.Role != RoleAdmin {
+		return fmt.Errorf("invalid role %s", create.Role)
+	}
+	if create.MaxUses != nil && *create.MaxUses < 0 {
+		return fmt.Errorf("max uses should not be negative")
+	}
+	if create.ExpiredTs < 0 {
+		return fmt.Errorf("expired ts should not be negative")
+	}
+	if create.Email != "" {
+		if len(create.Email) > 256 {
+			return fmt.Errorf("email is too long, maximum length is 256")
+		}
+		if !util.ValidateEmail(create.Email) {
+			return fmt.Errorf("invalid email format")
+		}
+	}
+	return nil
+}
+
+func (s *APIV1Service) registerInvitationRoutes(g *echo.Group) {
+	g.POST("/invitation", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.checkPermission(c, PermissionUserManage)
+		if err != nil {
+			return err
+		}
+
+		request := &CreateInvitationRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post invitation request").SetInternal(err)
+		}
+		if request.Role == "" {
+			request.Role = RoleUser
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid invitation request").SetInternal(err)
+		}
+		if request.Role == RoleAdmin && user.Role != store.RoleHost {
+			return echo.NewHTTPError(http.StatusForbidden, "Only the host can invite admins")
+		}
+
+		code, err := util.RandomString(24)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate invitation code").SetInternal(err)
+		}
+		invitationCreate := &store.Invitation{
+			CreatorID: user.ID,
+			Code:      code,
+			Email:     request.Email,
+			Role:      store.Role(request.Role),
+			MaxUses:   1,
+			ExpiredTs: request.ExpiredTs,
+		}
+		if request.MaxUses != nil {
+			invitationCreate.MaxUses = *request.MaxUses
+		}
+		invitation, err := s.Store.CreateInvitation(ctx, invitationCreate)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create invitation").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertInvitationFromStore(invitation, time.Now().Unix()))
+	})
+
+	// GET /invitation?status=PENDING - List the invitations, optionally of the status.
+	g.GET("/invitation", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+			return err
+		}
+
+		status := InvitationStatus(c.QueryParam("status"))
+		if status != "" && status != InvitationPending && status != InvitationUsed && status != InvitationExpired {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid invitation status: %s", status))
+		}
+		list, err := s.Store.ListInvitations(ctx, &store.FindInvitation{})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find invitation list").SetInternal(err)
+		}
+		now := time.Now().Unix()
+		invitationList := []*Invitation{}
+		for _, invitation := range list {
+			invitationMessage := convertInvitationFromStore(invitation, now)
+			if status != "" && invitationMessage.Status != status {
+				continue
+			}
+			invitationList = append(invitationList, invitationMessage)
+		}
+		return c.JSON(http.StatusOK, invitationList)
+	})
+
+	g.DELETE("/invitation/:invitationId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+			return err
+		}
+		invitationID, err := strconv.Atoi(c.Param("invitationId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("invitationId"))).SetInternal(err)
+		}
+
+		if err := s.Store.DeleteInvitation(ctx, &store.DeleteInvitation{
+			ID: invitationID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete invitation").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+// getSignUpMode returns the signup mode setting, falling back to the allow signup setting.
+func (s *APIV1Service) getSignUpMode(ctx context.Context) (SignUpMode, error) {
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingSignUpModeName.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if systemSetting != nil {
+		var signUpMode SignUpMode
+		if err := json.Unmarshal([]byte(systemSetting.Value), &signUpMode); err != nil {
+			return "", err
+		}
+		return signUpMode, nil
+	}
+
+	allowSignUpSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingAllowSignUpName.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	allowSignUp := false
+	if allowSignUpSetting != nil {
+		if err := json.Unmarshal([]byte(allowSignUpSetting.Value), &allowSignUp); err != nil {
+			return "", err
+		}
+	}
+	if allowSignUp {
+		return SignUpModeOpen, nil
+	}
+	return SignUpModeDisabled, nil
+}
+
+// findSignUpInvitation returns the invitation of the code if it can be used by the signup email, nil if not.
+func (s *APIV1Service) findSignUpInvitation(ctx context.Context, code, email string) (*store.Invitation, error) {
+	invitation, err := s.Store.GetInvitation(ctx, &store.FindInvitation{
+		Code: &code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || getInvitationStatus(invitation, time.Now().Unix()) != InvitationPending {
+		return nil, nil
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+		return nil, nil
+	}
+	return invitation, nil
+}
+
+func getInvitationStatus(invitation *store.Invitation, now int64) InvitationStatus {
+	if invitation.MaxUses > 0 && invitation.UsedCount >= invitation.MaxUses {
+		return InvitationUsed
+	}
+	if invitation.ExpiredTs > 0 && invitation.ExpiredTs <= now {
+		return InvitationExpired
+	}
+	return InvitationPending
+}
+
+func convertInvitationFromStore(invitation *store.Invitation, now int64) *Invitation {
+	return &Invitation{
+		ID:        invitation.ID,
+		CreatorID: invitation.CreatorID,
+		CreatedTs: invitation.CreatedTs,
+		UpdatedTs: invitation.UpdatedTs,
+		Code:      invitation.Code,
+		Email:     invitation.Email,
+		Role:      Role(invitation.Role),
+		MaxUses:   invitation.MaxUses,
+		UsedCount: invitation.UsedCount,
+		ExpiredTs: invitation.ExpiredTs,
+		Status:    getInvitationStatus(invitation, now),
+	}
+}
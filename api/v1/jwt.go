@@ -20,16 +20,30 @@ const (
 	// The key name used to store user id in the context
 	// user id is extracted from the jwt token subject field.
 	userIDContextKey = "user-id"
+	// The key name used to store the session id of the access token in the context.
+	sessionIDContextKey = "session-id"
+	// The key name used to store the id of the host acting as the user of an impersonation token in the context.
+	impersonatorIDContextKey = "impersonator-id"
 )
 
 func getUserIDContextKey() string {
 	return userIDContextKey
 }
 
+func getSessionIDContextKey() string {
+	return sessionIDContextKey
+}
+
+func getImpersonatorIDContextKey() string {
+	return impersonatorIDContextKey
+}
+
 // Claims creates a struct that will be encoded to a JWT.
 // We add jwt.RegisteredClaims as an embedded type, to provide fields such as name.
 type Claims struct {
-	Name string `json:"name"`
+	Name           string `json:"name"`
+	ImpersonatorID int    `json:"impersonatorId,omitempty"`
+	AllowWrite     bool   `json:"allowWrite,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -47,14 +61,15 @@ func extractTokenFromHeader(c echo.Context) (string, error) {
 	return authHeaderParts[1], nil
 }
 
+// findAccessToken returns the token of the Authorization header, or the one of the cookie if none.
+// The header takes precedence so that the host can send an impersonation token while signed in.
 func findAccessToken(c echo.Context) string {
-	accessToken := ""
-	cookie, _ := c.Cookie(auth.AccessTokenCookieName)
-	if cookie != nil {
-		accessToken = cookie.Value
-	}
+	accessToken, _ := extractTokenFromHeader(c)
 	if accessToken == "" {
-		accessToken, _ = extractTokenFromHeader(c)
+		cookie, _ := c.Cookie(auth.AccessTokenCookieName)
+		if cookie != nil {
+			accessToken = cookie.Value
+		}
 	}
 
 	return accessToken
@@ -112,6 +127,14 @@ func JWTMiddleware(server *APIV1Service, next echo.HandlerFunc, secret string) e
 			return nil, errors.Errorf("unexpected access token kid=%v", t.Header["kid"])
 		})
 
+		// The impersonation tokens are never refreshed and are checked against the session of the impersonator.
+		if audienceContains(claims.Audience, auth.ImpersonationTokenAudienceName) {
+			if err != nil || !accessToken.Valid {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired impersonation token.")
+			}
+			return server.serveImpersonation(c, next, claims)
+		}
+
 		if !accessToken.Valid {
 			auth.RemoveTokensAndCookies(c)
 			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid access token.")
@@ -152,6 +175,33 @@ func JWTMiddleware(server *APIV1Service, next echo.HandlerFunc, secret string) e
 		if user == nil {
 			return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("Failed to find user ID: %d", userID))
 		}
+		if user.RowStatus == store.Archived {
+			auth.RemoveTokensAndCookies(c)
+			return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("User has been archived with ID: %d", userID))
+		}
+
+		// The session of the token must not be revoked or expired.
+		userSession, err := server.Store.GetUserSession(ctx, &store.FindUserSession{
+			ID: &claims.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to find session of user ID: %d", userID)).SetInternal(err)
+		}
+		if claims.ID == "" || userSession == nil || userSession.UserID != userID || time.Since(time.Unix(userSession.UpdatedTs, 0)) > auth.RefreshTokenDuration {
+			auth.RemoveTokensAndCookies(c)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Session has been revoked or expired.")
+		}
+		if time.Since(time.Unix(userSession.LastSeenTs, 0)) > userSessionLastSeenInterval {
+			lastSeenTs := time.Now().Unix()
+			ip := echo.ExtractIPFromRealIPHeader()(c.Request())
+			if _, err := server.Store.UpdateUserSession(ctx, &store.UpdateUserSession{
+				ID:         userSession.ID,
+				LastSeenTs: &lastSeenTs,
+				IP:         &ip,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to update session of user ID: %d", userID)).SetInternal(err)
+			}
+		}
 
 		if generateToken {
 			generateTokenFunc := func() error {
@@ -189,11 +239,22 @@ func JWTMiddleware(server *APIV1Service, next echo.HandlerFunc, secret string) e
 						))
 				}
 
+				if refreshTokenClaims.ID != userSession.ID {
+					return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token, session mismatch.")
+				}
+
 				// If we have a valid refresh token, we will generate new access token and refresh token
 				if refreshToken != nil && refreshToken.Valid {
-					if err := auth.GenerateTokensAndSetCookies(c, user, secret); err != nil {
+					if err := auth.GenerateTokensAndSetCookies(c, user, userSession.ID, secret); err != nil {
 						return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to refresh expired token. User Id %d", userID)).SetInternal(err)
 					}
+					updatedTs := time.Now().Unix()
+					if _, err := server.Store.UpdateUserSession(ctx, &store.UpdateUserSession{
+						ID:        userSession.ID,
+						UpdatedTs: &updatedTs,
+					}); err != nil {
+						return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Server error to refresh session. User Id %d", userID)).SetInternal(err)
+					}
 				}
 
 				return nil
@@ -206,8 +267,9 @@ func JWTMiddleware(server *APIV1Service, next echo.HandlerFunc, secret string) e
 			}
 		}
 
-		// Stores userID into context.
+		// Stores userID and sessionID into context.
 		c.Set(getUserIDContextKey(), userID)
+		c.Set(getSessionIDContextKey(), userSession.ID)
 		return next(c)
 	}
 }
@@ -216,8 +278,8 @@ func (s *APIV1Service) defaultAuthSkipper(c echo.Context) bool {
 	ctx := c.Request().Context()
 	path := c.Path()
 
-	// Skip auth.
-	if util.HasPrefixes(path, "/api/v1/auth") {
+	// Skip auth, except for registering a passkey and impersonating a user which require a signed-in user.
+	if util.HasPrefixes(path, "/api/v1/auth") && !util.HasPrefixes(path, "/api/v1/auth/webauthn/registration", "/api/v1/auth/impersonate") {
 		return true
 	}
 
@@ -230,7 +292,7 @@ func (s *APIV1Service) defaultAuthSkipper(c echo.Context) bool {
 		if err != nil {
 			return false
 		}
-		if user != nil {
+		if user != nil && user.RowStatus != store.Archived {
 			// Stores userID into context.
 			c.Set(getUserIDContextKey(), user.ID)
 			return true
@@ -239,3 +301,30 @@ func (s *APIV1Service) defaultAuthSkipper(c echo.Context) bool {
 
 	return false
 }
+
+// findSessionID returns the session of the access token in the request, the token might be expired.
+func findSessionID(c echo.Context, secret string) string {
+	token := findAccessToken(c)
+	if token == "" {
+		return ""
+	}
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Name {
+			return nil, errors.Errorf("unexpected access token signing method=%v, expect %v", t.Header["alg"], jwt.SigningMethodHS256)
+		}
+		if kid, ok := t.Header["kid"].(string); ok && kid == "v1" {
+			return []byte(secret), nil
+		}
+		return nil, errors.Errorf("unexpected access token kid=%v", t.Header["kid"])
+	}); err != nil {
+		var ve *jwt.ValidationError
+		if !errors.As(err, &ve) || ve.Errors != jwt.ValidationErrorExpired {
+			return ""
+		}
+	}
+	if !audienceContains(claims.Audience, auth.AccessTokenAudienceName) {
+		return ""
+	}
+	return claims.ID
+}
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/mail"
+	"github.com/usememos/memos/store"
+)
+
+const (
+	emailVerificationTokenDuration = 24 * time.Hour
+	passwordResetTokenDuration     = time.Hour
+	// verificationTokenResendInterval throttles the emails sent to the same user.
+	verificationTokenResendInterval = time.Minute
+)
+
+// mailTemplate is a templated email, executed with mailTemplateData.
+type mailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type mailTemplateData struct {
+	// SiteName is the name of the customized profile.
+	SiteName    string
+	ExternalURL string
+	Username    string
+	Link        string
+}
+
+func newMailTemplate(name, subject, body string) *mailTemplate {
+	return &mailTemplate{
+		subject: template.Must(template.New(name + "-subject").Parse(subject)),
+		body:    template.Must(template.New(name + "-body").Parse(body)),
+	}
+}
+
+var emailVerificationMailTemplate = newMailTemplate("email-verification",
+	`Verify your email for {{.SiteName}}`,
+	`Hi {{.Username}},
+
+Please verify your email address for {{.SiteName}} by opening the link below:
+
+{{.Link}}
+
+The link expires in 24 hours. If you did not sign up on {{.ExternalURL}}, you can ignore this email.
+`)
+
+var passwordResetMailTemplate = newMailTemplate("password-reset",
+	`Reset your password for {{.SiteName}}`,
+	`Hi {{.Username}},
+
+Someone requested to reset the password of your account on {{.SiteName}}. Open the link below to choose a new password:
+
+{{.Link}}
+
+The link expires in 1 hour. If you did not request it, you can ignore this email and your password stays the same.
+`)
+
+func (t *mailTemplate) execute(data *mailTemplateData) (*mail.Message, error) {
+	subject, body := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := t.subject.Execute(subject, data); err != nil {
+		return nil, err
+	}
+	if err := t.body.Execute(body, data); err != nil {
+		return nil, err
+	}
+	return &mail.Message{
+		Subject: subject.String(),
+		Body:    body.String(),
+	}, nil
+}
+
+// getMailSender returns the sender of the SMTP config system setting, nil if not configured.
+func (s *APIV1Service) getMailSender(ctx context.Context) (mail.Sender, error) {
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingSMTPConfigName.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if systemSetting == nil {
+		return nil, nil
+	}
+	config := &SMTPConfig{}
+	if err := json.Unmarshal([]byte(systemSetting.Value), config); err != nil {
+		return nil, err
+	}
+	return mail.NewSMTPSender(config.toPluginConfig())
+}
+
+// sendVerificationMail creates a verification token of the kind for the email of the user, and sends the link
+// with it to the path of the external URL.
+func (s *APIV1Service) sendVerificationMail(ctx context.Context, sender mail.Sender, user *store.User, kind store.VerificationTokenKind) error {
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get system customized profile")
+	}
+	if customizedProfile.ExternalURL == "" {
+		return errors.New("external URL is required to send emails")
+	}
+
+	mailTemplate, path, duration := emailVerificationMailTemplate, "/auth/verify-email", emailVerificationTokenDuration
+	if kind == store.VerificationTokenPasswordReset {
+		mailTemplate, path, duration = passwordResetMailTemplate, "/auth/reset-password", passwordResetTokenDuration
+	}
+	token, err := s.createVerificationToken(ctx, user, kind, duration)
+	if err != nil {
+		return errors.Wrap(err, "failed to create verification token")
+	}
+	message, err := mailTemplate.execute(&mailTemplateData{
+		SiteName:    customizedProfile.Name,
+		ExternalURL: customizedProfile.ExternalURL,
+		Username:    user.Username,
+		Link:        strings.TrimSuffix(customizedProfile.ExternalURL, "/") + path + "?token=" + url.QueryEscape(token),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to execute mail template")
+	}
+	message.To = []string{user.Email}
+	return sender.Send(ctx, message)
+}
+
+// createVerificationToken replaces the tokens of the kind of the user with a new one, only its hash is stored.
+func (s *APIV1Service) createVerificationToken(ctx context.Context, user *store.User, kind store.VerificationTokenKind, duration time.Duration) (string, error) {
+	currentTs := time.Now().Unix()
+	if err := s.Store.DeleteVerificationToken(ctx, &store.DeleteVerificationToken{
+		ExpiredTsBefore: &currentTs,
+	}); err != nil {
+		return "", err
+	}
+	if err := s.Store.DeleteVerificationToken(ctx, &store.DeleteVerificationToken{
+		UserID: &user.ID,
+		Kind:   &kind,
+	}); err != nil {
+		return "", err
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	if _, err := s.Store.CreateVerificationToken(ctx, &store.VerificationToken{
+		UserID:    user.ID,
+		ExpiredTs: time.Now().Add(duration).Unix(),
+		Kind:      kind,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findVerificationToken returns the unexpired token of the kind, nil if not found.
+func (s *APIV1Service) findVerificationToken(ctx context.Context, kind store.VerificationTokenKind, token string) (*store.VerificationToken, error) {
+	tokenHash := hashToken(token)
+	verificationToken, err := s.Store.GetVerificationToken(ctx, &store.FindVerificationToken{
+		Kind:      &kind,
+		TokenHash: &tokenHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verificationToken == nil || verificationToken.ExpiredTs < time.Now().Unix() {
+		return nil, nil
+	}
+	return verificationToken, nil
+}
+
+// isVerificationMailThrottled reports whether a token of the kind was sent to the user recently.
+func (s *APIV1Service) isVerificationMailThrottled(ctx context.Context, userID int, kind store.VerificationTokenKind) (bool, error) {
+	verificationToken, err := s.Store.GetVerificationToken(ctx, &store.FindVerificationToken{
+		UserID: &userID,
+		Kind:   &kind,
+	})
+	if err != nil {
+		return false, err
+	}
+	return verificationToken != nil && time.Now().Add(-verificationTokenResendInterval).Unix() < verificationToken.CreatedTs, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -47,15 +47,17 @@ type Memo struct {
 	UpdatedTs int64     `json:"updatedTs"`
 
 	// Domain specific fields
-	DisplayTs  int64      `json:"displayTs"`
-	Content    string     `json:"content"`
-	Visibility Visibility `json:"visibility"`
-	Pinned     bool       `json:"pinned"`
+	DisplayTs   int64      `json:"displayTs"`
+	Content     string     `json:"content"`
+	Visibility  Visibility `json:"visibility"`
+	Pinned      bool       `json:"pinned"`
+	WorkspaceID int        `json:"workspaceId"`
 
 	// Related fields
 	CreatorName  string          `json:"creatorName"`
 	ResourceList []*Resource     `json:"resourceList"`
 	RelationList []*MemoRelation `json:"relationList"`
+	GroupIDList  []int           `json:"groupIdList"`
 }
 
 type CreateMemoRequest struct {
@@ -64,12 +66,15 @@ type CreateMemoRequest struct {
 	CreatedTs *int64 `json:"createdTs"`
 
 	// Domain specific fields
-	Visibility Visibility `json:"visibility"`
-	Content    string     `json:"content"`
+	Visibility  Visibility `json:"visibility"`
+	Content     string     `json:"content"`
+	WorkspaceID int        `json:"workspaceId"`
 
 	// Related fields
 	ResourceIDList []int                        `json:"resourceIdList"`
 	RelationList   []*UpsertMemoRelationRequest `json:"relationList"`
+	// GroupIDList is the groups the memo is shared with regardless of its visibility.
+	GroupIDList []int `json:"groupIdList"`
 }
 
 type PatchMemoRequest struct {
@@ -87,6 +92,8 @@ type PatchMemoRequest struct {
 	// Related fields
 	ResourceIDList []int                        `json:"resourceIdList"`
 	RelationList   []*UpsertMemoRelationRequest `json:"relationList"`
+	// GroupIDList is the groups the memo is shared with regardless of its visibility.
+	GroupIDList []int `json:"groupIdList"`
 }
 
 type FindMemoRequest struct {
@@ -124,6 +131,12 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		if len(createMemoRequest.Content) > maxContentLength {
 			return echo.NewHTTPError(http.StatusBadRequest, "Content size overflow, up to 1MB")
 		}
+		if err := s.checkWorkspaceContentCreatable(ctx, createMemoRequest.WorkspaceID, userID); err != nil {
+			return err
+		}
+		if err := s.checkGroupsExist(ctx, createMemoRequest.GroupIDList); err != nil {
+			return err
+		}
 
 		if createMemoRequest.Visibility == "" {
 			userMemoVisibilitySetting, err := s.Store.GetUserSetting(ctx, &store.FindUserSetting{
@@ -146,10 +159,8 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			}
 		}
 
-		// Find disable public memos system setting.
-		disablePublicMemosSystemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
-			Name: SystemSettingDisablePublicMemosName.String(),
-		})
+		// Find disable public memos system setting of the workspace.
+		disablePublicMemosSystemSetting, err := getWorkspaceSystemSetting(ctx, s.Store, createMemoRequest.WorkspaceID, SystemSettingDisablePublicMemosName)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find system setting").SetInternal(err)
 		}
@@ -169,8 +180,12 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 				if user == nil {
 					return echo.NewHTTPError(http.StatusNotFound, "User not found")
 				}
-				// Enforce normal user to create private memo if public memos are disabled.
-				if user.Role == store.RoleUser {
+				// Enforce the users except moderators to create private memo if public memos are disabled.
+				isModerator, err := hasPermission(ctx, s.Store, user, PermissionMemoModerate)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+				}
+				if !isModerator {
 					createMemoRequest.Visibility = Private
 				}
 			}
@@ -194,6 +209,12 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			}
 		}
 
+		if len(createMemoRequest.GroupIDList) > 0 {
+			if err := s.Store.SetMemoGroupShares(ctx, memo.ID, createMemoRequest.GroupIDList); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set memo group shares").SetInternal(err)
+			}
+		}
+
 		for _, memoRelationUpsert := range createMemoRequest.RelationList {
 			if _, err := s.Store.UpsertMemoRelation(ctx, &store.MemoRelation{
 				MemoID:        memo.ID,
@@ -214,6 +235,8 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo not found: %d", memo.ID))
 		}
 
+		s.refreshMemoEmbeddingAsync(memo)
+
 		memoResponse, err := s.convertMemoFromStore(ctx, memo)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compose memo response").SetInternal(err)
@@ -242,8 +265,11 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		if memo == nil {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo not found: %d", memoID))
 		}
-		if memo.CreatorID != userID {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		isModerating := memo.CreatorID != userID
+		if isModerating {
+			if err := s.checkMemoModeratable(c, memo); err != nil {
+				return err
+			}
 		}
 
 		currentTs := time.Now().Unix()
@@ -254,10 +280,23 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		if err := json.NewDecoder(c.Request().Body).Decode(patchMemoRequest); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch memo request").SetInternal(err)
 		}
+		// The moderators can only archive and lower the visibility of the memos of other users.
+		if isModerating && (patchMemoRequest.CreatedTs != nil || patchMemoRequest.Content != nil || patchMemoRequest.ResourceIDList != nil || patchMemoRequest.RelationList != nil || patchMemoRequest.GroupIDList != nil) {
+			return echo.NewHTTPError(http.StatusForbidden, "Only the row status and visibility of the memos of other users can be moderated")
+		}
+		if isModerating && patchMemoRequest.RowStatus != nil && *patchMemoRequest.RowStatus != Archived {
+			return echo.NewHTTPError(http.StatusForbidden, "The memos of other users can only be archived")
+		}
+		if isModerating && patchMemoRequest.Visibility != nil && *patchMemoRequest.Visibility == Public {
+			return echo.NewHTTPError(http.StatusForbidden, "The visibility of the memos of other users can only be lowered")
+		}
 
 		if patchMemoRequest.Content != nil && len(*patchMemoRequest.Content) > maxContentLength {
 			return echo.NewHTTPError(http.StatusBadRequest, "Content size overflow, up to 1MB").SetInternal(err)
 		}
+		if err := s.checkGroupsExist(ctx, patchMemoRequest.GroupIDList); err != nil {
+			return err
+		}
 
 		updateMemoMessage := &store.UpdateMemo{
 			ID:        memoID,
@@ -285,6 +324,12 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		if memo == nil {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo not found: %d", memoID))
 		}
+		if err := s.createActivity(ctx, userID, ActivityMemoUpdate, ActivityInfo, ActivityMemoUpdatePayload{
+			MemoID:     memo.ID,
+			Visibility: memo.Visibility.String(),
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 
 		if patchMemoRequest.ResourceIDList != nil {
 			addedResourceIDList, removedResourceIDList := getIDListDiff(memo.ResourceIDList, patchMemoRequest.ResourceIDList)
@@ -306,6 +351,12 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			}
 		}
 
+		if patchMemoRequest.GroupIDList != nil {
+			if err := s.Store.SetMemoGroupShares(ctx, memo.ID, patchMemoRequest.GroupIDList); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set memo group shares").SetInternal(err)
+			}
+		}
+
 		if patchMemoRequest.RelationList != nil {
 			patchMemoRelationList := make([]*store.MemoRelation, 0)
 			for _, memoRelation := range patchMemoRequest.RelationList {
@@ -340,6 +391,10 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo not found: %d", memoID))
 		}
 
+		if patchMemoRequest.Content != nil {
+			s.refreshMemoEmbeddingAsync(memo)
+		}
+
 		memoResponse, err := s.convertMemoFromStore(ctx, memo)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compose memo response").SetInternal(err)
@@ -371,7 +426,14 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 				visibilityList = append(visibilityList, store.Private)
 			}
 			findMemoMessage.VisibilityList = visibilityList
+			findMemoMessage.MemberID = &currentUserID
+			findMemoMessage.SharedUserID = &currentUserID
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
+		findMemoMessage.WorkspaceID = workspaceID
 
 		rowStatus := store.RowStatus(c.QueryParam("rowStatus"))
 		if rowStatus != "" {
@@ -442,13 +504,26 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		}
 
 		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if memo.Visibility == store.Private {
-			if !ok || memo.CreatorID != userID {
-				return echo.NewHTTPError(http.StatusForbidden, "this memo is private only")
-			}
-		} else if memo.Visibility == store.Protected {
+		if memo.Visibility != store.Public && (!ok || memo.CreatorID != userID) {
 			if !ok {
-				return echo.NewHTTPError(http.StatusForbidden, "this memo is protected, missing user in session")
+				return echo.NewHTTPError(http.StatusForbidden, "this memo is not public, missing user in session")
+			}
+			// The memos shared with the groups of the user are visible to them regardless of the visibility.
+			isShared, err := s.Store.IsMemoSharedWithUser(ctx, memo.ID, userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo group shares").SetInternal(err)
+			}
+			if !isShared {
+				if memo.Visibility == store.Private {
+					return echo.NewHTTPError(http.StatusForbidden, "this memo is private only")
+				}
+				isMember, err := isWorkspaceMember(ctx, s.Store, memo.WorkspaceID, userID)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member").SetInternal(err)
+				}
+				if !isMember {
+					return echo.NewHTTPError(http.StatusForbidden, "this memo is protected to the workspace members")
+				}
 			}
 		}
 		memoResponse, err := s.convertMemoFromStore(ctx, memo)
@@ -480,7 +555,14 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			} else {
 				findMemoMessage.VisibilityList = []store.Visibility{store.Public, store.Protected, store.Private}
 			}
+			findMemoMessage.MemberID = &currentUserID
+			findMemoMessage.SharedUserID = &currentUserID
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
+		findMemoMessage.WorkspaceID = workspaceID
 
 		memoDisplayWithUpdatedTs, err := s.getMemoDisplayWithUpdatedTsSettingValue(ctx)
 		if err != nil {
@@ -513,12 +595,19 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 	g.GET("/memo/all", func(c echo.Context) error {
 		ctx := c.Request().Context()
 		findMemoMessage := &store.FindMemo{}
-		_, ok := c.Get(getUserIDContextKey()).(int)
+		currentUserID, ok := c.Get(getUserIDContextKey()).(int)
 		if !ok {
 			findMemoMessage.VisibilityList = []store.Visibility{store.Public}
 		} else {
 			findMemoMessage.VisibilityList = []store.Visibility{store.Public, store.Protected}
+			findMemoMessage.MemberID = &currentUserID
+			findMemoMessage.SharedUserID = &currentUserID
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
+		findMemoMessage.WorkspaceID = workspaceID
 
 		pinnedStr := c.QueryParam("pinned")
 		if pinnedStr != "" {
@@ -592,7 +681,9 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo not found: %d", memoID))
 		}
 		if memo.CreatorID != userID {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+			if err := s.checkMemoModeratable(c, memo); err != nil {
+				return err
+			}
 		}
 
 		if err := s.Store.DeleteMemo(ctx, &store.DeleteMemo{
@@ -600,10 +691,28 @@ func (s *APIV1Service) registerMemoRoutes(g *echo.Group) {
 		}); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to delete memo ID: %v", memoID)).SetInternal(err)
 		}
+		if err := s.createActivity(ctx, userID, ActivityMemoDelete, ActivityInfo, ActivityMemoDeletePayload{
+			MemoID:     memoID,
+			Visibility: memo.Visibility.String(),
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 		return c.JSON(http.StatusOK, true)
 	})
 }
 
+// checkMemoModeratable rejects moderating the memo of another user by the current user.
+// Only the public memos are moderated, so the moderators can not read the protected and private ones.
+func (s *APIV1Service) checkMemoModeratable(c echo.Context, memo *store.Memo) error {
+	if memo.Visibility != store.Public {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	if _, err := s.checkPermission(c, PermissionMemoModerate); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (s *APIV1Service) createMemoCreateActivity(ctx context.Context, memo *store.Memo) error {
 	payload := ActivityMemoCreatePayload{
 		Content:    memo.Content,
@@ -627,14 +736,15 @@ func (s *APIV1Service) createMemoCreateActivity(ctx context.Context, memo *store
 
 func (s *APIV1Service) convertMemoFromStore(ctx context.Context, memo *store.Memo) (*Memo, error) {
 	memoResponse := &Memo{
-		ID:         memo.ID,
-		RowStatus:  RowStatus(memo.RowStatus.String()),
-		CreatorID:  memo.CreatorID,
-		CreatedTs:  memo.CreatedTs,
-		UpdatedTs:  memo.UpdatedTs,
-		Content:    memo.Content,
-		Visibility: Visibility(memo.Visibility.String()),
-		Pinned:     memo.Pinned,
+		ID:          memo.ID,
+		RowStatus:   RowStatus(memo.RowStatus.String()),
+		CreatorID:   memo.CreatorID,
+		CreatedTs:   memo.CreatedTs,
+		UpdatedTs:   memo.UpdatedTs,
+		Content:     memo.Content,
+		Visibility:  Visibility(memo.Visibility.String()),
+		Pinned:      memo.Pinned,
+		WorkspaceID: memo.WorkspaceID,
 	}
 
 	// Compose creator name.
@@ -681,6 +791,12 @@ func (s *APIV1Service) convertMemoFromStore(ctx context.Context, memo *store.Mem
 	}
 	memoResponse.ResourceList = resourceList
 
+	groupIDList, err := s.Store.ListMemoGroupShares(ctx, memo.ID)
+	if err != nil {
+		return nil, err
+	}
+	memoResponse.GroupIDList = groupIDList
+
 	return memoResponse, nil
 }
 
@@ -707,10 +823,11 @@ func convertCreateMemoRequestToMemoMessage(memoCreate *CreateMemoRequest) *store
 		createdTs = *memoCreate.CreatedTs
 	}
 	return &store.Memo{
-		CreatorID:  memoCreate.CreatorID,
-		CreatedTs:  createdTs,
-		Content:    memoCreate.Content,
-		Visibility: store.Visibility(memoCreate.Visibility),
+		CreatorID:   memoCreate.CreatorID,
+		CreatedTs:   createdTs,
+		Content:     memoCreate.Content,
+		Visibility:  store.Visibility(memoCreate.Visibility),
+		WorkspaceID: memoCreate.WorkspaceID,
 	}
 }
 
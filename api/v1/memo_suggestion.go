@@ -0,0 +1,409 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/ai"
+	"github.com/usememos/memos/store"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// memoSummaryMinLength is the minimum rune count of memos to be summarized.
+	memoSummaryMinLength = 280
+	maxSuggestedTagCount = 3
+	// maxMemoSuggestionBatchSize is the max number of memos processed per user in a single run.
+	maxMemoSuggestionBatchSize = 10
+)
+
+type MemoSuggestionType string
+
+const (
+	MemoSuggestionTag     MemoSuggestionType = "TAG"
+	MemoSuggestionSummary MemoSuggestionType = "SUMMARY"
+)
+
+func (t MemoSuggestionType) String() string {
+	return string(t)
+}
+
+type MemoSuggestionStatus string
+
+const (
+	MemoSuggestionPending  MemoSuggestionStatus = "PENDING"
+	MemoSuggestionAccepted MemoSuggestionStatus = "ACCEPTED"
+	MemoSuggestionRejected MemoSuggestionStatus = "REJECTED"
+)
+
+func (s MemoSuggestionStatus) String() string {
+	return string(s)
+}
+
+type MemoSuggestion struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	MemoID  int                  `json:"memoId"`
+	Type    MemoSuggestionType   `json:"type"`
+	Content string               `json:"content"`
+	Status  MemoSuggestionStatus `json:"status"`
+}
+
+type UpdateMemoSuggestionRequest struct {
+	Status MemoSuggestionStatus `json:"status"`
+}
+
+func (s *APIV1Service) registerMemoSuggestionRoutes(g *echo.Group) {
+	g.GET("/memo-suggestion", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		find := &store.FindMemoSuggestion{
+			CreatorID: &userID,
+		}
+		if memoID, err := strconv.Atoi(c.QueryParam("memoId")); err == nil {
+			find.MemoID = &memoID
+		}
+		if status := store.MemoSuggestionStatus(c.QueryParam("status")); status != "" {
+			find.Status = &status
+		}
+		list, err := s.Store.ListMemoSuggestions(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo suggestion list").SetInternal(err)
+		}
+		memoSuggestionList := []*MemoSuggestion{}
+		for _, memoSuggestion := range list {
+			memoSuggestionList = append(memoSuggestionList, convertMemoSuggestionFromStore(memoSuggestion))
+		}
+		return c.JSON(http.StatusOK, memoSuggestionList)
+	})
+
+	g.PATCH("/memo-suggestion/:suggestionId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+		}
+
+		suggestionID, err := strconv.Atoi(c.Param("suggestionId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("suggestionId"))).SetInternal(err)
+		}
+		request := &UpdateMemoSuggestionRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch memo suggestion request").SetInternal(err)
+		}
+		if request.Status != MemoSuggestionAccepted && request.Status != MemoSuggestionRejected {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid status: %s", request.Status))
+		}
+
+		memoSuggestion, err := s.Store.GetMemoSuggestion(ctx, &store.FindMemoSuggestion{
+			ID:        &suggestionID,
+			CreatorID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo suggestion").SetInternal(err)
+		}
+		if memoSuggestion == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo suggestion not found: %d", suggestionID))
+		}
+
+		if request.Status == MemoSuggestionAccepted {
+			if err := s.acceptMemoSuggestion(ctx, userID, memoSuggestion); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to accept memo suggestion").SetInternal(err)
+			}
+		}
+
+		currentTs := time.Now().Unix()
+		status := store.MemoSuggestionStatus(request.Status)
+		memoSuggestion, err = s.Store.UpdateMemoSuggestion(ctx, &store.UpdateMemoSuggestion{
+			ID:        memoSuggestion.ID,
+			UpdatedTs: &currentTs,
+			Status:    &status,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch memo suggestion").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertMemoSuggestionFromStore(memoSuggestion))
+	})
+}
+
+func (s *APIV1Service) acceptMemoSuggestion(ctx context.Context, userID int, memoSuggestion *store.MemoSuggestion) error {
+	switch memoSuggestion.Type {
+	case store.MemoSuggestionTag:
+		memo, err := s.Store.GetMemo(ctx, &store.FindMemo{
+			ID: &memoSuggestion.MemoID,
+		})
+		if err != nil {
+			return err
+		}
+		if memo == nil {
+			return errors.Errorf("memo not found: %d", memoSuggestion.MemoID)
+		}
+		if _, err := s.Store.UpsertTag(ctx, &store.Tag{
+			Name:      memoSuggestion.Content,
+			CreatorID: userID,
+		}); err != nil {
+			return err
+		}
+		if slices.Contains(findTagListFromMemoContent(memo.Content), memoSuggestion.Content) {
+			return nil
+		}
+
+		content := strings.TrimRight(memo.Content, " ") + " #" + memoSuggestion.Content + " "
+		currentTs := time.Now().Unix()
+		if err := s.Store.UpdateMemo(ctx, &store.UpdateMemo{
+			ID:        memo.ID,
+			UpdatedTs: &currentTs,
+			Content:   &content,
+		}); err != nil {
+			return err
+		}
+		// Adding an accepted tag doesn't need the memo to be processed again.
+		if _, err := s.Store.UpsertMemoSuggestionCheckpoint(ctx, &store.MemoSuggestionCheckpoint{
+			MemoID:      memo.ID,
+			ContentHash: getMemoContentHash(content),
+		}); err != nil {
+			return err
+		}
+		s.refreshMemoEmbeddingAsync(memo)
+	case store.MemoSuggestionSummary:
+		// Only a single summary of the memo can be accepted.
+		summaryType, acceptedStatus := store.MemoSuggestionSummary, store.MemoSuggestionAccepted
+		list, err := s.Store.ListMemoSuggestions(ctx, &store.FindMemoSuggestion{
+			MemoID: &memoSuggestion.MemoID,
+			Type:   &summaryType,
+			Status: &acceptedStatus,
+		})
+		if err != nil {
+			return err
+		}
+		rejectedStatus := store.MemoSuggestionRejected
+		for _, accepted := range list {
+			if _, err := s.Store.UpdateMemoSuggestion(ctx, &store.UpdateMemoSuggestion{
+				ID:     accepted.ID,
+				Status: &rejectedStatus,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getMemoSummary returns the accepted summary of the memo, or empty if there is none.
+func (s *APIV1Service) getMemoSummary(ctx context.Context, memoID int) (string, error) {
+	summaryType, acceptedStatus := store.MemoSuggestionSummary, store.MemoSuggestionAccepted
+	memoSuggestion, err := s.Store.GetMemoSuggestion(ctx, &store.FindMemoSuggestion{
+		MemoID: &memoID,
+		Type:   &summaryType,
+		Status: &acceptedStatus,
+	})
+	if err != nil {
+		return "", err
+	}
+	if memoSuggestion == nil {
+		return "", nil
+	}
+	return memoSuggestion.Content, nil
+}
+
+// ProcessMemoSuggestions generates tag and summary suggestions for the memos of the user
+// which are new or changed since they were processed. It returns the number of processed memos.
+func ProcessMemoSuggestions(ctx context.Context, s *store.Store, userID int) (int, error) {
+	aiConfig, err := getAIConfig(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	if aiConfig == nil {
+		return 0, nil
+	}
+
+	normalStatus := store.Normal
+	memoList, err := s.ListMemos(ctx, &store.FindMemo{
+		CreatorID: &userID,
+		RowStatus: &normalStatus,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list memos")
+	}
+	checkpointList, err := s.ListMemoSuggestionCheckpoints(ctx, &store.FindMemoSuggestionCheckpoint{
+		CreatorID: &userID,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list memo suggestion checkpoints")
+	}
+	checkpointMap := map[int]string{}
+	for _, checkpoint := range checkpointList {
+		checkpointMap[checkpoint.MemoID] = checkpoint.ContentHash
+	}
+	tagList, err := s.ListTags(ctx, &store.FindTag{
+		CreatorID: userID,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list tags")
+	}
+	tagNameList := []string{}
+	for _, tag := range tagList {
+		tagNameList = append(tagNameList, tag.Name)
+	}
+
+	processedCount := 0
+	for _, memo := range memoList {
+		if processedCount >= maxMemoSuggestionBatchSize {
+			break
+		}
+		contentHash := getMemoContentHash(memo.Content)
+		if checkpointMap[memo.ID] == contentHash {
+			continue
+		}
+
+		if err := suggestMemoTags(ctx, s, memo, tagNameList); err != nil {
+			return processedCount, errors.Wrap(err, "failed to suggest memo tags")
+		}
+		if err := suggestMemoSummary(ctx, s, memo); err != nil {
+			return processedCount, errors.Wrap(err, "failed to suggest memo summary")
+		}
+		if _, err := s.UpsertMemoSuggestionCheckpoint(ctx, &store.MemoSuggestionCheckpoint{
+			MemoID:      memo.ID,
+			ContentHash: contentHash,
+		}); err != nil {
+			return processedCount, errors.Wrap(err, "failed to upsert memo suggestion checkpoint")
+		}
+		processedCount++
+	}
+	return processedCount, nil
+}
+
+// suggestMemoTags asks the AI to pick the tags fitting the memo from the existing tag vocabulary of the user.
+func suggestMemoTags(ctx context.Context, s *store.Store, memo *store.Memo, tagNameList []string) error {
+	existingTagList := findTagListFromMemoContent(memo.Content)
+	candidateList := []string{}
+	for _, tagName := range tagNameList {
+		if !slices.Contains(existingTagList, tagName) {
+			candidateList = append(candidateList, tagName)
+		}
+	}
+	if len(candidateList) == 0 {
+		return nil
+	}
+
+	response, err := ChatWithAI(ctx, s, memo.CreatorID, []ai.Message{
+		{
+			Role: "system",
+			Content: fmt.Sprintf("You suggest tags for the memo of the user. "+
+				"Choose at most %d tags which describe the memo from the following list: %s. "+
+				"Reply only with the chosen tags separated by commas, or NONE if no tag fits.",
+				maxSuggestedTagCount, strings.Join(candidateList, ", ")),
+		},
+		{
+			Role:    "user",
+			Content: memo.Content,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	suggestedCount := 0
+	for _, field := range strings.FieldsFunc(response.Content, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		tagName := strings.TrimPrefix(strings.TrimSpace(field), "#")
+		if !slices.Contains(candidateList, tagName) || suggestedCount >= maxSuggestedTagCount {
+			continue
+		}
+		suggestedCount++
+		if err := createMemoSuggestionIfNotExists(ctx, s, memo.ID, store.MemoSuggestionTag, tagName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// suggestMemoSummary asks the AI for a one-line summary of long memos, replacing the pending one.
+func suggestMemoSummary(ctx context.Context, s *store.Store, memo *store.Memo) error {
+	if utf8.RuneCountInString(memo.Content) < memoSummaryMinLength {
+		return nil
+	}
+
+	response, err := ChatWithAI(ctx, s, memo.CreatorID, []ai.Message{
+		{
+			Role:    "system",
+			Content: "Summarize the memo of the user in a single line of at most 80 characters. Reply only with the summary.",
+		},
+		{
+			Role:    "user",
+			Content: memo.Content,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	summary := strings.Trim(strings.TrimSpace(strings.Split(strings.TrimSpace(response.Content), "\n")[0]), `"`)
+	if summary == "" {
+		return nil
+	}
+
+	summaryType, pendingStatus := store.MemoSuggestionSummary, store.MemoSuggestionPending
+	if err := s.DeleteMemoSuggestion(ctx, &store.DeleteMemoSuggestion{
+		MemoID: memo.ID,
+		Type:   &summaryType,
+		Status: &pendingStatus,
+	}); err != nil {
+		return err
+	}
+	return createMemoSuggestionIfNotExists(ctx, s, memo.ID, store.MemoSuggestionSummary, summary)
+}
+
+// createMemoSuggestionIfNotExists creates the suggestion unless it has been suggested before,
+// so that the rejected suggestions are not brought up again.
+func createMemoSuggestionIfNotExists(ctx context.Context, s *store.Store, memoID int, suggestionType store.MemoSuggestionType, content string) error {
+	list, err := s.ListMemoSuggestions(ctx, &store.FindMemoSuggestion{
+		MemoID: &memoID,
+		Type:   &suggestionType,
+	})
+	if err != nil {
+		return err
+	}
+	for _, memoSuggestion := range list {
+		if memoSuggestion.Content == content {
+			return nil
+		}
+	}
+
+	_, err = s.CreateMemoSuggestion(ctx, &store.MemoSuggestion{
+		MemoID:  memoID,
+		Type:    suggestionType,
+		Content: content,
+	})
+	return err
+}
+
+func convertMemoSuggestionFromStore(memoSuggestion *store.MemoSuggestion) *MemoSuggestion {
+	return &MemoSuggestion{
+		ID:        memoSuggestion.ID,
+		CreatedTs: memoSuggestion.CreatedTs,
+		UpdatedTs: memoSuggestion.UpdatedTs,
+		MemoID:    memoSuggestion.MemoID,
+		Type:      MemoSuggestionType(memoSuggestion.Type),
+		Content:   memoSuggestion.Content,
+		Status:    MemoSuggestionStatus(memoSuggestion.Status),
+	}
+}
@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordResetRequest struct {
+	// Username or Email finds the user to send the password reset email to.
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (s *APIV1Service) registerPasswordResetRoutes(g *echo.Group) {
+	// POST /auth/password-reset - Send a password reset email to the verified email of the user.
+	g.POST("/auth/password-reset", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		request := &PasswordResetRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted password reset request").SetInternal(err)
+		}
+		if request.Username == "" && request.Email == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Username or email is required")
+		}
+		if err := s.checkSignInLockout(c, ""); err != nil {
+			return err
+		}
+
+		sender, err := s.getMailSender(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get mail sender").SetInternal(err)
+		}
+		if sender == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "SMTP is not configured")
+		}
+
+		normalStatus := store.Normal
+		userFind := &store.FindUser{
+			RowStatus: &normalStatus,
+		}
+		if request.Username != "" {
+			userFind.Username = &request.Username
+		} else {
+			userFind.Email = &request.Email
+		}
+		userList, err := s.Store.ListUsers(ctx, userFind)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user list").SetInternal(err)
+		}
+		// Always respond with success, so the request does not reveal whether the account exists.
+		for _, user := range userList {
+			if user.Email == "" || !user.EmailVerified {
+				continue
+			}
+			throttled, err := s.isVerificationMailThrottled(ctx, user.ID, store.VerificationTokenPasswordReset)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find verification token").SetInternal(err)
+			}
+			if throttled {
+				continue
+			}
+			if err := s.sendVerificationMail(ctx, sender, user, store.VerificationTokenPasswordReset); err != nil {
+				log.WithContext(ctx).Warn(fmt.Sprintf("failed to send password reset email to user %d", user.ID), zap.Error(err))
+			}
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	// POST /auth/password-reset/confirm - Reset the password with the token of the password reset email.
+	g.POST("/auth/password-reset/confirm", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		request := &PasswordResetConfirmRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted password reset confirm request").SetInternal(err)
+		}
+		if err := (UpdateUserRequest{Password: &request.Password}).Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid password").SetInternal(err)
+		}
+		if err := s.checkSignInLockout(c, ""); err != nil {
+			return err
+		}
+
+		verificationToken, err := s.findVerificationToken(ctx, store.VerificationTokenPasswordReset, request.Token)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find verification token").SetInternal(err)
+		}
+		var user *store.User
+		if verificationToken != nil {
+			user, err = s.Store.GetUser(ctx, &store.FindUser{
+				ID: &verificationToken.UserID,
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+			}
+		}
+		// The token is only valid for the email it was sent to.
+		if user == nil || user.RowStatus == store.Archived || user.Email != verificationToken.Email {
+			if err := s.recordSignInFailure(c, UnknownID, ""); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired password reset token")
+		}
+
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
+		}
+		currentTs := time.Now().Unix()
+		passwordHashStr := string(passwordHash)
+		if _, err := s.Store.UpdateUser(ctx, &store.UpdateUser{
+			ID:           user.ID,
+			UpdatedTs:    &currentTs,
+			PasswordHash: &passwordHashStr,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch user").SetInternal(err)
+		}
+		// Resetting the password signs out everywhere and invalidates the other reset links.
+		if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+			UserID: &user.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke sessions").SetInternal(err)
+		}
+		kind := store.VerificationTokenPasswordReset
+		if err := s.Store.DeleteVerificationToken(ctx, &store.DeleteVerificationToken{
+			UserID: &user.ID,
+			Kind:   &kind,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete verification token").SetInternal(err)
+		}
+		if err := s.clearSignInFailures(ctx, user.Username); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to clear sign-in failures").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
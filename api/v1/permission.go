@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/store"
+)
+
+// Permission is a named power granted to users by their built-in role and custom roles.
+type Permission string
+
+const (
+	// PermissionUserManage allows to create, update, archive and delete users, and to manage their invitations, sign-in lockouts and provisioning.
+	PermissionUserManage Permission = "user.manage"
+	// PermissionRoleManage allows to define custom roles and assign them to users.
+	PermissionRoleManage Permission = "role.manage"
+	// PermissionStorageManage allows to manage the storages of resources.
+	PermissionStorageManage Permission = "storage.manage"
+	// PermissionIdentityProviderManage allows to manage the identity providers.
+	PermissionIdentityProviderManage Permission = "idp.manage"
+	// PermissionSystemManage allows to update the system settings and vacuum the database.
+	PermissionSystemManage Permission = "system.manage"
+	// PermissionActivityView allows to view the activities of all users.
+	PermissionActivityView Permission = "activity.view"
+	// PermissionMemoModerate allows to archive, delete and lower the visibility of the public memos of other users,
+	// and to publish public memos when they are disabled.
+	PermissionMemoModerate Permission = "memo.moderate"
+	// PermissionWorkspaceManage allows to create and delete workspaces, and to manage all of them as their admins.
+	PermissionWorkspaceManage Permission = "workspace.manage"
+	// PermissionGroupManage allows to create, update and delete user groups, and to manage their members.
+	PermissionGroupManage Permission = "group.manage"
+	// PermissionStatsView allows to view the instance-wide statistics.
+	PermissionStatsView Permission = "stats.view"
+)
+
+func (permission Permission) String() string {
+	return string(permission)
+}
+
+// permissionList is all the permissions in display order.
+var permissionList = []Permission{
+	PermissionUserManage,
+	PermissionRoleManage,
+	PermissionStorageManage,
+	PermissionIdentityProviderManage,
+	PermissionSystemManage,
+	PermissionActivityView,
+	PermissionMemoModerate,
+	PermissionWorkspaceManage,
+	PermissionGroupManage,
+	PermissionStatsView,
+}
+
+// builtInRolePermissions maps the built-in roles to their permissions.
+var builtInRolePermissions = map[store.Role][]Permission{
+	store.RoleHost: permissionList,
+	store.RoleAdmin: {
+		PermissionUserManage,
+		PermissionRoleManage,
+		PermissionActivityView,
+		PermissionMemoModerate,
+		PermissionWorkspaceManage,
+		PermissionGroupManage,
+		PermissionStatsView,
+	},
+	store.RoleUser: {},
+}
+
+func isValidPermission(permission Permission) bool {
+	for _, p := range permissionList {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// getUserPermissions returns the permissions of the built-in role and the custom roles of the user and their groups.
+func getUserPermissions(ctx context.Context, s *store.Store, user *store.User) ([]Permission, error) {
+	granted := map[Permission]bool{}
+	for _, permission := range builtInRolePermissions[user.Role] {
+		granted[permission] = true
+	}
+	customRoleList, err := s.ListCustomRoles(ctx, &store.FindCustomRole{
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	groupCustomRoleList, err := s.ListCustomRoles(ctx, &store.FindCustomRole{
+		GroupMemberID: &user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	customRoleList = append(customRoleList, groupCustomRoleList...)
+	for _, customRole := range customRoleList {
+		for _, permission := range customRole.Permissions {
+			granted[Permission(permission)] = true
+		}
+	}
+
+	permissions := []Permission{}
+	for _, permission := range permissionList {
+		if granted[permission] {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+// hasPermission returns whether the user is granted the permission.
+func hasPermission(ctx context.Context, s *store.Store, user *store.User, permission Permission) (bool, error) {
+	permissions, err := getUserPermissions(ctx, s, user)
+	if err != nil {
+		return false, err
+	}
+	return containsPermission(permissions, permission), nil
+}
+
+func containsPermission(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPermission returns the current user if granted the permission, or the HTTP error to return.
+func (s *APIV1Service) checkPermission(c echo.Context, permission Permission) (*store.User, error) {
+	user, err := s.getCurrentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := hasPermission(c.Request().Context(), s.Store, user, permission)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+	}
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Permission denied: %s", permission))
+	}
+	return user, nil
+}
+
+// checkUserManageable rejects managing the target user by the current user holding the user management permission.
+// The host can only be managed by itself and the admins only by the host. The other users can only be managed
+// when all their permissions are granted to the current user, preventing privilege escalation by taking them over.
+func (s *APIV1Service) checkUserManageable(c echo.Context, currentUser *store.User, targetUser *store.User) error {
+	if currentUser.ID == targetUser.ID {
+		return nil
+	}
+	if targetUser.Role == store.RoleHost {
+		return echo.NewHTTPError(http.StatusForbidden, "The host can not be managed by other users")
+	}
+	if targetUser.Role == store.RoleAdmin && currentUser.Role != store.RoleHost {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the host can manage admins")
+	}
+	targetPermissions, err := getUserPermissions(c.Request().Context(), s.Store, targetUser)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+	}
+	return s.checkPermissionsGrantable(c, currentUser, targetPermissions)
+}
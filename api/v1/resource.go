@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/plugin/metrics"
 	"github.com/usememos/memos/plugin/storage/s3"
 	"github.com/usememos/memos/store"
 	"go.uber.org/zap"
@@ -43,6 +44,7 @@ type Resource struct {
 	ExternalLink string `json:"externalLink"`
 	Type         string `json:"type"`
 	Size         int64  `json:"size"`
+	WorkspaceID  int    `json:"workspaceId"`
 
 	// Related fields
 	LinkedMemoAmount int `json:"linkedMemoAmount"`
@@ -54,6 +56,7 @@ type CreateResourceRequest struct {
 	ExternalLink    string `json:"externalLink"`
 	Type            string `json:"type"`
 	DownloadToLocal bool   `json:"downloadToLocal"`
+	WorkspaceID     int    `json:"workspaceId"`
 }
 
 type FindResourceRequest struct {
@@ -91,12 +94,16 @@ func (s *APIV1Service) registerResourceRoutes(g *echo.Group) {
 		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post resource request").SetInternal(err)
 		}
+		if err := s.checkWorkspaceContentCreatable(ctx, request.WorkspaceID, userID); err != nil {
+			return err
+		}
 
 		create := &store.Resource{
 			CreatorID:    userID,
 			Filename:     request.Filename,
 			ExternalLink: request.ExternalLink,
 			Type:         request.Type,
+			WorkspaceID:  request.WorkspaceID,
 		}
 		if request.ExternalLink != "" {
 			// Only allow those external links scheme with http/https
@@ -161,13 +168,27 @@ func (s *APIV1Service) registerResourceRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
 		}
 
+		workspaceID := store.DefaultWorkspaceID
+		if value := c.FormValue("workspaceId"); value != "" {
+			var err error
+			if workspaceID, err = strconv.Atoi(value); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Workspace ID is not a number: %s", value)).SetInternal(err)
+			}
+		}
+		if err := s.checkWorkspaceContentCreatable(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+
 		// This is the backend default max upload size limit.
-		maxUploadSetting := s.Store.GetSystemSettingValueWithDefault(&ctx, SystemSettingMaxUploadSizeMiBName.String(), "32")
+		maxUploadSetting := "32"
+		if systemSetting, err := getWorkspaceSystemSetting(ctx, s.Store, workspaceID, SystemSettingMaxUploadSizeMiBName); err == nil && systemSetting != nil {
+			maxUploadSetting = systemSetting.Value
+		}
 		var settingMaxUploadSizeBytes int
 		if settingMaxUploadSizeMiB, err := strconv.Atoi(maxUploadSetting); err == nil {
 			settingMaxUploadSizeBytes = settingMaxUploadSizeMiB * MebiByte
 		} else {
-			log.Warn("Failed to parse max upload size", zap.Error(err))
+			log.WithContext(ctx).Warn("Failed to parse max upload size", zap.Error(err))
 			settingMaxUploadSizeBytes = 0
 		}
 
@@ -194,10 +215,11 @@ func (s *APIV1Service) registerResourceRoutes(g *echo.Group) {
 		defer sourceFile.Close()
 
 		create := &store.Resource{
-			CreatorID: userID,
-			Filename:  file.Filename,
-			Type:      file.Header.Get("Content-Type"),
-			Size:      file.Size,
+			CreatorID:   userID,
+			Filename:    file.Filename,
+			Type:        file.Header.Get("Content-Type"),
+			Size:        file.Size,
+			WorkspaceID: workspaceID,
 		}
 		err = SaveResourceBlob(ctx, s.Store, create, sourceFile)
 		if err != nil {
@@ -223,6 +245,11 @@ func (s *APIV1Service) registerResourceRoutes(g *echo.Group) {
 		find := &store.FindResource{
 			CreatorID: &userID,
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
+		find.WorkspaceID = workspaceID
 		if limit, err := strconv.Atoi(c.QueryParam("limit")); err == nil {
 			find.Limit = &limit
 		}
@@ -310,23 +337,18 @@ func (s *APIV1Service) registerResourceRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Resource not found: %d", resourceID))
 		}
 
-		if resource.InternalPath != "" {
-			if err := os.Remove(resource.InternalPath); err != nil {
-				log.Warn(fmt.Sprintf("failed to delete local file with path %s", resource.InternalPath), zap.Error(err))
-			}
-		}
-
-		ext := filepath.Ext(resource.Filename)
-		thumbnailPath := filepath.Join(s.Profile.Data, thumbnailImagePath, fmt.Sprintf("%d%s", resource.ID, ext))
-		if err := os.Remove(thumbnailPath); err != nil {
-			log.Warn(fmt.Sprintf("failed to delete local thumbnail with path %s", thumbnailPath), zap.Error(err))
-		}
-
 		if err := s.Store.DeleteResource(ctx, &store.DeleteResource{
 			ID: resourceID,
 		}); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete resource").SetInternal(err)
 		}
+		s.deleteResourceLocalFiles(ctx, resource)
+		if err := s.createActivity(ctx, userID, ActivityResourceDelete, ActivityInfo, ActivityResourceDeletePayload{
+			ResourceID: resource.ID,
+			Filename:   resource.Filename,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 		return c.JSON(http.StatusOK, true)
 	})
 }
@@ -361,10 +383,30 @@ func (s *APIV1Service) registerResourcePublicRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Resource not found: %d", resourceID))
 		}
 
+		// Resource of a memo shared with the groups of the logined user is visible to them
+		if resourceVisibility != store.Public && ok && userID != resource.CreatorID {
+			isShared, err := isResourceSharedWithUser(ctx, s.Store, resourceID, userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo group shares").SetInternal(err)
+			}
+			if isShared {
+				resourceVisibility = store.Public
+			}
+		}
 		// Private resource require logined user is the creator
 		if resourceVisibility == store.Private && (!ok || userID != resource.CreatorID) {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Resource visibility not match").SetInternal(err)
 		}
+		// Protected resource of a workspace require logined user is its member
+		if resourceVisibility == store.Protected && userID != resource.CreatorID {
+			isMember, err := isWorkspaceMember(ctx, s.Store, resource.WorkspaceID, userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member").SetInternal(err)
+			}
+			if !isMember {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Resource visibility not match")
+			}
+		}
 
 		blob := resource.Blob
 		if resource.InternalPath != "" {
@@ -385,7 +427,7 @@ func (s *APIV1Service) registerResourcePublicRoutes(g *echo.Group) {
 			thumbnailPath := filepath.Join(s.Profile.Data, thumbnailImagePath, fmt.Sprintf("%d%s", resource.ID, ext))
 			thumbnailBlob, err := getOrGenerateThumbnailImage(blob, thumbnailPath)
 			if err != nil {
-				log.Warn(fmt.Sprintf("failed to get or generate local thumbnail with path %s", thumbnailPath), zap.Error(err))
+				log.WithContext(ctx).Warn(fmt.Sprintf("failed to get or generate local thumbnail with path %s", thumbnailPath), zap.Error(err))
 			} else {
 				blob = thumbnailBlob
 			}
@@ -455,7 +497,16 @@ func replacePathTemplate(path, filename string) string {
 	return path
 }
 
-var availableGeneratorAmount int32 = 32
+// thumbnailGeneratorAmount is the max number of thumbnails generated at the same time.
+const thumbnailGeneratorAmount = 32
+
+var availableGeneratorAmount int32 = thumbnailGeneratorAmount
+
+var (
+	thumbnailGeneratorsInUse           = metrics.NewGaugeVec("memos_thumbnail_generators_in_use", fmt.Sprintf("Thumbnails being generated, out of at most %d at the same time.", thumbnailGeneratorAmount))
+	thumbnailGenerationsTotal          = metrics.NewCounterVec("memos_thumbnail_generations_total", "Thumbnail generations by result, generated, failed or rejected when all the generators are in use.", "result")
+	thumbnailGenerationDurationSeconds = metrics.NewHistogramVec("memos_thumbnail_generation_duration_seconds", "Duration of the thumbnail generations.", nil)
+)
 
 func getOrGenerateThumbnailImage(srcBlob []byte, dstPath string) ([]byte, error) {
 	if _, err := os.Stat(dstPath); err != nil {
@@ -464,28 +515,24 @@ func getOrGenerateThumbnailImage(srcBlob []byte, dstPath string) ([]byte, error)
 		}
 
 		if atomic.LoadInt32(&availableGeneratorAmount) <= 0 {
+			thumbnailGenerationsTotal.Inc("rejected")
 			return nil, errors.New("not enough available generator amount")
 		}
 		atomic.AddInt32(&availableGeneratorAmount, -1)
+		thumbnailGeneratorsInUse.Inc()
 		defer func() {
 			atomic.AddInt32(&availableGeneratorAmount, 1)
+			thumbnailGeneratorsInUse.Dec()
 		}()
 
-		reader := bytes.NewReader(srcBlob)
-		src, err := imaging.Decode(reader, imaging.AutoOrientation(true))
+		start := time.Now()
+		err := generateThumbnailImage(srcBlob, dstPath)
+		thumbnailGenerationDurationSeconds.Observe(time.Since(start).Seconds())
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode thumbnail image")
-		}
-		thumbnailImage := imaging.Resize(src, 512, 0, imaging.Lanczos)
-
-		dstDir := path.Dir(dstPath)
-		if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
-			return nil, errors.Wrap(err, "failed to create thumbnail dir")
-		}
-
-		if err := imaging.Save(thumbnailImage, dstPath); err != nil {
-			return nil, errors.Wrap(err, "failed to resize thumbnail image")
+			thumbnailGenerationsTotal.Inc("failed")
+			return nil, err
 		}
+		thumbnailGenerationsTotal.Inc("generated")
 	}
 
 	dstFile, err := os.Open(dstPath)
@@ -500,6 +547,25 @@ func getOrGenerateThumbnailImage(srcBlob []byte, dstPath string) ([]byte, error)
 	return dstBlob, nil
 }
 
+func generateThumbnailImage(srcBlob []byte, dstPath string) error {
+	reader := bytes.NewReader(srcBlob)
+	src, err := imaging.Decode(reader, imaging.AutoOrientation(true))
+	if err != nil {
+		return errors.Wrap(err, "failed to decode thumbnail image")
+	}
+	thumbnailImage := imaging.Resize(src, 512, 0, imaging.Lanczos)
+
+	dstDir := path.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "failed to create thumbnail dir")
+	}
+
+	if err := imaging.Save(thumbnailImage, dstPath); err != nil {
+		return errors.Wrap(err, "failed to resize thumbnail image")
+	}
+	return nil
+}
+
 func checkResourceVisibility(ctx context.Context, s *store.Store, resourceID int) (store.Visibility, error) {
 	memoResources, err := s.ListMemoResources(ctx, &store.FindMemoResource{
 		ResourceID: &resourceID,
@@ -541,6 +607,26 @@ func checkResourceVisibility(ctx context.Context, s *store.Store, resourceID int
 	return store.Private, nil
 }
 
+// isResourceSharedWithUser returns whether any memo of the resource is shared with the groups of the user.
+func isResourceSharedWithUser(ctx context.Context, s *store.Store, resourceID int, userID int) (bool, error) {
+	memoResources, err := s.ListMemoResources(ctx, &store.FindMemoResource{
+		ResourceID: &resourceID,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, memoResource := range memoResources {
+		isShared, err := s.IsMemoSharedWithUser(ctx, memoResource.MemoID, userID)
+		if err != nil {
+			return false, err
+		}
+		if isShared {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func convertResourceFromStore(resource *store.Resource) *Resource {
 	return &Resource{
 		ID:               resource.ID,
@@ -553,6 +639,7 @@ func convertResourceFromStore(resource *store.Resource) *Resource {
 		ExternalLink:     resource.ExternalLink,
 		Type:             resource.Type,
 		Size:             resource.Size,
+		WorkspaceID:      resource.WorkspaceID,
 		LinkedMemoAmount: resource.LinkedMemoAmount,
 	}
 }
@@ -563,8 +650,9 @@ func convertResourceFromStore(resource *store.Resource) *Resource {
 // 1. *DatabaseStorage*: `create.Blob`.
 // 2. *LocalStorage*: `create.InternalPath`.
 // 3. Others( external service): `create.ExternalLink`.
+// The storage settings are the ones of the workspace of the resource.
 func SaveResourceBlob(ctx context.Context, s *store.Store, create *store.Resource, r io.Reader) error {
-	systemSettingStorageServiceID, err := s.GetSystemSetting(ctx, &store.FindSystemSetting{Name: SystemSettingStorageServiceIDName.String()})
+	systemSettingStorageServiceID, err := getWorkspaceSystemSetting(ctx, s, create.WorkspaceID, SystemSettingStorageServiceIDName)
 	if err != nil {
 		return fmt.Errorf("Failed to find SystemSettingStorageServiceIDName: %s", err)
 	}
@@ -589,7 +677,7 @@ func SaveResourceBlob(ctx context.Context, s *store.Store, create *store.Resourc
 
 	// `LocalStorage` means save blob into local disk
 	if storageServiceID == LocalStorage {
-		systemSettingLocalStoragePath, err := s.GetSystemSetting(ctx, &store.FindSystemSetting{Name: SystemSettingLocalStoragePathName.String()})
+		systemSettingLocalStoragePath, err := getWorkspaceSystemSetting(ctx, s, create.WorkspaceID, SystemSettingLocalStoragePathName)
 		if err != nil {
 			return fmt.Errorf("Failed to find SystemSettingLocalStoragePathName: %s", err)
 		}
@@ -642,15 +730,7 @@ func SaveResourceBlob(ctx context.Context, s *store.Store, create *store.Resourc
 	}
 
 	s3Config := storageMessage.Config.S3Config
-	s3Client, err := s3.NewClient(ctx, &s3.Config{
-		AccessKey: s3Config.AccessKey,
-		SecretKey: s3Config.SecretKey,
-		EndPoint:  s3Config.EndPoint,
-		Region:    s3Config.Region,
-		Bucket:    s3Config.Bucket,
-		URLPrefix: s3Config.URLPrefix,
-		URLSuffix: s3Config.URLSuffix,
-	})
+	s3Client, err := newS3Client(ctx, s3Config)
 	if err != nil {
 		return fmt.Errorf("Failed to create s3 client: %s", err)
 	}
@@ -667,5 +747,73 @@ func SaveResourceBlob(ctx context.Context, s *store.Store, create *store.Resourc
 	}
 
 	create.ExternalLink = link
+	create.StorageID = storage.ID
+	create.ObjectKey = filePath
 	return nil
 }
+
+func newS3Client(ctx context.Context, s3Config *StorageS3Config) (*s3.Client, error) {
+	return s3.NewClient(ctx, &s3.Config{
+		AccessKey: s3Config.AccessKey,
+		SecretKey: s3Config.SecretKey,
+		EndPoint:  s3Config.EndPoint,
+		Region:    s3Config.Region,
+		Bucket:    s3Config.Bucket,
+		URLPrefix: s3Config.URLPrefix,
+		URLSuffix: s3Config.URLSuffix,
+	})
+}
+
+// deleteResourceLocalFiles deletes the local file and the thumbnail of the deleted resource.
+// The failures are only logged as the resource is already deleted.
+func (s *APIV1Service) deleteResourceLocalFiles(ctx context.Context, resource *store.Resource) {
+	if resource.InternalPath != "" {
+		if err := os.Remove(resource.InternalPath); err != nil {
+			log.WithContext(ctx).Warn(fmt.Sprintf("failed to delete local file with path %s", resource.InternalPath), zap.Error(err))
+		}
+	}
+
+	ext := filepath.Ext(resource.Filename)
+	thumbnailPath := filepath.Join(s.Profile.Data, thumbnailImagePath, fmt.Sprintf("%d%s", resource.ID, ext))
+	if err := os.Remove(thumbnailPath); err != nil && !os.IsNotExist(err) {
+		log.WithContext(ctx).Warn(fmt.Sprintf("failed to delete local thumbnail with path %s", thumbnailPath), zap.Error(err))
+	}
+}
+
+// deleteS3Object deletes the object the server uploaded for the deleted resource by its recorded storage and key.
+// The resources only linking to an external object are left alone, as are the objects still used by other resources.
+func (s *APIV1Service) deleteS3Object(ctx context.Context, resource *store.Resource) error {
+	if resource.StorageID == 0 || resource.ObjectKey == "" {
+		return nil
+	}
+	list, err := s.Store.ListResources(ctx, &store.FindResource{
+		StorageID: &resource.StorageID,
+		ObjectKey: &resource.ObjectKey,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to find resources")
+	}
+	if len(list) > 0 {
+		return nil
+	}
+
+	storage, err := s.Store.GetStorage(ctx, &store.FindStorage{ID: &resource.StorageID})
+	if err != nil {
+		return errors.Wrap(err, "failed to find storage")
+	}
+	if storage == nil {
+		return nil
+	}
+	storageMessage, err := ConvertStorageFromStore(storage)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert storage")
+	}
+	if storageMessage.Type != StorageS3 || storageMessage.Config.S3Config == nil {
+		return nil
+	}
+	s3Client, err := newS3Client(ctx, storageMessage.Config.S3Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create s3 client")
+	}
+	return s3Client.DeleteFile(ctx, resource.ObjectKey)
+}
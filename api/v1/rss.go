@@ -3,8 +3,12 @@ package v1
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,118 +19,326 @@ import (
 	"github.com/usememos/memos/common/util"
 	"github.com/usememos/memos/store"
 	"github.com/yuin/goldmark"
+	"golang.org/x/exp/slices"
 )
 
 const maxRSSItemCount = 100
 const maxRSSItemTitleLength = 100
 
+// FeedFormat is the output format of a memo feed.
+type FeedFormat string
+
+const (
+	// FeedFormatRSS is the RSS 2.0 format.
+	FeedFormatRSS FeedFormat = "rss"
+	// FeedFormatAtom is the Atom 1.0 format.
+	FeedFormatAtom FeedFormat = "atom"
+	// FeedFormatJSON is the JSON Feed 1.0 format.
+	FeedFormatJSON FeedFormat = "json"
+)
+
+// feedFileNames maps the file names of feed routes to their formats.
+var feedFileNames = map[string]FeedFormat{
+	"rss.xml":   FeedFormatRSS,
+	"atom.xml":  FeedFormatAtom,
+	"feed.json": FeedFormatJSON,
+}
+
+// FindFeed is the filter of memos rendered into a feed.
+type FindFeed struct {
+	CreatorID *int
+	Tag       string
+}
+
 func (s *APIV1Service) registerRSSRoutes(g *echo.Group) {
-	g.GET("/explore/rss.xml", func(c echo.Context) error {
-		ctx := c.Request().Context()
-		systemCustomizedProfile, err := s.getSystemCustomizedProfile(ctx)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get system customized profile").SetInternal(err)
-		}
+	for filename, format := range feedFileNames {
+		format := format
 
-		normalStatus := store.Normal
-		memoFind := store.FindMemo{
-			RowStatus:      &normalStatus,
-			VisibilityList: []store.Visibility{store.Public},
-		}
-		memoList, err := s.Store.ListMemos(ctx, &memoFind)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo list").SetInternal(err)
-		}
+		// GET /explore/{rss.xml,atom.xml,feed.json} - Get the feed of all visible memos.
+		g.GET("/explore/"+filename, func(c echo.Context) error {
+			return s.serveFeed(c, format, &FindFeed{})
+		})
 
-		baseURL := c.Scheme() + "://" + c.Request().Host
-		rss, err := s.generateRSSFromMemoList(ctx, memoList, baseURL, systemCustomizedProfile)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate rss").SetInternal(err)
-		}
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationXMLCharsetUTF8)
-		return c.String(http.StatusOK, rss)
-	})
+		// GET /u/:id/{rss.xml,atom.xml,feed.json} - Get the feed of a user.
+		g.GET("/u/:id/"+filename, func(c echo.Context) error {
+			id, err := strconv.Atoi(c.Param("id"))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "User id is not a number").SetInternal(err)
+			}
+			return s.serveFeed(c, format, &FindFeed{
+				CreatorID: &id,
+			})
+		})
 
-	g.GET("/u/:id/rss.xml", func(c echo.Context) error {
-		ctx := c.Request().Context()
-		id, err := strconv.Atoi(c.Param("id"))
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "User id is not a number").SetInternal(err)
-		}
+		// GET /u/:id/tag/:tag/{rss.xml,atom.xml,feed.json} - Get the feed of a user filtered by tag.
+		g.GET("/u/:id/tag/:tag/"+filename, func(c echo.Context) error {
+			id, err := strconv.Atoi(c.Param("id"))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "User id is not a number").SetInternal(err)
+			}
+			tag := c.Param("tag")
+			if tag == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "Missing tag")
+			}
+			return s.serveFeed(c, format, &FindFeed{
+				CreatorID: &id,
+				Tag:       tag,
+			})
+		})
+	}
+}
 
-		systemCustomizedProfile, err := s.getSystemCustomizedProfile(ctx)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get system customized profile").SetInternal(err)
-		}
+func (s *APIV1Service) serveFeed(c echo.Context, format FeedFormat, find *FindFeed) error {
+	ctx := c.Request().Context()
+	systemCustomizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get system customized profile").SetInternal(err)
+	}
 
-		normalStatus := store.Normal
-		memoFind := store.FindMemo{
-			CreatorID:      &id,
-			RowStatus:      &normalStatus,
-			VisibilityList: []store.Visibility{store.Public},
-		}
-		memoList, err := s.Store.ListMemos(ctx, &memoFind)
+	visibilityList := []store.Visibility{store.Public}
+	var memberID *int
+	if token := c.QueryParam("token"); token != "" {
+		feedUserID, err := s.findUserIDByFeedToken(ctx, token)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo list").SetInternal(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find feed token").SetInternal(err)
+		}
+		if feedUserID == nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid feed token")
 		}
+		// A valid feed token grants the same access as a signed-in user.
+		visibilityList = append(visibilityList, store.Protected)
+		memberID = feedUserID
+	}
 
-		baseURL := c.Scheme() + "://" + c.Request().Host
-		rss, err := s.generateRSSFromMemoList(ctx, memoList, baseURL, systemCustomizedProfile)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate rss").SetInternal(err)
+	normalStatus := store.Normal
+	limit := maxRSSItemCount
+	memoFind := store.FindMemo{
+		CreatorID:      find.CreatorID,
+		RowStatus:      &normalStatus,
+		VisibilityList: visibilityList,
+		MemberID:       memberID,
+		Limit:          &limit,
+	}
+	if find.Tag != "" {
+		// The content search only narrows down the candidates, the tag is matched by listFeedMemos.
+		memoFind.ContentSearch = append(memoFind.ContentSearch, "#"+find.Tag)
+	}
+	// Every feed can be narrowed down by the search terms of the `q` query params, which must all be found in the content.
+	for _, search := range c.QueryParams()["q"] {
+		if search != "" {
+			memoFind.ContentSearch = append(memoFind.ContentSearch, search)
 		}
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationXMLCharsetUTF8)
-		return c.String(http.StatusOK, rss)
-	})
+	}
+	memoList, err := s.listFeedMemos(ctx, &memoFind, find.Tag)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find memo list").SetInternal(err)
+	}
+
+	baseURL := strings.TrimSuffix(systemCustomizedProfile.ExternalURL, "/")
+	if baseURL == "" {
+		baseURL = c.Scheme() + "://" + c.Request().Host
+	}
+	feed, err := s.generateFeedFromMemoList(ctx, memoList, baseURL, systemCustomizedProfile)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate feed").SetInternal(err)
+	}
+
+	var content, contentType string
+	switch format {
+	case FeedFormatAtom:
+		content, err = feed.ToAtom()
+		contentType = "application/atom+xml; charset=UTF-8"
+	case FeedFormatJSON:
+		content, err = s.generateJSONFeed(ctx, feed, memoList, baseURL)
+		contentType = "application/feed+json; charset=UTF-8"
+	default:
+		content, err = feed.ToRss()
+		contentType = echo.MIMEApplicationXMLCharsetUTF8
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate feed").SetInternal(err)
+	}
+
+	etag := getFeedETag(content)
+	c.Response().Header().Set("ETag", etag)
+	c.Response().Header().Set(echo.HeaderLastModified, feed.Updated.UTC().Format(http.TimeFormat))
+	if isFeedNotModified(c.Request(), etag, feed.Updated) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	return c.String(http.StatusOK, content)
 }
 
-func (s *APIV1Service) generateRSSFromMemoList(ctx context.Context, memoList []*store.Memo, baseURL string, profile *CustomizedProfile) (string, error) {
+func (s *APIV1Service) generateFeedFromMemoList(ctx context.Context, memoList []*store.Memo, baseURL string, profile *CustomizedProfile) (*feeds.Feed, error) {
 	feed := &feeds.Feed{
 		Title:       profile.Name,
 		Link:        &feeds.Link{Href: baseURL},
 		Description: profile.Description,
-		Created:     time.Now(),
+		Id:          baseURL,
 	}
 
 	var itemCountLimit = util.Min(len(memoList), maxRSSItemCount)
 	feed.Items = make([]*feeds.Item, itemCountLimit)
 	for i := 0; i < itemCountLimit; i++ {
 		memo := memoList[i]
-		feed.Items[i] = &feeds.Item{
-			Title:       getRSSItemTitle(memo.Content),
-			Link:        &feeds.Link{Href: baseURL + "/m/" + strconv.Itoa(memo.ID)},
-			Description: getRSSItemDescription(memo.Content),
+		memoURL := baseURL + "/m/" + strconv.Itoa(memo.ID)
+		description, err := getRSSItemDescription(memo.Content)
+		if err != nil {
+			return nil, err
+		}
+		// The accepted AI summary is preferred to the truncated first line as title.
+		title, err := s.getMemoSummary(ctx, memo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if title == "" {
+			title = getRSSItemTitle(memo.Content)
+		}
+		item := &feeds.Item{
+			Title:       title,
+			Link:        &feeds.Link{Href: memoURL},
+			Description: description,
+			Id:          memoURL,
 			Created:     time.Unix(memo.CreatedTs, 0),
-			Enclosure:   &feeds.Enclosure{Url: baseURL + "/m/" + strconv.Itoa(memo.ID) + "/image"},
+			Updated:     time.Unix(memo.UpdatedTs, 0),
 		}
-		if len(memo.ResourceIDList) > 0 {
-			resourceID := memo.ResourceIDList[0]
-			resource, err := s.Store.GetResource(ctx, &store.FindResource{
-				ID: &resourceID,
-			})
-			if err != nil {
-				return "", err
+		creator, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &memo.CreatorID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if creator != nil {
+			item.Author = &feeds.Author{Name: creator.Nickname}
+			if creator.Nickname == "" {
+				item.Author.Name = creator.Username
 			}
-			if resource == nil {
-				return "", fmt.Errorf("Resource not found: %d", resourceID)
+		}
+
+		resourceList, err := s.listFeedItemResources(ctx, memo)
+		if err != nil {
+			return nil, err
+		}
+		// RSS only supports a single enclosure per item, so every attachment is also listed in the item content.
+		if len(resourceList) > 0 {
+			resource := resourceList[0]
+			item.Enclosure = &feeds.Enclosure{
+				Url:    getFeedResourceURL(resource, baseURL),
+				Length: strconv.Itoa(int(resource.Size)),
+				Type:   resource.Type,
 			}
-			enclosure := feeds.Enclosure{}
-			if resource.ExternalLink != "" {
-				enclosure.Url = resource.ExternalLink
-			} else {
-				enclosure.Url = baseURL + "/o/r/" + strconv.Itoa(resource.ID)
+		}
+		item.Content = description + getFeedResourceListHTML(resourceList, baseURL)
+		feed.Items[i] = item
+
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+	}
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Unix(0, 0)
+	}
+	feed.Created = feed.Updated
+	return feed, nil
+}
+
+func (s *APIV1Service) generateJSONFeed(ctx context.Context, feed *feeds.Feed, memoList []*store.Memo, baseURL string) (string, error) {
+	jsonFeed := (&feeds.JSON{Feed: feed}).JSONFeed()
+	for i, item := range jsonFeed.Items {
+		memo := memoList[i]
+		item.Tags = findTagListFromMemoContent(memo.Content)
+		resourceList, err := s.listFeedItemResources(ctx, memo)
+		if err != nil {
+			return "", err
+		}
+		for _, resource := range resourceList {
+			item.Attachments = append(item.Attachments, feeds.JSONAttachment{
+				Url:      getFeedResourceURL(resource, baseURL),
+				MIMEType: resource.Type,
+				Title:    resource.Filename,
+				Size:     int32(resource.Size),
+			})
+		}
+	}
+	return jsonFeed.ToJSON()
+}
+
+// listFeedMemos lists the memos of the feed, keeping only the ones tagged with the tag if set.
+// The memos are filtered by their tag list, so the tag does not match the longer tags it is the prefix of.
+func (s *APIV1Service) listFeedMemos(ctx context.Context, memoFind *store.FindMemo, tag string) ([]*store.Memo, error) {
+	if tag == "" {
+		return s.Store.ListMemos(ctx, memoFind)
+	}
+
+	limit, offset := *memoFind.Limit, 0
+	memoFind.Offset = &offset
+	memoList := []*store.Memo{}
+	for len(memoList) < limit {
+		list, err := s.Store.ListMemos(ctx, memoFind)
+		if err != nil {
+			return nil, err
+		}
+		for _, memo := range list {
+			if slices.Contains(findTagListFromMemoContent(memo.Content), tag) && len(memoList) < limit {
+				memoList = append(memoList, memo)
 			}
-			enclosure.Length = strconv.Itoa(int(resource.Size))
-			enclosure.Type = resource.Type
-			feed.Items[i].Enclosure = &enclosure
 		}
+		if len(list) < limit {
+			break
+		}
+		offset += len(list)
 	}
+	return memoList, nil
+}
+
+// listFeedItemResources lists the resources of the memo, skipping the missing ones so they don't fail the whole feed.
+func (s *APIV1Service) listFeedItemResources(ctx context.Context, memo *store.Memo) ([]*store.Resource, error) {
+	resourceList := []*store.Resource{}
+	for _, resourceID := range memo.ResourceIDList {
+		resourceID := resourceID
+		resource, err := s.Store.GetResource(ctx, &store.FindResource{
+			ID: &resourceID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resource == nil {
+			continue
+		}
+		resourceList = append(resourceList, resource)
+	}
+	return resourceList, nil
+}
 
-	rss, err := feed.ToRss()
+// findUserIDByFeedToken returns the ID of the active user owning the feed token, or nil if no active user owns it.
+func (s *APIV1Service) findUserIDByFeedToken(ctx context.Context, token string) (*int, error) {
+	userSettingList, err := s.Store.ListUserSettings(ctx, &store.FindUserSetting{
+		Key: UserSettingFeedTokenKey.String(),
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	for _, userSetting := range userSettingList {
+		feedToken := ""
+		if err := json.Unmarshal([]byte(userSetting.Value), &feedToken); err != nil {
+			return nil, err
+		}
+		if feedToken != "" && subtle.ConstantTimeCompare([]byte(feedToken), []byte(token)) == 1 {
+			userID := userSetting.UserID
+			user, err := s.Store.GetUser(ctx, &store.FindUser{
+				ID: &userID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			// The token of an archived user doesn't grant access anymore.
+			if user == nil || user.RowStatus == store.Archived {
+				return nil, nil
+			}
+			return &userID, nil
+		}
 	}
-	return rss, nil
+	return nil, nil
 }
 
 func (s *APIV1Service) getSystemCustomizedProfile(ctx context.Context) (*CustomizedProfile, error) {
@@ -166,7 +378,7 @@ func getRSSItemTitle(content string) string {
 	return title
 }
 
-func getRSSItemDescription(content string) string {
+func getRSSItemDescription(content string) (string, error) {
 	var description string
 	if isTitleDefined(content) {
 		var firstLineEnd = strings.Index(content, "\n")
@@ -175,14 +387,70 @@ func getRSSItemDescription(content string) string {
 		description = content
 	}
 
-	// TODO: use our `./plugin/gomark` parser to handle markdown-like content.
+	// The feeds keep rendering with goldmark, as `./plugin/gomark` has no HTML renderer yet.
+	// TODO: use our `./plugin/gomark` parser to handle markdown-like content once it renders HTML.
 	var buf bytes.Buffer
 	if err := goldmark.Convert([]byte(description), &buf); err != nil {
-		panic(err)
+		return "", err
 	}
+	return buf.String(), nil
+}
+
+func getFeedResourceURL(resource *store.Resource, baseURL string) string {
+	if resource.ExternalLink != "" {
+		return resource.ExternalLink
+	}
+	return baseURL + "/o/r/" + strconv.Itoa(resource.ID)
+}
+
+func getFeedResourceListHTML(resourceList []*store.Resource, baseURL string) string {
+	if len(resourceList) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<ul>")
+	for _, resource := range resourceList {
+		resourceURL := html.EscapeString(getFeedResourceURL(resource, baseURL))
+		filename := html.EscapeString(resource.Filename)
+		buf.WriteString("<li>")
+		if strings.HasPrefix(resource.Type, "image/") {
+			buf.WriteString(fmt.Sprintf(`<img src="%s" alt="%s" />`, resourceURL, filename))
+		} else {
+			buf.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, resourceURL, filename))
+		}
+		buf.WriteString("</li>")
+	}
+	buf.WriteString("</ul>")
 	return buf.String()
 }
 
+func getFeedETag(content string) string {
+	sum := sha1.Sum([]byte(content))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// isFeedNotModified checks the conditional request headers. If-None-Match takes precedence over If-Modified-Since.
+func isFeedNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		t, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
 func isTitleDefined(content string) bool {
 	return strings.HasPrefix(content, "# ")
 }
@@ -0,0 +1,902 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	scimContentType         = "application/scim+json"
+	scimUserSchema          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListResponseSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimServiceConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	// scimMaxResults is the max number of resources of a list response.
+	scimMaxResults = 200
+)
+
+// scimGroupRoles are the roles provisioned as SCIM groups, the host is not a member of any group.
+var scimGroupRoles = []store.Role{store.RoleAdmin, store.RoleUser}
+
+// scimFilterRegexp matches the only supported filter form: `attribute eq "value"`.
+var scimFilterRegexp = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// SCIMToken is the bearer token authenticating the SCIM client, only returned when issued.
+type SCIMToken struct {
+	Token string `json:"token"`
+}
+
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type SCIMName struct {
+	Formatted string `json:"formatted,omitempty"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type SCIMUser struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *SCIMName    `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []*SCIMEmail `json:"emails,omitempty"`
+	// Active is false for the archived users, default is true.
+	Active *bool `json:"active,omitempty"`
+	// Password is write-only.
+	Password string        `json:"password,omitempty"`
+	Groups   []*SCIMMember `json:"groups,omitempty"`
+	Meta     *SCIMMeta     `json:"meta,omitempty"`
+}
+
+type SCIMGroup struct {
+	Schemas     []string      `json:"schemas"`
+	ID          string        `json:"id"`
+	DisplayName string        `json:"displayName"`
+	Members     []*SCIMMember `json:"members"`
+	Meta        *SCIMMeta     `json:"meta,omitempty"`
+}
+
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type SCIMPatchRequest struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []*SCIMPatchOperation `json:"Operations"`
+}
+
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func newSCIMError(code int, scimType, detail string) *echo.HTTPError {
+	return echo.NewHTTPError(code, &SCIMError{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(code),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
+
+func (s *APIV1Service) registerSCIMTokenRoutes(g *echo.Group) {
+	// POST /scim/token - Issue a new SCIM bearer token, replacing the previous one.
+	g.POST("/scim/token", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+			return err
+		}
+
+		tokenBytes := make([]byte, 32)
+		if _, err := rand.Read(tokenBytes); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate token").SetInternal(err)
+		}
+		token := hex.EncodeToString(tokenBytes)
+		if err := s.upsertSCIMTokenHash(ctx, hashToken(token)); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert system setting").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, &SCIMToken{Token: token})
+	})
+
+	// DELETE /scim/token - Revoke the SCIM bearer token, disabling the provisioning.
+	g.DELETE("/scim/token", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+			return err
+		}
+
+		if err := s.upsertSCIMTokenHash(ctx, ""); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert system setting").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *APIV1Service) registerSCIMRoutes(g *echo.Group) {
+	scimGroup := g.Group("/scim/v2")
+	scimGroup.Use(s.scimAuthMiddleware)
+
+	scimGroup.GET("/ServiceProviderConfig", func(c echo.Context) error {
+		return scimJSON(c, http.StatusOK, map[string]any{
+			"schemas":        []string{scimServiceConfigSchema},
+			"patch":          map[string]any{"supported": true},
+			"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+			"filter":         map[string]any{"supported": true, "maxResults": scimMaxResults},
+			"changePassword": map[string]any{"supported": true},
+			"sort":           map[string]any{"supported": false},
+			"etag":           map[string]any{"supported": false},
+			"authenticationSchemes": []map[string]any{{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer Token",
+				"description": "The token issued by an admin of memos.",
+			}},
+		})
+	})
+
+	scimGroup.GET("/Users", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userFind := &store.FindUser{}
+		if filter := c.QueryParam("filter"); filter != "" {
+			attribute, value, err := parseSCIMFilter(filter)
+			if err != nil || !strings.EqualFold(attribute, "userName") {
+				return newSCIMError(http.StatusBadRequest, "invalidFilter", fmt.Sprintf("Unsupported filter: %s", filter))
+			}
+			userFind.Username = &value
+		}
+		list, err := s.Store.ListUsers(ctx, userFind)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user list").SetInternal(err)
+		}
+
+		resources := []any{}
+		for _, user := range list {
+			resources = append(resources, convertSCIMUserFromStore(c, user))
+		}
+		return scimJSON(c, http.StatusOK, newSCIMListResponse(c, resources))
+	})
+
+	scimGroup.GET("/Users/:id", func(c echo.Context) error {
+		user, err := s.findSCIMUser(c)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, convertSCIMUserFromStore(c, user))
+	})
+
+	scimGroup.POST("/Users", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		scimUser := &SCIMUser{}
+		if err := json.NewDecoder(c.Request().Body).Decode(scimUser); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidSyntax", "Malformatted user")
+		}
+		password := scimUser.Password
+		if password == "" {
+			// The user signs in with the identity provider, or resets the password later.
+			randomPassword, err := util.RandomString(20)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate random password").SetInternal(err)
+			}
+			password = randomPassword
+		}
+		userCreate := &CreateUserRequest{
+			Username: scimUser.UserName,
+			Role:     RoleUser,
+			Email:    scimUser.primaryEmail(),
+			Nickname: scimUser.nickname(),
+			Password: password,
+		}
+		if err := userCreate.Validate(); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+		existingUser, err := s.Store.GetUser(ctx, &store.FindUser{
+			Username: &userCreate.Username,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if existingUser != nil {
+			return newSCIMError(http.StatusConflict, "uniqueness", fmt.Sprintf("User already exists with username %s", userCreate.Username))
+		}
+
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(userCreate.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
+		}
+		user, err := s.Store.CreateUser(ctx, &store.User{
+			Username:     userCreate.Username,
+			Role:         store.RoleUser,
+			Email:        userCreate.Email,
+			Nickname:     userCreate.Nickname,
+			PasswordHash: string(passwordHash),
+			OpenID:       util.GenUUID(),
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user").SetInternal(err)
+		}
+		if err := s.createUserCreateActivity(c, convertUserFromStore(user)); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		if scimUser.Active != nil && !*scimUser.Active {
+			if user, err = s.updateSCIMUser(ctx, user, scimUser); err != nil {
+				return err
+			}
+		}
+		return scimJSON(c, http.StatusCreated, convertSCIMUserFromStore(c, user))
+	})
+
+	scimGroup.PUT("/Users/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.findSCIMUser(c)
+		if err != nil {
+			return err
+		}
+		scimUser := &SCIMUser{}
+		if err := json.NewDecoder(c.Request().Body).Decode(scimUser); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidSyntax", "Malformatted user")
+		}
+
+		user, err = s.updateSCIMUser(ctx, user, scimUser)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, convertSCIMUserFromStore(c, user))
+	})
+
+	scimGroup.PATCH("/Users/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.findSCIMUser(c)
+		if err != nil {
+			return err
+		}
+		patch := &SCIMPatchRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(patch); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidSyntax", "Malformatted patch request")
+		}
+
+		// Apply the operations to the current user, then replace it.
+		scimUser := convertSCIMUserFromStore(c, user)
+		for _, operation := range patch.Operations {
+			if err := scimUser.applyPatchOperation(operation); err != nil {
+				return err
+			}
+		}
+		user, err = s.updateSCIMUser(ctx, user, scimUser)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, convertSCIMUserFromStore(c, user))
+	})
+
+	// DELETE /Users/:id - Deprovision the user, it is archived to keep the memos.
+	scimGroup.DELETE("/Users/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.findSCIMUser(c)
+		if err != nil {
+			return err
+		}
+		scimUser := convertSCIMUserFromStore(c, user)
+		active := false
+		scimUser.Active = &active
+		if _, err := s.updateSCIMUser(ctx, user, scimUser); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	scimGroup.GET("/Groups", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		roles := scimGroupRoles
+		if filter := c.QueryParam("filter"); filter != "" {
+			attribute, value, err := parseSCIMFilter(filter)
+			if err != nil || !strings.EqualFold(attribute, "displayName") {
+				return newSCIMError(http.StatusBadRequest, "invalidFilter", fmt.Sprintf("Unsupported filter: %s", filter))
+			}
+			roles = []store.Role{}
+			for _, role := range scimGroupRoles {
+				if strings.EqualFold(role.String(), value) {
+					roles = append(roles, role)
+				}
+			}
+		}
+
+		resources := []any{}
+		for _, role := range roles {
+			scimGroup, err := s.getSCIMGroup(ctx, c, role)
+			if err != nil {
+				return err
+			}
+			resources = append(resources, scimGroup)
+		}
+		return scimJSON(c, http.StatusOK, newSCIMListResponse(c, resources))
+	})
+
+	scimGroup.GET("/Groups/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		role, err := findSCIMGroupRole(c)
+		if err != nil {
+			return err
+		}
+		scimGroup, err := s.getSCIMGroup(ctx, c, role)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, scimGroup)
+	})
+
+	scimGroup.PUT("/Groups/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		role, err := findSCIMGroupRole(c)
+		if err != nil {
+			return err
+		}
+		request := &SCIMGroup{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidSyntax", "Malformatted group")
+		}
+
+		if err := s.replaceSCIMGroupMembers(ctx, role, request.Members); err != nil {
+			return err
+		}
+		scimGroup, err := s.getSCIMGroup(ctx, c, role)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, scimGroup)
+	})
+
+	scimGroup.PATCH("/Groups/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		role, err := findSCIMGroupRole(c)
+		if err != nil {
+			return err
+		}
+		patch := &SCIMPatchRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(patch); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidSyntax", "Malformatted patch request")
+		}
+
+		for _, operation := range patch.Operations {
+			if err := s.applySCIMGroupPatchOperation(ctx, role, operation); err != nil {
+				return err
+			}
+		}
+		scimGroup, err := s.getSCIMGroup(ctx, c, role)
+		if err != nil {
+			return err
+		}
+		return scimJSON(c, http.StatusOK, scimGroup)
+	})
+
+	notImplemented := func(c echo.Context) error {
+		return newSCIMError(http.StatusNotImplemented, "mutability", "Groups are the fixed roles ADMIN and USER")
+	}
+	scimGroup.POST("/Groups", notImplemented)
+	scimGroup.DELETE("/Groups/:id", notImplemented)
+}
+
+// scimAuthMiddleware authenticates the SCIM client with the issued bearer token.
+func (s *APIV1Service) scimAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		tokenHash, err := s.getSCIMTokenHash(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find system setting").SetInternal(err)
+		}
+		authHeader := c.Request().Header.Get(echo.HeaderAuthorization)
+		scheme, token, _ := strings.Cut(authHeader, " ")
+		if tokenHash == "" || !strings.EqualFold(scheme, "Bearer") || token == "" ||
+			subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(tokenHash)) != 1 {
+			return newSCIMError(http.StatusUnauthorized, "", "Invalid bearer token")
+		}
+		return next(c)
+	}
+}
+
+func (s *APIV1Service) getSCIMTokenHash(ctx context.Context) (string, error) {
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingSCIMTokenName.String(),
+	})
+	if err != nil || systemSetting == nil {
+		return "", err
+	}
+	tokenHash := ""
+	if err := json.Unmarshal([]byte(systemSetting.Value), &tokenHash); err != nil {
+		return "", err
+	}
+	return tokenHash, nil
+}
+
+func (s *APIV1Service) upsertSCIMTokenHash(ctx context.Context, tokenHash string) error {
+	value, err := json.Marshal(tokenHash)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.UpsertSystemSetting(ctx, &store.SystemSetting{
+		Name:        SystemSettingSCIMTokenName.String(),
+		Value:       string(value),
+		Description: "The hash of the SCIM bearer token.",
+	})
+	return err
+}
+
+func (s *APIV1Service) findSCIMUser(c echo.Context) (*store.User, error) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, newSCIMError(http.StatusNotFound, "", fmt.Sprintf("User not found with ID: %s", c.Param("id")))
+	}
+	user, err := s.Store.GetUser(c.Request().Context(), &store.FindUser{
+		ID: &userID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+	}
+	if user == nil {
+		return nil, newSCIMError(http.StatusNotFound, "", fmt.Sprintf("User not found with ID: %d", userID))
+	}
+	return user, nil
+}
+
+// updateSCIMUser replaces the user with the SCIM user, archiving it and revoking its access if not active.
+func (s *APIV1Service) updateSCIMUser(ctx context.Context, user *store.User, scimUser *SCIMUser) (*store.User, error) {
+	if user.Role == store.RoleHost {
+		return nil, newSCIMError(http.StatusForbidden, "mutability", "The host can not be provisioned")
+	}
+	username, email, nickname := scimUser.UserName, scimUser.primaryEmail(), scimUser.nickname()
+	request := &UpdateUserRequest{
+		Username: &username,
+		Email:    &email,
+		Nickname: &nickname,
+	}
+	if scimUser.Password != "" {
+		request.Password = &scimUser.Password
+	}
+	if err := request.Validate(); err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	if username != user.Username {
+		existingUser, err := s.Store.GetUser(ctx, &store.FindUser{
+			Username: &username,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if existingUser != nil {
+			return nil, newSCIMError(http.StatusConflict, "uniqueness", fmt.Sprintf("User already exists with username %s", username))
+		}
+	}
+
+	currentTs := time.Now().Unix()
+	rowStatus := store.Normal
+	if scimUser.Active != nil && !*scimUser.Active {
+		rowStatus = store.Archived
+	}
+	userUpdate := &store.UpdateUser{
+		ID:        user.ID,
+		UpdatedTs: &currentTs,
+		RowStatus: &rowStatus,
+		Username:  &username,
+		Email:     &email,
+		Nickname:  &nickname,
+	}
+	if email != user.Email {
+		emailVerified := false
+		userUpdate.EmailVerified = &emailVerified
+	}
+	if request.Password != nil {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(*request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate password hash").SetInternal(err)
+		}
+		passwordHashStr := string(passwordHash)
+		userUpdate.PasswordHash = &passwordHashStr
+	}
+	revoked := rowStatus == store.Archived && user.RowStatus != store.Archived
+	if revoked {
+		// The open ID grants access without a session.
+		openID := util.GenUUID()
+		userUpdate.OpenID = &openID
+	}
+	updatedUser, err := s.Store.UpdateUser(ctx, userUpdate)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch user").SetInternal(err)
+	}
+	if revoked || request.Password != nil {
+		if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+			UserID: &user.ID,
+		}); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke sessions").SetInternal(err)
+		}
+	}
+	if revoked {
+		// The feed token grants access to the protected memos without a session.
+		if err := s.Store.DeleteUserSetting(ctx, &store.DeleteUserSetting{
+			UserID: user.ID,
+			Key:    UserSettingFeedTokenKey.String(),
+		}); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke feed token").SetInternal(err)
+		}
+	}
+	return updatedUser, nil
+}
+
+// applyPatchOperation applies the operation to the user, the unsupported attributes are ignored.
+func (scimUser *SCIMUser) applyPatchOperation(operation *SCIMPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("Unsupported operation: %s", operation.Op))
+	}
+	if operation.Path == "" {
+		if op == "remove" {
+			return newSCIMError(http.StatusBadRequest, "noTarget", "Path is required to remove")
+		}
+		values := map[string]json.RawMessage{}
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "Value should be an object without path")
+		}
+		for path, value := range values {
+			if err := scimUser.applyPatchOperation(&SCIMPatchOperation{Op: op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := strings.ToLower(operation.Path)
+	invalidValue := newSCIMError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("Invalid value of %s", operation.Path))
+	switch {
+	case path == "active":
+		if op == "remove" {
+			return invalidValue
+		}
+		active, err := parseSCIMBool(operation.Value)
+		if err != nil {
+			return invalidValue
+		}
+		scimUser.Active = &active
+	case path == "username":
+		if op == "remove" || json.Unmarshal(operation.Value, &scimUser.UserName) != nil {
+			return invalidValue
+		}
+	case path == "password":
+		if op == "remove" || json.Unmarshal(operation.Value, &scimUser.Password) != nil {
+			return invalidValue
+		}
+	case path == "displayname":
+		scimUser.DisplayName = ""
+		if op != "remove" && json.Unmarshal(operation.Value, &scimUser.DisplayName) != nil {
+			return invalidValue
+		}
+	case path == "name.formatted":
+		scimUser.Name = &SCIMName{}
+		if op != "remove" && json.Unmarshal(operation.Value, &scimUser.Name.Formatted) != nil {
+			return invalidValue
+		}
+	case path == "name":
+		scimUser.Name = &SCIMName{}
+		if op != "remove" && json.Unmarshal(operation.Value, scimUser.Name) != nil {
+			return invalidValue
+		}
+	case path == "emails":
+		scimUser.Emails = nil
+		if op != "remove" && json.Unmarshal(operation.Value, &scimUser.Emails) != nil {
+			return invalidValue
+		}
+	case strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value"):
+		// Only the primary email is stored, e.g. `emails[type eq "work"].value` replaces it.
+		email := ""
+		if op != "remove" && json.Unmarshal(operation.Value, &email) != nil {
+			return invalidValue
+		}
+		scimUser.Emails = []*SCIMEmail{{Value: email, Primary: true}}
+	}
+	return nil
+}
+
+func (scimUser *SCIMUser) primaryEmail() string {
+	for _, email := range scimUser.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(scimUser.Emails) > 0 {
+		return scimUser.Emails[0].Value
+	}
+	return ""
+}
+
+func (scimUser *SCIMUser) nickname() string {
+	if scimUser.DisplayName != "" {
+		return scimUser.DisplayName
+	}
+	if scimUser.Name != nil && scimUser.Name.Formatted != "" {
+		return scimUser.Name.Formatted
+	}
+	return scimUser.UserName
+}
+
+func (s *APIV1Service) getSCIMGroup(ctx context.Context, c echo.Context, role store.Role) (*SCIMGroup, error) {
+	normalStatus := store.Normal
+	list, err := s.Store.ListUsers(ctx, &store.FindUser{
+		Role:      &role,
+		RowStatus: &normalStatus,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user list").SetInternal(err)
+	}
+	members := []*SCIMMember{}
+	for _, user := range list {
+		members = append(members, &SCIMMember{
+			Value:   strconv.Itoa(user.ID),
+			Display: user.Username,
+		})
+	}
+	return &SCIMGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          role.String(),
+		DisplayName: role.String(),
+		Members:     members,
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Location:     getSCIMBaseURL(c) + "/Groups/" + role.String(),
+		},
+	}, nil
+}
+
+// applySCIMGroupPatchOperation adds or removes the members of the group of the role. The members removed
+// from the ADMIN group become USER, as every user has a role.
+func (s *APIV1Service) applySCIMGroupPatchOperation(ctx context.Context, role store.Role, operation *SCIMPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	path := strings.ToLower(operation.Path)
+	members := []*SCIMMember{}
+	if path == "" && op != "remove" {
+		value := &SCIMGroup{}
+		if err := json.Unmarshal(operation.Value, value); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "Value should be an object without path")
+		}
+		if value.DisplayName != "" && value.DisplayName != role.String() {
+			return newSCIMError(http.StatusBadRequest, "mutability", "The name of the group can not be changed")
+		}
+		if value.Members == nil {
+			return nil
+		}
+		members, path = value.Members, "members"
+	} else if path == "members" && len(operation.Value) > 0 {
+		if err := json.Unmarshal(operation.Value, &members); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "Invalid value of members")
+		}
+	} else if strings.HasPrefix(path, "members[") && op == "remove" {
+		// e.g. `members[value eq "2"]`.
+		_, value, err := parseSCIMFilter(strings.TrimSuffix(operation.Path[len("members["):], "]"))
+		if err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidPath", fmt.Sprintf("Unsupported path: %s", operation.Path))
+		}
+		members, path = []*SCIMMember{{Value: value}}, "members"
+	}
+	if path != "members" {
+		return newSCIMError(http.StatusBadRequest, "invalidPath", fmt.Sprintf("Unsupported path: %s", operation.Path))
+	}
+
+	switch op {
+	case "add":
+		return s.setSCIMMemberRoles(ctx, members, role)
+	case "remove":
+		if role != store.RoleAdmin {
+			return newSCIMError(http.StatusBadRequest, "mutability", "Members can only be removed from the ADMIN group")
+		}
+		return s.setSCIMMemberRoles(ctx, members, store.RoleUser)
+	case "replace":
+		return s.replaceSCIMGroupMembers(ctx, role, members)
+	default:
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("Unsupported operation: %s", operation.Op))
+	}
+}
+
+// replaceSCIMGroupMembers sets the role of the members, the other ADMIN members become USER.
+func (s *APIV1Service) replaceSCIMGroupMembers(ctx context.Context, role store.Role, members []*SCIMMember) error {
+	if role == store.RoleAdmin {
+		list, err := s.Store.ListUsers(ctx, &store.FindUser{
+			Role: &role,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user list").SetInternal(err)
+		}
+		removed := []*SCIMMember{}
+		for _, user := range list {
+			userID := strconv.Itoa(user.ID)
+			if !containsSCIMMember(members, userID) {
+				removed = append(removed, &SCIMMember{Value: userID})
+			}
+		}
+		if err := s.setSCIMMemberRoles(ctx, removed, store.RoleUser); err != nil {
+			return err
+		}
+	}
+	return s.setSCIMMemberRoles(ctx, members, role)
+}
+
+func (s *APIV1Service) setSCIMMemberRoles(ctx context.Context, members []*SCIMMember, role store.Role) error {
+	for _, member := range members {
+		userID, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("Invalid member: %s", member.Value))
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("User not found with ID: %d", userID))
+		}
+		if user.Role == store.RoleHost {
+			return newSCIMError(http.StatusForbidden, "mutability", "The host can not be provisioned")
+		}
+		if user.Role == role {
+			continue
+		}
+		currentTs := time.Now().Unix()
+		if _, err := s.Store.UpdateUser(ctx, &store.UpdateUser{
+			ID:        userID,
+			UpdatedTs: &currentTs,
+			Role:      &role,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch user").SetInternal(err)
+		}
+	}
+	return nil
+}
+
+func findSCIMGroupRole(c echo.Context) (store.Role, error) {
+	for _, role := range scimGroupRoles {
+		if role.String() == c.Param("id") {
+			return role, nil
+		}
+	}
+	return "", newSCIMError(http.StatusNotFound, "", fmt.Sprintf("Group not found with ID: %s", c.Param("id")))
+}
+
+func containsSCIMMember(members []*SCIMMember, value string) bool {
+	for _, member := range members {
+		if member.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSCIMFilter parses the filter of the form `attribute eq "value"`.
+func parseSCIMFilter(filter string) (string, string, error) {
+	matches := scimFilterRegexp.FindStringSubmatch(filter)
+	if matches == nil {
+		return "", "", errors.Errorf("unsupported filter %s", filter)
+	}
+	value, err := strconv.Unquote(`"` + matches[2] + `"`)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid filter value")
+	}
+	return matches[1], value, nil
+}
+
+// parseSCIMBool parses a boolean value, some identity providers send it as a string, e.g. "False".
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var value bool
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(str)
+}
+
+// newSCIMListResponse paginates the resources with the 1-based startIndex and count query params.
+func newSCIMListResponse(c echo.Context, resources []any) *SCIMListResponse {
+	startIndex, err := strconv.Atoi(c.QueryParam("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.QueryParam("count"))
+	if err != nil || count > scimMaxResults {
+		count = scimMaxResults
+	}
+	if count < 0 {
+		count = 0
+	}
+	page := []any{}
+	if startIndex <= len(resources) {
+		page = resources[startIndex-1:]
+		if len(page) > count {
+			page = page[:count]
+		}
+	}
+	return &SCIMListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+func convertSCIMUserFromStore(c echo.Context, user *store.User) *SCIMUser {
+	id := strconv.Itoa(user.ID)
+	active := user.RowStatus == store.Normal
+	scimUser := &SCIMUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          id,
+		UserName:    user.Username,
+		Name:        &SCIMName{Formatted: user.Nickname},
+		DisplayName: user.Nickname,
+		Active:      &active,
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      time.Unix(user.CreatedTs, 0).UTC().Format(time.RFC3339),
+			LastModified: time.Unix(user.UpdatedTs, 0).UTC().Format(time.RFC3339),
+			Location:     getSCIMBaseURL(c) + "/Users/" + id,
+		},
+	}
+	if user.Email != "" {
+		scimUser.Emails = []*SCIMEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Role != store.RoleHost && active {
+		scimUser.Groups = []*SCIMMember{{Value: user.Role.String(), Display: user.Role.String()}}
+	}
+	return scimUser
+}
+
+func getSCIMBaseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host + "/scim/v2"
+}
+
+func scimJSON(c echo.Context, code int, i any) error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to marshal response").SetInternal(err)
+	}
+	return c.Blob(code, scimContentType, data)
+}
@@ -22,13 +22,15 @@ type Shortcut struct {
 	UpdatedTs int64     `json:"updatedTs"`
 
 	// Domain specific fields
-	Title   string `json:"title"`
-	Payload string `json:"payload"`
+	Title       string `json:"title"`
+	Payload     string `json:"payload"`
+	WorkspaceID int    `json:"workspaceId"`
 }
 
 type CreateShortcutRequest struct {
-	Title   string `json:"title"`
-	Payload string `json:"payload"`
+	Title       string `json:"title"`
+	Payload     string `json:"payload"`
+	WorkspaceID int    `json:"workspaceId"`
 }
 
 type UpdateShortcutRequest struct {
@@ -65,11 +67,15 @@ func (s *APIV1Service) registerShortcutRoutes(g *echo.Group) {
 		if err := json.NewDecoder(c.Request().Body).Decode(shortcutCreate); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post shortcut request").SetInternal(err)
 		}
+		if err := s.checkWorkspaceContentCreatable(ctx, shortcutCreate.WorkspaceID, userID); err != nil {
+			return err
+		}
 
 		shortcut, err := s.Store.CreateShortcut(ctx, &store.Shortcut{
-			CreatorID: userID,
-			Title:     shortcutCreate.Title,
-			Payload:   shortcutCreate.Payload,
+			CreatorID:   userID,
+			Title:       shortcutCreate.Title,
+			Payload:     shortcutCreate.Payload,
+			WorkspaceID: shortcutCreate.WorkspaceID,
 		})
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create shortcut").SetInternal(err)
@@ -131,6 +137,13 @@ func (s *APIV1Service) registerShortcutRoutes(g *echo.Group) {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch shortcut").SetInternal(err)
 		}
+		if err := s.createActivity(ctx, userID, ActivityShortcutUpdate, ActivityInfo, ActivityShortcutUpdatePayload{
+			ShortcutID: shortcut.ID,
+			Title:      shortcut.Title,
+			Payload:    shortcut.Payload,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 		return c.JSON(http.StatusOK, convertShortcutFromStore(shortcut))
 	})
 
@@ -140,9 +153,14 @@ func (s *APIV1Service) registerShortcutRoutes(g *echo.Group) {
 		if !ok {
 			return echo.NewHTTPError(http.StatusBadRequest, "Missing user id to find shortcut")
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
 
 		list, err := s.Store.ListShortcuts(ctx, &store.FindShortcut{
-			CreatorID: &userID,
+			CreatorID:   &userID,
+			WorkspaceID: workspaceID,
 		})
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get shortcut list").SetInternal(err)
@@ -202,6 +220,11 @@ func (s *APIV1Service) registerShortcutRoutes(g *echo.Group) {
 		}); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete shortcut").SetInternal(err)
 		}
+		if err := s.createActivity(ctx, userID, ActivityShortcutDelete, ActivityInfo, ActivityShortcutDeletePayload{
+			ShortcutID: shortcutID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 		return c.JSON(http.StatusOK, true)
 	})
 }
@@ -230,12 +253,13 @@ func (s *APIV1Service) createShortcutCreateActivity(c echo.Context, shortcut *Sh
 
 func convertShortcutFromStore(shortcut *store.Shortcut) *Shortcut {
 	return &Shortcut{
-		ID:        shortcut.ID,
-		RowStatus: RowStatus(shortcut.RowStatus),
-		CreatorID: shortcut.CreatorID,
-		Title:     shortcut.Title,
-		Payload:   shortcut.Payload,
-		CreatedTs: shortcut.CreatedTs,
-		UpdatedTs: shortcut.UpdatedTs,
+		ID:          shortcut.ID,
+		RowStatus:   RowStatus(shortcut.RowStatus),
+		CreatorID:   shortcut.CreatorID,
+		Title:       shortcut.Title,
+		Payload:     shortcut.Payload,
+		WorkspaceID: shortcut.WorkspaceID,
+		CreatedTs:   shortcut.CreatedTs,
+		UpdatedTs:   shortcut.UpdatedTs,
 	}
 }
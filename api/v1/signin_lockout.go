@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/store"
+)
+
+type SignInLockout struct {
+	ID int `json:"id"`
+
+	// Domain specific fields
+	Kind          store.SignInLockoutKind `json:"kind"`
+	Key           string                  `json:"key"`
+	FailedCount   int                     `json:"failedCount"`
+	LastFailedTs  int64                   `json:"lastFailedTs"`
+	LockedUntilTs int64                   `json:"lockedUntilTs"`
+	Locked        bool                    `json:"locked"`
+}
+
+func (s *APIV1Service) registerSignInLockoutRoutes(g *echo.Group) {
+	g.GET("/signin-lockout", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if err := s.checkSignInLockoutAdmin(c); err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListSignInLockouts(ctx, &store.FindSignInLockout{})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find sign-in lockout list").SetInternal(err)
+		}
+		now := time.Now().Unix()
+		signInLockoutList := []*SignInLockout{}
+		for _, signInLockout := range list {
+			signInLockoutList = append(signInLockoutList, convertSignInLockoutFromStore(signInLockout, now))
+		}
+		return c.JSON(http.StatusOK, signInLockoutList)
+	})
+
+	g.DELETE("/signin-lockout/:lockoutId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if err := s.checkSignInLockoutAdmin(c); err != nil {
+			return err
+		}
+		lockoutID, err := strconv.Atoi(c.Param("lockoutId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("lockoutId"))).SetInternal(err)
+		}
+
+		if err := s.Store.DeleteSignInLockout(ctx, &store.DeleteSignInLockout{
+			ID: &lockoutID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete sign-in lockout").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *APIV1Service) checkSignInLockoutAdmin(c echo.Context) error {
+	_, err := s.checkPermission(c, PermissionUserManage)
+	return err
+}
+
+// checkSignInLockout rejects the sign-in if the IP of the request or the username is locked.
+func (s *APIV1Service) checkSignInLockout(c echo.Context, username string) error {
+	ctx := c.Request().Context()
+	now := time.Now().Unix()
+	for kind, key := range getSignInLockoutKeys(c, username) {
+		kind, key := kind, key
+		signInLockout, err := s.Store.GetSignInLockout(ctx, &store.FindSignInLockout{
+			Kind: &kind,
+			Key:  &key,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find sign-in lockout").SetInternal(err)
+		}
+		if signInLockout != nil && signInLockout.LockedUntilTs > now {
+			retryAfter := signInLockout.LockedUntilTs - now
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+			return echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("Too many failed sign-in attempts, please try again in %d seconds", retryAfter))
+		}
+	}
+	return nil
+}
+
+// recordSignInFailure counts the failed attempt of the IP of the request and the username, locking them out
+// for exponentially longer once they exceed the max attempts.
+func (s *APIV1Service) recordSignInFailure(c echo.Context, userID int, username string) error {
+	ctx := c.Request().Context()
+	config, err := s.getSignInLockoutConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get sign-in lockout config")
+	}
+	now := time.Now().Unix()
+	forgottenTs := now - int64(config.MaxLockoutSeconds)
+	if err := s.Store.DeleteSignInLockout(ctx, &store.DeleteSignInLockout{
+		LastFailedTsBefore: &forgottenTs,
+	}); err != nil {
+		return errors.Wrap(err, "failed to delete forgotten sign-in lockouts")
+	}
+
+	for kind, key := range getSignInLockoutKeys(c, username) {
+		kind, key := kind, key
+		maxAttempts := config.IPMaxAttempts
+		if kind == store.SignInLockoutUsername {
+			maxAttempts = config.UsernameMaxAttempts
+		}
+		if maxAttempts == 0 {
+			continue
+		}
+
+		signInLockout, err := s.Store.GetSignInLockout(ctx, &store.FindSignInLockout{
+			Kind: &kind,
+			Key:  &key,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to find sign-in lockout")
+		}
+		if signInLockout == nil {
+			signInLockout = &store.SignInLockout{
+				Kind: kind,
+				Key:  key,
+			}
+		}
+		signInLockout.FailedCount++
+		signInLockout.LastFailedTs = now
+		if exceeded := signInLockout.FailedCount - maxAttempts; exceeded >= 0 {
+			lockoutSeconds := int64(config.MaxLockoutSeconds)
+			// Stop doubling before overflowing, the lockout is capped anyway.
+			if exceeded < 31 {
+				lockoutSeconds = int64(config.LockoutSeconds) << exceeded
+			}
+			if lockoutSeconds > int64(config.MaxLockoutSeconds) {
+				lockoutSeconds = int64(config.MaxLockoutSeconds)
+			}
+			signInLockout.LockedUntilTs = now + lockoutSeconds
+		}
+		if _, err := s.Store.UpsertSignInLockout(ctx, signInLockout); err != nil {
+			return errors.Wrap(err, "failed to upsert sign-in lockout")
+		}
+	}
+
+	return s.createAuthSignInFailedActivity(c, userID, username)
+}
+
+// clearSignInFailures forgets the failed attempts of the username after a successful sign-in.
+// The failed attempts of the IP are kept, so signing in to another account does not reset them.
+func (s *APIV1Service) clearSignInFailures(ctx context.Context, username string) error {
+	kind := store.SignInLockoutUsername
+	return s.Store.DeleteSignInLockout(ctx, &store.DeleteSignInLockout{
+		Kind: &kind,
+		Key:  &username,
+	})
+}
+
+func (s *APIV1Service) getSignInLockoutConfig(ctx context.Context) (*SignInLockoutConfig, error) {
+	config := &SignInLockoutConfig{
+		UsernameMaxAttempts: 5,
+		IPMaxAttempts:       20,
+		LockoutSeconds:      60,
+		MaxLockoutSeconds:   60 * 60,
+	}
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingSignInLockoutName.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if systemSetting != nil {
+		if err := json.Unmarshal([]byte(systemSetting.Value), config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (s *APIV1Service) createAuthSignInFailedActivity(c echo.Context, userID int, username string) error {
+	ctx := c.Request().Context()
+	payload := ActivityUserAuthSignInFailedPayload{
+		Username: username,
+		IP:       echo.ExtractIPFromRealIPHeader()(c.Request()),
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &store.Activity{
+		CreatorID: userID,
+		Type:      ActivityUserAuthSignInFailed.String(),
+		Level:     ActivityWarn.String(),
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
+
+func getSignInLockoutKeys(c echo.Context, username string) map[store.SignInLockoutKind]string {
+	keys := map[store.SignInLockoutKind]string{
+		store.SignInLockoutIP: echo.ExtractIPFromRealIPHeader()(c.Request()),
+	}
+	if username != "" {
+		keys[store.SignInLockoutUsername] = username
+	}
+	return keys
+}
+
+func convertSignInLockoutFromStore(signInLockout *store.SignInLockout, now int64) *SignInLockout {
+	return &SignInLockout{
+		ID:            signInLockout.ID,
+		Kind:          signInLockout.Kind,
+		Key:           signInLockout.Key,
+		FailedCount:   signInLockout.FailedCount,
+		LastFailedTs:  signInLockout.LastFailedTs,
+		LockedUntilTs: signInLockout.LockedUntilTs,
+		Locked:        signInLockout.LockedUntilTs > now,
+	}
+}
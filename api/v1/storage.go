@@ -64,19 +64,8 @@ type UpdateStorageRequest struct {
 func (s *APIV1Service) registerStorageRoutes(g *echo.Group) {
 	g.POST("/storage", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionStorageManage); err != nil {
+			return err
 		}
 
 		create := &CreateStorageRequest{}
@@ -110,19 +99,8 @@ func (s *APIV1Service) registerStorageRoutes(g *echo.Group) {
 
 	g.PATCH("/storage/:storageId", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionStorageManage); err != nil {
+			return err
 		}
 
 		storageID, err := strconv.Atoi(c.Param("storageId"))
@@ -164,20 +142,9 @@ func (s *APIV1Service) registerStorageRoutes(g *echo.Group) {
 
 	g.GET("/storage", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		// We should only show storage list to host user.
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		// We should only show storage list to the storage managers.
+		if _, err := s.checkPermission(c, PermissionStorageManage); err != nil {
+			return err
 		}
 
 		list, err := s.Store.ListStorages(ctx, &store.FindStorage{})
@@ -198,19 +165,8 @@ func (s *APIV1Service) registerStorageRoutes(g *echo.Group) {
 
 	g.DELETE("/storage/:storageId", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionStorageManage); err != nil {
+			return err
 		}
 
 		storageID, err := strconv.Atoi(c.Param("storageId"))
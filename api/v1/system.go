@@ -19,6 +19,8 @@ type SystemStatus struct {
 	// System settings
 	// Allow sign up.
 	AllowSignUp bool `json:"allowSignUp"`
+	// Sign up mode, derived from allow sign up if not set.
+	SignUpMode SignUpMode `json:"signUpMode"`
 	// Disable public memos.
 	DisablePublicMemos bool `json:"disablePublicMemos"`
 	// Max upload size.
@@ -85,14 +87,14 @@ func (s *APIV1Service) registerSystemRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find system setting list").SetInternal(err)
 		}
 		for _, systemSetting := range systemSettingList {
-			if systemSetting.Name == SystemSettingServerIDName.String() || systemSetting.Name == SystemSettingSecretSessionName.String() || systemSetting.Name == SystemSettingTelegramBotTokenName.String() {
+			if systemSetting.Name == SystemSettingServerIDName.String() || systemSetting.Name == SystemSettingSecretSessionName.String() || systemSetting.Name == SystemSettingTelegramBotTokenName.String() || systemSetting.Name == SystemSettingSCIMTokenName.String() {
 				continue
 			}
 
 			var baseValue any
 			err := json.Unmarshal([]byte(systemSetting.Value), &baseValue)
 			if err != nil {
-				log.Warn("Failed to unmarshal system setting value", zap.String("setting name", systemSetting.Name))
+				log.WithContext(ctx).Warn("Failed to unmarshal system setting value", zap.String("setting name", systemSetting.Name))
 				continue
 			}
 
@@ -119,8 +121,16 @@ func (s *APIV1Service) registerSystemRoutes(g *echo.Group) {
 				systemStatus.LocalStoragePath = baseValue.(string)
 			case SystemSettingMemoDisplayWithUpdatedTsName.String():
 				systemStatus.MemoDisplayWithUpdatedTs = baseValue.(bool)
+			case SystemSettingSignUpModeName.String():
+				systemStatus.SignUpMode = SignUpMode(baseValue.(string))
 			default:
-				log.Warn("Unknown system setting name", zap.String("setting name", systemSetting.Name))
+				log.WithContext(ctx).Warn("Unknown system setting name", zap.String("setting name", systemSetting.Name))
+			}
+		}
+		if systemStatus.SignUpMode == "" {
+			systemStatus.SignUpMode = SignUpModeDisabled
+			if systemStatus.AllowSignUp {
+				systemStatus.SignUpMode = SignUpModeOpen
 			}
 		}
 
@@ -129,19 +139,8 @@ func (s *APIV1Service) registerSystemRoutes(g *echo.Group) {
 
 	g.POST("/system/vacuum", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionSystemManage); err != nil {
+			return err
 		}
 
 		if err := s.Store.Vacuum(ctx); err != nil {
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/plugin/mail"
 	"github.com/usememos/memos/store"
 )
 
@@ -40,8 +41,24 @@ const (
 	SystemSettingMemoDisplayWithUpdatedTsName SystemSettingName = "memo-display-with-updated-ts"
 	// SystemSettingOpenAIConfigName is the name of OpenAI config.
 	SystemSettingOpenAIConfigName SystemSettingName = "openai-config"
+	// SystemSettingAIConfigName is the name of AI provider config.
+	SystemSettingAIConfigName SystemSettingName = "ai-config"
 	// SystemSettingAutoBackupIntervalName is the name of auto backup interval as seconds.
 	SystemSettingAutoBackupIntervalName SystemSettingName = "auto-backup-interval"
+	// SystemSettingTwoFactorRequiredRolesName is the name of the roles required to use two-factor authentication.
+	SystemSettingTwoFactorRequiredRolesName SystemSettingName = "two-factor-required-roles"
+	// SystemSettingSignInLockoutName is the name of the limits of failed sign-in attempts.
+	SystemSettingSignInLockoutName SystemSettingName = "signin-lockout"
+	// SystemSettingSMTPConfigName is the name of the SMTP server sending the emails.
+	SystemSettingSMTPConfigName SystemSettingName = "smtp-config"
+	// SystemSettingSignUpModeName is the name of the signup mode, overriding allow signup setting if set.
+	SystemSettingSignUpModeName SystemSettingName = "signup-mode"
+	// SystemSettingSCIMTokenName is the name of the hash of the SCIM bearer token.
+	SystemSettingSCIMTokenName SystemSettingName = "scim-token"
+	// SystemSettingActivityRetentionDaysName is the name of the days to keep the activities, 0 means forever.
+	SystemSettingActivityRetentionDaysName SystemSettingName = "activity-retention-days"
+	// SystemSettingIdentityLinkPolicyName is the name of the policy linking the identities to the existing users on SSO sign-in.
+	SystemSettingIdentityLinkPolicyName SystemSettingName = "identity-link-policy"
 )
 
 // CustomizedProfile is the struct definition for SystemSettingCustomizedProfileName system setting item.
@@ -76,6 +93,140 @@ type OpenAIConfig struct {
 	Host string `json:"host"`
 }
 
+type AIProviderType string
+
+const (
+	// AIProviderOpenAI is the type of OpenAI and OpenAI-compatible providers.
+	AIProviderOpenAI AIProviderType = "OPENAI"
+	// AIProviderOllama is the type of Ollama-style local model servers.
+	AIProviderOllama AIProviderType = "OLLAMA"
+)
+
+func (t AIProviderType) String() string {
+	return string(t)
+}
+
+// AIConfig is the struct definition for SystemSettingAIConfigName system setting item.
+type AIConfig struct {
+	Provider AIProviderType `json:"provider"`
+	// Host is the base url of the provider, empty means the provider default.
+	Host string `json:"host"`
+	// Key is the API key, only required by OpenAI.
+	Key   string `json:"key"`
+	Model string `json:"model"`
+	// EmbeddingModel is the model used to embed memos, empty means the provider default.
+	EmbeddingModel string  `json:"embeddingModel"`
+	Temperature    float64 `json:"temperature"`
+	MaxTokens      int     `json:"maxTokens"`
+	// RateLimitPerHour is the max number of requests per user per hour, 0 means unlimited.
+	RateLimitPerHour int `json:"rateLimitPerHour"`
+}
+
+func (config AIConfig) Validate() error {
+	switch config.Provider {
+	case AIProviderOpenAI:
+		if config.Key == "" {
+			return fmt.Errorf("key is required by provider %s", config.Provider)
+		}
+	case AIProviderOllama:
+		if config.Model == "" {
+			return fmt.Errorf("model is required by provider %s", config.Provider)
+		}
+	default:
+		return fmt.Errorf("invalid provider %s", config.Provider)
+	}
+	if config.Temperature < 0 || config.Temperature > 2 {
+		return fmt.Errorf("temperature should be between 0 and 2")
+	}
+	if config.MaxTokens < 0 {
+		return fmt.Errorf("max tokens should not be negative")
+	}
+	if config.RateLimitPerHour < 0 {
+		return fmt.Errorf("rate limit should not be negative")
+	}
+	return nil
+}
+
+// SignInLockoutConfig is the struct definition for SystemSettingSignInLockoutName system setting item.
+type SignInLockoutConfig struct {
+	// UsernameMaxAttempts is the number of failed attempts before the username is locked, 0 means unlimited.
+	UsernameMaxAttempts int `json:"usernameMaxAttempts"`
+	// IPMaxAttempts is the number of failed attempts before the IP is locked, 0 means unlimited.
+	IPMaxAttempts int `json:"ipMaxAttempts"`
+	// LockoutSeconds is the duration of the first lockout, doubled by every failed attempt after it.
+	LockoutSeconds int `json:"lockoutSeconds"`
+	// MaxLockoutSeconds caps the lockout, the failed attempts are forgotten after it without any failure.
+	MaxLockoutSeconds int `json:"maxLockoutSeconds"`
+}
+
+func (config SignInLockoutConfig) Validate() error {
+	if config.UsernameMaxAttempts < 0 || config.IPMaxAttempts < 0 {
+		return fmt.Errorf("max attempts should not be negative")
+	}
+	if config.LockoutSeconds <= 0 {
+		return fmt.Errorf("lockout seconds should > 0")
+	}
+	if config.MaxLockoutSeconds < config.LockoutSeconds {
+		return fmt.Errorf("max lockout seconds should not be less than lockout seconds")
+	}
+	return nil
+}
+
+type SignUpMode string
+
+const (
+	// SignUpModeOpen allows anyone to sign up, optionally with an invitation code for its role.
+	SignUpModeOpen SignUpMode = "OPEN"
+	// SignUpModeInviteOnly requires a valid invitation code to sign up.
+	SignUpModeInviteOnly SignUpMode = "INVITE_ONLY"
+	// SignUpModeDisabled rejects all signups except the one of the host.
+	SignUpModeDisabled SignUpMode = "DISABLED"
+)
+
+func (mode SignUpMode) String() string {
+	return string(mode)
+}
+
+type IdentityLinkPolicy string
+
+const (
+	// IdentityLinkPolicyVerifiedEmail links the identities to the users of the same verified email, except the host and admins.
+	IdentityLinkPolicyVerifiedEmail IdentityLinkPolicy = "VERIFIED_EMAIL"
+	// IdentityLinkPolicyNone never links the identities automatically, users link them from the settings. It is the default.
+	IdentityLinkPolicyNone IdentityLinkPolicy = "NONE"
+)
+
+func (policy IdentityLinkPolicy) String() string {
+	return string(policy)
+}
+
+// SMTPConfig is the struct definition for SystemSettingSMTPConfigName system setting item.
+type SMTPConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Username is optional, the emails are sent without authentication if empty.
+	Username string            `json:"username"`
+	Password string            `json:"password"`
+	From     string            `json:"from"`
+	Security mail.SMTPSecurity `json:"security"`
+}
+
+func (config SMTPConfig) Validate() error {
+	_, err := mail.NewSMTPSender(config.toPluginConfig())
+	return err
+}
+
+func (config SMTPConfig) toPluginConfig() *mail.SMTPConfig {
+	return &mail.SMTPConfig{
+		Host:     config.Host,
+		Port:     config.Port,
+		Username: config.Username,
+		Password: config.Password,
+		From:     config.From,
+		Security: config.Security,
+	}
+}
+
 type UpsertSystemSettingRequest struct {
 	Name        SystemSettingName `json:"name"`
 	Value       string            `json:"value"`
@@ -86,7 +237,7 @@ const systemSettingUnmarshalError = `failed to unmarshal value from system setti
 
 func (upsert UpsertSystemSettingRequest) Validate() error {
 	switch settingName := upsert.Name; settingName {
-	case SystemSettingServerIDName:
+	case SystemSettingServerIDName, SystemSettingSCIMTokenName:
 		return fmt.Errorf("updating %v is not allowed", settingName)
 	case SystemSettingAllowSignUpName:
 		var value bool
@@ -142,6 +293,14 @@ func (upsert UpsertSystemSettingRequest) Validate() error {
 		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
 			return fmt.Errorf(systemSettingUnmarshalError, settingName)
 		}
+	case SystemSettingAIConfigName:
+		value := AIConfig{}
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if err := value.Validate(); err != nil {
+			return err
+		}
 	case SystemSettingAutoBackupIntervalName:
 		var value string
 		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
@@ -177,6 +336,56 @@ func (upsert UpsertSystemSettingRequest) Validate() error {
 		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
 			return fmt.Errorf(systemSettingUnmarshalError, settingName)
 		}
+	case SystemSettingTwoFactorRequiredRolesName:
+		value := []Role{}
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		for _, role := range value {
+			if role != RoleHost && role != RoleAdmin && role != RoleUser {
+				return fmt.Errorf("invalid role %s", role)
+			}
+		}
+	case SystemSettingSignInLockoutName:
+		value := SignInLockoutConfig{}
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if err := value.Validate(); err != nil {
+			return err
+		}
+	case SystemSettingSignUpModeName:
+		var value SignUpMode
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if value != SignUpModeOpen && value != SignUpModeInviteOnly && value != SignUpModeDisabled {
+			return fmt.Errorf("invalid signup mode %s", value)
+		}
+	case SystemSettingActivityRetentionDaysName:
+		var value int
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if value < 0 {
+			return fmt.Errorf("activity retention days should not be negative")
+		}
+	case SystemSettingIdentityLinkPolicyName:
+		var value IdentityLinkPolicy
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if value != IdentityLinkPolicyVerifiedEmail && value != IdentityLinkPolicyNone {
+			return fmt.Errorf("invalid identity link policy %s", value)
+		}
+	case SystemSettingSMTPConfigName:
+		value := SMTPConfig{}
+		if err := json.Unmarshal([]byte(upsert.Value), &value); err != nil {
+			return fmt.Errorf(systemSettingUnmarshalError, settingName)
+		}
+		if err := value.Validate(); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid system setting name")
 	}
@@ -186,19 +395,8 @@ func (upsert UpsertSystemSettingRequest) Validate() error {
 func (s *APIV1Service) registerSystemSettingRoutes(g *echo.Group) {
 	g.POST("/system/setting", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionSystemManage); err != nil {
+			return err
 		}
 
 		systemSettingUpsert := &UpsertSystemSettingRequest{}
@@ -222,19 +420,8 @@ func (s *APIV1Service) registerSystemSettingRoutes(g *echo.Group) {
 
 	g.GET("/system/setting", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-
-		user, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if user == nil || user.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		if _, err := s.checkPermission(c, PermissionSystemManage); err != nil {
+			return err
 		}
 
 		list, err := s.Store.ListSystemSettings(ctx, &store.FindSystemSetting{})
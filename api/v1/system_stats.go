@@ -0,0 +1,250 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/store"
+)
+
+const (
+	// defaultMemoDailyStatsDays is the number of days of the memos created per day if not specified.
+	defaultMemoDailyStatsDays = 30
+	// maxMemoDailyStatsDays is the max number of days of the memos created per day.
+	maxMemoDailyStatsDays = 366
+)
+
+type SystemStats struct {
+	User        *UserStats              `json:"user"`
+	Memo        *MemoStats              `json:"memo"`
+	Resource    []*ResourceStorageStats `json:"resource"`
+	Database    *DatabaseStats          `json:"database"`
+	TelegramBot *TelegramBotStats       `json:"telegramBot"`
+	LastBackup  *BackupStats            `json:"lastBackup"`
+}
+
+type UserStats struct {
+	// CountList is the numbers of users by role and row status.
+	CountList []*UserCount `json:"countList"`
+	// ActiveCount7Days and ActiveCount30Days are the numbers of users signed in during the last 7 and 30 days.
+	ActiveCount7Days  int `json:"activeCount7Days"`
+	ActiveCount30Days int `json:"activeCount30Days"`
+}
+
+type UserCount struct {
+	Role      Role      `json:"role"`
+	RowStatus RowStatus `json:"rowStatus"`
+	Count     int       `json:"count"`
+}
+
+type MemoStats struct {
+	VisibilityCountList []*MemoVisibilityCount `json:"visibilityCountList"`
+	// DailyCountList is the numbers of memos created per UTC day, the days without memos are omitted.
+	DailyCountList []*MemoDailyCount `json:"dailyCountList"`
+}
+
+type MemoVisibilityCount struct {
+	Visibility Visibility `json:"visibility"`
+	Count      int        `json:"count"`
+}
+
+type MemoDailyCount struct {
+	// Date is formatted as YYYY-MM-DD.
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type ResourceStorageStats struct {
+	// Storage is DATABASE, LOCAL or EXTERNAL.
+	Storage string `json:"storage"`
+	Count   int    `json:"count"`
+	Size    int64  `json:"size"`
+}
+
+type DatabaseStats struct {
+	Size    int64 `json:"size"`
+	WALSize int64 `json:"walSize"`
+}
+
+type TelegramBotStats struct {
+	// Enabled is whether the bot token is set.
+	Enabled bool `json:"enabled"`
+	// Healthy is whether the last poll of updates succeeded.
+	Healthy     bool   `json:"healthy"`
+	LastPollTs  int64  `json:"lastPollTs"`
+	LastErrorTs int64  `json:"lastErrorTs"`
+	LastError   string `json:"lastError"`
+}
+
+type BackupStats struct {
+	CreatedTs int64  `json:"createdTs"`
+	Succeeded bool   `json:"succeeded"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	Error     string `json:"error"`
+}
+
+func (s *APIV1Service) registerSystemStatsRoutes(g *echo.Group) {
+	// GET /system/stats?days=30 - Get the statistics of the usage and the health of the instance.
+	g.GET("/system/stats", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionStatsView); err != nil {
+			return err
+		}
+		days := defaultMemoDailyStatsDays
+		if v := c.QueryParam("days"); v != "" {
+			var err error
+			if days, err = strconv.Atoi(v); err != nil || days <= 0 || days > maxMemoDailyStatsDays {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid days: %s, should be between 1 and %d", v, maxMemoDailyStatsDays))
+			}
+		}
+
+		now := time.Now()
+		userStats := &UserStats{
+			CountList: []*UserCount{},
+		}
+		userCountList, err := s.Store.ListUserCounts(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count users").SetInternal(err)
+		}
+		for _, userCount := range userCountList {
+			userStats.CountList = append(userStats.CountList, &UserCount{
+				Role:      Role(userCount.Role),
+				RowStatus: RowStatus(userCount.RowStatus),
+				Count:     userCount.Count,
+			})
+		}
+		if userStats.ActiveCount7Days, err = s.Store.CountActiveUsers(ctx, ActivityUserAuthSignIn.String(), now.AddDate(0, 0, -7).Unix()); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count active users").SetInternal(err)
+		}
+		if userStats.ActiveCount30Days, err = s.Store.CountActiveUsers(ctx, ActivityUserAuthSignIn.String(), now.AddDate(0, 0, -30).Unix()); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count active users").SetInternal(err)
+		}
+
+		memoStats := &MemoStats{
+			VisibilityCountList: []*MemoVisibilityCount{},
+			DailyCountList:      []*MemoDailyCount{},
+		}
+		memoVisibilityCountList, err := s.Store.ListMemoVisibilityCounts(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count memos").SetInternal(err)
+		}
+		for _, memoVisibilityCount := range memoVisibilityCountList {
+			memoStats.VisibilityCountList = append(memoStats.VisibilityCountList, &MemoVisibilityCount{
+				Visibility: Visibility(memoVisibilityCount.Visibility),
+				Count:      memoVisibilityCount.Count,
+			})
+		}
+		// The days are counted back from the start of the current UTC day.
+		year, month, day := now.UTC().Date()
+		createdTsAfter := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days).Unix()
+		memoDailyCountList, err := s.Store.ListMemoDailyCounts(ctx, createdTsAfter)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count memos").SetInternal(err)
+		}
+		for _, memoDailyCount := range memoDailyCountList {
+			memoStats.DailyCountList = append(memoStats.DailyCountList, &MemoDailyCount{
+				Date:  memoDailyCount.Date,
+				Count: memoDailyCount.Count,
+			})
+		}
+
+		resourceStatsList := []*ResourceStorageStats{}
+		resourceStorageUsageList, err := s.Store.ListResourceStorageUsages(ctx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count resources").SetInternal(err)
+		}
+		for _, resourceStorageUsage := range resourceStorageUsageList {
+			resourceStatsList = append(resourceStatsList, &ResourceStorageStats{
+				Storage: string(resourceStorageUsage.Storage),
+				Count:   resourceStorageUsage.Count,
+				Size:    resourceStorageUsage.Size,
+			})
+		}
+
+		databaseStats, err := s.getDatabaseStats()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to stat database file").SetInternal(err)
+		}
+		lastBackup, err := s.getLastBackupStats(c)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, &SystemStats{
+			User:        userStats,
+			Memo:        memoStats,
+			Resource:    resourceStatsList,
+			Database:    databaseStats,
+			TelegramBot: s.getTelegramBotStats(),
+			LastBackup:  lastBackup,
+		})
+	})
+}
+
+// getDatabaseStats returns the sizes of the database file and its write-ahead log, 0 if they do not exist.
+func (s *APIV1Service) getDatabaseStats() (*DatabaseStats, error) {
+	databaseStats := &DatabaseStats{}
+	for _, item := range []struct {
+		path string
+		size *int64
+	}{
+		{path: s.Profile.DSN, size: &databaseStats.Size},
+		{path: s.Profile.DSN + "-wal", size: &databaseStats.WALSize},
+	} {
+		fileInfo, err := os.Stat(item.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		*item.size = fileInfo.Size()
+	}
+	return databaseStats, nil
+}
+
+func (s *APIV1Service) getTelegramBotStats() *TelegramBotStats {
+	if s.TelegramBot == nil {
+		return &TelegramBotStats{}
+	}
+	status := s.TelegramBot.Status()
+	return &TelegramBotStats{
+		Enabled:     status.Enabled,
+		Healthy:     status.Enabled && status.LastPollTs > 0 && status.LastPollTs >= status.LastErrorTs,
+		LastPollTs:  status.LastPollTs,
+		LastErrorTs: status.LastErrorTs,
+		LastError:   status.LastError,
+	}
+}
+
+// getLastBackupStats returns the result of the last auto backup recorded in the activities, nil if none.
+func (s *APIV1Service) getLastBackupStats(c echo.Context) (*BackupStats, error) {
+	limit := 1
+	list, err := s.Store.ListActivities(c.Request().Context(), &store.FindActivity{
+		TypeList: []string{ActivityServerBackup.String()},
+		Limit:    &limit,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find activity list").SetInternal(err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	payload := &ActivityServerBackupPayload{}
+	if err := json.Unmarshal([]byte(list[0].Payload), payload); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to unmarshal backup activity payload").SetInternal(err)
+	}
+	return &BackupStats{
+		CreatedTs: list[0].CreatedTs,
+		Succeeded: payload.Error == "",
+		Filename:  payload.Filename,
+		Size:      payload.Size,
+		Error:     payload.Error,
+	}, nil
+}
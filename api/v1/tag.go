@@ -19,11 +19,13 @@ type Tag struct {
 }
 
 type UpsertTagRequest struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`
+	WorkspaceID int    `json:"workspaceId"`
 }
 
 type DeleteTagRequest struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`
+	WorkspaceID int    `json:"workspaceId"`
 }
 
 func (s *APIV1Service) registerTagRoutes(g *echo.Group) {
@@ -41,10 +43,14 @@ func (s *APIV1Service) registerTagRoutes(g *echo.Group) {
 		if tagUpsert.Name == "" {
 			return echo.NewHTTPError(http.StatusBadRequest, "Tag name shouldn't be empty")
 		}
+		if err := s.checkWorkspaceContentCreatable(ctx, tagUpsert.WorkspaceID, userID); err != nil {
+			return err
+		}
 
 		tag, err := s.Store.UpsertTag(ctx, &store.Tag{
-			Name:      tagUpsert.Name,
-			CreatorID: userID,
+			Name:        tagUpsert.Name,
+			CreatorID:   userID,
+			WorkspaceID: tagUpsert.WorkspaceID,
 		})
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert tag").SetInternal(err)
@@ -62,9 +68,14 @@ func (s *APIV1Service) registerTagRoutes(g *echo.Group) {
 		if !ok {
 			return echo.NewHTTPError(http.StatusBadRequest, "Missing user id to find tag")
 		}
+		workspaceID, err := parseWorkspaceIDParam(c)
+		if err != nil {
+			return err
+		}
 
 		list, err := s.Store.ListTags(ctx, &store.FindTag{
-			CreatorID: userID,
+			CreatorID:   userID,
+			WorkspaceID: workspaceID,
 		})
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find tag list").SetInternal(err)
@@ -72,6 +83,10 @@ func (s *APIV1Service) registerTagRoutes(g *echo.Group) {
 
 		tagNameList := []string{}
 		for _, tag := range list {
+			// The tags of the same name in different workspaces are listed once.
+			if len(tagNameList) > 0 && tagNameList[len(tagNameList)-1] == tag.Name {
+				continue
+			}
 			tagNameList = append(tagNameList, tag.Name)
 		}
 		return c.JSON(http.StatusOK, tagNameList)
@@ -138,12 +153,18 @@ func (s *APIV1Service) registerTagRoutes(g *echo.Group) {
 		}
 
 		err := s.Store.DeleteTag(ctx, &store.DeleteTag{
-			Name:      tagDelete.Name,
-			CreatorID: userID,
+			Name:        tagDelete.Name,
+			CreatorID:   userID,
+			WorkspaceID: tagDelete.WorkspaceID,
 		})
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to delete tag name: %v", tagDelete.Name)).SetInternal(err)
 		}
+		if err := s.createActivity(ctx, userID, ActivityTagDelete, ActivityInfo, ActivityTagDeletePayload{
+			TagName: tagDelete.Name,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 		return c.JSON(http.StatusOK, true)
 	})
 }
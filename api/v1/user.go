@@ -42,11 +42,14 @@ type User struct {
 	Username        string         `json:"username"`
 	Role            Role           `json:"role"`
 	Email           string         `json:"email"`
+	EmailVerified   bool           `json:"emailVerified"`
 	Nickname        string         `json:"nickname"`
 	PasswordHash    string         `json:"-"`
 	OpenID          string         `json:"openId"`
 	AvatarURL       string         `json:"avatarUrl"`
 	UserSettingList []*UserSetting `json:"userSettingList"`
+	// Permissions is the permissions granted to the user, only returned for the current user.
+	Permissions []Permission `json:"permissions,omitempty"`
 }
 
 type CreateUserRequest struct {
@@ -95,6 +98,33 @@ type UpdateUserRequest struct {
 	AvatarURL   *string    `json:"avatarUrl"`
 }
 
+// fields returns the names of the fields to update.
+func (update UpdateUserRequest) fields() []string {
+	fields := []string{}
+	if update.RowStatus != nil {
+		fields = append(fields, "rowStatus")
+	}
+	if update.Username != nil {
+		fields = append(fields, "username")
+	}
+	if update.Email != nil {
+		fields = append(fields, "email")
+	}
+	if update.Nickname != nil {
+		fields = append(fields, "nickname")
+	}
+	if update.Password != nil {
+		fields = append(fields, "password")
+	}
+	if update.ResetOpenID != nil && *update.ResetOpenID {
+		fields = append(fields, "openId")
+	}
+	if update.AvatarURL != nil {
+		fields = append(fields, "avatarUrl")
+	}
+	return fields
+}
+
 func (update UpdateUserRequest) Validate() error {
 	if update.Username != nil && len(*update.Username) < 3 {
 		return fmt.Errorf("username is too short, minimum length is 3")
@@ -132,21 +162,9 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 	// POST /user - Create a new user.
 	g.POST("/user", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		userID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing auth session")
-		}
-		currentUser, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &userID,
-		})
+		currentUser, err := s.checkPermission(c, PermissionUserManage)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user by id").SetInternal(err)
-		}
-		if currentUser == nil {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing auth session")
-		}
-		if currentUser.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized to create user")
+			return err
 		}
 
 		userCreate := &CreateUserRequest{}
@@ -160,6 +178,9 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		if userCreate.Role == RoleHost {
 			return echo.NewHTTPError(http.StatusForbidden, "Could not create host user")
 		}
+		if userCreate.Role == RoleAdmin && currentUser.Role != store.RoleHost {
+			return echo.NewHTTPError(http.StatusForbidden, "Only the host can create admins")
+		}
 
 		passwordHash, err := bcrypt.GenerateFromPassword([]byte(userCreate.Password), bcrypt.DefaultCost)
 		if err != nil {
@@ -230,11 +251,43 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		for _, userSetting := range list {
 			userSettingList = append(userSettingList, convertUserSettingFromStore(userSetting))
 		}
+		permissions, err := getUserPermissions(ctx, s.Store, user)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+		}
 		userMessage := convertUserFromStore(user)
 		userMessage.UserSettingList = userSettingList
+		userMessage.Permissions = permissions
 		return c.JSON(http.StatusOK, userMessage)
 	})
 
+	// POST /user/me/feed-token - Regenerate the feed token of current user.
+	g.POST("/user/me/feed-token", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		userID, ok := c.Get(getUserIDContextKey()).(int)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing auth session")
+		}
+
+		feedToken, err := util.RandomString(32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate feed token").SetInternal(err)
+		}
+		feedTokenValue, err := json.Marshal(feedToken)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to marshal feed token").SetInternal(err)
+		}
+		userSetting, err := s.Store.UpsertUserSetting(ctx, &store.UserSetting{
+			UserID: userID,
+			Key:    UserSettingFeedTokenKey.String(),
+			Value:  string(feedTokenValue),
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert user setting").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertUserSettingFromStore(userSetting))
+	})
+
 	// GET /user/:id - Get user by id.
 	g.GET("/user/:id", func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -276,8 +329,21 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		}
 		if currentUser == nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Current session user not found with ID: %d", currentUserID)).SetInternal(err)
-		} else if currentUser.Role != store.RoleHost && currentUserID != userID {
-			return echo.NewHTTPError(http.StatusForbidden, "Unauthorized to update user").SetInternal(err)
+		}
+		if currentUserID != userID {
+			if _, err := s.checkPermission(c, PermissionUserManage); err != nil {
+				return err
+			}
+			targetUser, err := s.Store.GetUser(ctx, &store.FindUser{ID: &userID})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+			}
+			if targetUser == nil {
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User not found with ID: %d", userID))
+			}
+			if err := s.checkUserManageable(c, currentUser, targetUser); err != nil {
+				return err
+			}
 		}
 
 		request := &UpdateUserRequest{}
@@ -302,6 +368,15 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		}
 		if request.Email != nil {
 			userUpdate.Email = request.Email
+			targetUser, err := s.Store.GetUser(ctx, &store.FindUser{ID: &userID})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+			}
+			// The new email has to be verified again.
+			if targetUser != nil && targetUser.Email != *request.Email {
+				emailVerified := false
+				userUpdate.EmailVerified = &emailVerified
+			}
 		}
 		if request.Nickname != nil {
 			userUpdate.Nickname = request.Nickname
@@ -327,6 +402,26 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch user").SetInternal(err)
 		}
+		if request.Password != nil {
+			// Changing the password signs out everywhere, the user changing their own password continues with a new session.
+			if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+				UserID: &user.ID,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke sessions").SetInternal(err)
+			}
+			if currentUserID == userID {
+				if err := s.createUserSessionAndSetCookies(c, user); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
+				}
+			}
+		}
+		if err := s.createActivity(ctx, currentUserID, ActivityUserUpdate, ActivityInfo, ActivityUserUpdatePayload{
+			UserID:   user.ID,
+			Username: user.Username,
+			Fields:   request.fields(),
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 
 		list, err := s.Store.ListUserSettings(ctx, &store.FindUserSetting{
 			UserID: &userID,
@@ -343,23 +438,13 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 		return c.JSON(http.StatusOK, userMessage)
 	})
 
-	// DELETE /user/:id - Delete user by id.
+	// DELETE /user/:id?mode=cascade|transfer&transferTo=2&dryRun=true - Delete user by id,
+	// deleting or transferring its content, or only reporting what would be done.
 	g.DELETE("/user/:id", func(c echo.Context) error {
 		ctx := c.Request().Context()
-		currentUserID, ok := c.Get(getUserIDContextKey()).(int)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
-		}
-		currentUser, err := s.Store.GetUser(ctx, &store.FindUser{
-			ID: &currentUserID,
-		})
+		currentUser, err := s.checkPermission(c, PermissionUserManage)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
-		}
-		if currentUser == nil {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Current session user not found with ID: %d", currentUserID)).SetInternal(err)
-		} else if currentUser.Role != store.RoleHost {
-			return echo.NewHTTPError(http.StatusForbidden, "Unauthorized to delete user").SetInternal(err)
+			return err
 		}
 
 		userID, err := strconv.Atoi(c.Param("id"))
@@ -367,13 +452,27 @@ func (s *APIV1Service) registerUserRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
 		}
 
-		userDelete := &store.DeleteUser{
-			ID: userID,
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User not found with ID: %d", userID))
 		}
-		if err := s.Store.DeleteUser(ctx, userDelete); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete user").SetInternal(err)
+		if err := s.checkUserManageable(c, currentUser, user); err != nil {
+			return err
+		}
+		if user.Role == store.RoleHost {
+			return echo.NewHTTPError(http.StatusForbidden, "Could not delete host user")
+		}
+
+		report, err := s.deleteUser(c, currentUser, user)
+		if err != nil {
+			return err
 		}
-		return c.JSON(http.StatusOK, true)
+		return c.JSON(http.StatusOK, report)
 	})
 }
 
@@ -402,16 +501,17 @@ func (s *APIV1Service) createUserCreateActivity(c echo.Context, user *User) erro
 
 func convertUserFromStore(user *store.User) *User {
 	return &User{
-		ID:           user.ID,
-		RowStatus:    RowStatus(user.RowStatus),
-		CreatedTs:    user.CreatedTs,
-		UpdatedTs:    user.UpdatedTs,
-		Username:     user.Username,
-		Role:         Role(user.Role),
-		Email:        user.Email,
-		Nickname:     user.Nickname,
-		PasswordHash: user.PasswordHash,
-		OpenID:       user.OpenID,
-		AvatarURL:    user.AvatarURL,
+		ID:            user.ID,
+		RowStatus:     RowStatus(user.RowStatus),
+		CreatedTs:     user.CreatedTs,
+		UpdatedTs:     user.UpdatedTs,
+		Username:      user.Username,
+		Role:          Role(user.Role),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Nickname:      user.Nickname,
+		PasswordHash:  user.PasswordHash,
+		OpenID:        user.OpenID,
+		AvatarURL:     user.AvatarURL,
 	}
 }
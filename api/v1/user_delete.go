@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+// UserDeleteMode is how the content of the deleted user is handled.
+type UserDeleteMode string
+
+const (
+	// UserDeleteCascade deletes the content of the user with the user, including the stored files.
+	UserDeleteCascade UserDeleteMode = "cascade"
+	// UserDeleteTransfer transfers the memos, resources and tags of the user to another user.
+	UserDeleteTransfer UserDeleteMode = "transfer"
+)
+
+func (mode UserDeleteMode) String() string {
+	return string(mode)
+}
+
+// DeleteUserReport is the content of the user deleted or transferred, or to be with a dry run.
+type DeleteUserReport struct {
+	UserID           int            `json:"userId"`
+	Username         string         `json:"username"`
+	Mode             UserDeleteMode `json:"mode"`
+	TransferToUserID int            `json:"transferToUserId,omitempty"`
+	DryRun           bool           `json:"dryRun"`
+
+	MemoCount             int   `json:"memoCount"`
+	ResourceCount         int   `json:"resourceCount"`
+	ResourceSize          int64 `json:"resourceSize"`
+	ShortcutCount         int   `json:"shortcutCount"`
+	TagCount              int   `json:"tagCount"`
+	SettingCount          int   `json:"settingCount"`
+	OrganizerCount        int   `json:"organizerCount"`
+	FeedSubscriptionCount int   `json:"feedSubscriptionCount"`
+	// FileCount is the local files and external objects of the resources deleted.
+	FileCount int `json:"fileCount"`
+}
+
+// deleteUser deletes the user in the mode of the query, returning the report of its content.
+func (s *APIV1Service) deleteUser(c echo.Context, currentUser *store.User, user *store.User) (*DeleteUserReport, error) {
+	ctx := c.Request().Context()
+	report := &DeleteUserReport{
+		UserID:   user.ID,
+		Username: user.Username,
+		Mode:     UserDeleteCascade,
+		DryRun:   c.QueryParam("dryRun") == "true",
+	}
+	if v := c.QueryParam("mode"); v != "" {
+		report.Mode = UserDeleteMode(v)
+	}
+
+	userDelete := &store.DeleteUser{
+		ID: user.ID,
+	}
+	switch report.Mode {
+	case UserDeleteCascade:
+	case UserDeleteTransfer:
+		transferToUserID, err := strconv.Atoi(c.QueryParam("transferTo"))
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Transfer to user ID is not a number: %s", c.QueryParam("transferTo"))).SetInternal(err)
+		}
+		if transferToUserID == user.ID {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Could not transfer the content to the deleted user")
+		}
+		transferToUser, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &transferToUserID,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if transferToUser == nil || transferToUser.RowStatus == store.Archived {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Active user not found with ID: %d", transferToUserID))
+		}
+		report.TransferToUserID = transferToUserID
+		userDelete.TransferToUserID = &transferToUserID
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid delete mode: %s", report.Mode))
+	}
+
+	userContent, err := s.Store.GetUserContent(ctx, user.ID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user content").SetInternal(err)
+	}
+	report.MemoCount = userContent.MemoCount
+	report.ResourceCount = userContent.ResourceCount
+	report.ResourceSize = userContent.ResourceSize
+	report.ShortcutCount = userContent.ShortcutCount
+	report.TagCount = userContent.TagCount
+	report.SettingCount = userContent.SettingCount
+	report.OrganizerCount = userContent.OrganizerCount
+	report.FeedSubscriptionCount = userContent.FeedSubscriptionCount
+
+	resourceList := []*store.Resource{}
+	if report.Mode == UserDeleteCascade {
+		resourceList, err = s.Store.ListResources(ctx, &store.FindResource{
+			CreatorID: &user.ID,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find resource list").SetInternal(err)
+		}
+		for _, resource := range resourceList {
+			if resource.InternalPath != "" || resource.ObjectKey != "" {
+				report.FileCount++
+			}
+		}
+	}
+	if report.DryRun {
+		return report, nil
+	}
+
+	if err := s.Store.DeleteUser(ctx, userDelete); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete user").SetInternal(err)
+	}
+	// The files are deleted only after the resources are, so a failed deletion keeps them.
+	for _, resource := range resourceList {
+		s.deleteResourceLocalFiles(ctx, resource)
+		if err := s.deleteS3Object(ctx, resource); err != nil {
+			log.WithContext(ctx).Warn(fmt.Sprintf("failed to delete S3 object with key %s", resource.ObjectKey), zap.Error(err))
+		}
+	}
+	if err := s.createActivity(ctx, currentUser.ID, ActivityUserDelete, ActivityWarn, ActivityUserDeletePayload{
+		UserID:           user.ID,
+		Username:         user.Username,
+		Mode:             report.Mode.String(),
+		TransferToUserID: report.TransferToUserID,
+		MemoCount:        report.MemoCount,
+		ResourceCount:    report.ResourceCount,
+	}); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+	}
+	return report, nil
+}
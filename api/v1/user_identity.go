@@ -0,0 +1,310 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/plugin/idp/ldap"
+	"github.com/usememos/memos/store"
+)
+
+type UserIdentity struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	IdentityProviderID   int    `json:"identityProviderId"`
+	IdentityProviderName string `json:"identityProviderName"`
+	ExternalID           string `json:"externalId"`
+	Email                string `json:"email"`
+}
+
+type LinkUserIdentityRequest struct {
+	IdentityProviderID int `json:"identityProviderId"`
+	// Code, RedirectURI and State are the authorization response of an OAuth2 or OIDC identity provider.
+	Code        string `json:"code"`
+	RedirectURI string `json:"redirectUri"`
+	State       string `json:"state"`
+	// Username and Password are the directory credentials of a LDAP identity provider.
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (s *APIV1Service) registerUserIdentityRoutes(g *echo.Group) {
+	g.GET("/user/me/identity", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListUserIdentities(ctx, &store.FindUserIdentity{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity list").SetInternal(err)
+		}
+		userIdentityList := []*UserIdentity{}
+		for _, userIdentity := range list {
+			identityProvider, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProvider{
+				ID: &userIdentity.IdentityProviderID,
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity provider").SetInternal(err)
+			}
+			// Skip the identities of the deleted identity providers, they are vacuumed later.
+			if identityProvider == nil {
+				continue
+			}
+			userIdentityMessage := convertUserIdentityFromStore(userIdentity)
+			userIdentityMessage.IdentityProviderName = identityProvider.Name
+			userIdentityList = append(userIdentityList, userIdentityMessage)
+		}
+		return c.JSON(http.StatusOK, userIdentityList)
+	})
+
+	// POST /user/me/identity - Link the identity authenticated by the identity provider to the current user.
+	g.POST("/user/me/identity", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		request := &LinkUserIdentityRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted link identity request").SetInternal(err)
+		}
+		identityProvider, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProvider{
+			ID: &request.IdentityProviderID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity provider").SetInternal(err)
+		}
+		if identityProvider == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Identity provider not found")
+		}
+
+		var userInfo *idp.IdentityProviderUserInfo
+		if identityProvider.Type == store.IdentityProviderLDAPType {
+			ldapIdentityProvider, err := ldap.NewIdentityProvider(identityProvider.Config.LDAPConfig)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create identity provider instance").SetInternal(err)
+			}
+			userInfo, err = ldapIdentityProvider.Authenticate(request.Username, request.Password)
+			if err != nil {
+				if errors.Is(err, ldap.ErrInvalidCredentials) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "Incorrect directory credentials")
+				}
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to authenticate with identity provider").SetInternal(err)
+			}
+		} else {
+			userInfo, err = s.getSSOUserInfo(c, identityProvider, &SSOSignIn{
+				IdentityProviderID: request.IdentityProviderID,
+				Code:               request.Code,
+				RedirectURI:        request.RedirectURI,
+				State:              request.State,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := checkIdentifierFilter(identityProvider, userInfo); err != nil {
+			return err
+		}
+
+		existedUserIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+			UserID:             &user.ID,
+			IdentityProviderID: &identityProvider.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+		}
+		if existedUserIdentity != nil && existedUserIdentity.ExternalID != getIdentitySubject(userInfo) {
+			return echo.NewHTTPError(http.StatusConflict, "Another identity of the identity provider is already linked, unlink it first")
+		}
+		if err := s.linkUserIdentity(ctx, user, identityProvider, userInfo); err != nil {
+			return err
+		}
+		userIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+			UserID:             &user.ID,
+			IdentityProviderID: &identityProvider.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+		}
+		userIdentityMessage := convertUserIdentityFromStore(userIdentity)
+		userIdentityMessage.IdentityProviderName = identityProvider.Name
+		return c.JSON(http.StatusOK, userIdentityMessage)
+	})
+
+	g.DELETE("/user/me/identity/:identityId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		identityID, err := strconv.Atoi(c.Param("identityId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("identityId"))).SetInternal(err)
+		}
+
+		userIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+			ID:     &identityID,
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+		}
+		if userIdentity == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Identity not found: %d", identityID))
+		}
+		if err := s.Store.DeleteUserIdentity(ctx, &store.DeleteUserIdentity{
+			ID:     userIdentity.ID,
+			UserID: &user.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unlink identity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+// findIdentityProviderUser returns the user linked to the identity, or the user to link the identity to
+// by the identity link policy, nil if none.
+func (s *APIV1Service) findIdentityProviderUser(ctx context.Context, identityProvider *store.IdentityProvider, userInfo *idp.IdentityProviderUserInfo) (*store.User, error) {
+	subject := getIdentitySubject(userInfo)
+	userIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+		IdentityProviderID: &identityProvider.ID,
+		ExternalID:         &subject,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+	}
+	if userIdentity != nil {
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &userIdentity.UserID,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+
+	identityLinkPolicy, err := s.getIdentityLinkPolicy(ctx)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get identity link policy").SetInternal(err)
+	}
+	candidates := []*store.User{}
+	switch identityLinkPolicy {
+	case IdentityLinkPolicyVerifiedEmail:
+		if userInfo.Email == "" || !userInfo.EmailVerified {
+			return nil, nil
+		}
+		users, err := s.Store.ListUsers(ctx, &store.FindUser{
+			Email: &userInfo.Email,
+		})
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user list").SetInternal(err)
+		}
+		for _, user := range users {
+			if user.EmailVerified {
+				candidates = append(candidates, user)
+			}
+		}
+	}
+	// The email is ambiguous if shared by several users.
+	if len(candidates) != 1 {
+		return nil, nil
+	}
+
+	user := candidates[0]
+	// The host and admins are never taken over by the identities, they link them from the settings.
+	if user.Role == store.RoleHost || user.Role == store.RoleAdmin {
+		return nil, nil
+	}
+	linkedUserIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+		UserID:             &user.ID,
+		IdentityProviderID: &identityProvider.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+	}
+	// The user is another person at the identity provider.
+	if linkedUserIdentity != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("User %s is linked to another identity of the identity provider", user.Username))
+	}
+	return user, nil
+}
+
+// linkUserIdentity links the identity to the user if not yet.
+func (s *APIV1Service) linkUserIdentity(ctx context.Context, user *store.User, identityProvider *store.IdentityProvider, userInfo *idp.IdentityProviderUserInfo) error {
+	subject := getIdentitySubject(userInfo)
+	userIdentity, err := s.Store.GetUserIdentity(ctx, &store.FindUserIdentity{
+		IdentityProviderID: &identityProvider.ID,
+		ExternalID:         &subject,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find identity").SetInternal(err)
+	}
+	if userIdentity != nil {
+		if userIdentity.UserID != user.ID {
+			return echo.NewHTTPError(http.StatusConflict, "The identity is already linked to another user")
+		}
+		return nil
+	}
+
+	if _, err := s.Store.CreateUserIdentity(ctx, &store.UserIdentity{
+		UserID:             user.ID,
+		IdentityProviderID: identityProvider.ID,
+		ExternalID:         subject,
+		Email:              userInfo.Email,
+	}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to link identity").SetInternal(err)
+	}
+	return nil
+}
+
+// getIdentityLinkPolicy returns the identity link policy setting, linking none if not set.
+func (s *APIV1Service) getIdentityLinkPolicy(ctx context.Context) (IdentityLinkPolicy, error) {
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingIdentityLinkPolicyName.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if systemSetting == nil {
+		return IdentityLinkPolicyNone, nil
+	}
+	var identityLinkPolicy IdentityLinkPolicy
+	if err := json.Unmarshal([]byte(systemSetting.Value), &identityLinkPolicy); err != nil {
+		return "", err
+	}
+	return identityLinkPolicy, nil
+}
+
+func getIdentitySubject(userInfo *idp.IdentityProviderUserInfo) string {
+	if userInfo.Subject != "" {
+		return userInfo.Subject
+	}
+	return userInfo.Identifier
+}
+
+func convertUserIdentityFromStore(userIdentity *store.UserIdentity) *UserIdentity {
+	return &UserIdentity{
+		ID:                 userIdentity.ID,
+		CreatedTs:          userIdentity.CreatedTs,
+		IdentityProviderID: userIdentity.IdentityProviderID,
+		ExternalID:         userIdentity.ExternalID,
+		Email:              userIdentity.Email,
+	}
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/api/v1/auth"
+	"github.com/usememos/memos/common/util"
+	"github.com/usememos/memos/store"
+)
+
+// userSessionLastSeenInterval throttles the updates of the last seen time of a session.
+const userSessionLastSeenInterval = time.Minute
+
+type UserSession struct {
+	ID string `json:"id"`
+
+	// Standard fields
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	LastSeenTs int64  `json:"lastSeenTs"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	// Current is whether the session is the one of the request.
+	Current bool `json:"current"`
+}
+
+func (s *APIV1Service) registerUserSessionRoutes(g *echo.Group) {
+	g.GET("/user/me/session", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListUserSessions(ctx, &store.FindUserSession{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find session list").SetInternal(err)
+		}
+		currentSessionID, _ := c.Get(getSessionIDContextKey()).(string)
+		expiredTs := time.Now().Add(-auth.RefreshTokenDuration).Unix()
+		userSessionList := []*UserSession{}
+		for _, userSession := range list {
+			if userSession.UpdatedTs < expiredTs {
+				continue
+			}
+			userSessionMessage := convertUserSessionFromStore(userSession)
+			userSessionMessage.Current = userSession.ID == currentSessionID
+			userSessionList = append(userSessionList, userSessionMessage)
+		}
+		return c.JSON(http.StatusOK, userSessionList)
+	})
+
+	// DELETE /user/me/session - Revoke all the sessions except the current one.
+	g.DELETE("/user/me/session", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		currentSessionID, _ := c.Get(getSessionIDContextKey()).(string)
+		if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+			UserID:    &user.ID,
+			ExcludeID: &currentSessionID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke sessions").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	g.DELETE("/user/me/session/:sessionId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		sessionID := c.Param("sessionId")
+		userSession, err := s.Store.GetUserSession(ctx, &store.FindUserSession{
+			ID:     &sessionID,
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find session").SetInternal(err)
+		}
+		if userSession == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Session not found: %s", sessionID))
+		}
+		if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+			ID: &userSession.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke session").SetInternal(err)
+		}
+		if currentSessionID, _ := c.Get(getSessionIDContextKey()).(string); currentSessionID == userSession.ID {
+			auth.RemoveTokensAndCookies(c)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+// createUserSessionAndSetCookies signs in the user with a new session.
+func (s *APIV1Service) createUserSessionAndSetCookies(c echo.Context, user *store.User) error {
+	ctx := c.Request().Context()
+	// Drop the expired sessions of the user, their refresh tokens cannot be used anymore.
+	expiredTs := time.Now().Add(-auth.RefreshTokenDuration).Unix()
+	if err := s.Store.DeleteUserSession(ctx, &store.DeleteUserSession{
+		UserID:          &user.ID,
+		UpdatedTsBefore: &expiredTs,
+	}); err != nil {
+		return err
+	}
+
+	userSession, err := s.Store.CreateUserSession(ctx, &store.UserSession{
+		ID:        util.GenUUID(),
+		UserID:    user.ID,
+		UserAgent: c.Request().UserAgent(),
+		IP:        echo.ExtractIPFromRealIPHeader()(c.Request()),
+	})
+	if err != nil {
+		return err
+	}
+	return auth.GenerateTokensAndSetCookies(c, user, userSession.ID, s.Secret)
+}
+
+func convertUserSessionFromStore(userSession *store.UserSession) *UserSession {
+	return &UserSession{
+		ID:         userSession.ID,
+		CreatedTs:  userSession.CreatedTs,
+		LastSeenTs: userSession.LastSeenTs,
+		UserAgent:  userSession.UserAgent,
+		IP:         userSession.IP,
+	}
+}
@@ -21,6 +21,12 @@ const (
 	UserSettingMemoVisibilityKey UserSettingKey = "memo-visibility"
 	// UserSettingTelegramUserID is the key type for telegram UserID of memos user.
 	UserSettingTelegramUserIDKey UserSettingKey = "telegram-user-id"
+	// UserSettingFeedTokenKey is the key type for the token used to access protected feeds.
+	UserSettingFeedTokenKey UserSettingKey = "feed-token"
+	// UserSettingAIPostProcessingKey is the key type for enabling AI tag and summary suggestions of memos.
+	UserSettingAIPostProcessingKey UserSettingKey = "ai-post-processing"
+	// UserSettingAIMemoEmbeddingKey is the key type for enabling the embedding of memos as soon as they are saved.
+	UserSettingAIMemoEmbeddingKey UserSettingKey = "ai-memo-embedding"
 )
 
 // String returns the string format of UserSettingKey type.
@@ -34,6 +40,12 @@ func (key UserSettingKey) String() string {
 		return "memo-visibility"
 	case UserSettingTelegramUserIDKey:
 		return "telegram-user-id"
+	case UserSettingFeedTokenKey:
+		return "feed-token"
+	case UserSettingAIPostProcessingKey:
+		return "ai-post-processing"
+	case UserSettingAIMemoEmbeddingKey:
+		return "ai-memo-embedding"
 	}
 	return ""
 }
@@ -110,6 +122,18 @@ func (upsert UpsertUserSettingRequest) Validate() error {
 		if err != nil {
 			return fmt.Errorf("invalid user setting telegram user id value")
 		}
+	} else if upsert.Key == UserSettingAIPostProcessingKey {
+		var enabled bool
+		err := json.Unmarshal([]byte(upsert.Value), &enabled)
+		if err != nil {
+			return fmt.Errorf("invalid user setting ai post processing value")
+		}
+	} else if upsert.Key == UserSettingAIMemoEmbeddingKey {
+		var enabled bool
+		err := json.Unmarshal([]byte(upsert.Value), &enabled)
+		if err != nil {
+			return fmt.Errorf("invalid user setting ai memo embedding value")
+		}
 	} else {
 		return fmt.Errorf("invalid user setting key")
 	}
@@ -142,6 +166,11 @@ func (s *APIV1Service) registerUserSettingRoutes(g *echo.Group) {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert user setting").SetInternal(err)
 		}
+		if err := s.createActivity(ctx, userID, ActivityUserSettingUpdate, ActivityInfo, ActivityUserSettingUpdatePayload{
+			Key: userSetting.Key,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
 
 		userSettingMessage := convertUserSettingFromStore(userSetting)
 		return c.JSON(http.StatusOK, userSettingMessage)
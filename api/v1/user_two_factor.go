@@ -0,0 +1,578 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/api/v1/auth"
+	"github.com/usememos/memos/plugin/totp"
+	"github.com/usememos/memos/store"
+)
+
+// recoveryCodeCount is the number of recovery codes generated at a time.
+const recoveryCodeCount = 10
+
+type UserTwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Required is whether the role of the user must use two-factor authentication.
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+	// RecoveryCodes are only returned by the enrollment during sign-in, which is confirmed by the sign-in itself.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorCodeRequest struct {
+	// Code is either a TOTP code or a recovery code.
+	Code string `json:"code"`
+}
+
+// SignInTwoFactorChallenge is returned by the password sign-in if the user has to pass the two-factor step.
+type SignInTwoFactorChallenge struct {
+	ChallengeToken string `json:"challengeToken"`
+	// EnrollmentRequired is whether the user has to enroll two-factor authentication before signing in.
+	EnrollmentRequired bool `json:"enrollmentRequired"`
+}
+
+type SignInTwoFactor struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+func (s *APIV1Service) registerUserTwoFactorRoutes(g *echo.Group) {
+	g.GET("/user/me/2fa", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+		}
+		required, err := s.isTwoFactorRequired(ctx, user.Role)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication setting").SetInternal(err)
+		}
+
+		status := &UserTwoFactorStatus{
+			Required: required,
+		}
+		if userTwoFactor != nil && userTwoFactor.Enabled {
+			status.Enabled = true
+			status.RecoveryCodesRemaining = len(userTwoFactor.RecoveryCodes)
+		}
+		return c.JSON(http.StatusOK, status)
+	})
+
+	g.POST("/user/me/2fa/enroll", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		enrollment, err := s.enrollTwoFactor(ctx, user, false)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, enrollment)
+	})
+
+	g.POST("/user/me/2fa/confirm", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		request := &TwoFactorCodeRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted two-factor confirm request").SetInternal(err)
+		}
+
+		userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+		}
+		if userTwoFactor == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is not enrolled")
+		}
+		if userTwoFactor.Enabled {
+			return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is already enabled")
+		}
+		step, ok := totp.Validate(userTwoFactor.Secret, request.Code, time.Now())
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid two-factor code")
+		}
+
+		recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate recovery codes").SetInternal(err)
+		}
+		enabled := true
+		if _, err := s.Store.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+			UserID:        user.ID,
+			Enabled:       &enabled,
+			RecoveryCodes: &recoveryCodeHashes,
+			LastUsedStep:  &step,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enable two-factor authentication").SetInternal(err)
+		}
+		if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorEnable, ActivityInfo); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, &TwoFactorRecoveryCodes{
+			RecoveryCodes: recoveryCodes,
+		})
+	})
+
+	g.POST("/user/me/2fa/recovery-code", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		request := &TwoFactorCodeRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted recovery code request").SetInternal(err)
+		}
+
+		if err := s.verifyTwoFactorCode(c, user, request.Code); err != nil {
+			return err
+		}
+		recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate recovery codes").SetInternal(err)
+		}
+		if _, err := s.Store.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+			UserID:        user.ID,
+			RecoveryCodes: &recoveryCodeHashes,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update recovery codes").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, &TwoFactorRecoveryCodes{
+			RecoveryCodes: recoveryCodes,
+		})
+	})
+
+	g.POST("/user/me/2fa/disable", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		request := &TwoFactorCodeRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted two-factor disable request").SetInternal(err)
+		}
+
+		required, err := s.isTwoFactorRequired(ctx, user.Role)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication setting").SetInternal(err)
+		}
+		if required {
+			return echo.NewHTTPError(http.StatusForbidden, "Two-factor authentication is required for your role")
+		}
+		if err := s.verifyTwoFactorCode(c, user, request.Code); err != nil {
+			return err
+		}
+		if err := s.Store.DeleteUserTwoFactor(ctx, &store.DeleteUserTwoFactor{
+			UserID: user.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to disable two-factor authentication").SetInternal(err)
+		}
+		if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorDisable, ActivityInfo); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	g.DELETE("/user/:id/2fa", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionUserManage)
+		if err != nil {
+			return err
+		}
+
+		userID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &userID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User not found: %d", userID))
+		}
+		if err := s.checkUserManageable(c, currentUser, user); err != nil {
+			return err
+		}
+		if err := s.Store.DeleteUserTwoFactor(ctx, &store.DeleteUserTwoFactor{
+			UserID: userID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset two-factor authentication").SetInternal(err)
+		}
+		if err := s.createAuthTwoFactorActivity(c, currentUser.ID, userID, ActivityUserAuthTwoFactorReset, ActivityWarn); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *APIV1Service) registerAuthTwoFactorRoutes(g *echo.Group) {
+	// POST /auth/signin/2fa/enroll - Enroll two-factor authentication required by the role during sign-in.
+	g.POST("/auth/signin/2fa/enroll", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		signin := &SignInTwoFactor{}
+		if err := json.NewDecoder(c.Request().Body).Decode(signin); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted two-factor signin request").SetInternal(err)
+		}
+		user, err := s.findTwoFactorChallengeUser(ctx, signin.ChallengeToken)
+		if err != nil {
+			return err
+		}
+
+		required, err := s.isTwoFactorRequired(ctx, user.Role)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication setting").SetInternal(err)
+		}
+		if !required {
+			return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is not required")
+		}
+		enrollment, err := s.enrollTwoFactor(ctx, user, true)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, enrollment)
+	})
+
+	// POST /auth/signin/2fa - Complete the sign-in with a TOTP code or a recovery code.
+	g.POST("/auth/signin/2fa", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		signin := &SignInTwoFactor{}
+		if err := json.NewDecoder(c.Request().Body).Decode(signin); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted two-factor signin request").SetInternal(err)
+		}
+		user, err := s.findTwoFactorChallengeUser(ctx, signin.ChallengeToken)
+		if err != nil {
+			return err
+		}
+		if err := s.checkSignInLockout(c, user.Username); err != nil {
+			return err
+		}
+
+		userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+		}
+		if userTwoFactor != nil && !userTwoFactor.Enabled {
+			// The sign-in confirms the enrollment required by the role.
+			step, ok := totp.Validate(userTwoFactor.Secret, signin.Code, time.Now())
+			if !ok {
+				if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorFailed, ActivityWarn); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+				}
+				if err := s.recordSignInFailure(c, user.ID, user.Username); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid two-factor code")
+			}
+			enabled := true
+			if _, err := s.Store.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+				UserID:       user.ID,
+				Enabled:      &enabled,
+				LastUsedStep: &step,
+			}); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enable two-factor authentication").SetInternal(err)
+			}
+			if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorEnable, ActivityInfo); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+			}
+		} else if err := s.verifyTwoFactorCode(c, user, signin.Code); err != nil {
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) && httpErr.Code == http.StatusUnauthorized {
+				if err := s.recordSignInFailure(c, user.ID, user.Username); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record sign-in failure").SetInternal(err)
+				}
+			}
+			return err
+		}
+
+		if err := s.clearSignInFailures(ctx, user.Username); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to clear sign-in failures").SetInternal(err)
+		}
+		if err := s.createUserSessionAndSetCookies(c, user); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
+		}
+		if err := s.createAuthSignInActivity(c, user); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, user)
+	})
+}
+
+// getTwoFactorChallenge returns the challenge of the two-factor sign-in step, or nil if the user can sign in with the password only.
+func (s *APIV1Service) getTwoFactorChallenge(ctx context.Context, user *store.User) (*SignInTwoFactorChallenge, error) {
+	userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+	}
+	enabled := userTwoFactor != nil && userTwoFactor.Enabled
+	required, err := s.isTwoFactorRequired(ctx, user.Role)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication setting").SetInternal(err)
+	}
+	if !enabled && !required {
+		return nil, nil
+	}
+
+	challengeToken, err := auth.GenerateTwoFactorChallengeToken(user.Username, user.ID, s.Secret)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate challenge token").SetInternal(err)
+	}
+	return &SignInTwoFactorChallenge{
+		ChallengeToken:     challengeToken,
+		EnrollmentRequired: !enabled,
+	}, nil
+}
+
+func (s *APIV1Service) findTwoFactorChallengeUser(ctx context.Context, challengeToken string) (*store.User, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(challengeToken, claims, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Name {
+			return nil, errors.Errorf("unexpected challenge token signing method=%v, expect %v", t.Header["alg"], jwt.SigningMethodHS256)
+		}
+		if kid, ok := t.Header["kid"].(string); ok && kid == "v1" {
+			return []byte(s.Secret), nil
+		}
+		return nil, errors.Errorf("unexpected challenge token kid=%v", t.Header["kid"])
+	})
+	if err != nil || !token.Valid || !audienceContains(claims.Audience, auth.TwoFactorChallengeTokenAudienceName) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired challenge token").SetInternal(err)
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Malformed ID in the token.")
+	}
+
+	user, err := s.Store.GetUser(ctx, &store.FindUser{
+		ID: &userID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+	}
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired challenge token")
+	}
+	if user.RowStatus == store.Archived {
+		return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", user.Username))
+	}
+	return user, nil
+}
+
+// enrollTwoFactor generates a new pending secret of the user, which is enabled once confirmed with a code.
+func (s *APIV1Service) enrollTwoFactor(ctx context.Context, user *store.User, withRecoveryCodes bool) (*TwoFactorEnrollment, error) {
+	userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+	}
+	if userTwoFactor != nil && userTwoFactor.Enabled {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate secret").SetInternal(err)
+	}
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get system customized profile").SetInternal(err)
+	}
+	enrollment := &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(customizedProfile.Name, user.Username, secret),
+	}
+	recoveryCodeHashes := []string{}
+	if withRecoveryCodes {
+		enrollment.RecoveryCodes, recoveryCodeHashes, err = generateRecoveryCodes()
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate recovery codes").SetInternal(err)
+		}
+	}
+
+	if _, err := s.Store.UpsertUserTwoFactor(ctx, &store.UserTwoFactor{
+		UserID:        user.ID,
+		Secret:        secret,
+		Enabled:       false,
+		RecoveryCodes: recoveryCodeHashes,
+	}); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to enroll two-factor authentication").SetInternal(err)
+	}
+	return enrollment, nil
+}
+
+// verifyTwoFactorCode verifies the TOTP code or consumes the recovery code of the user with enabled two-factor authentication.
+func (s *APIV1Service) verifyTwoFactorCode(c echo.Context, user *store.User, code string) error {
+	ctx := c.Request().Context()
+	userTwoFactor, err := s.Store.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find two-factor authentication").SetInternal(err)
+	}
+	if userTwoFactor == nil || !userTwoFactor.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	// A code is accepted only once, so an observed code cannot be replayed within its validity.
+	if step, ok := totp.Validate(userTwoFactor.Secret, code, time.Now()); ok && step > userTwoFactor.LastUsedStep {
+		if _, err := s.Store.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+			UserID:       user.ID,
+			LastUsedStep: &step,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update two-factor authentication").SetInternal(err)
+		}
+		return nil
+	}
+
+	codeHash := getRecoveryCodeHash(code)
+	for i, recoveryCodeHash := range userTwoFactor.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCodeHash), []byte(codeHash)) != 1 {
+			continue
+		}
+		recoveryCodes := append(userTwoFactor.RecoveryCodes[:i:i], userTwoFactor.RecoveryCodes[i+1:]...)
+		if _, err := s.Store.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+			UserID:        user.ID,
+			RecoveryCodes: &recoveryCodes,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update two-factor authentication").SetInternal(err)
+		}
+		if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorRecoveryCodeUse, ActivityWarn); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return nil
+	}
+
+	if err := s.createAuthTwoFactorActivity(c, user.ID, user.ID, ActivityUserAuthTwoFactorFailed, ActivityWarn); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, "Invalid two-factor code")
+}
+
+func (s *APIV1Service) isTwoFactorRequired(ctx context.Context, role store.Role) (bool, error) {
+	systemSetting, err := s.Store.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: SystemSettingTwoFactorRequiredRolesName.String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	if systemSetting == nil {
+		return false, nil
+	}
+
+	requiredRoles := []Role{}
+	if err := json.Unmarshal([]byte(systemSetting.Value), &requiredRoles); err != nil {
+		return false, err
+	}
+	for _, requiredRole := range requiredRoles {
+		if string(requiredRole) == string(role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *APIV1Service) getCurrentUser(c echo.Context) (*store.User, error) {
+	userID, ok := c.Get(getUserIDContextKey()).(int)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Missing user in session")
+	}
+	user, err := s.Store.GetUser(c.Request().Context(), &store.FindUser{
+		ID: &userID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+	}
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	return user, nil
+}
+
+func (s *APIV1Service) createAuthTwoFactorActivity(c echo.Context, creatorID, userID int, activityType ActivityType, level ActivityLevel) error {
+	ctx := c.Request().Context()
+	payload := ActivityUserAuthTwoFactorPayload{
+		UserID: userID,
+		IP:     echo.ExtractIPFromRealIPHeader()(c.Request()),
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &store.Activity{
+		CreatorID: creatorID,
+		Type:      activityType.String(),
+		Level:     level.String(),
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
+
+// generateRecoveryCodes generates the recovery codes and their hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, recoveryCodeHashes := []string{}, []string{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		recoveryCode := hex.EncodeToString(bytes)
+		recoveryCode = recoveryCode[:5] + "-" + recoveryCode[5:]
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, getRecoveryCodeHash(recoveryCode))
+	}
+	return recoveryCodes, recoveryCodeHashes, nil
+}
+
+func getRecoveryCodeHash(recoveryCode string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(recoveryCode))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
@@ -1,7 +1,10 @@
 package v1
 
 import (
+	"sync"
+
 	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/plugin/telegram"
 	"github.com/usememos/memos/server/profile"
 	"github.com/usememos/memos/store"
 )
@@ -10,6 +13,12 @@ type APIV1Service struct {
 	Secret  string
 	Profile *profile.Profile
 	Store   *store.Store
+
+	// TelegramBot is the bot of the server, its health is reported by the system stats if set.
+	TelegramBot *telegram.Bot
+
+	// webauthnSessions are the pending passkey ceremonies keyed by their challenges.
+	webauthnSessions sync.Map
 }
 
 func NewAPIV1Service(secret string, profile *profile.Profile, store *store.Store) *APIV1Service {
@@ -23,6 +32,8 @@ func NewAPIV1Service(secret string, profile *profile.Profile, store *store.Store
 func (s *APIV1Service) Register(rootGroup *echo.Group) {
 	// Register RSS routes.
 	s.registerRSSRoutes(rootGroup)
+	// Register SCIM routes, authenticated by their own bearer token.
+	s.registerSCIMRoutes(rootGroup)
 
 	// Register API v1 routes.
 	apiV1Group := rootGroup.Group("/api/v1")
@@ -31,10 +42,27 @@ func (s *APIV1Service) Register(rootGroup *echo.Group) {
 	})
 	s.registerSystemRoutes(apiV1Group)
 	s.registerSystemSettingRoutes(apiV1Group)
+	s.registerSystemStatsRoutes(apiV1Group)
 	s.registerAuthRoutes(apiV1Group)
+	s.registerAuthTwoFactorRoutes(apiV1Group)
+	s.registerAuthImpersonationRoutes(apiV1Group)
+	s.registerEmailVerificationRoutes(apiV1Group)
+	s.registerPasswordResetRoutes(apiV1Group)
+	s.registerWebAuthnRoutes(apiV1Group)
 	s.registerIdentityProviderRoutes(apiV1Group)
 	s.registerUserRoutes(apiV1Group)
 	s.registerUserSettingRoutes(apiV1Group)
+	s.registerUserTwoFactorRoutes(apiV1Group)
+	s.registerPasskeyRoutes(apiV1Group)
+	s.registerUserSessionRoutes(apiV1Group)
+	s.registerUserIdentityRoutes(apiV1Group)
+	s.registerSignInLockoutRoutes(apiV1Group)
+	s.registerInvitationRoutes(apiV1Group)
+	s.registerSCIMTokenRoutes(apiV1Group)
+	s.registerActivityRoutes(apiV1Group)
+	s.registerCustomRoleRoutes(apiV1Group)
+	s.registerWorkspaceRoutes(apiV1Group)
+	s.registerGroupRoutes(apiV1Group)
 	s.registerTagRoutes(apiV1Group)
 	s.registerShortcutRoutes(apiV1Group)
 	s.registerStorageRoutes(apiV1Group)
@@ -43,7 +71,10 @@ func (s *APIV1Service) Register(rootGroup *echo.Group) {
 	s.registerMemoOrganizerRoutes(apiV1Group)
 	s.registerMemoResourceRoutes(apiV1Group)
 	s.registerMemoRelationRoutes(apiV1Group)
-	s.registerOpenAIRoutes(apiV1Group)
+	s.registerMemoSuggestionRoutes(apiV1Group)
+	s.registerFeedSubscriptionRoutes(apiV1Group)
+	s.registerAIRoutes(apiV1Group)
+	s.registerAIAskRoutes(apiV1Group)
 
 	// Register public routes.
 	publicGroup := rootGroup.Group("/o")
@@ -0,0 +1,444 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/plugin/webauthn"
+	"github.com/usememos/memos/store"
+)
+
+// webauthnSessionDuration is how long a ceremony can take between its begin and finish requests.
+const webauthnSessionDuration = 5 * time.Minute
+
+type webauthnSessionKind string
+
+const (
+	webauthnSessionRegistration webauthnSessionKind = "registration"
+	webauthnSessionSignIn       webauthnSessionKind = "signin"
+)
+
+// webauthnSession is the server state of a ceremony, keyed by its challenge.
+type webauthnSession struct {
+	Kind      webauthnSessionKind
+	Challenge webauthn.Base64URL
+	// UserID is the user registering a passkey, or the user found by the username of the sign-in.
+	UserID    int
+	ExpiresAt time.Time
+}
+
+type Passkey struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name         string `json:"name"`
+	CredentialID string `json:"credentialId"`
+	LastUsedTs   int64  `json:"lastUsedTs"`
+}
+
+type PasskeyRegistrationFinish struct {
+	Name       string                         `json:"name"`
+	Credential *webauthn.RegistrationResponse `json:"credential"`
+}
+
+type PasskeySignInBegin struct {
+	// Username limits the allowed credentials to the passkeys of the user, otherwise a discoverable credential is used.
+	Username string `json:"username"`
+}
+
+type PasskeySignInFinish struct {
+	Credential *webauthn.AssertionResponse `json:"credential"`
+}
+
+type PatchPasskeyRequest struct {
+	Name *string `json:"name"`
+}
+
+func (s *APIV1Service) registerWebAuthnRoutes(g *echo.Group) {
+	// POST /auth/webauthn/registration/begin - Begin to register a passkey of the current user.
+	g.POST("/auth/webauthn/registration/begin", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		relyingParty, err := s.getWebAuthnRelyingParty(ctx)
+		if err != nil {
+			return err
+		}
+
+		credentialList, err := s.Store.ListWebAuthnCredentials(ctx, &store.FindWebAuthnCredential{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey list").SetInternal(err)
+		}
+		excludeCredentials := []webauthn.Base64URL{}
+		for _, credential := range credentialList {
+			id, err := webauthn.ParseBase64URL(credential.CredentialID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Malformatted passkey credential id").SetInternal(err)
+			}
+			excludeCredentials = append(excludeCredentials, id)
+		}
+
+		challenge, err := s.beginWebAuthnSession(webauthnSessionRegistration, user.ID)
+		if err != nil {
+			return err
+		}
+		displayName := user.Nickname
+		if displayName == "" {
+			displayName = user.Username
+		}
+		return c.JSON(http.StatusOK, relyingParty.CreationOptions(challenge, webauthn.UserEntity{
+			ID:          webauthn.Base64URL(strconv.Itoa(user.ID)),
+			Name:        user.Username,
+			DisplayName: displayName,
+		}, excludeCredentials))
+	})
+
+	// POST /auth/webauthn/registration/finish - Verify the created credential and store it as a passkey.
+	g.POST("/auth/webauthn/registration/finish", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		request := &PasskeyRegistrationFinish{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil || request.Credential == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted passkey registration request").SetInternal(err)
+		}
+		relyingParty, err := s.getWebAuthnRelyingParty(ctx)
+		if err != nil {
+			return err
+		}
+
+		session, err := s.finishWebAuthnSession(webauthnSessionRegistration, request.Credential.Response.ClientDataJSON)
+		if err != nil {
+			return err
+		}
+		if session.UserID != user.ID {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired passkey challenge")
+		}
+		credential, err := relyingParty.VerifyRegistration(request.Credential, session.Challenge)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid passkey registration: %s", err.Error())).SetInternal(err)
+		}
+
+		credentialID := credential.ID.String()
+		existing, err := s.Store.GetWebAuthnCredential(ctx, &store.FindWebAuthnCredential{
+			CredentialID: &credentialID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey").SetInternal(err)
+		}
+		if existing != nil {
+			return echo.NewHTTPError(http.StatusConflict, "Passkey is already registered")
+		}
+		name := request.Name
+		if name == "" {
+			name = "Passkey"
+		}
+		webAuthnCredential, err := s.Store.CreateWebAuthnCredential(ctx, &store.WebAuthnCredential{
+			UserID:       user.ID,
+			Name:         name,
+			CredentialID: credentialID,
+			PublicKey:    credential.PublicKey,
+			SignCount:    credential.SignCount,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create passkey").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertPasskeyFromStore(webAuthnCredential))
+	})
+
+	// POST /auth/webauthn/signin/begin - Begin to sign in with a passkey.
+	g.POST("/auth/webauthn/signin/begin", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		request := &PasskeySignInBegin{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted passkey signin request").SetInternal(err)
+		}
+		relyingParty, err := s.getWebAuthnRelyingParty(ctx)
+		if err != nil {
+			return err
+		}
+
+		userID := 0
+		allowCredentials := []webauthn.Base64URL{}
+		if request.Username != "" {
+			user, err := s.Store.GetUser(ctx, &store.FindUser{
+				Username: &request.Username,
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+			}
+			// An unknown user gets an empty allow list, so the existence of the username is not disclosed.
+			if user != nil {
+				userID = user.ID
+				credentialList, err := s.Store.ListWebAuthnCredentials(ctx, &store.FindWebAuthnCredential{
+					UserID: &user.ID,
+				})
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey list").SetInternal(err)
+				}
+				for _, credential := range credentialList {
+					id, err := webauthn.ParseBase64URL(credential.CredentialID)
+					if err != nil {
+						return echo.NewHTTPError(http.StatusInternalServerError, "Malformatted passkey credential id").SetInternal(err)
+					}
+					allowCredentials = append(allowCredentials, id)
+				}
+			}
+		}
+
+		challenge, err := s.beginWebAuthnSession(webauthnSessionSignIn, userID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, relyingParty.RequestOptions(challenge, allowCredentials))
+	})
+
+	// POST /auth/webauthn/signin/finish - Verify the assertion of the passkey and sign in.
+	g.POST("/auth/webauthn/signin/finish", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		request := &PasskeySignInFinish{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil || request.Credential == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted passkey signin request").SetInternal(err)
+		}
+		relyingParty, err := s.getWebAuthnRelyingParty(ctx)
+		if err != nil {
+			return err
+		}
+
+		session, err := s.finishWebAuthnSession(webauthnSessionSignIn, request.Credential.Response.ClientDataJSON)
+		if err != nil {
+			return err
+		}
+		credentialID := request.Credential.RawID.String()
+		webAuthnCredential, err := s.Store.GetWebAuthnCredential(ctx, &store.FindWebAuthnCredential{
+			CredentialID: &credentialID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey").SetInternal(err)
+		}
+		if webAuthnCredential == nil || (session.UserID != 0 && session.UserID != webAuthnCredential.UserID) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Unknown passkey")
+		}
+		if len(request.Credential.Response.UserHandle) > 0 && string(request.Credential.Response.UserHandle) != strconv.Itoa(webAuthnCredential.UserID) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Unknown passkey")
+		}
+
+		assertion, err := relyingParty.VerifyAssertion(request.Credential, session.Challenge, &webauthn.Credential{
+			ID:        request.Credential.RawID,
+			PublicKey: webAuthnCredential.PublicKey,
+			SignCount: webAuthnCredential.SignCount,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid passkey assertion").SetInternal(err)
+		}
+
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &webAuthnCredential.UserID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Unknown passkey")
+		}
+		if user.RowStatus == store.Archived {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", user.Username))
+		}
+
+		lastUsedTs := time.Now().Unix()
+		if _, err := s.Store.UpdateWebAuthnCredential(ctx, &store.UpdateWebAuthnCredential{
+			ID:         webAuthnCredential.ID,
+			SignCount:  &assertion.SignCount,
+			LastUsedTs: &lastUsedTs,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update passkey").SetInternal(err)
+		}
+
+		// A passkey verifying the user is a multi-factor credential, so the two-factor step is skipped.
+		// Otherwise it only proves the possession of the credential, like a security key without PIN.
+		if !assertion.UserVerified {
+			challenge, err := s.getTwoFactorChallenge(ctx, user)
+			if err != nil {
+				return err
+			}
+			if challenge != nil {
+				return c.JSON(http.StatusOK, challenge)
+			}
+		}
+		if err := s.createUserSessionAndSetCookies(c, user); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens").SetInternal(err)
+		}
+		if err := s.createAuthSignInActivity(c, user); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create activity").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, user)
+	})
+}
+
+func (s *APIV1Service) registerPasskeyRoutes(g *echo.Group) {
+	g.GET("/user/me/passkey", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		credentialList, err := s.Store.ListWebAuthnCredentials(ctx, &store.FindWebAuthnCredential{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey list").SetInternal(err)
+		}
+		passkeyList := []*Passkey{}
+		for _, credential := range credentialList {
+			passkeyList = append(passkeyList, convertPasskeyFromStore(credential))
+		}
+		return c.JSON(http.StatusOK, passkeyList)
+	})
+
+	g.PATCH("/user/me/passkey/:passkeyId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		passkey, err := s.findCurrentUserPasskey(c)
+		if err != nil {
+			return err
+		}
+		request := &PatchPasskeyRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch passkey request").SetInternal(err)
+		}
+		if request.Name != nil && *request.Name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Passkey name is required")
+		}
+
+		webAuthnCredential, err := s.Store.UpdateWebAuthnCredential(ctx, &store.UpdateWebAuthnCredential{
+			ID:   passkey.ID,
+			Name: request.Name,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch passkey").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertPasskeyFromStore(webAuthnCredential))
+	})
+
+	g.DELETE("/user/me/passkey/:passkeyId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		passkey, err := s.findCurrentUserPasskey(c)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Store.DeleteWebAuthnCredential(ctx, &store.DeleteWebAuthnCredential{
+			ID: passkey.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete passkey").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+// getWebAuthnRelyingParty returns the relying party identified by the external URL of the customized profile.
+func (s *APIV1Service) getWebAuthnRelyingParty(ctx context.Context) (*webauthn.RelyingParty, error) {
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get system customized profile").SetInternal(err)
+	}
+	if customizedProfile.ExternalURL == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "External URL is required to use passkeys")
+	}
+	relyingParty, err := webauthn.NewRelyingParty(customizedProfile.Name, customizedProfile.ExternalURL)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid external URL").SetInternal(err)
+	}
+	return relyingParty, nil
+}
+
+func (s *APIV1Service) beginWebAuthnSession(kind webauthnSessionKind, userID int) (webauthn.Base64URL, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate passkey challenge").SetInternal(err)
+	}
+
+	// Drop the abandoned ceremonies so the sessions do not grow unbounded.
+	now := time.Now()
+	s.webauthnSessions.Range(func(key, value any) bool {
+		if session, ok := value.(*webauthnSession); ok && now.After(session.ExpiresAt) {
+			s.webauthnSessions.Delete(key)
+		}
+		return true
+	})
+	s.webauthnSessions.Store(challenge.String(), &webauthnSession{
+		Kind:      kind,
+		Challenge: challenge,
+		UserID:    userID,
+		ExpiresAt: now.Add(webauthnSessionDuration),
+	})
+	return challenge, nil
+}
+
+// finishWebAuthnSession consumes the session of the challenge in the client data, so a response cannot be replayed.
+func (s *APIV1Service) finishWebAuthnSession(kind webauthnSessionKind, clientDataJSON []byte) (*webauthnSession, error) {
+	challenge, err := webauthn.ParseChallenge(clientDataJSON)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Malformatted passkey client data").SetInternal(err)
+	}
+	value, ok := s.webauthnSessions.LoadAndDelete(challenge)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired passkey challenge")
+	}
+	session, ok := value.(*webauthnSession)
+	if !ok || session.Kind != kind || time.Now().After(session.ExpiresAt) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid or expired passkey challenge")
+	}
+	return session, nil
+}
+
+func (s *APIV1Service) findCurrentUserPasskey(c echo.Context) (*store.WebAuthnCredential, error) {
+	user, err := s.getCurrentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	passkeyID, err := strconv.Atoi(c.Param("passkeyId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("passkeyId"))).SetInternal(err)
+	}
+
+	webAuthnCredential, err := s.Store.GetWebAuthnCredential(c.Request().Context(), &store.FindWebAuthnCredential{
+		ID:     &passkeyID,
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find passkey").SetInternal(err)
+	}
+	if webAuthnCredential == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Passkey not found: %d", passkeyID))
+	}
+	return webAuthnCredential, nil
+}
+
+func convertPasskeyFromStore(webAuthnCredential *store.WebAuthnCredential) *Passkey {
+	return &Passkey{
+		ID:           webAuthnCredential.ID,
+		CreatorID:    webAuthnCredential.UserID,
+		CreatedTs:    webAuthnCredential.CreatedTs,
+		UpdatedTs:    webAuthnCredential.UpdatedTs,
+		Name:         webAuthnCredential.Name,
+		CredentialID: webAuthnCredential.CredentialID,
+		LastUsedTs:   webAuthnCredential.LastUsedTs,
+	}
+}
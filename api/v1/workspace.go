@@ -0,0 +1,653 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/store"
+)
+
+// WorkspaceRole is the role of a member in a workspace.
+type WorkspaceRole string
+
+const (
+	// WorkspaceRoleAdmin is the role of the members managing the workspace, its members and settings.
+	WorkspaceRoleAdmin WorkspaceRole = "ADMIN"
+	// WorkspaceRoleMember is the role of the other members.
+	WorkspaceRoleMember WorkspaceRole = "MEMBER"
+)
+
+func (r WorkspaceRole) String() string {
+	return string(r)
+}
+
+type Workspace struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	RowStatus RowStatus `json:"rowStatus"`
+	CreatorID int       `json:"creatorId"`
+	CreatedTs int64     `json:"createdTs"`
+	UpdatedTs int64     `json:"updatedTs"`
+
+	// Domain specific fields
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (create CreateWorkspaceRequest) Validate() error {
+	if err := validateWorkspaceName(create.Name); err != nil {
+		return err
+	}
+	if len(create.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return nil
+}
+
+type UpdateWorkspaceRequest struct {
+	RowStatus   *RowStatus `json:"rowStatus"`
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+}
+
+func (update UpdateWorkspaceRequest) Validate() error {
+	if update.RowStatus != nil && *update.RowStatus != Normal && *update.RowStatus != Archived {
+		return fmt.Errorf("invalid row status %s", *update.RowStatus)
+	}
+	if update.Name != nil {
+		if err := validateWorkspaceName(*update.Name); err != nil {
+			return err
+		}
+	}
+	if update.Description != nil && len(*update.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return nil
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int           `json:"workspaceId"`
+	UserID      int           `json:"userId"`
+	Role        WorkspaceRole `json:"role"`
+	CreatedTs   int64         `json:"createdTs"`
+}
+
+type UpsertWorkspaceMemberRequest struct {
+	UserID int           `json:"userId"`
+	Role   WorkspaceRole `json:"role"`
+}
+
+type WorkspaceSetting struct {
+	WorkspaceID int               `json:"workspaceId"`
+	Name        SystemSettingName `json:"name"`
+	Value       string            `json:"value"`
+}
+
+type UpsertWorkspaceSettingRequest struct {
+	Name  SystemSettingName `json:"name"`
+	Value string            `json:"value"`
+}
+
+// workspaceSettingPermissions maps the system settings which can be overridden per workspace
+// to the permission required besides being the workspace admin, if any.
+var workspaceSettingPermissions = map[SystemSettingName]Permission{
+	SystemSettingDisablePublicMemosName: "",
+	SystemSettingMaxUploadSizeMiBName:   "",
+	SystemSettingStorageServiceIDName:   PermissionStorageManage,
+	SystemSettingLocalStoragePathName:   PermissionStorageManage,
+}
+
+func validateWorkspaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("name is too long, maximum length is 64")
+	}
+	return nil
+}
+
+func (s *APIV1Service) registerWorkspaceRoutes(g *echo.Group) {
+	// GET /workspace - List the workspaces of the current user, or all of them for the workspace managers.
+	g.GET("/workspace", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		find := &store.FindWorkspace{}
+		isManager, err := hasPermission(ctx, s.Store, currentUser, PermissionWorkspaceManage)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user permissions").SetInternal(err)
+		}
+		if !isManager {
+			find.MemberID = &currentUser.ID
+		}
+
+		list, err := s.Store.ListWorkspaces(ctx, find)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace list").SetInternal(err)
+		}
+		workspaceList := []*Workspace{}
+		for _, workspace := range list {
+			workspaceList = append(workspaceList, convertWorkspaceFromStore(workspace))
+		}
+		return c.JSON(http.StatusOK, workspaceList)
+	})
+
+	g.POST("/workspace", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		currentUser, err := s.checkPermission(c, PermissionWorkspaceManage)
+		if err != nil {
+			return err
+		}
+
+		request := &CreateWorkspaceRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post workspace request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid workspace request").SetInternal(err)
+		}
+		if err := s.checkWorkspaceNameAvailable(c, request.Name, UnknownID); err != nil {
+			return err
+		}
+
+		workspace, err := s.Store.CreateWorkspace(ctx, &store.Workspace{
+			CreatorID:   currentUser.ID,
+			Name:        request.Name,
+			Description: request.Description,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create workspace").SetInternal(err)
+		}
+		// The creator administrates the workspace.
+		if _, err := s.Store.UpsertWorkspaceMember(ctx, &store.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      currentUser.ID,
+			Role:        store.WorkspaceRoleAdmin,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert workspace member").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertWorkspaceFromStore(workspace))
+	})
+
+	g.GET("/workspace/:workspaceId", func(c echo.Context) error {
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceMember(c, workspace); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, convertWorkspaceFromStore(workspace))
+	})
+
+	g.PATCH("/workspace/:workspaceId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceAdmin(c, workspace); err != nil {
+			return err
+		}
+
+		request := &UpdateWorkspaceRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted patch workspace request").SetInternal(err)
+		}
+		if err := request.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid workspace request").SetInternal(err)
+		}
+		if request.Name != nil {
+			if err := s.checkWorkspaceNameAvailable(c, *request.Name, workspace.ID); err != nil {
+				return err
+			}
+		}
+
+		currentTs := time.Now().Unix()
+		update := &store.UpdateWorkspace{
+			ID:          workspace.ID,
+			UpdatedTs:   &currentTs,
+			Name:        request.Name,
+			Description: request.Description,
+		}
+		if request.RowStatus != nil {
+			rowStatus := store.RowStatus(request.RowStatus.String())
+			update.RowStatus = &rowStatus
+		}
+		workspace, err = s.Store.UpdateWorkspace(ctx, update)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to patch workspace").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertWorkspaceFromStore(workspace))
+	})
+
+	g.DELETE("/workspace/:workspaceId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		if _, err := s.checkPermission(c, PermissionWorkspaceManage); err != nil {
+			return err
+		}
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		hasContent, err := s.Store.WorkspaceHasContent(ctx, workspace.ID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace content").SetInternal(err)
+		}
+		if hasContent {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Workspace %s still has memos, resources or shortcuts", workspace.Name))
+		}
+
+		if err := s.Store.DeleteWorkspace(ctx, &store.DeleteWorkspace{
+			ID: workspace.ID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workspace").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	g.GET("/workspace/:workspaceId/member", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceMember(c, workspace); err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListWorkspaceMembers(ctx, &store.FindWorkspaceMember{
+			WorkspaceID: &workspace.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member list").SetInternal(err)
+		}
+		workspaceMemberList := []*WorkspaceMember{}
+		for _, workspaceMember := range list {
+			workspaceMemberList = append(workspaceMemberList, convertWorkspaceMemberFromStore(workspaceMember))
+		}
+		return c.JSON(http.StatusOK, workspaceMemberList)
+	})
+
+	// POST /workspace/:workspaceId/member - Add the user to the workspace or change their role.
+	g.POST("/workspace/:workspaceId/member", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceAdmin(c, workspace); err != nil {
+			return err
+		}
+
+		request := &UpsertWorkspaceMemberRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post workspace member request").SetInternal(err)
+		}
+		if request.Role == "" {
+			request.Role = WorkspaceRoleMember
+		}
+		if request.Role != WorkspaceRoleAdmin && request.Role != WorkspaceRoleMember {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid workspace role: %s", request.Role))
+		}
+		user, err := s.Store.GetUser(ctx, &store.FindUser{
+			ID: &request.UserID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find user").SetInternal(err)
+		}
+		if user == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User not found with ID: %d", request.UserID))
+		}
+		if request.Role != WorkspaceRoleAdmin {
+			if err := s.checkWorkspaceAdminKept(c, workspace, user.ID); err != nil {
+				return err
+			}
+		}
+
+		workspaceMember, err := s.Store.UpsertWorkspaceMember(ctx, &store.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			Role:        store.WorkspaceRole(request.Role),
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert workspace member").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertWorkspaceMemberFromStore(workspaceMember))
+	})
+
+	// DELETE /workspace/:workspaceId/member/:userId - Remove the member from the workspace, by its admins or the member leaving.
+	g.DELETE("/workspace/:workspaceId/member/:userId", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		userID, err := strconv.Atoi(c.Param("userId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("userId"))).SetInternal(err)
+		}
+		currentUser, err := s.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		if userID != currentUser.ID {
+			if _, err := s.checkWorkspaceAdmin(c, workspace); err != nil {
+				return err
+			}
+		}
+		if err := s.checkWorkspaceAdminKept(c, workspace, userID); err != nil {
+			return err
+		}
+
+		if err := s.Store.DeleteWorkspaceMember(ctx, &store.DeleteWorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      userID,
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workspace member").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+
+	g.GET("/workspace/:workspaceId/setting", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceMember(c, workspace); err != nil {
+			return err
+		}
+
+		list, err := s.Store.ListWorkspaceSettings(ctx, &store.FindWorkspaceSetting{
+			WorkspaceID: workspace.ID,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace setting list").SetInternal(err)
+		}
+		workspaceSettingList := []*WorkspaceSetting{}
+		for _, workspaceSetting := range list {
+			workspaceSettingList = append(workspaceSettingList, convertWorkspaceSettingFromStore(workspaceSetting))
+		}
+		return c.JSON(http.StatusOK, workspaceSettingList)
+	})
+
+	g.POST("/workspace/:workspaceId/setting", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceAdmin(c, workspace); err != nil {
+			return err
+		}
+
+		request := &UpsertWorkspaceSettingRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted post workspace setting request").SetInternal(err)
+		}
+		if err := s.checkWorkspaceSettingOverridable(c, request.Name); err != nil {
+			return err
+		}
+		if err := (UpsertSystemSettingRequest{Name: request.Name, Value: request.Value}).Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid workspace setting").SetInternal(err)
+		}
+
+		workspaceSetting, err := s.Store.UpsertWorkspaceSetting(ctx, &store.WorkspaceSetting{
+			WorkspaceID: workspace.ID,
+			Name:        request.Name.String(),
+			Value:       request.Value,
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upsert workspace setting").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, convertWorkspaceSettingFromStore(workspaceSetting))
+	})
+
+	// DELETE /workspace/:workspaceId/setting/:name - Remove the override, falling back to the system setting.
+	g.DELETE("/workspace/:workspaceId/setting/:name", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		workspace, err := s.findWorkspaceByParam(c)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkWorkspaceAdmin(c, workspace); err != nil {
+			return err
+		}
+		settingName := SystemSettingName(c.Param("name"))
+		if err := s.checkWorkspaceSettingOverridable(c, settingName); err != nil {
+			return err
+		}
+
+		if err := s.Store.DeleteWorkspaceSetting(ctx, &store.DeleteWorkspaceSetting{
+			WorkspaceID: workspace.ID,
+			Name:        settingName.String(),
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workspace setting").SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *APIV1Service) findWorkspaceByParam(c echo.Context) (*store.Workspace, error) {
+	workspaceID, err := strconv.Atoi(c.Param("workspaceId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("workspaceId"))).SetInternal(err)
+	}
+	workspace, err := s.Store.GetWorkspace(c.Request().Context(), &store.FindWorkspace{
+		ID: &workspaceID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace").SetInternal(err)
+	}
+	if workspace == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Workspace not found: %d", workspaceID))
+	}
+	return workspace, nil
+}
+
+// checkWorkspaceMember returns the current user if a member of the workspace or a workspace manager, or the HTTP error to return.
+func (s *APIV1Service) checkWorkspaceMember(c echo.Context, workspace *store.Workspace) (*store.User, error) {
+	ctx := c.Request().Context()
+	currentUser, err := s.getCurrentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	workspaceMember, err := s.Store.GetWorkspaceMember(ctx, &store.FindWorkspaceMember{
+		WorkspaceID: &workspace.ID,
+		UserID:      &currentUser.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member").SetInternal(err)
+	}
+	if workspaceMember != nil {
+		return currentUser, nil
+	}
+	if _, err := s.checkPermission(c, PermissionWorkspaceManage); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Not a member of workspace %s", workspace.Name))
+	}
+	return currentUser, nil
+}
+
+// checkWorkspaceAdmin returns the current user if an admin of the workspace or a workspace manager, or the HTTP error to return.
+func (s *APIV1Service) checkWorkspaceAdmin(c echo.Context, workspace *store.Workspace) (*store.User, error) {
+	ctx := c.Request().Context()
+	currentUser, err := s.getCurrentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	workspaceMember, err := s.Store.GetWorkspaceMember(ctx, &store.FindWorkspaceMember{
+		WorkspaceID: &workspace.ID,
+		UserID:      &currentUser.ID,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member").SetInternal(err)
+	}
+	if workspaceMember != nil && workspaceMember.Role == store.WorkspaceRoleAdmin {
+		return currentUser, nil
+	}
+	if _, err := s.checkPermission(c, PermissionWorkspaceManage); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Not an admin of workspace %s", workspace.Name))
+	}
+	return currentUser, nil
+}
+
+// checkWorkspaceAdminKept rejects removing or demoting the member if they are the last admin of the workspace,
+// so the workspace is never left without an admin.
+func (s *APIV1Service) checkWorkspaceAdminKept(c echo.Context, workspace *store.Workspace, userID int) error {
+	list, err := s.Store.ListWorkspaceMembers(c.Request().Context(), &store.FindWorkspaceMember{
+		WorkspaceID: &workspace.ID,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member list").SetInternal(err)
+	}
+	isAdmin, adminCount := false, 0
+	for _, workspaceMember := range list {
+		if workspaceMember.Role == store.WorkspaceRoleAdmin {
+			adminCount++
+			isAdmin = isAdmin || workspaceMember.UserID == userID
+		}
+	}
+	if isAdmin && adminCount == 1 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("The last admin of workspace %s can not be removed or demoted", workspace.Name))
+	}
+	return nil
+}
+
+func (s *APIV1Service) checkWorkspaceNameAvailable(c echo.Context, name string, workspaceID int) error {
+	workspace, err := s.Store.GetWorkspace(c.Request().Context(), &store.FindWorkspace{
+		Name: &name,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace").SetInternal(err)
+	}
+	if workspace != nil && workspace.ID != workspaceID {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Workspace %s already exists", name))
+	}
+	return nil
+}
+
+// checkWorkspaceSettingOverridable rejects the system settings which can not be overridden per workspace,
+// and the storage settings for the users not managing the storages, as they choose where the files are written.
+func (s *APIV1Service) checkWorkspaceSettingOverridable(c echo.Context, name SystemSettingName) error {
+	permission, ok := workspaceSettingPermissions[name]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("System setting %s can not be overridden per workspace", name))
+	}
+	if permission != "" {
+		if _, err := s.checkPermission(c, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkWorkspaceContentCreatable rejects creating the content in the workspace for the users not its members.
+// All the users are members of the default workspace.
+func (s *APIV1Service) checkWorkspaceContentCreatable(ctx context.Context, workspaceID int, userID int) error {
+	if workspaceID == store.DefaultWorkspaceID {
+		return nil
+	}
+	isMember, err := isWorkspaceMember(ctx, s.Store, workspaceID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find workspace member").SetInternal(err)
+	}
+	if !isMember {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Not a member of workspace %d", workspaceID))
+	}
+	return nil
+}
+
+// isWorkspaceMember returns whether the user is a member of the workspace. All the users are members of the default workspace.
+func isWorkspaceMember(ctx context.Context, s *store.Store, workspaceID int, userID int) (bool, error) {
+	if workspaceID == store.DefaultWorkspaceID {
+		return true, nil
+	}
+	workspaceMember, err := s.GetWorkspaceMember(ctx, &store.FindWorkspaceMember{
+		WorkspaceID: &workspaceID,
+		UserID:      &userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return workspaceMember != nil, nil
+}
+
+// getWorkspaceSystemSetting finds the system setting as overridden by the workspace, if it is.
+func getWorkspaceSystemSetting(ctx context.Context, s *store.Store, workspaceID int, name SystemSettingName) (*store.SystemSetting, error) {
+	if workspaceID != store.DefaultWorkspaceID {
+		settingName := name.String()
+		workspaceSetting, err := s.GetWorkspaceSetting(ctx, &store.FindWorkspaceSetting{
+			WorkspaceID: workspaceID,
+			Name:        &settingName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if workspaceSetting != nil {
+			return &store.SystemSetting{
+				Name:  workspaceSetting.Name,
+				Value: workspaceSetting.Value,
+			}, nil
+		}
+	}
+	return s.GetSystemSetting(ctx, &store.FindSystemSetting{
+		Name: name.String(),
+	})
+}
+
+// parseWorkspaceIDParam parses the optional workspace ID of the query.
+func parseWorkspaceIDParam(c echo.Context) (*int, error) {
+	value := c.QueryParam("workspaceId")
+	if value == "" {
+		return nil, nil
+	}
+	workspaceID, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Workspace ID is not a number: %s", value)).SetInternal(err)
+	}
+	return &workspaceID, nil
+}
+
+func convertWorkspaceFromStore(workspace *store.Workspace) *Workspace {
+	return &Workspace{
+		ID:          workspace.ID,
+		RowStatus:   RowStatus(workspace.RowStatus.String()),
+		CreatorID:   workspace.CreatorID,
+		CreatedTs:   workspace.CreatedTs,
+		UpdatedTs:   workspace.UpdatedTs,
+		Name:        workspace.Name,
+		Description: workspace.Description,
+	}
+}
+
+func convertWorkspaceMemberFromStore(workspaceMember *store.WorkspaceMember) *WorkspaceMember {
+	return &WorkspaceMember{
+		WorkspaceID: workspaceMember.WorkspaceID,
+		UserID:      workspaceMember.UserID,
+		Role:        WorkspaceRole(workspaceMember.Role.String()),
+		CreatedTs:   workspaceMember.CreatedTs,
+	}
+}
+
+func convertWorkspaceSettingFromStore(workspaceSetting *store.WorkspaceSetting) *WorkspaceSetting {
+	return &WorkspaceSetting{
+		WorkspaceID: workspaceSetting.WorkspaceID,
+		Name:        SystemSettingName(workspaceSetting.Name),
+		Value:       workspaceSetting.Value,
+	}
+}
@@ -6,17 +6,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/server"
 	_profile "github.com/usememos/memos/server/profile"
 	"github.com/usememos/memos/setup"
 	"github.com/usememos/memos/store"
 	"github.com/usememos/memos/store/db"
+	"go.uber.org/zap"
 )
 
 const (
@@ -126,6 +129,17 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&mode, "mode", "m", "demo", `mode of server, can be "prod" or "dev" or "demo"`)
 	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", 8081, "port of server")
 	rootCmd.PersistentFlags().StringVarP(&data, "data", "d", "", "data directory")
+	rootCmd.PersistentFlags().String("metrics-addr", "", "address to serve the metrics on, such as 127.0.0.1:9090, disabled if empty")
+	rootCmd.PersistentFlags().String("metrics-token", "", "bearer token required to read the metrics, which are served on the main port if no metrics address is set")
+	rootCmd.PersistentFlags().String("log-format", "console", `format of logs, can be "console" or "json"`)
+	rootCmd.PersistentFlags().String("log-level", "info", `level of logs, can be "debug", "info", "warn" or "error"`)
+	rootCmd.PersistentFlags().String("log-levels", "", `levels of logs by subsystem overriding the log level, such as "store=debug,telegram=warn", the subsystems are store, telegram, backup and http`)
+	rootCmd.PersistentFlags().Bool("log-file", false, "also write logs to files rotated by size in the logs folder of the data directory")
+	rootCmd.PersistentFlags().Int("log-file-max-size", 100, "size in megabytes at which the log file is rotated")
+	rootCmd.PersistentFlags().Int("log-file-max-backups", 5, "number of rotated log files kept")
+	rootCmd.PersistentFlags().String("tracing-exporter", "", `exporter of the OpenTelemetry traces, can be "otlp" or "stdout", disabled if empty`)
+	rootCmd.PersistentFlags().String("tracing-endpoint", "", "URL of the OTLP collector, such as http://localhost:4318, the OTEL_EXPORTER_OTLP_* environment variables apply if empty")
+	rootCmd.PersistentFlags().Float64("tracing-sample-ratio", 1, "ratio of the traces started by memos that are sampled, between 0 and 1")
 
 	err := viper.BindPFlag("mode", rootCmd.PersistentFlags().Lookup("mode"))
 	if err != nil {
@@ -139,10 +153,18 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	for _, name := range []string{"metrics-addr", "metrics-token", "log-format", "log-level", "log-levels", "log-file", "log-file-max-size", "log-file-max-backups", "tracing-exporter", "tracing-endpoint", "tracing-sample-ratio"} {
+		err = viper.BindPFlag(name, rootCmd.PersistentFlags().Lookup(name))
+		if err != nil {
+			panic(err)
+		}
+	}
 
 	viper.SetDefault("mode", "demo")
 	viper.SetDefault("port", 8081)
 	viper.SetEnvPrefix("memos")
+	// The flags with dashes are read from the environment variables with underscores, such as MEMOS_LOG_LEVEL.
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
 	setupCmd.Flags().String(setupCmdFlagHostUsername, "", "Owner username")
 	setupCmd.Flags().String(setupCmdFlagHostPassword, "", "Owner password")
@@ -158,6 +180,15 @@ func initConfig() {
 		fmt.Printf("failed to get profile, error: %+v\n", err)
 		return
 	}
+	logConfig, err := profile.GetLogConfig()
+	if err == nil {
+		err = log.Initialize(logConfig)
+	}
+	if err != nil {
+		// The default logger is kept if the configured one fails to initialize.
+		log.Error("failed to initialize logger", zap.Error(err))
+		os.Exit(1)
+	}
 
 	println("---")
 	println("Server profile")
@@ -0,0 +1,18 @@
+package log
+
+import (
+	"context"
+)
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of the context carrying the request ID, which is attached to the logs by WithContext.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, empty if none.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
@@ -2,66 +2,259 @@
 package log
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// The subsystems whose levels can be set apart from the global level.
+const (
+	SubsystemStore    = "store"
+	SubsystemTelegram = "telegram"
+	SubsystemBackup   = "backup"
+	SubsystemHTTP     = "http"
 )
 
+// Config is the configuration of the logging.
+type Config struct {
+	// Format is "console" or "json".
+	Format string
+	// Level is the global level, such as "info".
+	Level string
+	// SubsystemLevels are the levels of the subsystems overriding the global level, such as {"store": "debug"}.
+	SubsystemLevels map[string]string
+	// File is the path of the file the logs are also written to if not empty.
+	File string
+	// FileMaxSize is the size in megabytes at which the file is rotated.
+	FileMaxSize int
+	// FileMaxBackups is the number of rotated files kept.
+	FileMaxBackups int
+}
+
 var (
 	// `gl` is the global logger.
 	// Other packages should use public methods such as Info/Error to do the logging.
 	// For other types of logging, e.g. logging to a separate file, they should use their own loggers.
 	gl     *zap.Logger
 	gLevel zap.AtomicLevel
+
+	// mutex guards the loggers, which are replaced by Initialize.
+	mutex sync.RWMutex
+	// newCore creates the core of the loggers with the level.
+	newCore func(level zapcore.LevelEnabler) zapcore.Core
+	// loggerOptions are the options of the loggers.
+	loggerOptions []zap.Option
+	// subsystemLevels are the levels of the subsystems set apart from the global level.
+	subsystemLevels map[string]zap.AtomicLevel
+	// subsystemLoggers are the loggers of the subsystems created so far.
+	subsystemLoggers map[string]*zap.Logger
 )
 
 // Initializes the global console logger.
 func init() {
-	gLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
-	gl, _ = zap.Config{
-		Level:       gLevel,
-		Development: true,
-		// Use "console" to print readable stacktrace.
-		Encoding:         "console",
-		EncoderConfig:    zap.NewDevelopmentEncoderConfig(),
-		OutputPaths:      []string{"stderr"},
-		ErrorOutputPaths: []string{"stderr"},
-	}.Build(
+	if err := Initialize(&Config{
+		Format: "console",
+		Level:  "info",
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// Initialize replaces the loggers with the ones of the configuration.
+func Initialize(config *Config) error {
+	level, err := zapcore.ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	levels := map[string]zap.AtomicLevel{}
+	for subsystem, subsystemLevel := range config.SubsystemLevels {
+		l, err := zapcore.ParseLevel(subsystemLevel)
+		if err != nil {
+			return fmt.Errorf("invalid level of subsystem %s: %w", subsystem, err)
+		}
+		levels[subsystem] = zap.NewAtomicLevelAt(l)
+	}
+
+	var encoder zapcore.Encoder
+	options := []zap.Option{
+		zap.AddCaller(),
 		// Skip one caller stack to locate the correct caller.
 		zap.AddCallerSkip(1),
-	)
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	}
+	switch config.Format {
+	case "console":
+		// Use "console" to print readable stacktrace.
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	case "json":
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+		options = append(options, zap.AddStacktrace(zapcore.ErrorLevel))
+	default:
+		return fmt.Errorf("invalid log format %q, should be console or json", config.Format)
+	}
+
+	writer := zapcore.Lock(os.Stderr)
+	if config.File != "" {
+		writer = zapcore.NewMultiWriteSyncer(writer, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.FileMaxSize,
+			MaxBackups: config.FileMaxBackups,
+		}))
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	newCore = func(level zapcore.LevelEnabler) zapcore.Core {
+		return zapcore.NewCore(encoder, writer, level)
+	}
+	loggerOptions = options
+	gLevel = zap.NewAtomicLevelAt(level)
+	gl = zap.New(newCore(gLevel), loggerOptions...)
+	subsystemLevels = levels
+	subsystemLoggers = map[string]*zap.Logger{}
+	return nil
 }
 
 // SetLevel wraps the zap Level's SetLevel method.
 func SetLevel(level zapcore.Level) {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	gLevel.SetLevel(level)
 }
 
 // EnabledLevel wraps the zap Level's Enabled method.
 func EnabledLevel(level zapcore.Level) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return gLevel.Enabled(level)
 }
 
 // Debug wraps the zap Logger's Debug method.
 func Debug(msg string, fields ...zap.Field) {
-	gl.Debug(msg, fields...)
+	getLogger("").Debug(msg, fields...)
 }
 
 // Info wraps the zap Logger's Info method.
 func Info(msg string, fields ...zap.Field) {
-	gl.Info(msg, fields...)
+	getLogger("").Info(msg, fields...)
 }
 
 // Warn wraps the zap Logger's Warn method.
 func Warn(msg string, fields ...zap.Field) {
-	gl.Warn(msg, fields...)
+	getLogger("").Warn(msg, fields...)
 }
 
 // Error wraps the zap Logger's Error method.
 func Error(msg string, fields ...zap.Field) {
-	gl.Error(msg, fields...)
+	getLogger("").Error(msg, fields...)
 }
 
 // Sync wraps the zap Logger's Sync method.
 func Sync() {
-	_ = gl.Sync()
+	_ = getLogger("").Sync()
+}
+
+// getLogger returns the logger of the subsystem, or the global logger if the subsystem is empty.
+func getLogger(subsystem string) *zap.Logger {
+	mutex.RLock()
+	if subsystem == "" {
+		defer mutex.RUnlock()
+		return gl
+	}
+	logger, ok := subsystemLoggers[subsystem]
+	mutex.RUnlock()
+	if ok {
+		return logger
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if logger, ok := subsystemLoggers[subsystem]; ok {
+		return logger
+	}
+	level, ok := subsystemLevels[subsystem]
+	if !ok {
+		level = gLevel
+	}
+	logger = zap.New(newCore(level), loggerOptions...).Named(subsystem)
+	subsystemLoggers[subsystem] = logger
+	return logger
+}
+
+// Logger logs for a subsystem at its level, with the fields attached to it.
+type Logger struct {
+	subsystem string
+	fields    []zap.Field
+}
+
+// For returns the logger of the subsystem, which follows the configuration set by Initialize at any time.
+func For(subsystem string) *Logger {
+	return &Logger{
+		subsystem: subsystem,
+	}
+}
+
+// WithContext returns the global logger with the request ID of the context attached.
+func WithContext(ctx context.Context) *Logger {
+	return For("").WithContext(ctx)
+}
+
+// WithContext returns a copy of the logger with the request ID of the context attached, if any.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		return l
+	}
+	return l.With(zap.String("request_id", requestID))
+}
+
+// With returns a copy of the logger with the fields attached.
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return &Logger{
+		subsystem: l.subsystem,
+		fields:    l.allFields(fields),
+	}
+}
+
+// allFields returns the fields of the logger followed by the fields, without sharing the fields of the logger.
+func (l *Logger) allFields(fields []zap.Field) []zap.Field {
+	if len(l.fields) == 0 {
+		return fields
+	}
+	allFields := make([]zap.Field, 0, len(l.fields)+len(fields))
+	allFields = append(allFields, l.fields...)
+	return append(allFields, fields...)
+}
+
+// Enabled returns whether the logger logs at the level.
+func (l *Logger) Enabled(level zapcore.Level) bool {
+	return getLogger(l.subsystem).Core().Enabled(level)
+}
+
+// Debug wraps the zap Logger's Debug method.
+func (l *Logger) Debug(msg string, fields ...zap.Field) {
+	getLogger(l.subsystem).Debug(msg, l.allFields(fields)...)
+}
+
+// Info wraps the zap Logger's Info method.
+func (l *Logger) Info(msg string, fields ...zap.Field) {
+	getLogger(l.subsystem).Info(msg, l.allFields(fields)...)
+}
+
+// Warn wraps the zap Logger's Warn method.
+func (l *Logger) Warn(msg string, fields ...zap.Field) {
+	getLogger(l.subsystem).Warn(msg, l.allFields(fields)...)
+}
+
+// Error wraps the zap Logger's Error method.
+func (l *Logger) Error(msg string, fields ...zap.Field) {
+	getLogger(l.subsystem).Error(msg, l.allFields(fields)...)
 }
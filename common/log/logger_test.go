@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestInitialize(t *testing.T) {
+	defer func() {
+		require.NoError(t, Initialize(&Config{Format: "console", Level: "info"}))
+	}()
+	require.Error(t, Initialize(&Config{Format: "xml", Level: "info"}))
+	require.Error(t, Initialize(&Config{Format: "json", Level: "verbose"}))
+	require.Error(t, Initialize(&Config{Format: "json", Level: "info", SubsystemLevels: map[string]string{SubsystemStore: "verbose"}}))
+
+	file := filepath.Join(t.TempDir(), "logs", "memos.log")
+	require.NoError(t, Initialize(&Config{
+		Format: "json",
+		Level:  "warn",
+		SubsystemLevels: map[string]string{
+			SubsystemStore: "debug",
+		},
+		File:           file,
+		FileMaxSize:    1,
+		FileMaxBackups: 1,
+	}))
+	ctx := ContextWithRequestID(context.Background(), "request-id")
+	Info("dropped by the global level")
+	WithContext(ctx).Warn("global", zap.Int("count", 1))
+	For(SubsystemStore).WithContext(ctx).Debug("store")
+	For(SubsystemTelegram).Info("dropped by the global level")
+	For(SubsystemTelegram).Error("telegram")
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 3)
+	entries := []map[string]any{}
+	for _, line := range lines {
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	require.Equal(t, "global", entries[0]["msg"])
+	require.Equal(t, "request-id", entries[0]["request_id"])
+	require.Equal(t, float64(1), entries[0]["count"])
+	require.Equal(t, "store", entries[1]["msg"])
+	require.Equal(t, SubsystemStore, entries[1]["logger"])
+	require.Equal(t, "request-id", entries[1]["request_id"])
+	require.Equal(t, "telegram", entries[2]["msg"])
+	require.Nil(t, entries[2]["request_id"])
+	require.True(t, strings.HasPrefix(entries[2]["caller"].(string), "log/logger_test.go"))
+}
@@ -0,0 +1,73 @@
+// Package ai defines the abstraction of the AI providers used to chat with language models.
+package ai
+
+import (
+	"context"
+	"math"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatRequest struct {
+	Model    string
+	Messages []Message
+	// Temperature is the sampling temperature, 0 means the provider default.
+	Temperature float64
+	// MaxTokens is the maximum number of tokens to generate, 0 means the provider default.
+	MaxTokens int
+}
+
+// Usage is the number of tokens consumed by a chat request.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type ChatResponse struct {
+	Model   string
+	Content string
+	Usage   Usage
+}
+
+type EmbeddingRequest struct {
+	Model string
+	Input []string
+}
+
+type EmbeddingResponse struct {
+	Model string
+	// Embeddings are the vectors of the input texts in the same order.
+	Embeddings [][]float32
+	Usage      Usage
+}
+
+// Provider is the interface implemented by every AI provider.
+type Provider interface {
+	// Chat sends the messages and waits for the whole reply.
+	Chat(ctx context.Context, request *ChatRequest) (*ChatResponse, error)
+	// ChatStream sends the messages and calls onDelta with every piece of the reply as it arrives.
+	// The returned response contains the whole reply and the usage once the stream is finished.
+	ChatStream(ctx context.Context, request *ChatRequest, onDelta func(delta string) error) (*ChatResponse, error)
+	// Embed returns the embedding vectors of the input texts.
+	Embed(ctx context.Context, request *EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 if they are not comparable.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,231 @@
+// Package ollama is the plugin for Ollama-style local model servers.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/ai"
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DefaultHost = "http://localhost:11434"
+
+type Config struct {
+	Host string
+}
+
+// Provider represents an Ollama-style chat provider.
+type Provider struct {
+	config *Config
+}
+
+// NewProvider initializes a new Ollama-style provider with the given configuration.
+func NewProvider(config *Config) (*Provider, error) {
+	if config.Host == "" {
+		config.Host = DefaultHost
+	}
+	return &Provider{
+		config: config,
+	}, nil
+}
+
+type chatRequest struct {
+	Model    string         `json:"model"`
+	Messages []ai.Message   `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+type chatResponse struct {
+	Error           string      `json:"error"`
+	Model           string      `json:"model"`
+	Message         *ai.Message `json:"message"`
+	Done            bool        `json:"done"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+}
+
+// Chat sends the messages to the chat endpoint and waits for the whole reply.
+func (p *Provider) Chat(ctx context.Context, request *ai.ChatRequest) (*ai.ChatResponse, error) {
+	body, err := p.postChat(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	chat := &chatResponse{}
+	if err := json.NewDecoder(body).Decode(chat); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chat response")
+	}
+	if chat.Error != "" {
+		return nil, errors.New(chat.Error)
+	}
+
+	response := &ai.ChatResponse{
+		Model: chat.Model,
+		Usage: ai.Usage{
+			PromptTokens:     chat.PromptEvalCount,
+			CompletionTokens: chat.EvalCount,
+		},
+	}
+	if chat.Message != nil {
+		response.Content = chat.Message.Content
+	}
+	return response, nil
+}
+
+// ChatStream sends the messages to the chat endpoint and reads the reply from the newline-delimited JSON stream.
+func (p *Provider) ChatStream(ctx context.Context, request *ai.ChatRequest, onDelta func(delta string) error) (*ai.ChatResponse, error) {
+	body, err := p.postChat(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response := &ai.ChatResponse{
+		Model: request.Model,
+	}
+	content := strings.Builder{}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		chunk := &chatResponse{}
+		if err := json.Unmarshal([]byte(line), chunk); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal chat chunk")
+		}
+		if chunk.Error != "" {
+			return nil, errors.New(chunk.Error)
+		}
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Message != nil && chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if err := onDelta(chunk.Message.Content); err != nil {
+				return nil, err
+			}
+		}
+		if chunk.Done {
+			response.Usage = ai.Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read chat stream")
+	}
+
+	response.Content = content.String()
+	return response, nil
+}
+
+type embedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Error           string      `json:"error"`
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// Embed returns the embedding vectors of the input texts from the embed endpoint.
+func (p *Provider) Embed(ctx context.Context, request *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	body, err := p.post(ctx, "/api/embed", &embedRequest{
+		Model: request.Model,
+		Input: request.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	embed := &embedResponse{}
+	if err := json.NewDecoder(body).Decode(embed); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal embed response")
+	}
+	if embed.Error != "" {
+		return nil, errors.New(embed.Error)
+	}
+	if len(embed.Embeddings) != len(request.Input) {
+		return nil, errors.Errorf("expected %d embeddings but got %d", len(request.Input), len(embed.Embeddings))
+	}
+
+	return &ai.EmbeddingResponse{
+		Model:      embed.Model,
+		Embeddings: embed.Embeddings,
+		Usage: ai.Usage{
+			PromptTokens: embed.PromptEvalCount,
+		},
+	}, nil
+}
+
+func (p *Provider) postChat(ctx context.Context, request *ai.ChatRequest, stream bool) (io.ReadCloser, error) {
+	values := &chatRequest{
+		Model:    request.Model,
+		Messages: request.Messages,
+		Stream:   stream,
+		Options:  map[string]any{},
+	}
+	if request.Temperature != 0 {
+		values.Options["temperature"] = request.Temperature
+	}
+	if request.MaxTokens != 0 {
+		values.Options["num_predict"] = request.MaxTokens
+	}
+	return p.post(ctx, "/api/chat", values)
+}
+
+func (p *Provider) post(ctx context.Context, path string, values any) (_ io.ReadCloser, err error) {
+	// The span ends with the response headers, the streamed replies take longer to be read.
+	ctx, span := tracing.Start(ctx, "ollama POST "+path, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	url, err := url.JoinPath(p.config.Host, path)
+	if err != nil {
+		return nil, err
+	}
+	jsonValue, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := tracing.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		chat := &chatResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(chat); err == nil && chat.Error != "" {
+			return nil, errors.New(chat.Error)
+		}
+		return nil, errors.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
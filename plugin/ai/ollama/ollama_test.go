@@ -0,0 +1,101 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/plugin/ai"
+)
+
+func newMockServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embed" {
+			request := &embedRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			require.Equal(t, []string{"a", "b"}, request.Input)
+			fmt.Fprint(w, `{"model":"llama2","embeddings":[[1,0],[0,1]],"prompt_eval_count":2}`)
+			return
+		}
+		require.Equal(t, "/api/chat", r.URL.Path)
+
+		request := &chatRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+		require.Equal(t, "llama2", request.Model)
+		require.Equal(t, 0.5, request.Options["temperature"])
+		require.Equal(t, float64(100), request.Options["num_predict"])
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if !request.Stream {
+			fmt.Fprint(w, `{"model":"llama2","message":{"role":"assistant","content":"Hello there"},"done":true,"prompt_eval_count":5,"eval_count":2}`)
+			return
+		}
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":"Hello"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":" there"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":2}`)
+	}))
+}
+
+func TestProvider(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close()
+
+	provider, err := NewProvider(&Config{
+		Host: s.URL,
+	})
+	require.NoError(t, err)
+
+	request := &ai.ChatRequest{
+		Model:       "llama2",
+		Messages:    []ai.Message{{Role: "user", Content: "Hi"}},
+		Temperature: 0.5,
+		MaxTokens:   100,
+	}
+	expected := &ai.ChatResponse{
+		Model:   "llama2",
+		Content: "Hello there",
+		Usage: ai.Usage{
+			PromptTokens:     5,
+			CompletionTokens: 2,
+		},
+	}
+
+	response, err := provider.Chat(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+
+	deltas := []string{}
+	response, err = provider.ChatStream(context.Background(), request, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+	assert.Equal(t, []string{"Hello", " there"}, deltas)
+}
+
+func TestProviderEmbed(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close()
+
+	provider, err := NewProvider(&Config{
+		Host: s.URL,
+	})
+	require.NoError(t, err)
+
+	response, err := provider.Embed(context.Background(), &ai.EmbeddingRequest{
+		Model: "llama2",
+		Input: []string{"a", "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &ai.EmbeddingResponse{
+		Model:      "llama2",
+		Embeddings: [][]float32{{1, 0}, {0, 1}},
+		Usage:      ai.Usage{PromptTokens: 2},
+	}, response)
+}
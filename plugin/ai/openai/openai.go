@@ -0,0 +1,303 @@
+// Package openai is the plugin for OpenAI and OpenAI-compatible chat completion endpoints.
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/ai"
+	"github.com/usememos/memos/plugin/metrics"
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	requestsTotal = metrics.NewCounterVec("memos_openai_requests_total", "Requests to the OpenAI-compatible endpoints by path and response status, 0 if no response.", "path", "status")
+	// requestDurationSeconds is the time until the response headers, the streamed replies take longer to be read.
+	requestDurationSeconds = metrics.NewHistogramVec("memos_openai_request_duration_seconds", "Duration of the requests to the OpenAI-compatible endpoints by path.", []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}, "path")
+)
+
+const (
+	DefaultHost  = "https://api.openai.com"
+	DefaultModel = "gpt-3.5-turbo"
+	// DefaultEmbeddingModel is the default model of embeddings.
+	DefaultEmbeddingModel = "text-embedding-ada-002"
+)
+
+type Config struct {
+	Host string
+	Key  string
+}
+
+// Provider represents an OpenAI-compatible chat completion provider.
+type Provider struct {
+	config *Config
+}
+
+// NewProvider initializes a new OpenAI-compatible provider with the given configuration.
+func NewProvider(config *Config) (*Provider, error) {
+	if config.Key == "" {
+		return nil, errors.New(`the field "key" is empty but required`)
+	}
+	if config.Host == "" {
+		config.Host = DefaultHost
+	}
+	return &Provider{
+		config: config,
+	}, nil
+}
+
+type chatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ai.Message   `json:"messages"`
+	Temperature   float64        `json:"temperature"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatCompletionResponse struct {
+	Error   any    `json:"error"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message *ai.Message `json:"message"`
+		Delta   *ai.Message `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Chat sends the messages to the chat completion endpoint and waits for the whole reply.
+func (p *Provider) Chat(ctx context.Context, request *ai.ChatRequest) (*ai.ChatResponse, error) {
+	body, err := p.postChatCompletion(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	completion := &chatCompletionResponse{}
+	if err := json.NewDecoder(body).Decode(completion); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chat completion response")
+	}
+	if err := responseError(completion.Error); err != nil {
+		return nil, err
+	}
+
+	response := &ai.ChatResponse{
+		Model: completion.Model,
+	}
+	if len(completion.Choices) > 0 && completion.Choices[0].Message != nil {
+		response.Content = completion.Choices[0].Message.Content
+	}
+	if completion.Usage != nil {
+		response.Usage = ai.Usage{
+			PromptTokens:     completion.Usage.PromptTokens,
+			CompletionTokens: completion.Usage.CompletionTokens,
+		}
+	}
+	return response, nil
+}
+
+// ChatStream sends the messages to the chat completion endpoint and reads the reply from the server-sent events.
+func (p *Provider) ChatStream(ctx context.Context, request *ai.ChatRequest, onDelta func(delta string) error) (*ai.ChatResponse, error) {
+	body, err := p.postChatCompletion(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response := &ai.ChatResponse{
+		Model: request.Model,
+	}
+	content := strings.Builder{}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		chunk := &chatCompletionResponse{}
+		if err := json.Unmarshal([]byte(data), chunk); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal chat completion chunk")
+		}
+		if err := responseError(chunk.Error); err != nil {
+			return nil, err
+		}
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.Usage = ai.Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta == nil || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read chat completion stream")
+	}
+
+	response.Content = content.String()
+	return response, nil
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Error any    `json:"error"`
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage *struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+// Embed returns the embedding vectors of the input texts from the embeddings endpoint.
+func (p *Provider) Embed(ctx context.Context, request *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	model := request.Model
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	body, err := p.post(ctx, "/v1/embeddings", &embeddingRequest{
+		Model: model,
+		Input: request.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	embedding := &embeddingResponse{}
+	if err := json.NewDecoder(body).Decode(embedding); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal embedding response")
+	}
+	if err := responseError(embedding.Error); err != nil {
+		return nil, err
+	}
+	if len(embedding.Data) != len(request.Input) {
+		return nil, errors.Errorf("expected %d embeddings but got %d", len(request.Input), len(embedding.Data))
+	}
+
+	response := &ai.EmbeddingResponse{
+		Model:      embedding.Model,
+		Embeddings: make([][]float32, len(request.Input)),
+	}
+	for _, data := range embedding.Data {
+		if data.Index < 0 || data.Index >= len(request.Input) {
+			return nil, errors.Errorf("unexpected embedding index %d", data.Index)
+		}
+		response.Embeddings[data.Index] = data.Embedding
+	}
+	if embedding.Usage != nil {
+		response.Usage.PromptTokens = embedding.Usage.PromptTokens
+	}
+	return response, nil
+}
+
+func (p *Provider) postChatCompletion(ctx context.Context, request *ai.ChatRequest, stream bool) (io.ReadCloser, error) {
+	model := request.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	values := &chatCompletionRequest{
+		Model:       model,
+		Messages:    request.Messages,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Stream:      stream,
+	}
+	if stream {
+		values.StreamOptions = &streamOptions{
+			IncludeUsage: true,
+		}
+	}
+	return p.post(ctx, "/v1/chat/completions", values)
+}
+
+func (p *Provider) post(ctx context.Context, path string, values any) (_ io.ReadCloser, err error) {
+	// The span ends with the response headers, the streamed replies take longer to be read.
+	ctx, span := tracing.Start(ctx, "openai POST "+path, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	url, err := url.JoinPath(p.config.Host, path)
+	if err != nil {
+		return nil, err
+	}
+	jsonValue, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.Key)
+
+	start := time.Now()
+	resp, err := tracing.HTTPClient.Do(req)
+	requestDurationSeconds.Observe(time.Since(start).Seconds(), path)
+	if err != nil {
+		requestsTotal.Inc(path, "0")
+		return nil, err
+	}
+	requestsTotal.Inc(path, strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		completion := &chatCompletionResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(completion); err == nil && completion.Error != nil {
+			return nil, responseError(completion.Error)
+		}
+		return nil, errors.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// responseError converts the error object of the response into an error.
+func responseError(v any) error {
+	if v == nil {
+		return nil
+	}
+	errorBytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return errors.New(string(errorBytes))
+}
@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/plugin/ai"
+)
+
+func newMockServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		if r.URL.Path == "/v1/embeddings" {
+			request := &embeddingRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			require.Equal(t, DefaultEmbeddingModel, request.Model)
+			// The data might be out of order, the index is the source of truth.
+			fmt.Fprint(w, `{"model":"text-embedding-ada-002","data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":2}}`)
+			return
+		}
+		require.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		request := &chatCompletionRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+		require.Equal(t, "test-model", request.Model)
+		require.Equal(t, 0.5, request.Temperature)
+		require.Equal(t, 100, request.MaxTokens)
+
+		if !request.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"model":"test-model","choices":[{"message":{"role":"assistant","content":"Hello there"}}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`)
+			return
+		}
+		require.NotNil(t, request.StreamOptions)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"model\":\"test-model\",\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"test-model\",\"choices\":[{\"delta\":{\"content\":\" there\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"test-model\",\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestProvider(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close()
+
+	provider, err := NewProvider(&Config{
+		Host: s.URL,
+		Key:  "test-key",
+	})
+	require.NoError(t, err)
+
+	request := &ai.ChatRequest{
+		Model:       "test-model",
+		Messages:    []ai.Message{{Role: "user", Content: "Hi"}},
+		Temperature: 0.5,
+		MaxTokens:   100,
+	}
+	expected := &ai.ChatResponse{
+		Model:   "test-model",
+		Content: "Hello there",
+		Usage: ai.Usage{
+			PromptTokens:     5,
+			CompletionTokens: 2,
+		},
+	}
+
+	response, err := provider.Chat(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+
+	deltas := []string{}
+	response, err = provider.ChatStream(context.Background(), request, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+	assert.Equal(t, []string{"Hello", " there"}, deltas)
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider(&Config{})
+	require.Error(t, err)
+}
+
+func TestProviderEmbed(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close()
+
+	provider, err := NewProvider(&Config{
+		Host: s.URL,
+		Key:  "test-key",
+	})
+	require.NoError(t, err)
+
+	response, err := provider.Embed(context.Background(), &ai.EmbeddingRequest{
+		Input: []string{"a", "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &ai.EmbeddingResponse{
+		Model:      DefaultEmbeddingModel,
+		Embeddings: [][]float32{{1, 0}, {0, 1}},
+		Usage:      ai.Usage{PromptTokens: 2},
+	}, response)
+}
@@ -0,0 +1,171 @@
+package getter
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+)
+
+type Feed struct {
+	Title   string       `json:"title"`
+	Entries []*FeedEntry `json:"entries"`
+}
+
+type FeedEntry struct {
+	GUID  string `json:"guid"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	// Summary is the plain text summary of the entry.
+	Summary     string `json:"summary"`
+	PublishedTs int64  `json:"publishedTs"`
+}
+
+type rawFeed struct {
+	XMLName xml.Name
+	// RSS 2.0 fields.
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// Atom 1.0 fields.
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+func GetFeed(ctx context.Context, urlStr string) (_ *Feed, err error) {
+	ctx, span := tracing.Start(ctx, "getter.GetFeed", trace.WithAttributes(attribute.String("url", urlStr)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	response, err := get(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected feed response status %d", response.StatusCode)
+	}
+
+	return parseFeed(response.Body)
+}
+
+func parseFeed(r io.Reader) (*Feed, error) {
+	raw := &rawFeed{}
+	if err := xml.NewDecoder(r).Decode(raw); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Entries: []*FeedEntry{},
+	}
+	switch raw.XMLName.Local {
+	case "rss":
+		feed.Title = strings.TrimSpace(raw.Channel.Title)
+		for _, item := range raw.Channel.Items {
+			entry := &FeedEntry{
+				GUID:        strings.TrimSpace(item.GUID),
+				Title:       strings.TrimSpace(item.Title),
+				Link:        strings.TrimSpace(item.Link),
+				Summary:     extractHTMLText(item.Description),
+				PublishedTs: parseFeedTime(item.PubDate, time.RFC1123Z, time.RFC1123),
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+	case "feed":
+		feed.Title = strings.TrimSpace(raw.Title)
+		for _, item := range raw.Entries {
+			entry := &FeedEntry{
+				GUID:        strings.TrimSpace(item.ID),
+				Title:       strings.TrimSpace(item.Title),
+				Summary:     extractHTMLText(item.Summary),
+				PublishedTs: parseFeedTime(item.Published, time.RFC3339),
+			}
+			if entry.Summary == "" {
+				entry.Summary = extractHTMLText(item.Content)
+			}
+			if entry.PublishedTs == 0 {
+				entry.PublishedTs = parseFeedTime(item.Updated, time.RFC3339)
+			}
+			for _, link := range item.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					entry.Link = strings.TrimSpace(link.Href)
+					break
+				}
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported feed format %s", raw.XMLName.Local)
+	}
+
+	for _, entry := range feed.Entries {
+		// Fallback to the link as GUID for those feeds without identifier.
+		if entry.GUID == "" {
+			entry.GUID = entry.Link
+		}
+	}
+	return feed, nil
+}
+
+func parseFeedTime(value string, layouts ...string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
+// extractHTMLText returns the text content of the HTML fragment.
+func extractHTMLText(content string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	texts := []string{}
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		if tokenType == html.TextToken {
+			if text := strings.TrimSpace(string(tokenizer.Text())); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, " ")
+}
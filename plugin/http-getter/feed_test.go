@@ -0,0 +1,70 @@
+package getter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		content string
+		feed    Feed
+	}{
+		{
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>RSS Feed</title>
+    <item>
+      <guid>rss-1</guid>
+      <title>First post</title>
+      <link>https://example.com/posts/1</link>
+      <description>&lt;p&gt;Hello &lt;b&gt;world&lt;/b&gt;&lt;/p&gt;</description>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+    </item>
+    <item>
+      <title>Second post</title>
+      <link>https://example.com/posts/2</link>
+    </item>
+  </channel>
+</rss>`,
+			feed: Feed{
+				Title: "RSS Feed",
+				Entries: []*FeedEntry{
+					{GUID: "rss-1", Title: "First post", Link: "https://example.com/posts/1", Summary: "Hello world", PublishedTs: 1136214245},
+					{GUID: "https://example.com/posts/2", Title: "Second post", Link: "https://example.com/posts/2"},
+				},
+			},
+		},
+		{
+			content: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Feed</title>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Atom post</title>
+    <link rel="self" href="https://example.com/self"/>
+    <link href="https://example.com/atom/1"/>
+    <content type="html">&lt;p&gt;Atom content&lt;/p&gt;</content>
+    <updated>2006-01-02T15:04:05Z</updated>
+  </entry>
+</feed>`,
+			feed: Feed{
+				Title: "Atom Feed",
+				Entries: []*FeedEntry{
+					{GUID: "urn:uuid:1", Title: "Atom post", Link: "https://example.com/atom/1", Summary: "Atom content", PublishedTs: 1136214245},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		feed, err := parseFeed(strings.NewReader(test.content))
+		require.NoError(t, err)
+		require.Equal(t, test.feed, *feed)
+	}
+
+	_, err := parseFeed(strings.NewReader(`<html></html>`))
+	require.Error(t, err)
+}
@@ -1,11 +1,13 @@
 package getter
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -16,12 +18,13 @@ type HTMLMeta struct {
 	Image       string `json:"image"`
 }
 
-func GetHTMLMeta(urlStr string) (*HTMLMeta, error) {
-	if _, err := url.Parse(urlStr); err != nil {
-		return nil, err
-	}
+func GetHTMLMeta(ctx context.Context, urlStr string) (_ *HTMLMeta, err error) {
+	ctx, span := tracing.Start(ctx, "getter.GetHTMLMeta", trace.WithAttributes(attribute.String("url", urlStr)))
+	defer func() {
+		tracing.End(span, err)
+	}()
 
-	response, err := http.Get(urlStr)
+	response, err := get(ctx, urlStr)
 	if err != nil {
 		return nil, err
 	}
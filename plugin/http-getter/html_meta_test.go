@@ -1,6 +1,7 @@
 package getter
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,7 +13,7 @@ func TestGetHTMLMeta(t *testing.T) {
 		htmlMeta HTMLMeta
 	}{}
 	for _, test := range tests {
-		metadata, err := GetHTMLMeta(test.urlStr)
+		metadata, err := GetHTMLMeta(context.Background(), test.urlStr)
 		require.NoError(t, err)
 		require.Equal(t, test.htmlMeta, *metadata)
 	}
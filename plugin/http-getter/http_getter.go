@@ -1,4 +1,5 @@
 // Package getter is using to get resources from url.
 // * Get metadata for website;
 // * Get image blob to avoid CORS;
+// * Get entries of RSS/Atom feed;
 package getter
@@ -1,11 +1,15 @@
 package getter
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Image struct {
@@ -13,12 +17,13 @@ type Image struct {
 	Mediatype string
 }
 
-func GetImage(urlStr string) (*Image, error) {
-	if _, err := url.Parse(urlStr); err != nil {
-		return nil, err
-	}
+func GetImage(ctx context.Context, urlStr string) (_ *Image, err error) {
+	ctx, span := tracing.Start(ctx, "getter.GetImage", trace.WithAttributes(attribute.String("url", urlStr)))
+	defer func() {
+		tracing.End(span, err)
+	}()
 
-	response, err := http.Get(urlStr)
+	response, err := get(ctx, urlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +48,25 @@ func GetImage(urlStr string) (*Image, error) {
 	}
 	return image, nil
 }
+
+// GetOpenGraphImage gets the image declared by the `og:image` meta of the website.
+func GetOpenGraphImage(ctx context.Context, urlStr string) (*Image, error) {
+	htmlMeta, err := GetHTMLMeta(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if htmlMeta.Image == "" {
+		return nil, fmt.Errorf("Image meta not found")
+	}
+
+	// The image url might be relative to the website url.
+	baseURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	imageURL, err := baseURL.Parse(htmlMeta.Image)
+	if err != nil {
+		return nil, err
+	}
+	return GetImage(ctx, imageURL.String())
+}
@@ -1,8 +1,11 @@
 package getter
 
 import (
+	"context"
 	"mime"
 	"net/http"
+
+	"github.com/usememos/memos/plugin/tracing"
 )
 
 func getMediatype(response *http.Response) (string, error) {
@@ -13,3 +16,12 @@ func getMediatype(response *http.Response) (string, error) {
 	}
 	return mediatype, nil
 }
+
+// get sends a GET request to the url with the context, which is traced if the tracing is enabled.
+func get(ctx context.Context, urlStr string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tracing.HTTPClient.Do(request)
+}
@@ -1,7 +1,13 @@
 package idp
 
 type IdentityProviderUserInfo struct {
+	// Subject is the stable identifier of the user at the identity provider.
+	Subject     string
 	Identifier  string
 	DisplayName string
 	Email       string
+	// EmailVerified is whether the identity provider asserts the ownership of the email.
+	EmailVerified bool
+	// Groups are the groups of the user if the identity provider supports them.
+	Groups []string
 }
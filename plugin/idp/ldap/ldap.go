@@ -0,0 +1,180 @@
+// Package ldap is the plugin for LDAP Identity Provider.
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/store"
+)
+
+const (
+	DefaultIdentifierAttribute  = "uid"
+	DefaultDisplayNameAttribute = "cn"
+	DefaultEmailAttribute       = "mail"
+	DefaultGroupAttribute       = "memberOf"
+
+	timeout = 10 * time.Second
+)
+
+// ErrInvalidCredentials is returned if the user is not found in the directory or the password is wrong.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// IdentityProvider represents an LDAP Identity Provider.
+type IdentityProvider struct {
+	config *store.IdentityProviderLDAPConfig
+}
+
+// NewIdentityProvider initializes a new LDAP Identity Provider with the given configuration.
+func NewIdentityProvider(config *store.IdentityProviderLDAPConfig) (*IdentityProvider, error) {
+	for v, field := range map[string]string{
+		config.URL:        "url",
+		config.BaseDN:     "baseDn",
+		config.UserFilter: "userFilter",
+	} {
+		if v == "" {
+			return nil, errors.Errorf(`the field "%s" is empty but required`, field)
+		}
+	}
+	if strings.Count(config.UserFilter, "%s") != 1 {
+		return nil, errors.New(`the field "userFilter" must contain exactly one "%s"`)
+	}
+
+	return &IdentityProvider{
+		config: config,
+	}, nil
+}
+
+// Authenticate searches the user by the username and binds as the user with the password.
+func (p *IdentityProvider) Authenticate(username, password string) (*idp.IdentityProviderUserInfo, error) {
+	// An empty password would be an unauthenticated bind which most directories accept.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if p.config.BindDN != "" {
+		if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+			return nil, errors.Wrap(err, "failed to bind with the search account")
+		}
+	}
+
+	identifierAttribute, displayNameAttribute, emailAttribute := p.attributes()
+	groupAttribute := p.config.GroupAttribute
+	if groupAttribute == "" {
+		groupAttribute = DefaultGroupAttribute
+	}
+	searchRequest := goldap.NewSearchRequest(
+		p.config.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		2,
+		int(timeout.Seconds()),
+		false,
+		fmt.Sprintf(p.config.UserFilter, goldap.EscapeFilter(username)),
+		[]string{identifierAttribute, displayNameAttribute, emailAttribute, groupAttribute},
+		nil,
+	)
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search user")
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.Errorf("the user filter matches %d entries for %q", len(result.Entries), username)
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "failed to bind with the user account")
+	}
+
+	userInfo := &idp.IdentityProviderUserInfo{
+		Identifier:  entry.GetAttributeValue(identifierAttribute),
+		DisplayName: entry.GetAttributeValue(displayNameAttribute),
+		Email:       entry.GetAttributeValue(emailAttribute),
+		Groups:      entry.GetAttributeValues(groupAttribute),
+	}
+	if userInfo.Identifier == "" {
+		return nil, errors.Errorf("the attribute %q is not found in entry %q", identifierAttribute, entry.DN)
+	}
+	if userInfo.DisplayName == "" {
+		userInfo.DisplayName = userInfo.Identifier
+	}
+	userInfo.Subject = userInfo.Identifier
+	// The directory is managed by the administrators, so its emails are trusted.
+	userInfo.EmailVerified = userInfo.Email != ""
+	return userInfo, nil
+}
+
+// IsAdmin returns whether any of the groups is one of the admin groups.
+// A group matches an admin group by its full DN or by the value of its first RDN, e.g. the CN.
+func (p *IdentityProvider) IsAdmin(groups []string) bool {
+	for _, group := range groups {
+		name := GroupName(group)
+		for _, adminGroup := range p.config.AdminGroups {
+			if strings.EqualFold(adminGroup, group) || strings.EqualFold(adminGroup, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GroupName returns the value of the first RDN of the group DN, e.g. the CN, or the group itself if it is not a DN.
+func GroupName(group string) string {
+	if dn, err := goldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+		return dn.RDNs[0].Attributes[0].Value
+	}
+	return group
+}
+
+func (p *IdentityProvider) dial() (*goldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: p.config.InsecureSkipVerify,
+	}
+	conn, err := goldap.DialURL(p.config.URL, goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to ldap server")
+	}
+	conn.SetTimeout(timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "failed to start tls")
+		}
+	}
+	return conn, nil
+}
+
+func (p *IdentityProvider) attributes() (identifier, displayName, email string) {
+	identifier, displayName, email = DefaultIdentifierAttribute, DefaultDisplayNameAttribute, DefaultEmailAttribute
+	if fieldMapping := p.config.FieldMapping; fieldMapping != nil {
+		if fieldMapping.Identifier != "" {
+			identifier = fieldMapping.Identifier
+		}
+		if fieldMapping.DisplayName != "" {
+			displayName = fieldMapping.DisplayName
+		}
+		if fieldMapping.Email != "" {
+			email = fieldMapping.Email
+		}
+	}
+	return identifier, displayName, email
+}
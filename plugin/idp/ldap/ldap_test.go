@@ -0,0 +1,95 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/store"
+	"github.com/usememos/memos/test"
+)
+
+func TestNewIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *store.IdentityProviderLDAPConfig
+		containsErr string
+	}{
+		{
+			name: "no url",
+			config: &store.IdentityProviderLDAPConfig{
+				BaseDN:     "dc=example,dc=com",
+				UserFilter: "(uid=%s)",
+			},
+			containsErr: `the field "url" is empty but required`,
+		},
+		{
+			name: "no placeholder in user filter",
+			config: &store.IdentityProviderLDAPConfig{
+				URL:        "ldap://localhost:389",
+				BaseDN:     "dc=example,dc=com",
+				UserFilter: "(uid=john)",
+			},
+			containsErr: `the field "userFilter" must contain exactly one "%s"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewIdentityProvider(test.config)
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+}
+
+func TestIdentityProvider(t *testing.T) {
+	s := test.NewLDAPServer(t, []*test.LDAPEntry{
+		{
+			DN:       "cn=reader,dc=example,dc=com",
+			Password: "reader-password",
+		},
+		{
+			DN:       "uid=john,ou=people,dc=example,dc=com",
+			Password: "john-password",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"john"},
+				"cn":          {"John Doe"},
+				"mail":        {"john.doe@example.com"},
+				"memberOf":    {"cn=admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+	})
+	ldap, err := NewIdentityProvider(&store.IdentityProviderLDAPConfig{
+		URL:          s.URL,
+		BindDN:       "cn=reader,dc=example,dc=com",
+		BindPassword: "reader-password",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid=%s))",
+		AdminGroups:  []string{"admins"},
+	})
+	require.NoError(t, err)
+
+	userInfo, err := ldap.Authenticate("john", "john-password")
+	require.NoError(t, err)
+	assert.Equal(t, &idp.IdentityProviderUserInfo{
+		Subject:       "john",
+		Identifier:    "john",
+		DisplayName:   "John Doe",
+		Email:         "john.doe@example.com",
+		EmailVerified: true,
+		Groups:        []string{"cn=admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+	}, userInfo)
+	assert.True(t, ldap.IsAdmin(userInfo.Groups))
+	assert.False(t, ldap.IsAdmin([]string{"cn=staff,ou=groups,dc=example,dc=com"}))
+
+	_, err = ldap.Authenticate("john", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = ldap.Authenticate("john", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = ldap.Authenticate("jane", "john-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	// The username is escaped so it cannot widen the filter.
+	_, err = ldap.Authenticate("*", "john-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
@@ -111,5 +111,10 @@ func (p *IdentityProvider) UserInfo(token string) (*idp.IdentityProviderUserInfo
 			userInfo.Email = v
 		}
 	}
+	if v, ok := claims["email_verified"].(bool); ok {
+		userInfo.EmailVerified = v
+	}
+	// The identifier is the only stable field known of a generic OAuth2 provider.
+	userInfo.Subject = userInfo.Identifier
 	return userInfo, nil
 }
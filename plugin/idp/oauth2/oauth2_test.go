@@ -155,6 +155,7 @@ func TestIdentityProvider(t *testing.T) {
 	require.NoError(t, err)
 
 	wantUserInfo := &idp.IdentityProviderUserInfo{
+		Subject:     testSubject,
 		Identifier:  testSubject,
 		DisplayName: testName,
 		Email:       testEmail,
@@ -0,0 +1,423 @@
+// Package oidc is the plugin for OpenID Connect Identity Provider.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/store"
+	"golang.org/x/oauth2"
+)
+
+// DefaultScopes are the scopes requested if none is configured.
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+// documentCacheDuration is how long the provider metadata and JSON web key sets are reused before fetched again.
+const documentCacheDuration = time.Hour
+
+// httpClient requests the identity providers, so a slow issuer can not hang the sign-in.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// ProviderMetadata is the subset of the OpenID Provider metadata used by memos.
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IdentityProvider represents an OpenID Connect Identity Provider.
+type IdentityProvider struct {
+	config *store.IdentityProviderOIDCConfig
+}
+
+// NewIdentityProvider initializes a new OIDC Identity Provider with the given configuration.
+func NewIdentityProvider(config *store.IdentityProviderOIDCConfig) (*IdentityProvider, error) {
+	for v, field := range map[string]string{
+		config.Issuer:       "issuer",
+		config.ClientID:     "clientId",
+		config.ClientSecret: "clientSecret",
+	} {
+		if v == "" {
+			return nil, errors.Errorf(`the field "%s" is empty but required`, field)
+		}
+	}
+
+	return &IdentityProvider{
+		config: config,
+	}, nil
+}
+
+// Discover loads the OpenID Provider metadata from the well-known configuration of the issuer, cached for a while.
+func (p *IdentityProvider) Discover(ctx context.Context) (*ProviderMetadata, error) {
+	wellKnownURL := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if metadata, ok := loadDocument(wellKnownURL); ok {
+		return metadata.(*ProviderMetadata), nil
+	}
+	metadata := &ProviderMetadata{}
+	if err := getJSON(ctx, wellKnownURL, "", metadata); err != nil {
+		return nil, errors.Wrap(err, "failed to get openid configuration")
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, errors.Errorf("issuer %q of openid configuration does not match %q", metadata.Issuer, p.config.Issuer)
+	}
+	for v, field := range map[string]string{
+		metadata.AuthorizationEndpoint: "authorization_endpoint",
+		metadata.TokenEndpoint:         "token_endpoint",
+		metadata.JWKSURI:               "jwks_uri",
+	} {
+		if v == "" {
+			return nil, errors.Errorf(`the field "%s" is missing from openid configuration`, field)
+		}
+	}
+	storeDocument(wellKnownURL, metadata)
+	return metadata, nil
+}
+
+// AuthorizationURL returns the URL of the authorization endpoint to redirect the user to.
+func (p *IdentityProvider) AuthorizationURL(ctx context.Context, redirectURL, state, nonce string) (string, error) {
+	metadata, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(metadata, redirectURL).AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce)), nil
+}
+
+// UserInfo exchanges the authorization code, verifies the returned ID token and returns the mapped user information.
+// The nonce must be the one sent in the authorization request.
+func (p *IdentityProvider) UserInfo(ctx context.Context, redirectURL, code, nonce string) (*idp.IdentityProviderUserInfo, error) {
+	metadata, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2Config(metadata, redirectURL).Exchange(context.WithValue(ctx, oauth2.HTTPClient, httpClient), code)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to exchange token")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New(`missing "id_token" from token response`)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, metadata, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.UserInfoEndpoint != "" && token.AccessToken != "" {
+		userInfoClaims := map[string]any{}
+		if err := getJSON(ctx, metadata.UserInfoEndpoint, token.AccessToken, &userInfoClaims); err != nil {
+			return nil, errors.Wrap(err, "failed to get user information")
+		}
+		if userInfoClaims["sub"] != claims["sub"] {
+			return nil, errors.New("the subject of user information does not match the ID token")
+		}
+		// The claims of the ID token take precedence over the user information.
+		for key, value := range userInfoClaims {
+			if _, ok := claims[key]; !ok {
+				claims[key] = value
+			}
+		}
+	}
+	return p.mapUserInfo(claims)
+}
+
+// VerifyIDToken verifies the signature, issuer, audience, expiry and nonce of the ID token and returns its claims.
+func (p *IdentityProvider) VerifyIDToken(ctx context.Context, metadata *ProviderMetadata, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	cachedKeySet, cached := loadDocument(metadata.JWKSURI)
+	keySet, _ := cachedKeySet.(*jsonWebKeySet)
+	if !cached {
+		var err error
+		if keySet, err = getJSONWebKeySet(ctx, metadata.JWKSURI); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	if _, err := parser.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := keySet.find(kid)
+		if err == nil || !cached {
+			return key, err
+		}
+		// The keys may have been rotated since cached, so the unknown key is looked up in the fetched ones.
+		keySet, err = getJSONWebKeySet(ctx, metadata.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+		return keySet.find(kid)
+	}); err != nil {
+		return nil, errors.Wrap(err, "invalid ID token")
+	}
+
+	if !claims.VerifyIssuer(metadata.Issuer, true) {
+		return nil, errors.New("invalid ID token issuer")
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("invalid ID token audience")
+	}
+	// The authorized party must be the client if the token is issued to multiple audiences.
+	if azp, ok := claims["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, errors.New("invalid ID token authorized party")
+	}
+	// The nonce is always required to prevent replaying the ID token.
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid ID token nonce")
+	}
+	return claims, nil
+}
+
+func (p *IdentityProvider) oauth2Config(metadata *ProviderMetadata, redirectURL string) *oauth2.Config {
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   metadata.AuthorizationEndpoint,
+			TokenURL:  metadata.TokenEndpoint,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+func (p *IdentityProvider) mapUserInfo(claims map[string]any) (*idp.IdentityProviderUserInfo, error) {
+	fieldMapping := &store.FieldMapping{}
+	if p.config.FieldMapping != nil {
+		fieldMapping = p.config.FieldMapping
+	}
+
+	userInfo := &idp.IdentityProviderUserInfo{}
+	identifierField := fieldMapping.Identifier
+	if identifierField == "" {
+		// Prefer the readable username, the subject is an opaque identifier of some providers.
+		identifierField = "preferred_username"
+		if v, _ := claims[identifierField].(string); v == "" {
+			identifierField = "sub"
+		}
+	}
+	userInfo.Identifier, _ = claims[identifierField].(string)
+	if userInfo.Identifier == "" {
+		return nil, errors.Errorf("the field %q is not found in claims or has empty value", identifierField)
+	}
+
+	displayNameField := fieldMapping.DisplayName
+	if displayNameField == "" {
+		displayNameField = "name"
+	}
+	userInfo.DisplayName, _ = claims[displayNameField].(string)
+	if userInfo.DisplayName == "" {
+		userInfo.DisplayName = userInfo.Identifier
+	}
+	emailField := fieldMapping.Email
+	if emailField == "" {
+		emailField = "email"
+	}
+	userInfo.Email, _ = claims[emailField].(string)
+	// Some providers send the boolean claims as strings.
+	switch emailVerified := claims["email_verified"].(type) {
+	case bool:
+		userInfo.EmailVerified = emailVerified
+	case string:
+		userInfo.EmailVerified = emailVerified == "true"
+	}
+	userInfo.Subject, _ = claims["sub"].(string)
+	if userInfo.Subject == "" {
+		userInfo.Subject = userInfo.Identifier
+	}
+
+	if p.config.GroupsClaim != "" {
+		switch groups := claims[p.config.GroupsClaim].(type) {
+		case string:
+			userInfo.Groups = []string{groups}
+		case []any:
+			for _, group := range groups {
+				if v, ok := group.(string); ok {
+					userInfo.Groups = append(userInfo.Groups, v)
+				}
+			}
+		}
+	}
+	return userInfo, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA fields.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC fields.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	keys map[string]any
+}
+
+func (s *jsonWebKeySet) find(kid string) (any, error) {
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	// The key ID is optional if there is only a single key.
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+	return nil, errors.Errorf("signing key %q not found", kid)
+}
+
+// getJSONWebKeySet fetches the JSON web key set and caches it.
+func getJSONWebKeySet(ctx context.Context, jwksURI string) (*jsonWebKeySet, error) {
+	raw := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := getJSON(ctx, jwksURI, "", &raw); err != nil {
+		return nil, errors.Wrap(err, "failed to get JSON web key set")
+	}
+
+	keySet := &jsonWebKeySet{
+		keys: map[string]any{},
+	}
+	for _, key := range raw.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if publicKey != nil {
+			keySet.keys[key.Kid] = publicKey
+		}
+	}
+	storeDocument(jwksURI, keySet)
+	return keySet, nil
+}
+
+// publicKey returns the public key of the JSON web key, or nil if the key type is not supported.
+func (k *jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %q of key %q", k.Crv, k.Kid)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBase64URLInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JSON web key")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+type documentCacheEntry struct {
+	value     any
+	expiredAt time.Time
+}
+
+// documentCache caches the provider metadata by issuer and the JSON web key sets by URI.
+var documentCache = struct {
+	sync.Mutex
+	entries map[string]documentCacheEntry
+}{
+	entries: map[string]documentCacheEntry{},
+}
+
+func loadDocument(key string) (any, bool) {
+	documentCache.Lock()
+	defer documentCache.Unlock()
+	entry, ok := documentCache.entries[key]
+	if !ok || time.Now().After(entry.expiredAt) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func storeDocument(key string, value any) {
+	documentCache.Lock()
+	defer documentCache.Unlock()
+	documentCache.entries[key] = documentCacheEntry{
+		value:     value,
+		expiredAt: time.Now().Add(documentCacheDuration),
+	}
+}
+
+func getJSON(ctx context.Context, url, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to new http request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "failed to unmarshal response body")
+	}
+	return nil
+}
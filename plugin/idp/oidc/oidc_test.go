@@ -0,0 +1,278 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/plugin/idp"
+	"github.com/usememos/memos/store"
+)
+
+const (
+	testClientID     = "test-client-id"
+	testClientSecret = "test-client-secret"
+	testCode         = "test-code"
+	testAccessToken  = "test-access-token"
+	testKeyID        = "test-key"
+)
+
+func TestNewIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *store.IdentityProviderOIDCConfig
+		containsErr string
+	}{
+		{
+			name: "no issuer",
+			config: &store.IdentityProviderOIDCConfig{
+				ClientID:     testClientID,
+				ClientSecret: testClientSecret,
+			},
+			containsErr: `the field "issuer" is empty but required`,
+		},
+		{
+			name: "no clientId",
+			config: &store.IdentityProviderOIDCConfig{
+				Issuer:       "https://example.com",
+				ClientSecret: testClientSecret,
+			},
+			containsErr: `the field "clientId" is empty but required`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewIdentityProvider(test.config)
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+}
+
+// mockServer is a minimal OpenID Provider issuing ID tokens with the given claims.
+type mockServer struct {
+	*httptest.Server
+	// key signs the ID tokens and publishedKey is published as the key of kid.
+	key          *rsa.PrivateKey
+	publishedKey *rsa.PrivateKey
+	kid          string
+	claims       jwt.MapClaims
+	// discoveryCount and jwksCount are the numbers of the requests of the documents.
+	discoveryCount atomic.Int32
+	jwksCount      atomic.Int32
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s := &mockServer{
+		key:          key,
+		publishedKey: key,
+		kid:          testKeyID,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		s.discoveryCount.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"userinfo_endpoint":      s.URL + "/userinfo",
+			"jwks_uri":               s.URL + "/jwks",
+		}))
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.jwksCount.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{
+				{
+					"kty": "RSA",
+					"kid": s.kid,
+					"use": "sig",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(s.publishedKey.PublicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.publishedKey.PublicKey.E)).Bytes()),
+				},
+			},
+		}))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		vals, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		require.Equal(t, testCode, vals.Get("code"))
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims)
+		token.Header["kid"] = s.kid
+		rawIDToken, err := token.SignedString(s.key)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"access_token": testAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     rawIDToken,
+		}))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer "+testAccessToken, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"sub":   s.claims["sub"],
+			"email": "john.doe@example.com",
+		}))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *mockServer) validClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":                s.URL,
+		"sub":                "123456789",
+		"aud":                testClientID,
+		"exp":                now.Add(time.Hour).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              nonce,
+		"name":               "John Doe",
+		"preferred_username": "john",
+		"groups":             []string{"staff", "admins"},
+	}
+}
+
+func TestIdentityProvider(t *testing.T) {
+	ctx := context.Background()
+	s := newMockServer(t)
+	oidc, err := NewIdentityProvider(&store.IdentityProviderOIDCConfig{
+		Issuer:       s.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		GroupsClaim:  "groups",
+	})
+	require.NoError(t, err)
+	redirectURL := "https://example.com/auth/callback"
+
+	t.Run("authorization url", func(t *testing.T) {
+		authorizationURL, err := oidc.AuthorizationURL(ctx, redirectURL, "test-state", "test-nonce")
+		require.NoError(t, err)
+		u, err := url.Parse(authorizationURL)
+		require.NoError(t, err)
+		assert.Equal(t, s.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "test-nonce", u.Query().Get("nonce"))
+		assert.Equal(t, "openid profile email", u.Query().Get("scope"))
+	})
+
+	t.Run("valid id token", func(t *testing.T) {
+		s.claims = s.validClaims("test-nonce")
+		userInfo, err := oidc.UserInfo(ctx, redirectURL, testCode, "test-nonce")
+		require.NoError(t, err)
+		assert.Equal(t, &idp.IdentityProviderUserInfo{
+			Subject:     "123456789",
+			Identifier:  "john",
+			DisplayName: "John Doe",
+			Email:       "john.doe@example.com",
+			Groups:      []string{"staff", "admins"},
+		}, userInfo)
+	})
+
+	tests := []struct {
+		name        string
+		modify      func(claims jwt.MapClaims)
+		containsErr string
+	}{
+		{
+			name: "wrong nonce",
+			modify: func(claims jwt.MapClaims) {
+				claims["nonce"] = "other-nonce"
+			},
+			containsErr: "invalid ID token nonce",
+		},
+		{
+			name: "wrong audience",
+			modify: func(claims jwt.MapClaims) {
+				claims["aud"] = "other-client-id"
+			},
+			containsErr: "invalid ID token audience",
+		},
+		{
+			name: "wrong issuer",
+			modify: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://example.com"
+			},
+			containsErr: "invalid ID token issuer",
+		},
+		{
+			name: "expired",
+			modify: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			},
+			containsErr: "Token is expired",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s.claims = s.validClaims("test-nonce")
+			test.modify(s.claims)
+			_, err := oidc.UserInfo(ctx, redirectURL, testCode, "test-nonce")
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+
+	t.Run("missing nonce", func(t *testing.T) {
+		s.claims = s.validClaims("")
+		_, err := oidc.UserInfo(ctx, redirectURL, testCode, "")
+		assert.ErrorContains(t, err, "invalid ID token nonce")
+	})
+
+	t.Run("wrong signing key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		validKey := s.key
+		s.key = otherKey
+		defer func() {
+			s.key = validKey
+		}()
+
+		s.claims = s.validClaims("test-nonce")
+		_, err = oidc.UserInfo(ctx, redirectURL, testCode, "test-nonce")
+		assert.ErrorContains(t, err, "invalid ID token")
+	})
+
+	t.Run("cached documents", func(t *testing.T) {
+		// The documents are fetched once for all the sign-ins.
+		assert.Equal(t, int32(1), s.discoveryCount.Load())
+		assert.Equal(t, int32(1), s.jwksCount.Load())
+
+		// The key set is fetched again for the unknown key after the rotation.
+		rotatedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		s.key, s.publishedKey, s.kid = rotatedKey, rotatedKey, "rotated-key"
+		s.claims = s.validClaims("test-nonce")
+		_, err = oidc.UserInfo(ctx, redirectURL, testCode, "test-nonce")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), s.jwksCount.Load())
+		_, err = oidc.UserInfo(ctx, redirectURL, testCode, "test-nonce")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), s.jwksCount.Load())
+	})
+}
@@ -0,0 +1,174 @@
+// Package mail is the plugin for sending emails, e.g. the email verification and password reset messages.
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender sends emails.
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// SMTPSecurity is how the connection to the SMTP server is secured.
+type SMTPSecurity string
+
+const (
+	// SMTPSecurityNone sends the emails in plain text, only for trusted networks.
+	SMTPSecurityNone SMTPSecurity = "NONE"
+	// SMTPSecuritySTARTTLS upgrades the connection with the STARTTLS command, usually on port 587.
+	SMTPSecuritySTARTTLS SMTPSecurity = "STARTTLS"
+	// SMTPSecurityTLS connects with implicit TLS, usually on port 465.
+	SMTPSecurityTLS SMTPSecurity = "TLS"
+)
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username is optional, the sender does not authenticate without it.
+	Username string
+	Password string
+	// From is the sender address, e.g. `memos <noreply@example.com>`.
+	From     string
+	Security SMTPSecurity
+}
+
+// SMTPSender sends emails with an SMTP server.
+type SMTPSender struct {
+	config *SMTPConfig
+	from   *mail.Address
+}
+
+// NewSMTPSender initializes a new SMTP sender with the given configuration.
+func NewSMTPSender(config *SMTPConfig) (*SMTPSender, error) {
+	if config.Host == "" {
+		return nil, errors.New(`the field "host" is empty but required`)
+	}
+	if config.Port <= 0 || config.Port > 65535 {
+		return nil, errors.Errorf("invalid port %d", config.Port)
+	}
+	switch config.Security {
+	case SMTPSecurityNone, SMTPSecuritySTARTTLS, SMTPSecurityTLS:
+	default:
+		return nil, errors.Errorf("invalid security %s", config.Security)
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid from address")
+	}
+
+	return &SMTPSender{
+		config: config,
+		from:   from,
+	}, nil
+}
+
+// Send sends the message with a new connection to the SMTP server.
+func (s *SMTPSender) Send(ctx context.Context, message *Message) error {
+	if len(message.To) == 0 {
+		return errors.New("no recipient")
+	}
+	for _, to := range message.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return errors.Wrapf(err, "invalid recipient %s", to)
+		}
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+	tlsConfig := &tls.Config{
+		ServerName: s.config.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if s.config.Security == SMTPSecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to create SMTP client")
+	}
+	defer client.Close()
+
+	if s.config.Security == SMTPSecuritySTARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return errors.Wrap(err, "failed to authenticate")
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return errors.Wrap(err, "failed to set sender")
+	}
+	for _, to := range message.To {
+		if err := client.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "failed to set recipient %s", to)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed to start data")
+	}
+	if _, err := writer.Write(s.buildMessage(message)); err != nil {
+		return errors.Wrap(err, "failed to write message")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTPSender) buildMessage(message *Message) []byte {
+	headers := [][2]string{
+		{"From", s.from.String()},
+		{"To", strings.Join(message.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "8bit"},
+	}
+	builder := &strings.Builder{}
+	for _, header := range headers {
+		fmt.Fprintf(builder, "%s: %s\r\n", header[0], header[1])
+	}
+	builder.WriteString("\r\n")
+	// Normalize the line endings to CRLF as required by SMTP.
+	body := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	builder.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
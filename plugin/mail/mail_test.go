@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/test"
+)
+
+func TestNewSMTPSender(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *SMTPConfig
+		containsErr string
+	}{
+		{
+			name:        "no host",
+			config:      &SMTPConfig{Port: 25, From: "noreply@example.com", Security: SMTPSecurityNone},
+			containsErr: `the field "host" is empty but required`,
+		},
+		{
+			name:        "invalid security",
+			config:      &SMTPConfig{Host: "localhost", Port: 25, From: "noreply@example.com", Security: "SSL"},
+			containsErr: "invalid security SSL",
+		},
+		{
+			name:        "invalid from",
+			config:      &SMTPConfig{Host: "localhost", Port: 25, From: "noreply", Security: SMTPSecurityNone},
+			containsErr: "invalid from address",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewSMTPSender(test.config)
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	ctx := context.Background()
+	s := test.NewSMTPServer(t)
+	s.Username = "smtp-user"
+	s.Password = "smtp-password"
+
+	sender, err := NewSMTPSender(&SMTPConfig{
+		Host:     s.Host,
+		Port:     s.Port,
+		Username: "smtp-user",
+		Password: "wrong-password",
+		From:     "memos <noreply@example.com>",
+		Security: SMTPSecurityNone,
+	})
+	require.NoError(t, err)
+	message := &Message{
+		To:      []string{"steven@example.com"},
+		Subject: "Welcome to memos ✨",
+		Body:    "Hello\nWorld",
+	}
+	require.ErrorContains(t, sender.Send(ctx, message), "failed to authenticate")
+
+	sender.config.Password = "smtp-password"
+	require.NoError(t, sender.Send(ctx, message))
+	messages := s.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "noreply@example.com", messages[0].From)
+	assert.Equal(t, []string{"steven@example.com"}, messages[0].To)
+	assert.Equal(t, "Welcome to memos ✨", messages[0].Subject)
+	assert.Equal(t, "Hello\nWorld\n", messages[0].Body)
+
+	require.ErrorContains(t, sender.Send(ctx, &Message{To: []string{"steven@example.com\r\nBcc: eve@example.com"}}), "invalid recipient")
+}
@@ -0,0 +1,342 @@
+// Package metrics is a minimal collector of counters, gauges and histograms exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default upper bounds in seconds of the histogram buckets, suitable for latencies.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// defaultRegistry holds the metrics created by the package level constructors.
+var defaultRegistry = NewRegistry()
+
+// Registry is a set of metrics written together.
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors map[string]collector
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: map[string]collector{},
+	}
+}
+
+// register adds the metric to the registry, it panics if the name is taken as metrics are defined once at startup.
+func (r *Registry) register(name string, c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", name))
+	}
+	r.collectors[name] = c
+}
+
+// Write writes all metrics of the registry in the Prometheus text format, sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mutex.RUnlock()
+
+	writer := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(writer)
+	}
+	return writer.Flush()
+}
+
+// Write writes the metrics created by the package level constructors.
+func Write(w io.Writer) error {
+	return defaultRegistry.Write(w)
+}
+
+// vec holds the series of a metric keyed by their label values.
+type vec struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// bucketCounts, count and sum are only used by histograms.
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+func newVec(name, help, kind string, labelNames []string) *vec {
+	return &vec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		series:     map[string]*series{},
+	}
+}
+
+// with returns the series of the label values, creating it if absent. The caller must hold the mutex.
+func (v *vec) with(labelValues []string, bucketCount int) *series {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values but got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{
+			labelValues:  append([]string{}, labelValues...),
+			bucketCounts: make([]uint64, bucketCount),
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sortedSeries returns the series sorted by their label values. The caller must hold the mutex.
+func (v *vec) sortedSeries() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, v.series[key])
+	}
+	return list
+}
+
+func (v *vec) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+// CounterVec is a set of counters partitioned by labels, which only go up.
+type CounterVec struct {
+	*vec
+}
+
+// NewCounterVec creates a counter in the registry.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labelNames)}
+	if len(labelNames) == 0 {
+		c.with(nil, 0)
+	}
+	r.register(name, c)
+	return c
+}
+
+// NewCounterVec creates a counter in the default registry.
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return defaultRegistry.NewCounterVec(name, help, labelNames...)
+}
+
+// Inc increments the counter of the label values by 1.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the non-negative value to the counter of the label values.
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic(fmt.Sprintf("metrics: counter %s can not decrease", c.name))
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.with(labelValues, 0).value += value
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writeHeader(w)
+	for _, s := range c.sortedSeries() {
+		writeSample(w, c.name, c.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// GaugeVec is a set of gauges partitioned by labels, which go up and down.
+type GaugeVec struct {
+	*vec
+}
+
+// NewGaugeVec creates a gauge in the registry.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labelNames)}
+	if len(labelNames) == 0 {
+		g.with(nil, 0)
+	}
+	r.register(name, g)
+	return g
+}
+
+// NewGaugeVec creates a gauge in the default registry.
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return defaultRegistry.NewGaugeVec(name, help, labelNames...)
+}
+
+// Set sets the gauge of the label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.with(labelValues, 0).value = value
+}
+
+// Add adds the value to the gauge of the label values, which may be negative.
+func (g *GaugeVec) Add(value float64, labelValues ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.with(labelValues, 0).value += value
+}
+
+// Inc increments the gauge of the label values by 1.
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge of the label values by 1.
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.writeHeader(w)
+	for _, s := range g.sortedSeries() {
+		writeSample(w, g.name, g.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec is a set of histograms partitioned by labels, which count the observations in buckets.
+type HistogramVec struct {
+	*vec
+	buckets []float64
+}
+
+// NewHistogramVec creates a histogram in the registry, the buckets are the sorted upper bounds or DefBuckets if nil.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of %s are not sorted", name))
+	}
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labelNames), buckets: buckets}
+	if len(labelNames) == 0 {
+		h.with(nil, len(buckets))
+	}
+	r.register(name, h)
+	return h
+}
+
+// NewHistogramVec creates a histogram in the default registry.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return defaultRegistry.NewHistogramVec(name, help, buckets, labelNames...)
+}
+
+// Observe adds the value to the histogram of the label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	s := h.with(labelValues, len(h.buckets))
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.writeHeader(w)
+	for _, s := range h.sortedSeries() {
+		for i, upperBound := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labelNames, s.labelValues, "le", formatValue(upperBound), float64(s.bucketCounts[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labelNames, s.labelValues, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labelNames, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes a line of the sample, with the extra label if its name is not empty.
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraLabelName, extraLabelValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraLabelName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, labelName, escapeLabelValue(labelValues[i]))
+		}
+		if extraLabelName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabelName, escapeLabelValue(extraLabelValue))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(value))
+	w.WriteByte('\n')
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests by route.", "route")
+	inFlight := r.NewGaugeVec("test_in_flight", "Requests in flight.")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency of requests.", []float64{0.1, 1}, "route")
+
+	requests.Inc(`/a"b`)
+	requests.Add(2, "/c")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency.Observe(0.05, "/c")
+	latency.Observe(0.5, "/c")
+	latency.Observe(5, "/c")
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, r.Write(buffer))
+	require.Equal(t, `# HELP test_in_flight Requests in flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_latency_seconds Latency of requests.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/c",le="0.1"} 1
+test_latency_seconds_bucket{route="/c",le="1"} 2
+test_latency_seconds_bucket{route="/c",le="+Inf"} 3
+test_latency_seconds_sum{route="/c"} 5.55
+test_latency_seconds_count{route="/c"} 3
+# HELP test_requests_total Requests by route.
+# TYPE test_requests_total counter
+test_requests_total{route="/a\"b"} 1
+test_requests_total{route="/c"} 2
+`, buffer.String())
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_total", "Test.", "label")
+	require.Panics(t, func() {
+		r.NewGaugeVec("test_total", "Duplicate.")
+	})
+	require.Panics(t, func() {
+		counter.Inc()
+	})
+	require.Panics(t, func() {
+		counter.Add(-1, "value")
+	})
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
@@ -61,7 +64,15 @@ func NewClient(ctx context.Context, config *Config) (*Client, error) {
 	}, nil
 }
 
-func (client *Client) UploadFile(ctx context.Context, filename string, fileType string, src io.Reader) (string, error) {
+func (client *Client) UploadFile(ctx context.Context, filename string, fileType string, src io.Reader) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "s3.UploadFile", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("s3.bucket", client.Config.Bucket),
+		attribute.String("s3.key", filename),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	uploader := manager.NewUploader(client.Client)
 	uploadOutput, err := uploader.Upload(ctx, &awss3.PutObjectInput{
 		Bucket:      aws.String(client.Config.Bucket),
@@ -84,3 +95,17 @@ func (client *Client) UploadFile(ctx context.Context, filename string, fileType
 	}
 	return link, nil
 }
+
+// DeleteFile deletes the object of the key from the bucket.
+func (client *Client) DeleteFile(ctx context.Context, key string) error {
+	ctx, span := tracing.Start(ctx, "s3.DeleteFile", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("s3.bucket", client.Config.Bucket),
+		attribute.String("s3.key", key),
+	))
+	_, err := client.Client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(client.Config.Bucket),
+		Key:    aws.String(key),
+	})
+	tracing.End(span, err)
+	return err
+}
@@ -3,7 +3,6 @@ package telegram
 import (
 	"path"
 
-	"github.com/usememos/memos/common/log"
 	"go.uber.org/zap"
 )
 
@@ -29,7 +28,7 @@ func (b Attachment) GetMimeType() string {
 	mime, ok := mimeTypes[path.Ext(b.FileName)]
 	if !ok {
 		// Handle unknown file extension
-		log.Warn("Unknown file type for ", zap.String("filename", b.FileName))
+		logger.Warn("Unknown file type for ", zap.String("filename", b.FileName))
 
 		return "application/octet-stream"
 	}
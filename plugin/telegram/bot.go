@@ -5,12 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/metrics"
 	"go.uber.org/zap"
 )
 
+var logger = log.For(log.SubsystemTelegram)
+
+var pollErrorsTotal = metrics.NewCounterVec("memos_telegram_poll_errors_total", "Failed polls of updates from Telegram.")
+
 type Handler interface {
 	BotToken(ctx context.Context) string
 	MessageHandle(ctx context.Context, bot *Bot, message Message, attachments []Attachment) error
@@ -19,6 +25,21 @@ type Handler interface {
 
 type Bot struct {
 	handler Handler
+
+	// statusMutex guards the status of the polling.
+	statusMutex sync.RWMutex
+	status      Status
+}
+
+// Status is the health of the polling of updates from Telegram.
+type Status struct {
+	// Enabled is whether the bot token is set.
+	Enabled bool
+	// LastPollTs is the time of the last successful poll.
+	LastPollTs int64
+	// LastErrorTs and LastError are the time and the error of the last failed poll.
+	LastErrorTs int64
+	LastError   string
 }
 
 // NewBotWithHandler create a telegram bot with specified handler.
@@ -35,12 +56,13 @@ func (b *Bot) Start(ctx context.Context) {
 
 	for {
 		updates, err := b.GetUpdates(ctx, offset)
+		b.updateStatus(err)
 		if err == ErrInvalidToken {
 			time.Sleep(noTokenWait)
 			continue
 		}
 		if err != nil {
-			log.Warn("fail to telegram.GetUpdates", zap.Error(err))
+			logger.Warn("fail to telegram.GetUpdates", zap.Error(err))
 			time.Sleep(errRetryWait)
 			continue
 		}
@@ -55,7 +77,7 @@ func (b *Bot) Start(ctx context.Context) {
 			if update.CallbackQuery != nil {
 				err := b.handler.CallbackQueryHandle(ctx, b, *update.CallbackQuery)
 				if err != nil {
-					log.Error("fail to handle CallbackQuery", zap.Error(err))
+					logger.Error("fail to handle CallbackQuery", zap.Error(err))
 				}
 
 				continue
@@ -69,7 +91,7 @@ func (b *Bot) Start(ctx context.Context) {
 				if !message.IsSupported() {
 					_, err := b.SendReplyMessage(ctx, message.Chat.ID, message.MessageID, "Supported messages: animation, audio, text, document, photo, video, video note, voice, other messages with caption")
 					if err != nil {
-						log.Error(fmt.Sprintf("fail to telegram.SendReplyMessage for messageID=%d", message.MessageID), zap.Error(err))
+						logger.Error(fmt.Sprintf("fail to telegram.SendReplyMessage for messageID=%d", message.MessageID), zap.Error(err))
 					}
 					continue
 				}
@@ -87,16 +109,37 @@ func (b *Bot) Start(ctx context.Context) {
 
 		err = b.handleSingleMessages(ctx, singleMessages)
 		if err != nil {
-			log.Error("fail to handle singleMessage", zap.Error(err))
+			logger.Error("fail to handle singleMessage", zap.Error(err))
 		}
 
 		err = b.handleGroupMessages(ctx, groupMessages)
 		if err != nil {
-			log.Error("fail to handle plain text message", zap.Error(err))
+			logger.Error("fail to handle plain text message", zap.Error(err))
 		}
 	}
 }
 
+// Status returns the health of the polling of updates.
+func (b *Bot) Status() Status {
+	b.statusMutex.RLock()
+	defer b.statusMutex.RUnlock()
+	return b.status
+}
+
+// updateStatus records the result of a poll of updates.
+func (b *Bot) updateStatus(err error) {
+	b.statusMutex.Lock()
+	defer b.statusMutex.Unlock()
+	b.status.Enabled = err != ErrInvalidToken
+	if err == nil {
+		b.status.LastPollTs = time.Now().Unix()
+	} else if err != ErrInvalidToken {
+		b.status.LastErrorTs = time.Now().Unix()
+		b.status.LastError = err.Error()
+		pollErrorsTotal.Inc()
+	}
+}
+
 var ErrInvalidToken = errors.New("token is invalid")
 
 func (b *Bot) apiURL(ctx context.Context) (string, error) {
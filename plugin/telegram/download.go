@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/usememos/memos/plugin/tracing"
 )
 
 func (b *Bot) downloadAttachment(ctx context.Context, message *Message) (*Attachment, error) {
@@ -77,7 +79,12 @@ func (b *Bot) downloadFileID(ctx context.Context, fileID string) (*Attachment, e
 }
 
 // downloadFilepath download file with filepath, you can get filepath by calling GetFile.
-func (b *Bot) downloadFilepath(ctx context.Context, filePath string) ([]byte, error) {
+func (b *Bot) downloadFilepath(ctx context.Context, filePath string) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "telegram.downloadFile")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	apiURL, err := b.apiURL(ctx)
 	if err != nil {
 		return nil, err
@@ -90,7 +97,11 @@ func (b *Bot) downloadFilepath(ctx context.Context, filePath string) ([]byte, er
 
 	fileURL := apiURL[:idx] + "/file" + apiURL[idx:]
 
-	resp, err := http.Get(fileURL + "/" + filePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL+"/"+filePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to http.NewRequest: %s", err)
+	}
+	resp, err := tracing.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fail to http.Get: %s", err)
 	}
@@ -7,15 +7,29 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+
+	"github.com/usememos/memos/plugin/tracing"
 )
 
-func (b *Bot) postForm(ctx context.Context, apiPath string, formData url.Values, result any) error {
+func (b *Bot) postForm(ctx context.Context, apiPath string, formData url.Values, result any) (err error) {
+	// The span is named after the method of the API, the URL is left out as it carries the token.
+	ctx, span := tracing.Start(ctx, "telegram."+strings.TrimPrefix(apiPath, "/"))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	apiURL, err := b.apiURL(ctx)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.PostForm(apiURL+apiPath, formData)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+apiPath, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return fmt.Errorf("fail to http.NewRequest: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := tracing.HTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("fail to http.PostForm: %s", err)
 	}
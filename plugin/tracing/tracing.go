@@ -0,0 +1,192 @@
+// Package tracing is the plugin for the OpenTelemetry tracing, which costs nothing when disabled.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterOTLP exports the spans to an OTLP collector over HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints the spans to the standard output.
+	ExporterStdout = "stdout"
+
+	tracerName = "github.com/usememos/memos"
+)
+
+type Config struct {
+	// Exporter is ExporterOTLP, ExporterStdout, or empty to disable the tracing.
+	Exporter string
+	// Endpoint is the URL of the OTLP collector, such as http://localhost:4318.
+	// The OTEL_EXPORTER_OTLP_* environment variables or their defaults apply if empty.
+	Endpoint string
+	// SampleRatio is the ratio of the traces started by the server that are sampled, between 0 and 1.
+	// The traces continued from an incoming request follow its sampling decision.
+	SampleRatio float64
+	// ServiceVersion is the version of the server reported with the spans.
+	ServiceVersion string
+}
+
+var (
+	enabled atomic.Bool
+	// noopSpan is returned by Start when the tracing is disabled.
+	noopSpan = trace.SpanFromContext(context.Background())
+)
+
+// Initialize enables the tracing with the exporter of the config, it returns the function flushing and disabling the tracing.
+// Nothing is done if no exporter is set.
+func Initialize(ctx context.Context, config *Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{}
+		if config.Endpoint != "" {
+			endpoint, err := url.Parse(config.Endpoint)
+			if err != nil || endpoint.Host == "" {
+				return nil, fmt.Errorf("invalid tracing endpoint %q, should be like http://localhost:4318", config.Endpoint)
+			}
+			options = append(options, otlptracehttp.WithEndpoint(endpoint.Host))
+			if endpoint.Scheme == "http" {
+				options = append(options, otlptracehttp.WithInsecure())
+			}
+			if path := strings.TrimSuffix(endpoint.Path, "/"); path != "" {
+				options = append(options, otlptracehttp.WithURLPath(path))
+			}
+		}
+		otlpExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = otlpExporter
+	case ExporterStdout:
+		stdoutExporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdoutExporter
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q, should be %s or %s", config.Exporter, ExporterOTLP, ExporterStdout)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid tracing sample ratio %v, should be between 0 and 1", config.SampleRatio)
+	}
+	return Setup(sdktrace.NewBatchSpanProcessor(exporter), config), nil
+}
+
+// Setup enables the tracing with the span processor, such as a batch span processor of an exporter.
+// It returns the function flushing and disabling the tracing.
+func Setup(processor sdktrace.SpanProcessor, config *Config) func(context.Context) error {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("memos"),
+			semconv.ServiceVersion(config.ServiceVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled.Store(true)
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		return provider.Shutdown(ctx)
+	}
+}
+
+// Enabled returns whether the tracing is enabled, the callers may skip preparing the spans if not.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start starts a span as a child of the span of the context, if any.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !Enabled() {
+		return ctx, noopSpan
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// End ends the span, marking it as failed with the error if not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract returns a copy of the context carrying the span context of the headers of an incoming request, such as traceparent.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if !Enabled() {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceID returns the ID of the trace of the context, empty if none.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// HTTPClient is the HTTP client tracing the requests with a context.
+var HTTPClient = &http.Client{
+	Transport: NewTransport(http.DefaultTransport),
+}
+
+// Transport traces the requests in client spans and propagates the span context to the servers.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport returns a transport tracing the requests sent by the base transport.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		base: base,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. The path of the URL is left out of the span as it may carry secrets, such as bot tokens.
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !Enabled() {
+		return t.base.RoundTrip(request)
+	}
+	ctx, span := Start(request.Context(), "HTTP "+request.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPMethod(request.Method),
+		semconv.NetPeerName(request.URL.Hostname()),
+	))
+	request = request.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, response.Status)
+	}
+	span.End()
+	return response, nil
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInitialize(t *testing.T) {
+	ctx := context.Background()
+	shutdown, err := Initialize(ctx, &Config{})
+	require.NoError(t, err)
+	require.False(t, Enabled())
+	require.NoError(t, shutdown(ctx))
+
+	_, err = Initialize(ctx, &Config{Exporter: "zipkin"})
+	require.Error(t, err)
+	_, err = Initialize(ctx, &Config{Exporter: ExporterOTLP, Endpoint: "localhost"})
+	require.Error(t, err)
+	_, err = Initialize(ctx, &Config{Exporter: ExporterStdout, SampleRatio: 2})
+	require.Error(t, err)
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "disabled")
+	require.Equal(t, ctx, spanCtx)
+	require.False(t, span.IsRecording())
+	End(span, errors.New("ignored"))
+	require.Empty(t, TraceID(spanCtx))
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	shutdown := Setup(recorder, &Config{SampleRatio: 1})
+	require.True(t, Enabled())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span := Start(Extract(ctx, header), "parent")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", TraceID(ctx))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/bot-token/getMe", nil)
+	require.NoError(t, err)
+	response, err := HTTPClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	End(span, errors.New("failed"))
+
+	require.NoError(t, shutdown(ctx))
+	require.False(t, Enabled())
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	client, parent := spans[0], spans[1]
+	require.Equal(t, "HTTP GET", client.Name())
+	require.Equal(t, trace.SpanKindClient, client.SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", client.SpanContext().TraceID().String())
+	require.Contains(t, traceparent, client.SpanContext().SpanID().String())
+	for _, attribute := range client.Attributes() {
+		require.NotContains(t, attribute.Value.Emit(), "bot-token")
+	}
+	require.Equal(t, "failed", parent.Status().Description)
+	require.Len(t, parent.Events(), 1)
+}
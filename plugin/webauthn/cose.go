@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+)
+
+// COSE key types, algorithms and curves, see https://www.iana.org/assignments/cose/cose.xhtml.
+const (
+	keyTypeOKP = 1
+	keyTypeEC2 = 2
+	keyTypeRSA = 3
+
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+
+	curveP256    = 1
+	curveEd25519 = 6
+
+	labelKeyType = 1
+	labelAlg     = 3
+	// The labels of the key parameters depend on the key type.
+	labelCurve    = -1
+	labelX        = -2
+	labelY        = -3
+	labelModulus  = -1
+	labelExponent = -2
+)
+
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+func parsePublicKey(data []byte) (*publicKey, error) {
+	params := map[int]cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &params); err != nil {
+		return nil, errors.Wrap(err, "invalid COSE key")
+	}
+	var keyType, alg int64
+	if err := unmarshalParam(params, labelKeyType, &keyType); err != nil {
+		return nil, err
+	}
+	if err := unmarshalParam(params, labelAlg, &alg); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case keyType == keyTypeEC2 && alg == algES256:
+		var curve int64
+		var x, y []byte
+		if err := unmarshalParam(params, labelCurve, &curve); err != nil {
+			return nil, err
+		}
+		if err := unmarshalParam(params, labelX, &x); err != nil {
+			return nil, err
+		}
+		if err := unmarshalParam(params, labelY, &y); err != nil {
+			return nil, err
+		}
+		if curve != curveP256 {
+			return nil, errors.Errorf("unsupported curve %d", curve)
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		return &publicKey{alg: alg, key: key}, nil
+	case keyType == keyTypeOKP && alg == algEdDSA:
+		var curve int64
+		var x []byte
+		if err := unmarshalParam(params, labelCurve, &curve); err != nil {
+			return nil, err
+		}
+		if err := unmarshalParam(params, labelX, &x); err != nil {
+			return nil, err
+		}
+		if curve != curveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("unsupported curve %d", curve)
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case keyType == keyTypeRSA && alg == algRS256:
+		var n, e []byte
+		if err := unmarshalParam(params, labelModulus, &n); err != nil {
+			return nil, err
+		}
+		if err := unmarshalParam(params, labelExponent, &e); err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(exponent.Int64()),
+		}
+		return &publicKey{alg: alg, key: key}, nil
+	default:
+		return nil, errors.Errorf("unsupported key type %d with algorithm %d", keyType, alg)
+	}
+}
+
+func (k *publicKey) verify(data, signature []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.Errorf("unsupported public key %T", key)
+	}
+	return nil
+}
+
+func unmarshalParam(params map[int]cbor.RawMessage, label int, v any) error {
+	raw, ok := params[label]
+	if !ok {
+		return errors.Errorf("missing COSE key parameter %d", label)
+	}
+	if err := cbor.Unmarshal(raw, v); err != nil {
+		return errors.Wrapf(err, "invalid COSE key parameter %d", label)
+	}
+	return nil
+}
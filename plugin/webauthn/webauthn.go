@@ -0,0 +1,370 @@
+// Package webauthn implements the relying party of the Web Authentication ceremonies for passkeys.
+// Attestation statements are not verified, the credentials are trusted on first use.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Timeout is the number of milliseconds the client waits for the user.
+	Timeout = 5 * 60 * 1000
+
+	challengeSize = 32
+
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+// Base64URL is binary data encoded as unpadded base64url in JSON, as used by the WebAuthn JSON serialization.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := ParseBase64URL(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// ParseBase64URL decodes the base64url encoded data with or without padding.
+func ParseBase64URL(s string) (Base64URL, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (b Base64URL) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// RelyingParty is the server side of the ceremonies, identified by the origin of the website.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// NewRelyingParty creates a relying party from the external URL of the website.
+func NewRelyingParty(name, externalURL string) (*RelyingParty, error) {
+	u, err := url.Parse(externalURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid external url")
+	}
+	if u.Scheme == "" || u.Hostname() == "" {
+		return nil, errors.Errorf("invalid external url %q", externalURL)
+	}
+	return &RelyingParty{
+		ID:     u.Hostname(),
+		Name:   name,
+		Origin: u.Scheme + "://" + u.Host,
+	}, nil
+}
+
+// Credential is a public key credential registered by an authenticator.
+type Credential struct {
+	ID Base64URL
+	// PublicKey is the COSE encoded public key.
+	PublicKey []byte
+	SignCount uint32
+}
+
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the options of `navigator.credentials.create()`.
+type CreationOptions struct {
+	Challenge              Base64URL               `json:"challenge"`
+	RelyingParty           RelyingPartyEntity      `json:"rp"`
+	User                   UserEntity              `json:"user"`
+	PubKeyCredParams       []CredentialParameter   `json:"pubKeyCredParams"`
+	Timeout                int                     `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor  `json:"excludeCredentials"`
+	AuthenticatorSelection *AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                  `json:"attestation"`
+}
+
+// RequestOptions are the options of `navigator.credentials.get()`.
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	RelyingPartyID   string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the JSON serialization of the credential created by the client.
+type RegistrationResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON serialization of the assertion signed by the client.
+type AssertionResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Format   string          `cbor:"fmt"`
+	AuthData []byte          `cbor:"authData"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+}
+
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// The attested credential data is only present in registrations.
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// NewChallenge generates a random challenge of a ceremony.
+func NewChallenge() (Base64URL, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, errors.Wrap(err, "failed to generate challenge")
+	}
+	return challenge, nil
+}
+
+// CreationOptions returns the options to register a new credential of the user.
+func (rp *RelyingParty) CreationOptions(challenge Base64URL, user UserEntity, excludeCredentials []Base64URL) *CreationOptions {
+	options := &CreationOptions{
+		Challenge: challenge,
+		RelyingParty: RelyingPartyEntity{
+			ID:   rp.ID,
+			Name: rp.Name,
+		},
+		User: user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: algES256},
+			{Type: "public-key", Alg: algEdDSA},
+			{Type: "public-key", Alg: algRS256},
+		},
+		Timeout:            Timeout,
+		ExcludeCredentials: []CredentialDescriptor{},
+		AuthenticatorSelection: &AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+	for _, id := range excludeCredentials {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return options
+}
+
+// RequestOptions returns the options to sign in, an empty allow list lets the user pick a discoverable credential.
+func (rp *RelyingParty) RequestOptions(challenge Base64URL, allowCredentials []Base64URL) *RequestOptions {
+	options := &RequestOptions{
+		Challenge:        challenge,
+		RelyingPartyID:   rp.ID,
+		Timeout:          Timeout,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "preferred",
+	}
+	for _, id := range allowCredentials {
+		options.AllowCredentials = append(options.AllowCredentials, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return options
+}
+
+// ParseChallenge returns the challenge in the client data of the response to find the ceremony it belongs to.
+func ParseChallenge(clientDataJSON []byte) (string, error) {
+	data := &clientData{}
+	if err := json.Unmarshal(clientDataJSON, data); err != nil {
+		return "", errors.Wrap(err, "invalid client data")
+	}
+	return data.Challenge, nil
+}
+
+// VerifyRegistration verifies the response of the registration ceremony and returns the created credential.
+func (rp *RelyingParty) VerifyRegistration(response *RegistrationResponse, challenge Base64URL) (*Credential, error) {
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	attestation := &attestationObject{}
+	if err := cbor.Unmarshal(response.Response.AttestationObject, attestation); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation object")
+	}
+	authData, err := rp.parseAuthenticatorData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.Flags&flagAttestedCredentialData == 0 || len(authData.CredentialID) == 0 {
+		return nil, errors.New("missing attested credential data")
+	}
+	if !bytes.Equal(authData.CredentialID, response.RawID) {
+		return nil, errors.New("credential id mismatch")
+	}
+	if _, err := parsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// Assertion is the verified response of the authentication ceremony.
+type Assertion struct {
+	// SignCount is the new sign count of the credential.
+	SignCount uint32
+	// UserVerified is whether the authenticator verified the user, such as by a PIN or biometrics, besides the possession of the credential.
+	UserVerified bool
+}
+
+// VerifyAssertion verifies the response of the authentication ceremony with the credential.
+func (rp *RelyingParty) VerifyAssertion(response *AssertionResponse, challenge Base64URL, credential *Credential) (*Assertion, error) {
+	if !bytes.Equal(response.RawID, credential.ID) {
+		return nil, errors.New("credential id mismatch")
+	}
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+	authData, err := rp.parseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signedData := append(append([]byte{}, response.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := publicKey.verify(signedData, response.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// A sign count not increasing means the authenticator might be cloned, unless the authenticator does not count.
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return nil, errors.Errorf("sign count %d is not greater than %d", authData.SignCount, credential.SignCount)
+	}
+	return &Assertion{
+		SignCount:    authData.SignCount,
+		UserVerified: authData.Flags&flagUserVerified != 0,
+	}, nil
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremonyType string, challenge Base64URL) error {
+	data := &clientData{}
+	if err := json.Unmarshal(clientDataJSON, data); err != nil {
+		return errors.Wrap(err, "invalid client data")
+	}
+	if data.Type != ceremonyType {
+		return errors.Errorf("unexpected client data type %q", data.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge.String())) != 1 {
+		return errors.New("challenge mismatch")
+	}
+	if data.Origin != rp.Origin {
+		return errors.Errorf("unexpected origin %q, expected %q", data.Origin, rp.Origin)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+	authData := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return nil, errors.New("relying party id hash mismatch")
+	}
+	if authData.Flags&flagUserPresent == 0 {
+		return nil, errors.New("user is not present")
+	}
+
+	if authData.Flags&flagAttestedCredentialData != 0 {
+		// AAGUID (16 bytes), credential id length (2 bytes), credential id and the COSE public key.
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		credentialIDLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < credentialIDLength {
+			return nil, errors.New("credential id is too short")
+		}
+		authData.CredentialID = rest[:credentialIDLength]
+
+		publicKey := cbor.RawMessage{}
+		if err := cbor.NewDecoder(bytes.NewReader(rest[credentialIDLength:])).Decode(&publicKey); err != nil {
+			return nil, errors.Wrap(err, "invalid credential public key")
+		}
+		authData.PublicKey = publicKey
+	}
+	return authData, nil
+}
@@ -0,0 +1,76 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/test"
+)
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("memos", "https://memos.example.com:8443/path")
+	require.NoError(t, err)
+	require.Equal(t, &RelyingParty{ID: "memos.example.com", Name: "memos", Origin: "https://memos.example.com:8443"}, rp)
+
+	_, err = NewRelyingParty("memos", "memos.example.com")
+	require.Error(t, err)
+}
+
+func TestCeremonies(t *testing.T) {
+	rp, err := NewRelyingParty("memos", "https://memos.example.com")
+	require.NoError(t, err)
+	authenticator := test.NewWebAuthnAuthenticator(t, rp.Origin, rp.ID)
+
+	challenge, err := NewChallenge()
+	require.NoError(t, err)
+	registration := &RegistrationResponse{}
+	require.NoError(t, json.Unmarshal(authenticator.Register(t, challenge.String(), []byte("1")), registration))
+
+	// The registration is bound to the challenge and the origin.
+	otherChallenge, err := NewChallenge()
+	require.NoError(t, err)
+	_, err = rp.VerifyRegistration(registration, otherChallenge)
+	require.ErrorContains(t, err, "challenge mismatch")
+	otherRP := &RelyingParty{ID: rp.ID, Origin: "https://evil.example.com"}
+	_, err = otherRP.VerifyRegistration(registration, challenge)
+	require.ErrorContains(t, err, "unexpected origin")
+	otherRP = &RelyingParty{ID: "evil.example.com", Origin: rp.Origin}
+	_, err = otherRP.VerifyRegistration(registration, challenge)
+	require.ErrorContains(t, err, "relying party id hash mismatch")
+
+	credential, err := rp.VerifyRegistration(registration, challenge)
+	require.NoError(t, err)
+	require.Equal(t, authenticator.CredentialID(), credential.ID.String())
+	require.Equal(t, uint32(0), credential.SignCount)
+
+	challenge, err = NewChallenge()
+	require.NoError(t, err)
+	assertion := &AssertionResponse{}
+	require.NoError(t, json.Unmarshal(authenticator.Assert(t, challenge.String()), assertion))
+	// The assertion is bound to the challenge.
+	_, err = rp.VerifyAssertion(assertion, otherChallenge, credential)
+	require.ErrorContains(t, err, "challenge mismatch")
+	verified, err := rp.VerifyAssertion(assertion, challenge, credential)
+	require.NoError(t, err)
+	require.Equal(t, &Assertion{SignCount: 1, UserVerified: true}, verified)
+
+	// A tampered signature is rejected.
+	tampered := *assertion
+	tampered.Response.Signature = append(Base64URL{}, assertion.Response.Signature...)
+	tampered.Response.Signature[len(tampered.Response.Signature)-1] ^= 0xff
+	_, err = rp.VerifyAssertion(&tampered, challenge, credential)
+	require.Error(t, err)
+
+	// A sign count not increasing indicates a cloned authenticator.
+	credential.SignCount = verified.SignCount
+	_, err = rp.VerifyAssertion(assertion, challenge, credential)
+	require.ErrorContains(t, err, "sign count")
+
+	// An authenticator without user verification only proves the possession of the credential.
+	authenticator.SkipUserVerification = true
+	require.NoError(t, json.Unmarshal(authenticator.Assert(t, challenge.String()), assertion))
+	verified, err = rp.VerifyAssertion(assertion, challenge, credential)
+	require.NoError(t, err)
+	require.False(t, verified.UserVerified)
+}
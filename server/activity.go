@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+// activityPruneInterval is how often the activities are pruned by the retention setting.
+const activityPruneInterval = time.Hour
+
+func autoPruneActivities(ctx context.Context, s *store.Store) {
+	ticker := time.NewTicker(activityPruneInterval)
+	defer ticker.Stop()
+
+	var t time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stop pruning activities graceful.")
+			return
+		case t = <-ticker.C:
+		}
+
+		deletedCount, err := apiv1.PruneActivities(ctx, s, t.Unix())
+		if err != nil {
+			log.Error("fail to prune activities", zap.Error(err))
+			continue
+		}
+		if deletedCount > 0 {
+			log.Info(fmt.Sprintf("pruned %d activities", deletedCount))
+		}
+	}
+}
@@ -2,30 +2,35 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strconv"
 	"time"
 
+	"github.com/pkg/errors"
 	apiv1 "github.com/usememos/memos/api/v1"
 	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/store"
 	"go.uber.org/zap"
 )
 
+var backupLogger = log.For(log.SubsystemBackup)
+
 func autoBackup(ctx context.Context, s *store.Store) {
 	intervalStr := s.GetSystemSettingValueWithDefault(&ctx, apiv1.SystemSettingAutoBackupIntervalName.String(), "")
 	if intervalStr == "" {
-		log.Info("no SystemSettingAutoBackupIntervalName setting, disable auto backup")
+		backupLogger.Info("no SystemSettingAutoBackupIntervalName setting, disable auto backup")
 		return
 	}
 
 	interval, err := strconv.Atoi(intervalStr)
 	if err != nil || interval <= 0 {
-		log.Error(fmt.Sprintf("invalid SystemSettingAutoBackupIntervalName value %s, disable auto backup", intervalStr), zap.Error(err))
+		backupLogger.Error(fmt.Sprintf("invalid SystemSettingAutoBackupIntervalName value %s, disable auto backup", intervalStr), zap.Error(err))
 		return
 	}
 
-	log.Info("enable auto backup every " + intervalStr + " seconds")
+	backupLogger.Info("enable auto backup every " + intervalStr + " seconds")
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
 	defer ticker.Stop()
 
@@ -33,16 +38,49 @@ func autoBackup(ctx context.Context, s *store.Store) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("stop auto backup graceful.")
+			backupLogger.Info("stop auto backup graceful.")
 			return
 		case t = <-ticker.C:
 		}
 
 		filename := s.Profile.DSN + t.Format("-20060102-150405.bak")
-		log.Info(fmt.Sprintf("create backup to %s", filename))
+		backupLogger.Info(fmt.Sprintf("create backup to %s", filename))
 		err := s.BackupTo(ctx, filename)
 		if err != nil {
-			log.Error("fail to create backup", zap.Error(err))
+			backupLogger.Error("fail to create backup", zap.Error(err))
+			backupLastFailureTimestampSeconds.Set(float64(time.Now().Unix()))
+		} else {
+			backupLastSuccessTimestampSeconds.Set(float64(time.Now().Unix()))
+		}
+		if err := createServerBackupActivity(ctx, s, filename, err); err != nil {
+			backupLogger.Error("fail to create backup activity", zap.Error(err))
 		}
 	}
 }
+
+// createServerBackupActivity records the result of the backup, which is reported by the system stats.
+func createServerBackupActivity(ctx context.Context, s *store.Store, filename string, backupErr error) error {
+	payload := apiv1.ActivityServerBackupPayload{
+		Filename: filename,
+	}
+	level := apiv1.ActivityInfo
+	if backupErr != nil {
+		payload.Error = backupErr.Error()
+		level = apiv1.ActivityError
+	} else if fileInfo, err := os.Stat(filename); err == nil {
+		payload.Size = fileInfo.Size()
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	if _, err := s.CreateActivity(ctx, &store.Activity{
+		CreatorID: apiv1.UnknownID,
+		Type:      apiv1.ActivityServerBackup.String(),
+		Level:     level.String(),
+		Payload:   string(payloadBytes),
+	}); err != nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return nil
+}
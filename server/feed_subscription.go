@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+// feedSubscriptionCheckInterval is how often the subscriptions are checked for being due to poll.
+const feedSubscriptionCheckInterval = time.Minute
+
+func autoPollFeedSubscriptions(ctx context.Context, s *store.Store) {
+	ticker := time.NewTicker(feedSubscriptionCheckInterval)
+	defer ticker.Stop()
+
+	var t time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stop polling feed subscriptions graceful.")
+			return
+		case t = <-ticker.C:
+		}
+
+		pollDueFeedSubscriptions(ctx, s, t.Unix())
+	}
+}
+
+func pollDueFeedSubscriptions(ctx context.Context, s *store.Store, currentTs int64) {
+	normalStatus := store.Normal
+	list, err := s.ListFeedSubscriptions(ctx, &store.FindFeedSubscription{
+		RowStatus: &normalStatus,
+	})
+	if err != nil {
+		log.Error("fail to list feed subscriptions", zap.Error(err))
+		return
+	}
+
+	for _, feedSubscription := range list {
+		if feedSubscription.LastPolledTs+int64(feedSubscription.PollInterval) > currentTs {
+			continue
+		}
+		importedCount, err := apiv1.PollFeedSubscription(ctx, s, feedSubscription)
+		if err != nil {
+			log.Error(fmt.Sprintf("fail to poll feed subscription %d", feedSubscription.ID), zap.Error(err))
+			// Wait for the next interval rather than retrying a broken feed on every check.
+			if _, err := s.UpdateFeedSubscription(ctx, &store.UpdateFeedSubscription{
+				ID:           feedSubscription.ID,
+				LastPolledTs: &currentTs,
+			}); err != nil {
+				log.Error("fail to update feed subscription", zap.Error(err))
+			}
+			continue
+		}
+		if importedCount > 0 {
+			log.Info(fmt.Sprintf("imported %d entries from feed %s", importedCount, feedSubscription.URL))
+		}
+	}
+}
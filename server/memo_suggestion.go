@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
+)
+
+// memoSuggestionInterval is how often the memos are processed for AI suggestions.
+const memoSuggestionInterval = 5 * time.Minute
+
+func autoProcessMemoSuggestions(ctx context.Context, s *store.Store) {
+	ticker := time.NewTicker(memoSuggestionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stop processing memo suggestions graceful.")
+			return
+		case <-ticker.C:
+		}
+
+		processMemoSuggestions(ctx, s)
+	}
+}
+
+func processMemoSuggestions(ctx context.Context, s *store.Store) {
+	userSettingList, err := s.ListUserSettings(ctx, &store.FindUserSetting{
+		Key: apiv1.UserSettingAIPostProcessingKey.String(),
+	})
+	if err != nil {
+		log.Error("fail to list user settings", zap.Error(err))
+		return
+	}
+
+	for _, userSetting := range userSettingList {
+		if userSetting.Value != "true" {
+			continue
+		}
+		processedCount, err := apiv1.ProcessMemoSuggestions(ctx, s, userSetting.UserID)
+		if err != nil {
+			log.Error(fmt.Sprintf("fail to process memo suggestions of user %d", userSetting.UserID), zap.Error(err))
+			continue
+		}
+		if processedCount > 0 {
+			log.Info(fmt.Sprintf("processed %d memos for suggestions of user %d", processedCount, userSetting.UserID))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/metrics"
+	"go.uber.org/zap"
+)
+
+var (
+	httpRequestsTotal          = metrics.NewCounterVec("memos_http_requests_total", "HTTP requests by method, route and status.", "method", "route", "status")
+	httpRequestDurationSeconds = metrics.NewHistogramVec("memos_http_request_duration_seconds", "Duration of the HTTP requests by method and route.", nil, "method", "route")
+
+	backupLastSuccessTimestampSeconds = metrics.NewGaugeVec("memos_backup_last_success_timestamp_seconds", "Time of the last successful auto backup.")
+	backupLastFailureTimestampSeconds = metrics.NewGaugeVec("memos_backup_last_failure_timestamp_seconds", "Time of the last failed auto backup.")
+)
+
+// metricsMiddleware records the requests by their route pattern rather than their path to keep the label values bounded.
+func metricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			// The error is written to the response by the error handler after the middlewares.
+			status = http.StatusInternalServerError
+			if httpError, ok := err.(*echo.HTTPError); ok {
+				status = httpError.Code
+			}
+		}
+		method, route := c.Request().Method, c.Path()
+		httpRequestsTotal.Inc(method, route, strconv.Itoa(status))
+		httpRequestDurationSeconds.Observe(time.Since(start).Seconds(), method, route)
+		return err
+	}
+}
+
+// newMetricsHandler serves the metrics, requiring the token as the bearer token if not empty.
+func newMetricsHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(echo.HeaderAuthorization)), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "Invalid metrics token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set(echo.HeaderContentType, metrics.ContentType)
+		if err := metrics.Write(w); err != nil {
+			log.Warn("failed to write metrics", zap.Error(err))
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/spf13/viper"
+	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/server/version"
 )
 
@@ -23,12 +24,60 @@ type Profile struct {
 	DSN string `json:"-"`
 	// Version is the current version of server
 	Version string `json:"version"`
+	// MetricsAddr is the address of a separate listener serving the metrics, such as "127.0.0.1:9090"
+	MetricsAddr string `json:"-" mapstructure:"metrics-addr"`
+	// MetricsToken is the bearer token required to read the metrics, they are served on the main port behind it if MetricsAddr is empty
+	MetricsToken string `json:"-" mapstructure:"metrics-token"`
+	// LogFormat is the format of the logs, "console" or "json"
+	LogFormat string `json:"-" mapstructure:"log-format"`
+	// LogLevel is the level of the logs, such as "info"
+	LogLevel string `json:"-" mapstructure:"log-level"`
+	// LogLevels are the levels of the subsystems overriding LogLevel, such as "store=debug,telegram=warn"
+	LogLevels string `json:"-" mapstructure:"log-levels"`
+	// LogFile is whether the logs are also written to files rotated by size in the data directory
+	LogFile bool `json:"-" mapstructure:"log-file"`
+	// LogFileMaxSize is the size in megabytes at which the log file is rotated
+	LogFileMaxSize int `json:"-" mapstructure:"log-file-max-size"`
+	// LogFileMaxBackups is the number of rotated log files kept
+	LogFileMaxBackups int `json:"-" mapstructure:"log-file-max-backups"`
+	// TracingExporter is the exporter of the traces, "otlp" or "stdout", the tracing is disabled if empty
+	TracingExporter string `json:"-" mapstructure:"tracing-exporter"`
+	// TracingEndpoint is the URL of the OTLP collector, such as "http://localhost:4318"
+	TracingEndpoint string `json:"-" mapstructure:"tracing-endpoint"`
+	// TracingSampleRatio is the ratio of the traces started by the server that are sampled, between 0 and 1
+	TracingSampleRatio float64 `json:"-" mapstructure:"tracing-sample-ratio"`
 }
 
 func (p *Profile) IsDev() bool {
 	return p.Mode != "prod"
 }
 
+// GetLogConfig returns the logging configuration of the profile.
+func (p *Profile) GetLogConfig() (*log.Config, error) {
+	config := &log.Config{
+		Format:          p.LogFormat,
+		Level:           p.LogLevel,
+		SubsystemLevels: map[string]string{},
+	}
+	for _, item := range strings.Split(p.LogLevels, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		subsystem, level, ok := strings.Cut(item, "=")
+		if !ok || subsystem == "" {
+			return nil, fmt.Errorf("invalid subsystem log level %q, should be like store=debug", item)
+		}
+		config.SubsystemLevels[strings.TrimSpace(subsystem)] = strings.TrimSpace(level)
+	}
+	if p.LogFile {
+		config.File = filepath.Join(p.Data, "logs", "memos.log")
+		config.FileMaxSize = p.LogFileMaxSize
+		config.FileMaxBackups = p.LogFileMaxBackups
+	}
+	return config, nil
+}
+
 func checkDSN(dataDir string) (string, error) {
 	// Convert to absolute path if relative path is supplied.
 	if !filepath.IsAbs(dataDir) {
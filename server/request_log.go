@@ -0,0 +1,51 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/tracing"
+	"go.uber.org/zap"
+)
+
+var httpLogger = log.For(log.SubsystemHTTP)
+
+// setRequestID carries the request ID by the context of the request down to the store, so that the logs of a request share it.
+func setRequestID(c echo.Context, requestID string) {
+	request := c.Request()
+	c.SetRequest(request.WithContext(log.ContextWithRequestID(request.Context(), requestID)))
+}
+
+// logRequest logs the request with its request ID and trace ID, and the error of the handler including its internal error if any.
+func logRequest(c echo.Context, v middleware.RequestLoggerValues) error {
+	status := v.Status
+	httpError := &echo.HTTPError{}
+	if v.Error != nil && !c.Response().Committed && !errors.As(v.Error, &httpError) {
+		// The error is turned into an internal server error by the error handler after the middlewares.
+		status = http.StatusInternalServerError
+	}
+	fields := []zap.Field{
+		zap.String("request_id", v.RequestID),
+		zap.String("method", v.Method),
+		zap.String("uri", v.URI),
+		zap.String("route", v.RoutePath),
+		zap.Int("status", status),
+		zap.Duration("latency", v.Latency),
+		zap.String("remote_ip", v.RemoteIP),
+	}
+	if traceID := tracing.TraceID(c.Request().Context()); traceID != "" {
+		fields = append(fields, zap.String("trace_id", traceID))
+	}
+	if v.Error != nil {
+		fields = append(fields, zap.Error(v.Error))
+	}
+	if status >= http.StatusInternalServerError {
+		httpLogger.Error("request", fields...)
+	} else {
+		httpLogger.Info("request", fields...)
+	}
+	return nil
+}
@@ -10,10 +10,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/common/log"
 	"github.com/usememos/memos/common/util"
 	"github.com/usememos/memos/plugin/telegram"
+	"github.com/usememos/memos/plugin/tracing"
 	"github.com/usememos/memos/server/profile"
 	"github.com/usememos/memos/store"
+	"go.uber.org/zap"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -28,6 +31,10 @@ type Server struct {
 	Store   *store.Store
 
 	telegramBot *telegram.Bot
+	// metricsServer serves the metrics on their own address if set.
+	metricsServer *http.Server
+	// shutdownTracing flushes the pending spans and disables the tracing.
+	shutdownTracing func(context.Context) error
 }
 
 func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store) (*Server, error) {
@@ -42,15 +49,41 @@ func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store
 		Profile: profile,
 	}
 
+	shutdownTracing, err := tracing.Initialize(ctx, &tracing.Config{
+		Exporter:       profile.TracingExporter,
+		Endpoint:       profile.TracingEndpoint,
+		SampleRatio:    profile.TracingSampleRatio,
+		ServiceVersion: profile.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	s.shutdownTracing = shutdownTracing
+
 	telegramBotHandler := newTelegramHandler(store)
 	s.telegramBot = telegram.NewBotWithHandler(telegramBotHandler)
 
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: `{"time":"${time_rfc3339}",` +
-			`"method":"${method}","uri":"${uri}",` +
-			`"status":${status},"error":"${error}"}` + "\n",
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator:        uuid.NewString,
+		RequestIDHandler: setRequestID,
 	}))
 
+	e.Use(tracingMiddleware)
+
+	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogLatency:    true,
+		LogRemoteIP:   true,
+		LogMethod:     true,
+		LogURI:        true,
+		LogRoutePath:  true,
+		LogRequestID:  true,
+		LogStatus:     true,
+		LogError:      true,
+		LogValuesFunc: logRequest,
+	}))
+
+	e.Use(metricsMiddleware)
+
 	e.Use(middleware.Gzip())
 
 	e.Use(middleware.CORS())
@@ -65,9 +98,9 @@ func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store
 
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
 		Skipper: func(c echo.Context) bool {
-			// this is a hack to skip timeout for openai chat streaming
+			// this is a hack to skip timeout for the ai chat streaming, including its legacy openai route,
 			// because streaming require to flush response. But the timeout middleware will break it.
-			return c.Request().URL.Path == "/api/v1/openai/chat-streaming"
+			return util.HasPrefixes(c.Request().URL.Path, "/api/v1/ai/chat-streaming", "/api/v1/openai/chat-streaming")
 		},
 		ErrorMessage: "Request timeout",
 		Timeout:      30 * time.Second,
@@ -79,6 +112,19 @@ func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store
 	}
 	s.ID = serverID
 
+	// The metrics are opt-in, served either on their own address or behind a token.
+	if profile.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", newMetricsHandler(profile.MetricsToken))
+		s.metricsServer = &http.Server{
+			Addr:              profile.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	} else if profile.MetricsToken != "" {
+		e.GET("/metrics", echo.WrapHandler(newMetricsHandler(profile.MetricsToken)))
+	}
+
 	embedFrontend(e)
 
 	secret := "usememos"
@@ -92,6 +138,7 @@ func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store
 
 	rootGroup := e.Group("")
 	apiV1Service := apiv1.NewAPIV1Service(s.Secret, profile, store)
+	apiV1Service.TelegramBot = s.telegramBot
 	apiV1Service.Register(rootGroup)
 
 	return s, nil
@@ -104,6 +151,16 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go s.telegramBot.Start(ctx)
 	go autoBackup(ctx, s.Store)
+	go autoPollFeedSubscriptions(ctx, s.Store)
+	go autoProcessMemoSuggestions(ctx, s.Store)
+	go autoPruneActivities(ctx, s.Store)
+	if s.metricsServer != nil {
+		go func() {
+			if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("failed to start metrics server", zap.Error(err))
+			}
+		}()
+	}
 
 	return s.e.Start(fmt.Sprintf(":%d", s.Profile.Port))
 }
@@ -117,6 +174,16 @@ func (s *Server) Shutdown(ctx context.Context) {
 		fmt.Printf("failed to shutdown server, error: %v\n", err)
 	}
 
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown metrics server", zap.Error(err))
+		}
+	}
+
+	if err := s.shutdownTracing(ctx); err != nil {
+		log.Error("failed to shutdown tracing", zap.Error(err))
+	}
+
 	// Close database connection
 	if err := s.Store.GetDB().Close(); err != nil {
 		fmt.Printf("failed to close database, error: %v\n", err)
@@ -194,5 +261,5 @@ func defaultGetRequestSkipper(c echo.Context) bool {
 
 func defaultAPIRequestSkipper(c echo.Context) bool {
 	path := c.Path()
-	return util.HasPrefixes(path, "/api", "/api/v1")
+	return util.HasPrefixes(path, "/api", "/api/v1", "/scim")
 }
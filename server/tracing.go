@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/usememos/memos/plugin/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware traces the requests in server spans named by their route pattern,
+// continuing the traces of the incoming traceparent headers.
+func tracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !tracing.Enabled() {
+			return next(c)
+		}
+		request := c.Request()
+		method, route := request.Method, c.Path()
+		ctx := tracing.Extract(request.Context(), request.Header)
+		ctx, span := tracing.Start(ctx, method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPMethod(method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPTarget(request.URL.Path),
+		))
+		defer span.End()
+		c.SetRequest(request.WithContext(ctx))
+
+		err := next(c)
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			// The error is written to the response by the error handler after the middlewares.
+			status = http.StatusInternalServerError
+			if httpError, ok := err.(*echo.HTTPError); ok {
+				status = httpError.Code
+			}
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 )
 
 type Activity struct {
@@ -17,6 +19,24 @@ type Activity struct {
 	Payload string
 }
 
+type FindActivity struct {
+	ID        *int
+	CreatorID *int
+	// TypeList matches any of the types if not empty.
+	TypeList []string
+	Level    *string
+	// CreatedTsAfter is inclusive and CreatedTsBefore is exclusive.
+	CreatedTsAfter  *int64
+	CreatedTsBefore *int64
+	// IDBefore is the cursor of the pagination, the activities are listed by descending ID.
+	IDBefore *int
+	Limit    *int
+}
+
+type DeleteActivity struct {
+	CreatedTsBefore *int64
+}
+
 func (s *Store) CreateActivity(ctx context.Context, create *Activity) (*Activity, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -48,3 +68,121 @@ func (s *Store) CreateActivity(ctx context.Context, create *Activity) (*Activity
 	activity := create
 	return activity, nil
 }
+
+func (s *Store) ListActivities(ctx context.Context, find *FindActivity) ([]*Activity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listActivities(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// DeleteActivities deletes the activities matching the delete and returns the number of deleted ones.
+func (s *Store) DeleteActivities(ctx context.Context, delete *DeleteActivity) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := delete.CreatedTsBefore; v != nil {
+		where, args = append(where, "created_ts < ?"), append(args, *v)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM activity WHERE `+strings.Join(where, " AND "), args...)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, nil
+}
+
+func listActivities(ctx context.Context, tx *sql.Tx, find *FindActivity) ([]*Activity, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if len(find.TypeList) > 0 {
+		placeholders := []string{}
+		for _, activityType := range find.TypeList {
+			placeholders, args = append(placeholders, "?"), append(args, activityType)
+		}
+		where = append(where, "type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if v := find.Level; v != nil {
+		where, args = append(where, "level = ?"), append(args, *v)
+	}
+	if v := find.CreatedTsAfter; v != nil {
+		where, args = append(where, "created_ts >= ?"), append(args, *v)
+	}
+	if v := find.CreatedTsBefore; v != nil {
+		where, args = append(where, "created_ts < ?"), append(args, *v)
+	}
+	if v := find.IDBefore; v != nil {
+		where, args = append(where, "id < ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			type,
+			level,
+			payload
+		FROM activity
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id DESC`
+	if v := find.Limit; v != nil {
+		query, args = query+" LIMIT ?", append(args, *v)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*Activity, 0)
+	for rows.Next() {
+		activity := &Activity{}
+		if err := rows.Scan(
+			&activity.ID,
+			&activity.CreatorID,
+			&activity.CreatedTs,
+			&activity.Type,
+			&activity.Level,
+			&activity.Payload,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, activity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
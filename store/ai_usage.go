@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// AIUsage records the tokens consumed by a single AI request of a user.
+type AIUsage struct {
+	ID int
+
+	// Standard fields
+	UserID    int
+	CreatedTs int64
+
+	// Domain specific fields
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type FindAIUsage struct {
+	UserID *int
+	// CreatedTsAfter filters the usages created at or after the timestamp.
+	CreatedTsAfter *int64
+}
+
+func (s *Store) CreateAIUsage(ctx context.Context, create *AIUsage) (*AIUsage, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ai_usage (
+			user_id,
+			provider,
+			model,
+			prompt_tokens,
+			completion_tokens
+		)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.UserID,
+		create.Provider,
+		create.Model,
+		create.PromptTokens,
+		create.CompletionTokens,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	aiUsage := create
+	return aiUsage, nil
+}
+
+func (s *Store) ListAIUsages(ctx context.Context, find *FindAIUsage) ([]*AIUsage, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.CreatedTsAfter; v != nil {
+		where, args = append(where, "created_ts >= ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			provider,
+			model,
+			prompt_tokens,
+			completion_tokens
+		FROM ai_usage
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts DESC, id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*AIUsage, 0)
+	for rows.Next() {
+		aiUsage := &AIUsage{}
+		if err := rows.Scan(
+			&aiUsage.ID,
+			&aiUsage.UserID,
+			&aiUsage.CreatedTs,
+			&aiUsage.Provider,
+			&aiUsage.Model,
+			&aiUsage.PromptTokens,
+			&aiUsage.CompletionTokens,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, aiUsage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumAIUsage(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		ai_usage
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
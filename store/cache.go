@@ -2,8 +2,24 @@ package store
 
 import (
 	"fmt"
+	"sync"
+
+	"github.com/usememos/memos/plugin/metrics"
 )
 
+var cacheLookupsTotal = metrics.NewCounterVec("memos_store_cache_lookups_total", "Lookups of the store caches by cache and result, hit or miss.", "cache", "result")
+
 func getUserSettingCacheKey(userID int, key string) string {
 	return fmt.Sprintf("%d-%s", userID, key)
 }
+
+// loadCache looks up the key in the cache, counting the lookup as a hit or a miss of the named cache.
+func loadCache(cache *sync.Map, name string, key any) (any, bool) {
+	value, ok := cache.Load(key)
+	result := "miss"
+	if ok {
+		result = "hit"
+	}
+	cacheLookupsTotal.Inc(name, result)
+	return value, ok
+}
@@ -0,0 +1,327 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// CustomRole is a role defined by the admins granting its permissions to the users assigned to it,
+// in addition to the permissions of their built-in role.
+type CustomRole struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name        string
+	Description string
+	Permissions []string
+}
+
+type FindCustomRole struct {
+	ID   *int
+	Name *string
+	// UserID finds the custom roles assigned to the user.
+	UserID *int
+	// GroupID finds the custom roles assigned to the group.
+	GroupID *int
+	// GroupMemberID finds the custom roles assigned to the groups of the user.
+	GroupMemberID *int
+}
+
+type UpdateCustomRole struct {
+	ID          int
+	UpdatedTs   *int64
+	Name        *string
+	Description *string
+	Permissions []string
+}
+
+type DeleteCustomRole struct {
+	ID int
+}
+
+func (s *Store) CreateCustomRole(ctx context.Context, create *CustomRole) (*CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	permissionsBytes, err := json.Marshal(create.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO custom_role (
+			creator_id,
+			name,
+			description,
+			permissions
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, created_ts, updated_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.CreatorID,
+		create.Name,
+		create.Description,
+		string(permissionsBytes),
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	customRole := create
+	return customRole, nil
+}
+
+func (s *Store) ListCustomRoles(ctx context.Context, find *FindCustomRole) ([]*CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listCustomRoles(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetCustomRole(ctx context.Context, find *FindCustomRole) (*CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listCustomRoles(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateCustomRole(ctx context.Context, update *UpdateCustomRole) (*CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := update.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	if v := update.Permissions; v != nil {
+		permissionsBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		set, args = append(set, "permissions = ?"), append(args, string(permissionsBytes))
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE custom_role
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, name, description, permissions
+	`
+	customRole := &CustomRole{}
+	var permissions string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&customRole.ID,
+		&customRole.CreatorID,
+		&customRole.CreatedTs,
+		&customRole.UpdatedTs,
+		&customRole.Name,
+		&customRole.Description,
+		&permissions,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(permissions), &customRole.Permissions); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return customRole, nil
+}
+
+// DeleteCustomRole deletes the custom role and unassigns it from the users and groups.
+func (s *Store) DeleteCustomRole(ctx context.Context, delete *DeleteCustomRole) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_custom_role WHERE role_id = ?`, delete.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_group_custom_role WHERE role_id = ?`, delete.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM custom_role WHERE id = ?`, delete.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetUserCustomRoles replaces the custom roles assigned to the user.
+func (s *Store) SetUserCustomRoles(ctx context.Context, userID int, roleIDList []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_custom_role WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDList {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO user_custom_role (user_id, role_id) VALUES (?, ?)`, userID, roleID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listCustomRoles(ctx context.Context, tx *sql.Tx, find *FindCustomRole) ([]*CustomRole, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.Name; v != nil {
+		where, args = append(where, "name = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "id IN (SELECT role_id FROM user_custom_role WHERE user_id = ?)"), append(args, *v)
+	}
+	if v := find.GroupID; v != nil {
+		where, args = append(where, "id IN (SELECT role_id FROM user_group_custom_role WHERE group_id = ?)"), append(args, *v)
+	}
+	if v := find.GroupMemberID; v != nil {
+		where, args = append(where, "id IN (SELECT role_id FROM user_group_custom_role WHERE group_id IN (SELECT group_id FROM user_group_member WHERE user_id = ?))"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			name,
+			description,
+			permissions
+		FROM custom_role
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*CustomRole, 0)
+	for rows.Next() {
+		customRole := &CustomRole{}
+		var permissions string
+		if err := rows.Scan(
+			&customRole.ID,
+			&customRole.CreatorID,
+			&customRole.CreatedTs,
+			&customRole.UpdatedTs,
+			&customRole.Name,
+			&customRole.Description,
+			&permissions,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(permissions), &customRole.Permissions); err != nil {
+			return nil, err
+		}
+		list = append(list, customRole)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumUserCustomRole(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_custom_role
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR role_id NOT IN (
+			SELECT
+				id
+			FROM
+				custom_role
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/usememos/memos/common/log"
+	"github.com/usememos/memos/plugin/metrics"
+	"github.com/usememos/memos/plugin/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"modernc.org/sqlite"
+)
+
+const (
+	// instrumentedDriverName is the sqlite driver timing and tracing the queries of the store.
+	instrumentedDriverName = "sqlite-instrumented"
+	// storePackagePath is the prefix of the functions of the store, including the ones of this package.
+	storePackagePath = "github.com/usememos/memos/store"
+)
+
+var logger = log.For(log.SubsystemStore)
+
+var (
+	queryDurationSeconds = metrics.NewHistogramVec("memos_db_query_duration_seconds", "Duration of the SQLite queries by statement.", nil, "statement")
+	queryErrorsTotal     = metrics.NewCounterVec("memos_db_query_errors_total", "Failed SQLite queries by statement.", "statement")
+)
+
+func init() {
+	sql.Register(instrumentedDriverName, &instrumentedDriver{
+		driver: &sqlite.Driver{},
+	})
+}
+
+// sqliteConn is the connection of the sqlite driver, which is also used for backups.
+type sqliteConn interface {
+	driver.Conn
+	driver.Pinger
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	NewBackup(string) (*sqlite.Backup, error)
+}
+
+type instrumentedDriver struct {
+	driver driver.Driver
+}
+
+func (d *instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(sqliteConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected sqlite connection %T", conn)
+	}
+	return &instrumentedConn{sqliteConn: c}, nil
+}
+
+// instrumentedConn times and traces the queries executed directly on the connection, which are all the queries of the store.
+type instrumentedConn struct {
+	sqliteConn
+
+	// txCtx carries the span of the transaction in progress when traced, the connection is used by one goroutine at a time.
+	txCtx context.Context
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if !tracing.Enabled() {
+		return c.sqliteConn.BeginTx(ctx, opts)
+	}
+	// The span of the transaction is named after the store method, so that it groups the queries of the method.
+	ctx, span := tracing.Start(ctx, getStoreFunction())
+	tx, err := c.sqliteConn.BeginTx(ctx, opts)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	c.txCtx = ctx
+	return &tracedTx{Tx: tx, conn: c, span: span}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	span := c.startQuerySpan(ctx, query)
+	result, err := c.sqliteConn.ExecContext(ctx, query, args)
+	observeQuery(ctx, query, start, err)
+	if span != nil {
+		tracing.End(span, err)
+	}
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	span := c.startQuerySpan(ctx, query)
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	observeQuery(ctx, query, start, err)
+	if span != nil {
+		tracing.End(span, err)
+	}
+	return rows, err
+}
+
+// startQuerySpan starts the span of the query as a child of the transaction in progress, nil if not traced.
+func (c *instrumentedConn) startQuerySpan(ctx context.Context, query string) trace.Span {
+	if !tracing.Enabled() {
+		return nil
+	}
+	name := strings.ToUpper(getStatement(query))
+	if c.txCtx != nil {
+		ctx = c.txCtx
+	} else {
+		name = getStoreFunction() + " " + name
+	}
+	_, span := tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemSqlite,
+		semconv.DBOperation(getStatement(query)),
+		semconv.DBStatement(strings.Join(strings.Fields(query), " ")),
+	))
+	return span
+}
+
+// tracedTx ends the span of the transaction when it is committed or rolled back.
+type tracedTx struct {
+	driver.Tx
+	conn *instrumentedConn
+	span trace.Span
+}
+
+func (t *tracedTx) Commit() error {
+	err := t.Tx.Commit()
+	t.end(err)
+	return err
+}
+
+func (t *tracedTx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.end(err)
+	return err
+}
+
+func (t *tracedTx) end(err error) {
+	t.conn.txCtx = nil
+	tracing.End(t.span, err)
+}
+
+// getStoreFunction returns the name of the function of the store running the query, such as store.ListMemos.
+func getStoreFunction() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, storePackagePath) && !strings.HasSuffix(frame.File, "store/db/driver.go") {
+			function := strings.TrimPrefix(frame.Function, storePackagePath[:strings.LastIndex(storePackagePath, "/")+1])
+			return strings.Replace(function, "(*Store).", "", 1)
+		}
+		if !more {
+			return "store"
+		}
+	}
+}
+
+// observeQuery records the duration and the error of the query, which is logged at the debug level with the request ID.
+func observeQuery(ctx context.Context, query string, start time.Time, err error) {
+	duration := time.Since(start)
+	statement := getStatement(query)
+	queryDurationSeconds.Observe(duration.Seconds(), statement)
+	if err != nil {
+		queryErrorsTotal.Inc(statement)
+	}
+	if logger.Enabled(zapcore.DebugLevel) {
+		logger.WithContext(ctx).Debug("query", zap.String("statement", statement), zap.String("query", strings.Join(strings.Fields(query), " ")), zap.Duration("duration", duration), zap.Error(err))
+	}
+}
+
+// getStatement returns the lowercase keyword the query starts with, or "other" to keep the label values bounded.
+func getStatement(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	statement := strings.ToLower(fields[0])
+	switch statement {
+	case "select", "insert", "update", "delete", "with", "create", "drop", "alter", "pragma":
+		return statement
+	}
+	return "other"
+}
@@ -21,6 +21,7 @@ CREATE TABLE user (
   username TEXT NOT NULL UNIQUE,
   role TEXT NOT NULL CHECK (role IN ('HOST', 'ADMIN', 'USER')) DEFAULT 'USER',
   email TEXT NOT NULL DEFAULT '',
+  email_verified INTEGER NOT NULL CHECK (email_verified IN (0, 1)) DEFAULT 0,
   nickname TEXT NOT NULL DEFAULT '',
   password_hash TEXT NOT NULL,
   open_id TEXT NOT NULL UNIQUE,
@@ -45,13 +46,16 @@ CREATE TABLE memo (
   updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
   row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
   content TEXT NOT NULL DEFAULT '',
-  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE'
+  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE',
+  workspace_id INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE INDEX idx_memo_creator_id ON memo (creator_id);
 CREATE INDEX idx_memo_content ON memo (content);
 CREATE INDEX idx_memo_visibility ON memo (visibility);
 
+CREATE INDEX idx_memo_workspace_id ON memo (workspace_id);
+
 -- memo_organizer
 CREATE TABLE memo_organizer (
   memo_id INTEGER NOT NULL,
@@ -68,7 +72,8 @@ CREATE TABLE shortcut (
   updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
   row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
   title TEXT NOT NULL DEFAULT '',
-  payload TEXT NOT NULL DEFAULT '{}'
+  payload TEXT NOT NULL DEFAULT '{}',
+  workspace_id INTEGER NOT NULL DEFAULT 0
 );
 
 -- resource
@@ -82,7 +87,10 @@ CREATE TABLE resource (
   external_link TEXT NOT NULL DEFAULT '',
   type TEXT NOT NULL DEFAULT '',
   size INTEGER NOT NULL DEFAULT 0,
-  internal_path TEXT NOT NULL DEFAULT ''
+  internal_path TEXT NOT NULL DEFAULT '',
+  workspace_id INTEGER NOT NULL DEFAULT 0,
+  storage_id INTEGER NOT NULL DEFAULT 0,
+  object_key TEXT NOT NULL DEFAULT ''
 );
 
 CREATE INDEX idx_resource_creator_id ON resource (creator_id);
@@ -100,7 +108,8 @@ CREATE TABLE memo_resource (
 CREATE TABLE tag (
   name TEXT NOT NULL,
   creator_id INTEGER NOT NULL,
-  UNIQUE(name, creator_id)
+  workspace_id INTEGER NOT NULL DEFAULT 0,
+  UNIQUE(name, creator_id, workspace_id)
 );
 
 -- activity
@@ -113,6 +122,10 @@ CREATE TABLE activity (
   payload TEXT NOT NULL DEFAULT '{}'
 );
 
+CREATE INDEX idx_activity_created_ts ON activity (created_ts);
+
+CREATE INDEX idx_activity_creator_id ON activity (creator_id);
+
 -- storage
 CREATE TABLE storage (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -137,3 +150,247 @@ CREATE TABLE memo_relation (
   type TEXT NOT NULL,
   UNIQUE(memo_id, related_memo_id, type)
 );
+
+-- feed_subscription
+CREATE TABLE feed_subscription (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
+  url TEXT NOT NULL,
+  poll_interval INTEGER NOT NULL DEFAULT 3600,
+  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE',
+  tags TEXT NOT NULL DEFAULT '',
+  last_polled_ts BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(creator_id, url)
+);
+
+-- feed_subscription_entry
+CREATE TABLE feed_subscription_entry (
+  subscription_id INTEGER NOT NULL,
+  guid TEXT NOT NULL,
+  memo_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(subscription_id, guid)
+);
+
+-- ai_usage
+CREATE TABLE ai_usage (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  provider TEXT NOT NULL,
+  model TEXT NOT NULL DEFAULT '',
+  prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_ai_usage_user_id_created_ts ON ai_usage (user_id, created_ts);
+
+-- memo_embedding
+CREATE TABLE memo_embedding (
+  memo_id INTEGER NOT NULL,
+  model TEXT NOT NULL,
+  content_hash TEXT NOT NULL,
+  embedding BLOB NOT NULL,
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(memo_id)
+);
+
+-- memo_suggestion
+CREATE TABLE memo_suggestion (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  memo_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  type TEXT NOT NULL CHECK (type IN ('TAG', 'SUMMARY')),
+  content TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('PENDING', 'ACCEPTED', 'REJECTED')) DEFAULT 'PENDING',
+  UNIQUE(memo_id, type, content)
+);
+
+-- memo_suggestion_checkpoint
+CREATE TABLE memo_suggestion_checkpoint (
+  memo_id INTEGER NOT NULL,
+  content_hash TEXT NOT NULL,
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(memo_id)
+);
+
+-- user_two_factor
+CREATE TABLE user_two_factor (
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  secret TEXT NOT NULL,
+  enabled INTEGER NOT NULL CHECK (enabled IN (0, 1)) DEFAULT 0,
+  recovery_codes TEXT NOT NULL DEFAULT '[]',
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(user_id)
+);
+
+-- webauthn_credential
+CREATE TABLE webauthn_credential (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  name TEXT NOT NULL DEFAULT '',
+  credential_id TEXT NOT NULL UNIQUE,
+  public_key BLOB NOT NULL,
+  sign_count INTEGER NOT NULL DEFAULT 0,
+  last_used_ts BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_webauthn_credential_user_id ON webauthn_credential (user_id);
+
+-- user_session
+CREATE TABLE user_session (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  last_seen_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_user_session_user_id ON user_session (user_id);
+
+-- signin_lockout
+CREATE TABLE signin_lockout (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL CHECK (kind IN ('IP', 'USERNAME')),
+  key TEXT NOT NULL,
+  failed_count INTEGER NOT NULL DEFAULT 0,
+  last_failed_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  locked_until_ts BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(kind, key)
+);
+
+-- verification_token
+CREATE TABLE verification_token (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  expired_ts BIGINT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('EMAIL_VERIFICATION', 'PASSWORD_RESET')),
+  token_hash TEXT NOT NULL UNIQUE,
+  email TEXT NOT NULL DEFAULT ''
+);
+
+-- invitation
+CREATE TABLE invitation (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  code TEXT NOT NULL UNIQUE,
+  email TEXT NOT NULL DEFAULT '',
+  role TEXT NOT NULL CHECK (role IN ('ADMIN', 'USER')) DEFAULT 'USER',
+  max_uses INTEGER NOT NULL DEFAULT 1,
+  used_count INTEGER NOT NULL DEFAULT 0,
+  expired_ts BIGINT NOT NULL DEFAULT 0
+);
+
+-- user_identity
+CREATE TABLE user_identity (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  idp_id INTEGER NOT NULL,
+  external_id TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  UNIQUE(idp_id, external_id),
+  UNIQUE(user_id, idp_id)
+);
+
+-- custom_role
+CREATE TABLE custom_role (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  permissions TEXT NOT NULL DEFAULT '[]'
+);
+
+-- user_custom_role
+CREATE TABLE user_custom_role (
+  user_id INTEGER NOT NULL,
+  role_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(user_id, role_id)
+);
+
+-- workspace
+CREATE TABLE workspace (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+-- workspace_member
+CREATE TABLE workspace_member (
+  workspace_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('ADMIN', 'MEMBER')) DEFAULT 'MEMBER',
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(workspace_id, user_id)
+);
+
+CREATE INDEX idx_workspace_member_user_id ON workspace_member (user_id);
+
+-- workspace_setting
+CREATE TABLE workspace_setting (
+  workspace_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  value TEXT NOT NULL,
+  UNIQUE(workspace_id, name)
+);
+
+-- user_group
+CREATE TABLE user_group (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  idp_group TEXT NOT NULL DEFAULT ''
+);
+
+-- user_group_member
+CREATE TABLE user_group_member (
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  idp_id INTEGER NOT NULL DEFAULT 0,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(group_id, user_id)
+);
+
+CREATE INDEX idx_user_group_member_user_id ON user_group_member (user_id);
+
+-- user_group_custom_role
+CREATE TABLE user_group_custom_role (
+  group_id INTEGER NOT NULL,
+  role_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(group_id, role_id)
+);
+
+-- memo_group_share
+CREATE TABLE memo_group_share (
+  memo_id INTEGER NOT NULL,
+  group_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(memo_id, group_id)
+);
+
+CREATE INDEX idx_memo_group_share_group_id ON memo_group_share (group_id);
@@ -0,0 +1,23 @@
+-- feed_subscription
+CREATE TABLE feed_subscription (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
+  url TEXT NOT NULL,
+  poll_interval INTEGER NOT NULL DEFAULT 3600,
+  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE',
+  tags TEXT NOT NULL DEFAULT '',
+  last_polled_ts BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(creator_id, url)
+);
+
+-- feed_subscription_entry
+CREATE TABLE feed_subscription_entry (
+  subscription_id INTEGER NOT NULL,
+  guid TEXT NOT NULL,
+  memo_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(subscription_id, guid)
+);
@@ -0,0 +1,19 @@
+-- memo_suggestion
+CREATE TABLE memo_suggestion (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  memo_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  type TEXT NOT NULL CHECK (type IN ('TAG', 'SUMMARY')),
+  content TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('PENDING', 'ACCEPTED', 'REJECTED')) DEFAULT 'PENDING',
+  UNIQUE(memo_id, type, content)
+);
+
+-- memo_suggestion_checkpoint
+CREATE TABLE memo_suggestion_checkpoint (
+  memo_id INTEGER NOT NULL,
+  content_hash TEXT NOT NULL,
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(memo_id)
+);
@@ -0,0 +1,11 @@
+-- user_two_factor
+CREATE TABLE user_two_factor (
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  secret TEXT NOT NULL,
+  enabled INTEGER NOT NULL CHECK (enabled IN (0, 1)) DEFAULT 0,
+  recovery_codes TEXT NOT NULL DEFAULT '[]',
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(user_id)
+);
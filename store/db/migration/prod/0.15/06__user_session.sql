@@ -0,0 +1,12 @@
+-- user_session
+CREATE TABLE user_session (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  last_seen_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_user_session_user_id ON user_session (user_id);
@@ -0,0 +1,10 @@
+-- signin_lockout
+CREATE TABLE signin_lockout (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL CHECK (kind IN ('IP', 'USERNAME')),
+  key TEXT NOT NULL,
+  failed_count INTEGER NOT NULL DEFAULT 0,
+  last_failed_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  locked_until_ts BIGINT NOT NULL DEFAULT 0,
+  UNIQUE(kind, key)
+);
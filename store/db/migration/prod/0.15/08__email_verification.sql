@@ -0,0 +1,15 @@
+ALTER TABLE
+  user
+ADD
+  COLUMN email_verified INTEGER NOT NULL CHECK (email_verified IN (0, 1)) DEFAULT 0;
+
+-- verification_token
+CREATE TABLE verification_token (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  expired_ts BIGINT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('EMAIL_VERIFICATION', 'PASSWORD_RESET')),
+  token_hash TEXT NOT NULL UNIQUE,
+  email TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,11 @@
+-- user_identity
+CREATE TABLE user_identity (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  idp_id INTEGER NOT NULL,
+  external_id TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  UNIQUE(idp_id, external_id),
+  UNIQUE(user_id, idp_id)
+);
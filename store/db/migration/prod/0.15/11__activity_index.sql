@@ -0,0 +1,4 @@
+-- activity
+CREATE INDEX idx_activity_created_ts ON activity (created_ts);
+
+CREATE INDEX idx_activity_creator_id ON activity (creator_id);
@@ -0,0 +1,54 @@
+-- workspace
+CREATE TABLE workspace (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+-- workspace_member
+CREATE TABLE workspace_member (
+  workspace_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('ADMIN', 'MEMBER')) DEFAULT 'MEMBER',
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(workspace_id, user_id)
+);
+
+CREATE INDEX idx_workspace_member_user_id ON workspace_member (user_id);
+
+-- workspace_setting
+CREATE TABLE workspace_setting (
+  workspace_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  value TEXT NOT NULL,
+  UNIQUE(workspace_id, name)
+);
+
+-- The content without a workspace belongs to the default workspace 0.
+ALTER TABLE memo ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_memo_workspace_id ON memo (workspace_id);
+
+ALTER TABLE resource ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE shortcut ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+
+-- tag
+CREATE TABLE _tag_old AS SELECT * FROM tag;
+
+DROP TABLE tag;
+
+CREATE TABLE tag (
+  name TEXT NOT NULL,
+  creator_id INTEGER NOT NULL,
+  workspace_id INTEGER NOT NULL DEFAULT 0,
+  UNIQUE(name, creator_id, workspace_id)
+);
+
+INSERT INTO tag (name, creator_id) SELECT name, creator_id FROM _tag_old;
+
+DROP TABLE _tag_old;
@@ -0,0 +1,4 @@
+-- resource
+ALTER TABLE resource ADD COLUMN storage_id INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE resource ADD COLUMN object_key TEXT NOT NULL DEFAULT '';
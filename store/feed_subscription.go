@@ -0,0 +1,424 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+type FeedSubscription struct {
+	ID int
+
+	// Standard fields
+	RowStatus RowStatus
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	URL string
+	// PollInterval is the interval between two polls in seconds.
+	PollInterval int
+	Visibility   Visibility
+	Tags         []string
+	LastPolledTs int64
+}
+
+type UpdateFeedSubscription struct {
+	ID int
+
+	UpdatedTs    *int64
+	RowStatus    *RowStatus
+	PollInterval *int
+	Visibility   *Visibility
+	Tags         *[]string
+	LastPolledTs *int64
+}
+
+type FindFeedSubscription struct {
+	ID        *int
+	RowStatus *RowStatus
+	CreatorID *int
+	URL       *string
+}
+
+type DeleteFeedSubscription struct {
+	ID        int
+	CreatorID *int
+}
+
+// FeedSubscriptionEntry records a feed entry which has been imported as a memo.
+type FeedSubscriptionEntry struct {
+	SubscriptionID int
+	GUID           string
+	MemoID         int
+	CreatedTs      int64
+}
+
+type FindFeedSubscriptionEntry struct {
+	SubscriptionID *int
+	GUID           *string
+}
+
+func (s *Store) CreateFeedSubscription(ctx context.Context, create *FeedSubscription) (*FeedSubscription, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO feed_subscription (
+			creator_id,
+			url,
+			poll_interval,
+			visibility,
+			tags
+		)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_ts, updated_ts, row_status, last_polled_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.CreatorID,
+		create.URL,
+		create.PollInterval,
+		create.Visibility,
+		strings.Join(create.Tags, ","),
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.RowStatus,
+		&create.LastPolledTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	feedSubscription := create
+	return feedSubscription, nil
+}
+
+func (s *Store) ListFeedSubscriptions(ctx context.Context, find *FindFeedSubscription) ([]*FeedSubscription, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listFeedSubscriptions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetFeedSubscription(ctx context.Context, find *FindFeedSubscription) (*FeedSubscription, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listFeedSubscriptions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateFeedSubscription(ctx context.Context, update *UpdateFeedSubscription) (*FeedSubscription, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.RowStatus; v != nil {
+		set, args = append(set, "row_status = ?"), append(args, *v)
+	}
+	if v := update.PollInterval; v != nil {
+		set, args = append(set, "poll_interval = ?"), append(args, *v)
+	}
+	if v := update.Visibility; v != nil {
+		set, args = append(set, "visibility = ?"), append(args, *v)
+	}
+	if v := update.Tags; v != nil {
+		set, args = append(set, "tags = ?"), append(args, strings.Join(*v, ","))
+	}
+	if v := update.LastPolledTs; v != nil {
+		set, args = append(set, "last_polled_ts = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE feed_subscription
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, row_status, url, poll_interval, visibility, tags, last_polled_ts
+	`
+	feedSubscription := &FeedSubscription{}
+	var tags string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&feedSubscription.ID,
+		&feedSubscription.CreatorID,
+		&feedSubscription.CreatedTs,
+		&feedSubscription.UpdatedTs,
+		&feedSubscription.RowStatus,
+		&feedSubscription.URL,
+		&feedSubscription.PollInterval,
+		&feedSubscription.Visibility,
+		&tags,
+		&feedSubscription.LastPolledTs,
+	); err != nil {
+		return nil, err
+	}
+	feedSubscription.Tags = splitFeedSubscriptionTags(tags)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return feedSubscription, nil
+}
+
+func (s *Store) DeleteFeedSubscription(ctx context.Context, delete *DeleteFeedSubscription) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"id = ?"}, []any{delete.ID}
+	if v := delete.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM feed_subscription WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+	if err := vacuumFeedSubscriptionEntry(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) CreateFeedSubscriptionEntry(ctx context.Context, create *FeedSubscriptionEntry) (*FeedSubscriptionEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO feed_subscription_entry (
+			subscription_id,
+			guid,
+			memo_id
+		)
+		VALUES (?, ?, ?)
+		RETURNING created_ts
+	`
+	if err := tx.QueryRowContext(ctx, query, create.SubscriptionID, create.GUID, create.MemoID).Scan(
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	feedSubscriptionEntry := create
+	return feedSubscriptionEntry, nil
+}
+
+func (s *Store) ListFeedSubscriptionEntries(ctx context.Context, find *FindFeedSubscriptionEntry) ([]*FeedSubscriptionEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.SubscriptionID; v != nil {
+		where, args = append(where, "subscription_id = ?"), append(args, *v)
+	}
+	if v := find.GUID; v != nil {
+		where, args = append(where, "guid = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			subscription_id,
+			guid,
+			memo_id,
+			created_ts
+		FROM feed_subscription_entry
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*FeedSubscriptionEntry, 0)
+	for rows.Next() {
+		feedSubscriptionEntry := &FeedSubscriptionEntry{}
+		if err := rows.Scan(
+			&feedSubscriptionEntry.SubscriptionID,
+			&feedSubscriptionEntry.GUID,
+			&feedSubscriptionEntry.MemoID,
+			&feedSubscriptionEntry.CreatedTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, feedSubscriptionEntry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func listFeedSubscriptions(ctx context.Context, tx *sql.Tx, find *FindFeedSubscription) ([]*FeedSubscription, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.RowStatus; v != nil {
+		where, args = append(where, "row_status = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if v := find.URL; v != nil {
+		where, args = append(where, "url = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			row_status,
+			url,
+			poll_interval,
+			visibility,
+			tags,
+			last_polled_ts
+		FROM feed_subscription
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*FeedSubscription, 0)
+	for rows.Next() {
+		var feedSubscription FeedSubscription
+		var tags string
+		if err := rows.Scan(
+			&feedSubscription.ID,
+			&feedSubscription.CreatorID,
+			&feedSubscription.CreatedTs,
+			&feedSubscription.UpdatedTs,
+			&feedSubscription.RowStatus,
+			&feedSubscription.URL,
+			&feedSubscription.PollInterval,
+			&feedSubscription.Visibility,
+			&tags,
+			&feedSubscription.LastPolledTs,
+		); err != nil {
+			return nil, err
+		}
+		feedSubscription.Tags = splitFeedSubscriptionTags(tags)
+		list = append(list, &feedSubscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func splitFeedSubscriptionTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
+
+func vacuumFeedSubscription(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		feed_subscription
+	WHERE
+		creator_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return vacuumFeedSubscriptionEntry(ctx, tx)
+}
+
+func vacuumFeedSubscriptionEntry(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		feed_subscription_entry
+	WHERE
+		subscription_id NOT IN (
+			SELECT
+				id
+			FROM
+				feed_subscription
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
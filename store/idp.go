@@ -12,6 +12,8 @@ type IdentityProviderType string
 
 const (
 	IdentityProviderOAuth2Type IdentityProviderType = "OAUTH2"
+	IdentityProviderOIDCType   IdentityProviderType = "OIDC"
+	IdentityProviderLDAPType   IdentityProviderType = "LDAP"
 )
 
 func (t IdentityProviderType) String() string {
@@ -20,6 +22,8 @@ func (t IdentityProviderType) String() string {
 
 type IdentityProviderConfig struct {
 	OAuth2Config *IdentityProviderOAuth2Config
+	OIDCConfig   *IdentityProviderOIDCConfig
+	LDAPConfig   *IdentityProviderLDAPConfig
 }
 
 type IdentityProviderOAuth2Config struct {
@@ -32,6 +36,37 @@ type IdentityProviderOAuth2Config struct {
 	FieldMapping *FieldMapping `json:"fieldMapping"`
 }
 
+// IdentityProviderOIDCConfig is the config of an OpenID Connect identity provider.
+// The endpoints are discovered from the issuer, and the field mapping is optional for the standard claims.
+type IdentityProviderOIDCConfig struct {
+	Issuer       string        `json:"issuer"`
+	ClientID     string        `json:"clientId"`
+	ClientSecret string        `json:"clientSecret"`
+	Scopes       []string      `json:"scopes"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+	// GroupsClaim is the name of the claim containing the groups of the user, e.g. "groups".
+	GroupsClaim string `json:"groupsClaim"`
+}
+
+// IdentityProviderLDAPConfig is the config of an LDAP directory used by password sign-in.
+type IdentityProviderLDAPConfig struct {
+	// URL is the address of the directory, e.g. "ldaps://ldap.example.com:636".
+	URL                string `json:"url"`
+	StartTLS           bool   `json:"startTls"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	// BindDN and BindPassword are the credentials used to search users, empty means anonymous search.
+	BindDN       string `json:"bindDn"`
+	BindPassword string `json:"bindPassword"`
+	BaseDN       string `json:"baseDn"`
+	// UserFilter is the filter to search the user, "%s" is replaced with the escaped username, e.g. "(uid=%s)".
+	UserFilter   string        `json:"userFilter"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+	// GroupAttribute is the attribute of the user containing their groups, e.g. "memberOf".
+	GroupAttribute string `json:"groupAttribute"`
+	// AdminGroups are the groups whose members are ADMIN, the others are USER. Empty means roles are not managed.
+	AdminGroups []string `json:"adminGroups"`
+}
+
 type FieldMapping struct {
 	Identifier  string `json:"identifier"`
 	DisplayName string `json:"displayName"`
@@ -69,14 +104,9 @@ func (s *Store) CreateIdentityProvider(ctx context.Context, create *IdentityProv
 	}
 	defer tx.Rollback()
 
-	var configBytes []byte
-	if create.Type == IdentityProviderOAuth2Type {
-		configBytes, err = json.Marshal(create.Config.OAuth2Config)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, fmt.Errorf("unsupported idp type %s", string(create.Type))
+	configBytes, err := marshalIdentityProviderConfig(create.Type, create.Config)
+	if err != nil {
+		return nil, err
 	}
 
 	query := `
@@ -135,7 +165,7 @@ func (s *Store) ListIdentityProviders(ctx context.Context, find *FindIdentityPro
 
 func (s *Store) GetIdentityProvider(ctx context.Context, find *FindIdentityProvider) (*IdentityProvider, error) {
 	if find.ID != nil {
-		if cache, ok := s.idpCache.Load(*find.ID); ok {
+		if cache, ok := loadCache(&s.idpCache, "identity_provider", *find.ID); ok {
 			return cache.(*IdentityProvider), nil
 		}
 	}
@@ -178,14 +208,9 @@ func (s *Store) UpdateIdentityProvider(ctx context.Context, update *UpdateIdenti
 		set, args = append(set, "identifier_filter = ?"), append(args, *v)
 	}
 	if v := update.Config; v != nil {
-		var configBytes []byte
-		if update.Type == IdentityProviderOAuth2Type {
-			configBytes, err = json.Marshal(update.Config.OAuth2Config)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported idp type %s", string(update.Type))
+		configBytes, err := marshalIdentityProviderConfig(update.Type, v)
+		if err != nil {
+			return nil, err
 		}
 		set, args = append(set, "config = ?"), append(args, string(configBytes))
 	}
@@ -209,23 +234,16 @@ func (s *Store) UpdateIdentityProvider(ctx context.Context, update *UpdateIdenti
 		return nil, err
 	}
 
-	if identityProvider.Type == IdentityProviderOAuth2Type {
-		oauth2Config := &IdentityProviderOAuth2Config{}
-		if err := json.Unmarshal([]byte(identityProviderConfig), oauth2Config); err != nil {
-			return nil, err
-		}
-		identityProvider.Config = &IdentityProviderConfig{
-			OAuth2Config: oauth2Config,
-		}
-	} else {
-		return nil, fmt.Errorf("unsupported idp type %s", string(identityProvider.Type))
+	identityProvider.Config, err = unmarshalIdentityProviderConfig(identityProvider.Type, identityProviderConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	s.idpCache.Store(identityProvider.ID, identityProvider)
+	s.idpCache.Store(identityProvider.ID, &identityProvider)
 	return &identityProvider, nil
 }
 
@@ -291,17 +309,11 @@ func listIdentityProviders(ctx context.Context, tx *sql.Tx, find *FindIdentityPr
 			return nil, err
 		}
 
-		if identityProvider.Type == IdentityProviderOAuth2Type {
-			oauth2Config := &IdentityProviderOAuth2Config{}
-			if err := json.Unmarshal([]byte(identityProviderConfig), oauth2Config); err != nil {
-				return nil, err
-			}
-			identityProvider.Config = &IdentityProviderConfig{
-				OAuth2Config: oauth2Config,
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported idp type %s", string(identityProvider.Type))
+		config, err := unmarshalIdentityProviderConfig(identityProvider.Type, identityProviderConfig)
+		if err != nil {
+			return nil, err
 		}
+		identityProvider.Config = config
 		identityProviders = append(identityProviders, &identityProvider)
 	}
 
@@ -311,3 +323,51 @@ func listIdentityProviders(ctx context.Context, tx *sql.Tx, find *FindIdentityPr
 
 	return identityProviders, nil
 }
+
+func marshalIdentityProviderConfig(identityProviderType IdentityProviderType, config *IdentityProviderConfig) ([]byte, error) {
+	if config == nil {
+		return nil, fmt.Errorf("missing config of idp type %s", string(identityProviderType))
+	}
+
+	switch identityProviderType {
+	case IdentityProviderOAuth2Type:
+		return json.Marshal(config.OAuth2Config)
+	case IdentityProviderOIDCType:
+		return json.Marshal(config.OIDCConfig)
+	case IdentityProviderLDAPType:
+		return json.Marshal(config.LDAPConfig)
+	default:
+		return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderType))
+	}
+}
+
+func unmarshalIdentityProviderConfig(identityProviderType IdentityProviderType, configString string) (*IdentityProviderConfig, error) {
+	switch identityProviderType {
+	case IdentityProviderOAuth2Type:
+		oauth2Config := &IdentityProviderOAuth2Config{}
+		if err := json.Unmarshal([]byte(configString), oauth2Config); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			OAuth2Config: oauth2Config,
+		}, nil
+	case IdentityProviderOIDCType:
+		oidcConfig := &IdentityProviderOIDCConfig{}
+		if err := json.Unmarshal([]byte(configString), oidcConfig); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			OIDCConfig: oidcConfig,
+		}, nil
+	case IdentityProviderLDAPType:
+		ldapConfig := &IdentityProviderLDAPConfig{}
+		if err := json.Unmarshal([]byte(configString), ldapConfig); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			LDAPConfig: ldapConfig,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderType))
+	}
+}
@@ -0,0 +1,260 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Invitation is a code allowing to sign up with a preset role.
+type Invitation struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Code string
+	// Email binds the invitation to the email, empty means any.
+	Email string
+	Role  Role
+	// MaxUses is the number of signups allowed with the invitation, 0 means unlimited.
+	MaxUses   int
+	UsedCount int
+	// ExpiredTs is the time after which the invitation can not be used, 0 means never.
+	ExpiredTs int64
+}
+
+type FindInvitation struct {
+	ID        *int
+	CreatorID *int
+	Code      *string
+}
+
+type DeleteInvitation struct {
+	ID int
+}
+
+func (s *Store) CreateInvitation(ctx context.Context, create *Invitation) (*Invitation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO invitation (
+			creator_id,
+			code,
+			email,
+			role,
+			max_uses,
+			expired_ts
+		)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_ts, updated_ts, used_count
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.CreatorID,
+		create.Code,
+		create.Email,
+		create.Role,
+		create.MaxUses,
+		create.ExpiredTs,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.UsedCount,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	invitation := create
+	return invitation, nil
+}
+
+func (s *Store) ListInvitations(ctx context.Context, find *FindInvitation) ([]*Invitation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listInvitations(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetInvitation(ctx context.Context, find *FindInvitation) (*Invitation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listInvitations(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+// CreateInvitedUser creates the user and counts the signup with the invitation in one transaction,
+// returning nil if the invitation is used up or expired at the time, so a failed signup never spends a use.
+func (s *Store) CreateInvitedUser(ctx context.Context, create *User, invitationID int, ts int64) (*User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Count the use before creating the user, so concurrent signups can not exceed the max uses.
+	result, err := tx.ExecContext(ctx, `
+		UPDATE invitation
+		SET used_count = used_count + 1, updated_ts = ?
+		WHERE id = ? AND (max_uses = 0 OR used_count < max_uses) AND (expired_ts = 0 OR expired_ts > ?)
+	`, ts, invitationID, ts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, nil
+	}
+	if err := createUser(ctx, tx, create); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	user := create
+	s.userCache.Store(user.ID, user)
+	return user, nil
+}
+
+func (s *Store) DeleteInvitation(ctx context.Context, delete *DeleteInvitation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invitation WHERE id = ?`, delete.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listInvitations(ctx context.Context, tx *sql.Tx, find *FindInvitation) ([]*Invitation, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if v := find.Code; v != nil {
+		where, args = append(where, "code = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			code,
+			email,
+			role,
+			max_uses,
+			used_count,
+			expired_ts
+		FROM invitation
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts DESC, id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*Invitation, 0)
+	for rows.Next() {
+		invitation := &Invitation{}
+		if err := rows.Scan(
+			&invitation.ID,
+			&invitation.CreatorID,
+			&invitation.CreatedTs,
+			&invitation.UpdatedTs,
+			&invitation.Code,
+			&invitation.Email,
+			&invitation.Role,
+			&invitation.MaxUses,
+			&invitation.UsedCount,
+			&invitation.ExpiredTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumInvitation(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		invitation
+	WHERE
+		creator_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
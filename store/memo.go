@@ -43,8 +43,9 @@ type Memo struct {
 	UpdatedTs int64
 
 	// Domain specific fields
-	Content    string
-	Visibility Visibility
+	Content     string
+	Visibility  Visibility
+	WorkspaceID int
 
 	// Composed fields
 	Pinned         bool
@@ -63,6 +64,12 @@ type FindMemo struct {
 	Pinned         *bool
 	ContentSearch  []string
 	VisibilityList []Visibility
+	WorkspaceID    *int
+	// MemberID limits the PROTECTED memos to the ones of the default workspace,
+	// of the workspaces the user is a member of and of the user.
+	MemberID *int
+	// SharedUserID finds the memos shared with the groups of the user besides the visible ones.
+	SharedUserID *int
 
 	// Pagination
 	Limit            *int
@@ -99,9 +106,10 @@ func (s *Store) CreateMemo(ctx context.Context, create *Memo) (*Memo, error) {
 			creator_id,
 			created_ts,
 			content,
-			visibility
+			visibility,
+			workspace_id
 		)
-		VALUES (?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?)
 		RETURNING id, created_ts, updated_ts, row_status
 	`
 	if err := tx.QueryRowContext(
@@ -111,6 +119,7 @@ func (s *Store) CreateMemo(ctx context.Context, create *Memo) (*Memo, error) {
 		create.CreatedTs,
 		create.Content,
 		create.Visibility,
+		create.WorkspaceID,
 	).Scan(
 		&create.ID,
 		&create.CreatedTs,
@@ -287,14 +296,25 @@ func listMemos(ctx context.Context, tx *sql.Tx, find *FindMemo) ([]*Memo, error)
 			where, args = append(where, "memo.content LIKE ?"), append(args, "%"+s+"%")
 		}
 	}
+	if v := find.WorkspaceID; v != nil {
+		where, args = append(where, "memo.workspace_id = ?"), append(args, *v)
+	}
+	visibilityWhere := []string{}
+	if v := find.MemberID; v != nil {
+		visibilityWhere, args = append(visibilityWhere, "(memo.visibility != 'PROTECTED' OR memo.workspace_id = 0 OR memo.creator_id = ? OR memo.workspace_id IN (SELECT workspace_id FROM workspace_member WHERE user_id = ?))"), append(args, *v, *v)
+	}
 	if v := find.VisibilityList; len(v) != 0 {
 		list := []string{}
 		for _, visibility := range v {
 			list = append(list, fmt.Sprintf("$%d", len(args)+1))
 			args = append(args, visibility)
 		}
-		where = append(where, fmt.Sprintf("memo.visibility in (%s)", strings.Join(list, ",")))
+		visibilityWhere = append(visibilityWhere, fmt.Sprintf("memo.visibility in (%s)", strings.Join(list, ",")))
+	}
+	if v := find.SharedUserID; v != nil && len(visibilityWhere) != 0 {
+		visibilityWhere, args = []string{fmt.Sprintf("((%s) OR memo.id IN (SELECT memo_id FROM memo_group_share WHERE group_id IN (SELECT group_id FROM user_group_member WHERE user_id = ?)))", strings.Join(visibilityWhere, " AND "))}, append(args, *v)
 	}
+	where = append(where, visibilityWhere...)
 	orders := []string{"pinned DESC"}
 	if find.OrderByUpdatedTs {
 		orders = append(orders, "updated_ts DESC")
@@ -312,6 +332,7 @@ func listMemos(ctx context.Context, tx *sql.Tx, find *FindMemo) ([]*Memo, error)
 		memo.row_status AS row_status,
 		memo.content AS content,
 		memo.visibility AS visibility,
+		memo.workspace_id AS workspace_id,
 		CASE WHEN memo_organizer.pinned = 1 THEN 1 ELSE 0 END AS pinned,
 		GROUP_CONCAT(memo_resource.resource_id) AS resource_id_list,
 		(
@@ -360,6 +381,7 @@ func listMemos(ctx context.Context, tx *sql.Tx, find *FindMemo) ([]*Memo, error)
 			&memo.RowStatus,
 			&memo.Content,
 			&memo.Visibility,
+			&memo.WorkspaceID,
 			&memo.Pinned,
 			&memoResourceIDList,
 			&memoRelationList,
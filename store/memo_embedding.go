@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MemoEmbedding is the embedding vector of a memo content used by the AI retrieval.
+type MemoEmbedding struct {
+	MemoID int
+	Model  string
+	// ContentHash is the hash of the memo content when the embedding was computed.
+	ContentHash string
+	Embedding   []float32
+	UpdatedTs   int64
+}
+
+type FindMemoEmbedding struct {
+	MemoID *int
+	// CreatorID filters the embeddings of memos created by the user.
+	CreatorID *int
+}
+
+func (s *Store) UpsertMemoEmbedding(ctx context.Context, upsert *MemoEmbedding) (*MemoEmbedding, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO memo_embedding (
+			memo_id,
+			model,
+			content_hash,
+			embedding,
+			updated_ts
+		)
+		VALUES (?, ?, ?, ?, strftime('%s', 'now'))
+		ON CONFLICT(memo_id) DO UPDATE
+		SET
+			model = EXCLUDED.model,
+			content_hash = EXCLUDED.content_hash,
+			embedding = EXCLUDED.embedding,
+			updated_ts = EXCLUDED.updated_ts
+		RETURNING updated_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		upsert.MemoID,
+		upsert.Model,
+		upsert.ContentHash,
+		encodeEmbedding(upsert.Embedding),
+	).Scan(
+		&upsert.UpdatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	memoEmbedding := upsert
+	return memoEmbedding, nil
+}
+
+func (s *Store) ListMemoEmbeddings(ctx context.Context, find *FindMemoEmbedding) ([]*MemoEmbedding, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "memo_embedding.memo_id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			memo_embedding.memo_id,
+			memo_embedding.model,
+			memo_embedding.content_hash,
+			memo_embedding.embedding,
+			memo_embedding.updated_ts
+		FROM memo_embedding
+		LEFT JOIN memo ON memo_embedding.memo_id = memo.id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY memo_embedding.memo_id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*MemoEmbedding, 0)
+	for rows.Next() {
+		memoEmbedding := &MemoEmbedding{}
+		var embedding []byte
+		if err := rows.Scan(
+			&memoEmbedding.MemoID,
+			&memoEmbedding.Model,
+			&memoEmbedding.ContentHash,
+			&embedding,
+			&memoEmbedding.UpdatedTs,
+		); err != nil {
+			return nil, err
+		}
+		memoEmbedding.Embedding, err = decodeEmbedding(embedding)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, memoEmbedding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// encodeEmbedding encodes the vector as little-endian float32 values.
+func encodeEmbedding(embedding []float32) []byte {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("invalid embedding length %d", len(buf))
+	}
+	embedding := make([]float32, len(buf)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return embedding, nil
+}
+
+func vacuumMemoEmbedding(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_embedding
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// MemoSuggestionType is the type of a memo suggestion.
+type MemoSuggestionType string
+
+const (
+	// MemoSuggestionTag is the type of a suggested tag.
+	MemoSuggestionTag MemoSuggestionType = "TAG"
+	// MemoSuggestionSummary is the type of a suggested one-line summary.
+	MemoSuggestionSummary MemoSuggestionType = "SUMMARY"
+)
+
+// MemoSuggestionStatus is the status of a memo suggestion.
+type MemoSuggestionStatus string
+
+const (
+	// MemoSuggestionPending is the status of a suggestion waiting for the user.
+	MemoSuggestionPending MemoSuggestionStatus = "PENDING"
+	// MemoSuggestionAccepted is the status of a suggestion accepted by the user.
+	MemoSuggestionAccepted MemoSuggestionStatus = "ACCEPTED"
+	// MemoSuggestionRejected is the status of a suggestion rejected by the user.
+	MemoSuggestionRejected MemoSuggestionStatus = "REJECTED"
+)
+
+type MemoSuggestion struct {
+	ID int
+
+	// Standard fields
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	MemoID  int
+	Type    MemoSuggestionType
+	Content string
+	Status  MemoSuggestionStatus
+}
+
+type FindMemoSuggestion struct {
+	ID     *int
+	MemoID *int
+	// CreatorID filters the suggestions of memos created by the user.
+	CreatorID *int
+	Type      *MemoSuggestionType
+	Status    *MemoSuggestionStatus
+}
+
+type UpdateMemoSuggestion struct {
+	ID        int
+	UpdatedTs *int64
+	Status    *MemoSuggestionStatus
+}
+
+type DeleteMemoSuggestion struct {
+	MemoID int
+	Type   *MemoSuggestionType
+	Status *MemoSuggestionStatus
+}
+
+// MemoSuggestionCheckpoint records the memo content which has been processed for suggestions.
+type MemoSuggestionCheckpoint struct {
+	MemoID      int
+	ContentHash string
+	UpdatedTs   int64
+}
+
+type FindMemoSuggestionCheckpoint struct {
+	// CreatorID filters the checkpoints of memos created by the user.
+	CreatorID *int
+}
+
+func (s *Store) CreateMemoSuggestion(ctx context.Context, create *MemoSuggestion) (*MemoSuggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO memo_suggestion (
+			memo_id,
+			type,
+			content
+		)
+		VALUES (?, ?, ?)
+		RETURNING id, created_ts, updated_ts, status
+	`
+	if err := tx.QueryRowContext(ctx, query, create.MemoID, create.Type, create.Content).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.Status,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	memoSuggestion := create
+	return memoSuggestion, nil
+}
+
+func (s *Store) ListMemoSuggestions(ctx context.Context, find *FindMemoSuggestion) ([]*MemoSuggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listMemoSuggestions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetMemoSuggestion(ctx context.Context, find *FindMemoSuggestion) (*MemoSuggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listMemoSuggestions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateMemoSuggestion(ctx context.Context, update *UpdateMemoSuggestion) (*MemoSuggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.Status; v != nil {
+		set, args = append(set, "status = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE memo_suggestion
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, memo_id, created_ts, updated_ts, type, content, status
+	`
+	memoSuggestion := &MemoSuggestion{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&memoSuggestion.ID,
+		&memoSuggestion.MemoID,
+		&memoSuggestion.CreatedTs,
+		&memoSuggestion.UpdatedTs,
+		&memoSuggestion.Type,
+		&memoSuggestion.Content,
+		&memoSuggestion.Status,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return memoSuggestion, nil
+}
+
+func (s *Store) DeleteMemoSuggestion(ctx context.Context, delete *DeleteMemoSuggestion) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"memo_id = ?"}, []any{delete.MemoID}
+	if v := delete.Type; v != nil {
+		where, args = append(where, "type = ?"), append(args, *v)
+	}
+	if v := delete.Status; v != nil {
+		where, args = append(where, "status = ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM memo_suggestion WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) UpsertMemoSuggestionCheckpoint(ctx context.Context, upsert *MemoSuggestionCheckpoint) (*MemoSuggestionCheckpoint, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO memo_suggestion_checkpoint (
+			memo_id,
+			content_hash,
+			updated_ts
+		)
+		VALUES (?, ?, strftime('%s', 'now'))
+		ON CONFLICT(memo_id) DO UPDATE
+		SET
+			content_hash = EXCLUDED.content_hash,
+			updated_ts = EXCLUDED.updated_ts
+		RETURNING updated_ts
+	`
+	if err := tx.QueryRowContext(ctx, query, upsert.MemoID, upsert.ContentHash).Scan(
+		&upsert.UpdatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	memoSuggestionCheckpoint := upsert
+	return memoSuggestionCheckpoint, nil
+}
+
+func (s *Store) ListMemoSuggestionCheckpoints(ctx context.Context, find *FindMemoSuggestionCheckpoint) ([]*MemoSuggestionCheckpoint, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			memo_suggestion_checkpoint.memo_id,
+			memo_suggestion_checkpoint.content_hash,
+			memo_suggestion_checkpoint.updated_ts
+		FROM memo_suggestion_checkpoint
+		LEFT JOIN memo ON memo_suggestion_checkpoint.memo_id = memo.id
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*MemoSuggestionCheckpoint, 0)
+	for rows.Next() {
+		memoSuggestionCheckpoint := &MemoSuggestionCheckpoint{}
+		if err := rows.Scan(
+			&memoSuggestionCheckpoint.MemoID,
+			&memoSuggestionCheckpoint.ContentHash,
+			&memoSuggestionCheckpoint.UpdatedTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, memoSuggestionCheckpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func listMemoSuggestions(ctx context.Context, tx *sql.Tx, find *FindMemoSuggestion) ([]*MemoSuggestion, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "memo_suggestion.id = ?"), append(args, *v)
+	}
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "memo_suggestion.memo_id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
+	}
+	if v := find.Type; v != nil {
+		where, args = append(where, "memo_suggestion.type = ?"), append(args, *v)
+	}
+	if v := find.Status; v != nil {
+		where, args = append(where, "memo_suggestion.status = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			memo_suggestion.id,
+			memo_suggestion.memo_id,
+			memo_suggestion.created_ts,
+			memo_suggestion.updated_ts,
+			memo_suggestion.type,
+			memo_suggestion.content,
+			memo_suggestion.status
+		FROM memo_suggestion
+		LEFT JOIN memo ON memo_suggestion.memo_id = memo.id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY memo_suggestion.updated_ts DESC, memo_suggestion.id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*MemoSuggestion, 0)
+	for rows.Next() {
+		memoSuggestion := &MemoSuggestion{}
+		if err := rows.Scan(
+			&memoSuggestion.ID,
+			&memoSuggestion.MemoID,
+			&memoSuggestion.CreatedTs,
+			&memoSuggestion.UpdatedTs,
+			&memoSuggestion.Type,
+			&memoSuggestion.Content,
+			&memoSuggestion.Status,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, memoSuggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumMemoSuggestion(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range []string{"memo_suggestion", "memo_suggestion_checkpoint"} {
+		stmt := `
+		DELETE FROM
+			` + table + `
+		WHERE
+			memo_id NOT IN (
+				SELECT
+					id
+				FROM
+					memo
+			)`
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -22,6 +22,9 @@ type Resource struct {
 	ExternalLink     string
 	Type             string
 	Size             int64
+	WorkspaceID      int
+	StorageID        int
+	ObjectKey        string
 	LinkedMemoAmount int
 }
 
@@ -30,9 +33,13 @@ type FindResource struct {
 	ID        *int
 	CreatorID *int
 	Filename  *string
-	MemoID    *int
-	Limit     *int
-	Offset    *int
+	// StorageID and ObjectKey find the resources uploaded to the external storage at the key.
+	StorageID   *int
+	ObjectKey   *string
+	MemoID      *int
+	WorkspaceID *int
+	Limit       *int
+	Offset      *int
 }
 
 type UpdateResource struct {
@@ -60,12 +67,15 @@ func (s *Store) CreateResource(ctx context.Context, create *Resource) (*Resource
 			type,
 			size,
 			creator_id,
-			internal_path
+			internal_path,
+			workspace_id,
+			storage_id,
+			object_key
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		RETURNING id, created_ts, updated_ts
 	`,
-		create.Filename, create.Blob, create.ExternalLink, create.Type, create.Size, create.CreatorID, create.InternalPath,
+		create.Filename, create.Blob, create.ExternalLink, create.Type, create.Size, create.CreatorID, create.InternalPath, create.WorkspaceID, create.StorageID, create.ObjectKey,
 	).Scan(&create.ID, &create.CreatedTs, &create.UpdatedTs); err != nil {
 		return nil, err
 	}
@@ -137,7 +147,7 @@ func (s *Store) UpdateResource(ctx context.Context, update *UpdateResource) (*Re
 	}
 
 	args = append(args, update.ID)
-	fields := []string{"id", "filename", "external_link", "type", "size", "creator_id", "created_ts", "updated_ts", "internal_path"}
+	fields := []string{"id", "filename", "external_link", "type", "size", "creator_id", "created_ts", "updated_ts", "internal_path", "workspace_id", "storage_id", "object_key"}
 	query := `
 		UPDATE resource
 		SET ` + strings.Join(set, ", ") + `
@@ -154,6 +164,9 @@ func (s *Store) UpdateResource(ctx context.Context, update *UpdateResource) (*Re
 		&resource.CreatedTs,
 		&resource.UpdatedTs,
 		&resource.InternalPath,
+		&resource.WorkspaceID,
+		&resource.StorageID,
+		&resource.ObjectKey,
 	}
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(dests...); err != nil {
 		return nil, err
@@ -200,11 +213,20 @@ func listResources(ctx context.Context, tx *sql.Tx, find *FindResource) ([]*Reso
 	if v := find.Filename; v != nil {
 		where, args = append(where, "resource.filename = ?"), append(args, *v)
 	}
+	if v := find.StorageID; v != nil {
+		where, args = append(where, "resource.storage_id = ?"), append(args, *v)
+	}
+	if v := find.ObjectKey; v != nil {
+		where, args = append(where, "resource.object_key = ?"), append(args, *v)
+	}
+	if v := find.WorkspaceID; v != nil {
+		where, args = append(where, "resource.workspace_id = ?"), append(args, *v)
+	}
 	if v := find.MemoID; v != nil {
 		where, args = append(where, "resource.id in (SELECT resource_id FROM memo_resource WHERE memo_id = ?)"), append(args, *v)
 	}
 
-	fields := []string{"resource.id", "resource.filename", "resource.external_link", "resource.type", "resource.size", "resource.creator_id", "resource.created_ts", "resource.updated_ts", "internal_path"}
+	fields := []string{"resource.id", "resource.filename", "resource.external_link", "resource.type", "resource.size", "resource.creator_id", "resource.created_ts", "resource.updated_ts", "internal_path", "resource.workspace_id", "resource.storage_id", "resource.object_key"}
 	if find.GetBlob {
 		fields = append(fields, "resource.blob")
 	}
@@ -246,6 +268,9 @@ func listResources(ctx context.Context, tx *sql.Tx, find *FindResource) ([]*Reso
 			&resource.CreatedTs,
 			&resource.UpdatedTs,
 			&resource.InternalPath,
+			&resource.WorkspaceID,
+			&resource.StorageID,
+			&resource.ObjectKey,
 		}
 		if find.GetBlob {
 			dests = append(dests, &resource.Blob)
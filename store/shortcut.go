@@ -16,8 +16,9 @@ type Shortcut struct {
 	UpdatedTs int64
 
 	// Domain specific fields
-	Title   string
-	Payload string
+	Title       string
+	Payload     string
+	WorkspaceID int
 }
 
 type UpdateShortcut struct {
@@ -30,9 +31,10 @@ type UpdateShortcut struct {
 }
 
 type FindShortcut struct {
-	ID        *int
-	CreatorID *int
-	Title     *string
+	ID          *int
+	CreatorID   *int
+	Title       *string
+	WorkspaceID *int
 }
 
 type DeleteShortcut struct {
@@ -51,12 +53,13 @@ func (s *Store) CreateShortcut(ctx context.Context, create *Shortcut) (*Shortcut
 		INSERT INTO shortcut (
 			title, 
 			payload, 
-			creator_id
+			creator_id,
+			workspace_id
 		)
-		VALUES (?, ?, ?)
+		VALUES (?, ?, ?, ?)
 		RETURNING id, created_ts, updated_ts, row_status
 	`
-	if err := tx.QueryRowContext(ctx, query, create.Title, create.Payload, create.CreatorID).Scan(
+	if err := tx.QueryRowContext(ctx, query, create.Title, create.Payload, create.CreatorID, create.WorkspaceID).Scan(
 		&create.ID,
 		&create.CreatedTs,
 		&create.UpdatedTs,
@@ -142,7 +145,7 @@ func (s *Store) UpdateShortcut(ctx context.Context, update *UpdateShortcut) (*Sh
 		UPDATE shortcut
 		SET ` + strings.Join(set, ", ") + `
 		WHERE id = ?
-		RETURNING id, title, payload, creator_id, created_ts, updated_ts, row_status
+		RETURNING id, title, payload, creator_id, created_ts, updated_ts, row_status, workspace_id
 	`
 	shortcut := &Shortcut{}
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(
@@ -153,6 +156,7 @@ func (s *Store) UpdateShortcut(ctx context.Context, update *UpdateShortcut) (*Sh
 		&shortcut.CreatedTs,
 		&shortcut.UpdatedTs,
 		&shortcut.RowStatus,
+		&shortcut.WorkspaceID,
 	); err != nil {
 		return nil, err
 	}
@@ -204,6 +208,9 @@ func listShortcuts(ctx context.Context, tx *sql.Tx, find *FindShortcut) ([]*Shor
 	if v := find.Title; v != nil {
 		where, args = append(where, "title = ?"), append(args, *v)
 	}
+	if v := find.WorkspaceID; v != nil {
+		where, args = append(where, "workspace_id = ?"), append(args, *v)
+	}
 
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
@@ -213,7 +220,8 @@ func listShortcuts(ctx context.Context, tx *sql.Tx, find *FindShortcut) ([]*Shor
 			creator_id,
 			created_ts,
 			updated_ts,
-			row_status
+			row_status,
+			workspace_id
 		FROM shortcut
 		WHERE `+strings.Join(where, " AND ")+`
 		ORDER BY created_ts DESC`,
@@ -235,6 +243,7 @@ func listShortcuts(ctx context.Context, tx *sql.Tx, find *FindShortcut) ([]*Shor
 			&shortcut.CreatedTs,
 			&shortcut.UpdatedTs,
 			&shortcut.RowStatus,
+			&shortcut.WorkspaceID,
 		); err != nil {
 			return nil, err
 		}
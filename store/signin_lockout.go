@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// SignInLockoutKind is the dimension the failed sign-in attempts are tracked by.
+type SignInLockoutKind string
+
+const (
+	SignInLockoutIP       SignInLockoutKind = "IP"
+	SignInLockoutUsername SignInLockoutKind = "USERNAME"
+)
+
+// SignInLockout is the failed sign-in attempts of an IP or a username.
+type SignInLockout struct {
+	ID   int
+	Kind SignInLockoutKind
+	// Key is the IP or the username.
+	Key string
+
+	FailedCount  int
+	LastFailedTs int64
+	// LockedUntilTs is the time until which the sign-in is rejected, 0 if not locked.
+	LockedUntilTs int64
+}
+
+type FindSignInLockout struct {
+	ID   *int
+	Kind *SignInLockoutKind
+	Key  *string
+}
+
+type DeleteSignInLockout struct {
+	ID   *int
+	Kind *SignInLockoutKind
+	Key  *string
+	// LastFailedTsBefore deletes the attempts not repeated since the time.
+	LastFailedTsBefore *int64
+}
+
+func (s *Store) UpsertSignInLockout(ctx context.Context, upsert *SignInLockout) (*SignInLockout, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO signin_lockout (
+			kind,
+			key,
+			failed_count,
+			last_failed_ts,
+			locked_until_ts
+		)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(kind, key) DO UPDATE
+		SET
+			failed_count = EXCLUDED.failed_count,
+			last_failed_ts = EXCLUDED.last_failed_ts,
+			locked_until_ts = EXCLUDED.locked_until_ts
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		upsert.Kind,
+		upsert.Key,
+		upsert.FailedCount,
+		upsert.LastFailedTs,
+		upsert.LockedUntilTs,
+	).Scan(
+		&upsert.ID,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	signInLockout := upsert
+	return signInLockout, nil
+}
+
+func (s *Store) ListSignInLockouts(ctx context.Context, find *FindSignInLockout) ([]*SignInLockout, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listSignInLockouts(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetSignInLockout(ctx context.Context, find *FindSignInLockout) (*SignInLockout, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listSignInLockouts(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) DeleteSignInLockout(ctx context.Context, delete *DeleteSignInLockout) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := delete.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := delete.Kind; v != nil {
+		where, args = append(where, "kind = ?"), append(args, *v)
+	}
+	if v := delete.Key; v != nil {
+		where, args = append(where, "key = ?"), append(args, *v)
+	}
+	if v := delete.LastFailedTsBefore; v != nil {
+		where, args = append(where, "last_failed_ts < ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM signin_lockout WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listSignInLockouts(ctx context.Context, tx *sql.Tx, find *FindSignInLockout) ([]*SignInLockout, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.Kind; v != nil {
+		where, args = append(where, "kind = ?"), append(args, *v)
+	}
+	if v := find.Key; v != nil {
+		where, args = append(where, "key = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			kind,
+			key,
+			failed_count,
+			last_failed_ts,
+			locked_until_ts
+		FROM signin_lockout
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY last_failed_ts DESC, id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*SignInLockout, 0)
+	for rows.Next() {
+		signInLockout := &SignInLockout{}
+		if err := rows.Scan(
+			&signInLockout.ID,
+			&signInLockout.Kind,
+			&signInLockout.Key,
+			&signInLockout.FailedCount,
+			&signInLockout.LastFailedTs,
+			&signInLockout.LockedUntilTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, signInLockout)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
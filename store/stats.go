@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+)
+
+type UserCount struct {
+	Role      Role
+	RowStatus RowStatus
+	Count     int
+}
+
+type MemoVisibilityCount struct {
+	Visibility Visibility
+	Count      int
+}
+
+type MemoDailyCount struct {
+	// Date is the UTC date of the creation of the memos, formatted as YYYY-MM-DD.
+	Date  string
+	Count int
+}
+
+// ResourceStorageType is the storage backend of resources, derived from where their blobs are.
+type ResourceStorageType string
+
+const (
+	// ResourceStorageDatabase is the storage of the resources with their blobs in the database.
+	ResourceStorageDatabase ResourceStorageType = "DATABASE"
+	// ResourceStorageLocal is the storage of the resources with their blobs in the local file system.
+	ResourceStorageLocal ResourceStorageType = "LOCAL"
+	// ResourceStorageExternal is the storage of the resources linked to an external service, such as S3.
+	ResourceStorageExternal ResourceStorageType = "EXTERNAL"
+)
+
+type ResourceStorageUsage struct {
+	Storage ResourceStorageType
+	Count   int
+	Size    int64
+}
+
+// ListUserCounts returns the numbers of users by role and row status.
+func (s *Store) ListUserCounts(ctx context.Context) ([]*UserCount, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT role, row_status, COUNT(*)
+		FROM user
+		GROUP BY role, row_status
+		ORDER BY role, row_status`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*UserCount{}
+	for rows.Next() {
+		userCount := &UserCount{}
+		if err := rows.Scan(&userCount.Role, &userCount.RowStatus, &userCount.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, userCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CountActiveUsers returns the number of distinct users with activities of the type created since the time.
+func (s *Store) CountActiveUsers(ctx context.Context, activityType string, createdTsAfter int64) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count := 0
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT creator_id)
+		FROM activity
+		WHERE type = ? AND created_ts >= ?`,
+		activityType, createdTsAfter,
+	).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ListMemoVisibilityCounts returns the numbers of memos by visibility.
+func (s *Store) ListMemoVisibilityCounts(ctx context.Context) ([]*MemoVisibilityCount, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT visibility, COUNT(*)
+		FROM memo
+		GROUP BY visibility
+		ORDER BY visibility`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*MemoVisibilityCount{}
+	for rows.Next() {
+		memoVisibilityCount := &MemoVisibilityCount{}
+		if err := rows.Scan(&memoVisibilityCount.Visibility, &memoVisibilityCount.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, memoVisibilityCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListMemoDailyCounts returns the numbers of memos created per UTC day since the time, the days without memos are omitted.
+func (s *Store) ListMemoDailyCounts(ctx context.Context, createdTsAfter int64) ([]*MemoDailyCount, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT date(created_ts, 'unixepoch') AS created_date, COUNT(*)
+		FROM memo
+		WHERE created_ts >= ?
+		GROUP BY created_date
+		ORDER BY created_date`,
+		createdTsAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*MemoDailyCount{}
+	for rows.Next() {
+		memoDailyCount := &MemoDailyCount{}
+		if err := rows.Scan(&memoDailyCount.Date, &memoDailyCount.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, memoDailyCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListResourceStorageUsages returns the numbers and bytes of resources by storage.
+func (s *Store) ListResourceStorageUsages(ctx context.Context) ([]*ResourceStorageUsage, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			CASE
+				WHEN internal_path != '' THEN ?
+				WHEN external_link != '' THEN ?
+				ELSE ?
+			END AS storage,
+			COUNT(*),
+			COALESCE(SUM(size), 0)
+		FROM resource
+		GROUP BY storage
+		ORDER BY storage`,
+		ResourceStorageLocal, ResourceStorageExternal, ResourceStorageDatabase,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*ResourceStorageUsage{}
+	for rows.Next() {
+		resourceStorageUsage := &ResourceStorageUsage{}
+		if err := rows.Scan(&resourceStorageUsage.Storage, &resourceStorageUsage.Count, &resourceStorageUsage.Size); err != nil {
+			return nil, err
+		}
+		list = append(list, resourceStorageUsage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -116,6 +116,54 @@ func (*Store) vacuumImpl(ctx context.Context, tx *sql.Tx) error {
 		return err
 	}
 	if err := vacuumTag(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumFeedSubscription(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumAIUsage(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoEmbedding(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoSuggestion(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserTwoFactor(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumWebAuthnCredential(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserSession(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumVerificationToken(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumInvitation(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserIdentity(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserCustomRole(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumWorkspaceMember(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumWorkspaceSetting(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserGroupMember(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserGroupCustomRole(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoGroupShare(ctx, tx); err != nil {
 		// Prevent revive warning.
 		return err
 	}
@@ -42,6 +42,7 @@ func (s *Store) UpsertSystemSetting(ctx context.Context, upsert *SystemSetting)
 	}
 
 	systemSetting := upsert
+	s.systemSettingCache.Store(systemSetting.Name, systemSetting)
 	return systemSetting, nil
 }
 
@@ -69,7 +70,7 @@ func (s *Store) ListSystemSettings(ctx context.Context, find *FindSystemSetting)
 
 func (s *Store) GetSystemSetting(ctx context.Context, find *FindSystemSetting) (*SystemSetting, error) {
 	if find.Name != "" {
-		if cache, ok := s.systemSettingCache.Load(find.Name); ok {
+		if cache, ok := loadCache(&s.systemSettingCache, "system_setting", find.Name); ok {
 			return cache.(*SystemSetting), nil
 		}
 	}
@@ -8,17 +8,20 @@ import (
 )
 
 type Tag struct {
-	Name      string
-	CreatorID int
+	Name        string
+	CreatorID   int
+	WorkspaceID int
 }
 
 type FindTag struct {
-	CreatorID int
+	CreatorID   int
+	WorkspaceID *int
 }
 
 type DeleteTag struct {
-	Name      string
-	CreatorID int
+	Name        string
+	CreatorID   int
+	WorkspaceID int
 }
 
 func (s *Store) UpsertTag(ctx context.Context, upsert *Tag) (*Tag, error) {
@@ -30,14 +33,14 @@ func (s *Store) UpsertTag(ctx context.Context, upsert *Tag) (*Tag, error) {
 
 	query := `
 		INSERT INTO tag (
-			name, creator_id
+			name, creator_id, workspace_id
 		)
-		VALUES (?, ?)
-		ON CONFLICT(name, creator_id) DO UPDATE 
+		VALUES (?, ?, ?)
+		ON CONFLICT(name, creator_id, workspace_id) DO UPDATE 
 		SET
 			name = EXCLUDED.name
 	`
-	if _, err := tx.ExecContext(ctx, query, upsert.Name, upsert.CreatorID); err != nil {
+	if _, err := tx.ExecContext(ctx, query, upsert.Name, upsert.CreatorID, upsert.WorkspaceID); err != nil {
 		return nil, err
 	}
 
@@ -57,10 +60,14 @@ func (s *Store) ListTags(ctx context.Context, find *FindTag) ([]*Tag, error) {
 	defer tx.Rollback()
 
 	where, args := []string{"creator_id = ?"}, []any{find.CreatorID}
+	if v := find.WorkspaceID; v != nil {
+		where, args = append(where, "workspace_id = ?"), append(args, *v)
+	}
 	query := `
 		SELECT
 			name,
-			creator_id
+			creator_id,
+			workspace_id
 		FROM tag
 		WHERE ` + strings.Join(where, " AND ") + `
 		ORDER BY name ASC
@@ -77,6 +84,7 @@ func (s *Store) ListTags(ctx context.Context, find *FindTag) ([]*Tag, error) {
 		if err := rows.Scan(
 			&tag.Name,
 			&tag.CreatorID,
+			&tag.WorkspaceID,
 		); err != nil {
 			return nil, err
 		}
@@ -102,7 +110,7 @@ func (s *Store) DeleteTag(ctx context.Context, delete *DeleteTag) error {
 	}
 	defer tx.Rollback()
 
-	where, args := []string{"name = ?", "creator_id = ?"}, []any{delete.Name, delete.CreatorID}
+	where, args := []string{"name = ?", "creator_id = ?", "workspace_id = ?"}, []any{delete.Name, delete.CreatorID, delete.WorkspaceID}
 	query := `DELETE FROM tag WHERE ` + strings.Join(where, " AND ")
 	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -40,28 +40,31 @@ type User struct {
 	UpdatedTs int64
 
 	// Domain specific fields
-	Username     string
-	Role         Role
-	Email        string
-	Nickname     string
-	PasswordHash string
-	OpenID       string
-	AvatarURL    string
+	Username      string
+	Role          Role
+	Email         string
+	EmailVerified bool
+	Nickname      string
+	PasswordHash  string
+	OpenID        string
+	AvatarURL     string
 }
 
 type UpdateUser struct {
 	ID int
 
-	UpdatedTs    *int64
-	RowStatus    *RowStatus
-	Username     *string `json:"username"`
-	Email        *string `json:"email"`
-	Nickname     *string `json:"nickname"`
-	Password     *string `json:"password"`
-	ResetOpenID  *bool   `json:"resetOpenId"`
-	AvatarURL    *string `json:"avatarUrl"`
-	PasswordHash *string
-	OpenID       *string
+	UpdatedTs     *int64
+	RowStatus     *RowStatus
+	Username      *string `json:"username"`
+	Role          *Role
+	Email         *string `json:"email"`
+	EmailVerified *bool
+	Nickname      *string `json:"nickname"`
+	Password      *string `json:"password"`
+	ResetOpenID   *bool   `json:"resetOpenId"`
+	AvatarURL     *string `json:"avatarUrl"`
+	PasswordHash  *string
+	OpenID        *string
 }
 
 type FindUser struct {
@@ -76,6 +79,21 @@ type FindUser struct {
 
 type DeleteUser struct {
 	ID int
+	// TransferToUserID is the user taking over the memos, resources and tags of the deleted user,
+	// which are deleted with the user if not set.
+	TransferToUserID *int
+}
+
+// UserContent is the amount of the content owned by a user.
+type UserContent struct {
+	MemoCount             int
+	ResourceCount         int
+	ResourceSize          int64
+	ShortcutCount         int
+	TagCount              int
+	SettingCount          int
+	OrganizerCount        int
+	FeedSubscriptionCount int
 }
 
 func (s *Store) CreateUser(ctx context.Context, create *User) (*User, error) {
@@ -85,32 +103,7 @@ func (s *Store) CreateUser(ctx context.Context, create *User) (*User, error) {
 	}
 	defer tx.Rollback()
 
-	query := `
-		INSERT INTO user (
-			username,
-			role,
-			email,
-			nickname,
-			password_hash,
-			open_id
-		)
-		VALUES (?, ?, ?, ?, ?, ?)
-		RETURNING id, avatar_url, created_ts, updated_ts, row_status
-	`
-	if err := tx.QueryRowContext(ctx, query,
-		create.Username,
-		create.Role,
-		create.Email,
-		create.Nickname,
-		create.PasswordHash,
-		create.OpenID,
-	).Scan(
-		&create.ID,
-		&create.AvatarURL,
-		&create.CreatedTs,
-		&create.UpdatedTs,
-		&create.RowStatus,
-	); err != nil {
+	if err := createUser(ctx, tx, create); err != nil {
 		return nil, err
 	}
 	if err := tx.Commit(); err != nil {
@@ -139,9 +132,15 @@ func (s *Store) UpdateUser(ctx context.Context, update *UpdateUser) (*User, erro
 	if v := update.Username; v != nil {
 		set, args = append(set, "username = ?"), append(args, *v)
 	}
+	if v := update.Role; v != nil {
+		set, args = append(set, "role = ?"), append(args, *v)
+	}
 	if v := update.Email; v != nil {
 		set, args = append(set, "email = ?"), append(args, *v)
 	}
+	if v := update.EmailVerified; v != nil {
+		set, args = append(set, "email_verified = ?"), append(args, *v)
+	}
 	if v := update.Nickname; v != nil {
 		set, args = append(set, "nickname = ?"), append(args, *v)
 	}
@@ -160,7 +159,7 @@ func (s *Store) UpdateUser(ctx context.Context, update *UpdateUser) (*User, erro
 		UPDATE user
 		SET ` + strings.Join(set, ", ") + `
 		WHERE id = ?
-		RETURNING id, username, role, email, nickname, password_hash, open_id, avatar_url, created_ts, updated_ts, row_status
+		RETURNING id, username, role, email, email_verified, nickname, password_hash, open_id, avatar_url, created_ts, updated_ts, row_status
 	`
 	user := &User{}
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(
@@ -168,6 +167,7 @@ func (s *Store) UpdateUser(ctx context.Context, update *UpdateUser) (*User, erro
 		&user.Username,
 		&user.Role,
 		&user.Email,
+		&user.EmailVerified,
 		&user.Nickname,
 		&user.PasswordHash,
 		&user.OpenID,
@@ -211,7 +211,7 @@ func (s *Store) ListUsers(ctx context.Context, find *FindUser) ([]*User, error)
 
 func (s *Store) GetUser(ctx context.Context, find *FindUser) (*User, error) {
 	if find.ID != nil {
-		if cache, ok := s.userCache.Load(*find.ID); ok {
+		if cache, ok := loadCache(&s.userCache, "user", *find.ID); ok {
 			return cache.(*User), nil
 		}
 	}
@@ -246,6 +246,17 @@ func (s *Store) DeleteUser(ctx context.Context, delete *DeleteUser) error {
 	}
 	defer tx.Rollback()
 
+	if v := delete.TransferToUserID; v != nil {
+		for _, stmt := range []string{
+			`UPDATE memo SET creator_id = ? WHERE creator_id = ?`,
+			`UPDATE resource SET creator_id = ? WHERE creator_id = ?`,
+			`INSERT OR IGNORE INTO tag (name, creator_id, workspace_id) SELECT name, ?, workspace_id FROM tag WHERE creator_id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, *v, delete.ID); err != nil {
+				return err
+			}
+		}
+	}
 	result, err := tx.ExecContext(ctx, `
 		DELETE FROM user WHERE id = ?
 	`, delete.ID)
@@ -272,6 +283,76 @@ func (s *Store) DeleteUser(ctx context.Context, delete *DeleteUser) error {
 	return nil
 }
 
+// GetUserContent counts the content owned by the user.
+func (s *Store) GetUserContent(ctx context.Context, userID int) (*UserContent, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userContent := &UserContent{}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM memo WHERE creator_id = ?),
+			(SELECT COUNT(*) FROM resource WHERE creator_id = ?),
+			(SELECT COALESCE(SUM(size), 0) FROM resource WHERE creator_id = ?),
+			(SELECT COUNT(*) FROM shortcut WHERE creator_id = ?),
+			(SELECT COUNT(*) FROM tag WHERE creator_id = ?),
+			(SELECT COUNT(*) FROM user_setting WHERE user_id = ?),
+			(SELECT COUNT(*) FROM memo_organizer WHERE user_id = ?),
+			(SELECT COUNT(*) FROM feed_subscription WHERE creator_id = ?)
+	`, userID, userID, userID, userID, userID, userID, userID, userID).Scan(
+		&userContent.MemoCount,
+		&userContent.ResourceCount,
+		&userContent.ResourceSize,
+		&userContent.ShortcutCount,
+		&userContent.TagCount,
+		&userContent.SettingCount,
+		&userContent.OrganizerCount,
+		&userContent.FeedSubscriptionCount,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userContent, nil
+}
+
+func createUser(ctx context.Context, tx *sql.Tx, create *User) error {
+	query := `
+		INSERT INTO user (
+			username,
+			role,
+			email,
+			email_verified,
+			nickname,
+			password_hash,
+			open_id
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, avatar_url, created_ts, updated_ts, row_status
+	`
+	return tx.QueryRowContext(ctx, query,
+		create.Username,
+		create.Role,
+		create.Email,
+		create.EmailVerified,
+		create.Nickname,
+		create.PasswordHash,
+		create.OpenID,
+	).Scan(
+		&create.ID,
+		&create.AvatarURL,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.RowStatus,
+	)
+}
+
 func listUsers(ctx context.Context, tx *sql.Tx, find *FindUser) ([]*User, error) {
 	where, args := []string{"1 = 1"}, []any{}
 
@@ -300,6 +381,7 @@ func listUsers(ctx context.Context, tx *sql.Tx, find *FindUser) ([]*User, error)
 			username,
 			role,
 			email,
+			email_verified,
 			nickname,
 			password_hash,
 			open_id,
@@ -325,6 +407,7 @@ func listUsers(ctx context.Context, tx *sql.Tx, find *FindUser) ([]*User, error)
 			&user.Username,
 			&user.Role,
 			&user.Email,
+			&user.EmailVerified,
 			&user.Nickname,
 			&user.PasswordHash,
 			&user.OpenID,
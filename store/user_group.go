@@ -0,0 +1,556 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// UserGroup is a group of users managed by the admins, sharing the memos shared with it and the custom roles assigned to it.
+type UserGroup struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name        string
+	Description string
+	// IdentityProviderGroup is the group of the identity providers whose users are synced into the group on sign-in, empty means none.
+	IdentityProviderGroup string
+}
+
+type FindUserGroup struct {
+	ID   *int
+	Name *string
+	// MemberID finds the groups the user is a member of.
+	MemberID *int
+}
+
+type UpdateUserGroup struct {
+	ID                    int
+	UpdatedTs             *int64
+	Name                  *string
+	Description           *string
+	IdentityProviderGroup *string
+}
+
+type DeleteUserGroup struct {
+	ID int
+}
+
+type UserGroupMember struct {
+	GroupID int
+	UserID  int
+	// IdentityProviderID is the identity provider which synced the member, 0 means added manually.
+	IdentityProviderID int
+	CreatedTs          int64
+}
+
+type FindUserGroupMember struct {
+	GroupID *int
+	UserID  *int
+}
+
+type DeleteUserGroupMember struct {
+	GroupID int
+	UserID  int
+}
+
+func (s *Store) CreateUserGroup(ctx context.Context, create *UserGroup) (*UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_group (
+			creator_id,
+			name,
+			description,
+			idp_group
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, created_ts, updated_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.CreatorID,
+		create.Name,
+		create.Description,
+		create.IdentityProviderGroup,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userGroup := create
+	return userGroup, nil
+}
+
+func (s *Store) ListUserGroups(ctx context.Context, find *FindUserGroup) ([]*UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserGroups(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetUserGroup(ctx context.Context, find *FindUserGroup) (*UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserGroups(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateUserGroup(ctx context.Context, update *UpdateUserGroup) (*UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := update.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	if v := update.IdentityProviderGroup; v != nil {
+		set, args = append(set, "idp_group = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE user_group
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, name, description, idp_group
+	`
+	userGroup := &UserGroup{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&userGroup.ID,
+		&userGroup.CreatorID,
+		&userGroup.CreatedTs,
+		&userGroup.UpdatedTs,
+		&userGroup.Name,
+		&userGroup.Description,
+		&userGroup.IdentityProviderGroup,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userGroup, nil
+}
+
+// DeleteUserGroup deletes the group with its members, custom role assignments and memo shares.
+func (s *Store) DeleteUserGroup(ctx context.Context, delete *DeleteUserGroup) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`DELETE FROM user_group_member WHERE group_id = ?`,
+		`DELETE FROM user_group_custom_role WHERE group_id = ?`,
+		`DELETE FROM memo_group_share WHERE group_id = ?`,
+		`DELETE FROM user_group WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, delete.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) UpsertUserGroupMember(ctx context.Context, upsert *UserGroupMember) (*UserGroupMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_group_member (
+			group_id, user_id, idp_id
+		)
+		VALUES (?, ?, ?)
+		ON CONFLICT(group_id, user_id) DO UPDATE
+		SET
+			idp_id = EXCLUDED.idp_id
+		RETURNING created_ts
+	`
+	if err := tx.QueryRowContext(ctx, query, upsert.GroupID, upsert.UserID, upsert.IdentityProviderID).Scan(&upsert.CreatedTs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userGroupMember := upsert
+	return userGroupMember, nil
+}
+
+func (s *Store) ListUserGroupMembers(ctx context.Context, find *FindUserGroupMember) ([]*UserGroupMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.GroupID; v != nil {
+		where, args = append(where, "group_id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			group_id,
+			user_id,
+			idp_id,
+			created_ts
+		FROM user_group_member
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts ASC, user_id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*UserGroupMember, 0)
+	for rows.Next() {
+		userGroupMember := &UserGroupMember{}
+		if err := rows.Scan(
+			&userGroupMember.GroupID,
+			&userGroupMember.UserID,
+			&userGroupMember.IdentityProviderID,
+			&userGroupMember.CreatedTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, userGroupMember)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) DeleteUserGroupMember(ctx context.Context, delete *DeleteUserGroupMember) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_group_member WHERE group_id = ? AND user_id = ?`, delete.GroupID, delete.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetUserGroupCustomRoles replaces the custom roles assigned to the group.
+func (s *Store) SetUserGroupCustomRoles(ctx context.Context, groupID int, roleIDList []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_group_custom_role WHERE group_id = ?`, groupID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDList {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO user_group_custom_role (group_id, role_id) VALUES (?, ?)`, groupID, roleID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetMemoGroupShares replaces the groups the memo is shared with.
+func (s *Store) SetMemoGroupShares(ctx context.Context, memoID int, groupIDList []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM memo_group_share WHERE memo_id = ?`, memoID); err != nil {
+		return err
+	}
+	for _, groupID := range groupIDList {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO memo_group_share (memo_id, group_id) VALUES (?, ?)`, memoID, groupID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListMemoGroupShares returns the IDs of the groups the memo is shared with.
+func (s *Store) ListMemoGroupShares(ctx context.Context, memoID int) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT group_id FROM memo_group_share WHERE memo_id = ? ORDER BY group_id ASC`, memoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []int{}
+	for rows.Next() {
+		var groupID int
+		if err := rows.Scan(&groupID); err != nil {
+			return nil, err
+		}
+		list = append(list, groupID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// IsMemoSharedWithUser returns whether the memo is shared with any group of the user.
+func (s *Store) IsMemoSharedWithUser(ctx context.Context, memoID int, userID int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var shared bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM memo_group_share
+			WHERE memo_id = ? AND group_id IN (SELECT group_id FROM user_group_member WHERE user_id = ?)
+		)`,
+		memoID, userID,
+	).Scan(&shared); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return shared, nil
+}
+
+func listUserGroups(ctx context.Context, tx *sql.Tx, find *FindUserGroup) ([]*UserGroup, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.Name; v != nil {
+		where, args = append(where, "name = ?"), append(args, *v)
+	}
+	if v := find.MemberID; v != nil {
+		where, args = append(where, "id IN (SELECT group_id FROM user_group_member WHERE user_id = ?)"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			name,
+			description,
+			idp_group
+		FROM user_group
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*UserGroup, 0)
+	for rows.Next() {
+		userGroup := &UserGroup{}
+		if err := rows.Scan(
+			&userGroup.ID,
+			&userGroup.CreatorID,
+			&userGroup.CreatedTs,
+			&userGroup.UpdatedTs,
+			&userGroup.Name,
+			&userGroup.Description,
+			&userGroup.IdentityProviderGroup,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, userGroup)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumUserGroupMember(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_group_member
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR group_id NOT IN (
+			SELECT
+				id
+			FROM
+				user_group
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func vacuumUserGroupCustomRole(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_group_custom_role
+	WHERE
+		group_id NOT IN (
+			SELECT
+				id
+			FROM
+				user_group
+		)
+		OR role_id NOT IN (
+			SELECT
+				id
+			FROM
+				custom_role
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func vacuumMemoGroupShare(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_group_share
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)
+		OR group_id NOT IN (
+			SELECT
+				id
+			FROM
+				user_group
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
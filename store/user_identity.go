@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// UserIdentity links the identity of an identity provider to a user.
+type UserIdentity struct {
+	ID        int
+	UserID    int
+	CreatedTs int64
+
+	IdentityProviderID int
+	// ExternalID is the subject of the user at the identity provider.
+	ExternalID string
+	// Email is the email of the identity when linked, for display only.
+	Email string
+}
+
+type FindUserIdentity struct {
+	ID                 *int
+	UserID             *int
+	IdentityProviderID *int
+	ExternalID         *string
+}
+
+type DeleteUserIdentity struct {
+	ID     int
+	UserID *int
+}
+
+func (s *Store) CreateUserIdentity(ctx context.Context, create *UserIdentity) (*UserIdentity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_identity (
+			user_id,
+			idp_id,
+			external_id,
+			email
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, created_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.UserID,
+		create.IdentityProviderID,
+		create.ExternalID,
+		create.Email,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userIdentity := create
+	return userIdentity, nil
+}
+
+func (s *Store) ListUserIdentities(ctx context.Context, find *FindUserIdentity) ([]*UserIdentity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserIdentities(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetUserIdentity(ctx context.Context, find *FindUserIdentity) (*UserIdentity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserIdentities(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) DeleteUserIdentity(ctx context.Context, delete *DeleteUserIdentity) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"id = ?"}, []any{delete.ID}
+	if v := delete.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_identity WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listUserIdentities(ctx context.Context, tx *sql.Tx, find *FindUserIdentity) ([]*UserIdentity, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.IdentityProviderID; v != nil {
+		where, args = append(where, "idp_id = ?"), append(args, *v)
+	}
+	if v := find.ExternalID; v != nil {
+		where, args = append(where, "external_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			idp_id,
+			external_id,
+			email
+		FROM user_identity
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts ASC, id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*UserIdentity, 0)
+	for rows.Next() {
+		userIdentity := &UserIdentity{}
+		if err := rows.Scan(
+			&userIdentity.ID,
+			&userIdentity.UserID,
+			&userIdentity.CreatedTs,
+			&userIdentity.IdentityProviderID,
+			&userIdentity.ExternalID,
+			&userIdentity.Email,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, userIdentity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumUserIdentity(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_identity
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR idp_id NOT IN (
+			SELECT
+				id
+			FROM
+				idp
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// UserSession is a signed-in session of a user, referenced by the id of the issued tokens.
+type UserSession struct {
+	ID        string
+	UserID    int
+	CreatedTs int64
+	// UpdatedTs is the time the tokens of the session were last issued.
+	UpdatedTs  int64
+	LastSeenTs int64
+
+	UserAgent string
+	IP        string
+}
+
+type FindUserSession struct {
+	ID     *string
+	UserID *int
+}
+
+type UpdateUserSession struct {
+	ID         string
+	UpdatedTs  *int64
+	LastSeenTs *int64
+	IP         *string
+}
+
+type DeleteUserSession struct {
+	ID     *string
+	UserID *int
+	// ExcludeID keeps the session, e.g. the current one, when deleting the sessions of the user.
+	ExcludeID *string
+	// UpdatedTsBefore deletes the sessions not refreshed since the time.
+	UpdatedTsBefore *int64
+}
+
+func (s *Store) CreateUserSession(ctx context.Context, create *UserSession) (*UserSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_session (
+			id,
+			user_id,
+			user_agent,
+			ip
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING created_ts, updated_ts, last_seen_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.ID,
+		create.UserID,
+		create.UserAgent,
+		create.IP,
+	).Scan(
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.LastSeenTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userSession := create
+	return userSession, nil
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, find *FindUserSession) ([]*UserSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserSessions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetUserSession(ctx context.Context, find *FindUserSession) (*UserSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserSessions(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateUserSession(ctx context.Context, update *UpdateUserSession) (*UserSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.LastSeenTs; v != nil {
+		set, args = append(set, "last_seen_ts = ?"), append(args, *v)
+	}
+	if v := update.IP; v != nil {
+		set, args = append(set, "ip = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE user_session
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, user_id, created_ts, updated_ts, last_seen_ts, user_agent, ip
+	`
+	userSession := &UserSession{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&userSession.ID,
+		&userSession.UserID,
+		&userSession.CreatedTs,
+		&userSession.UpdatedTs,
+		&userSession.LastSeenTs,
+		&userSession.UserAgent,
+		&userSession.IP,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userSession, nil
+}
+
+func (s *Store) DeleteUserSession(ctx context.Context, delete *DeleteUserSession) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := delete.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := delete.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := delete.ExcludeID; v != nil {
+		where, args = append(where, "id != ?"), append(args, *v)
+	}
+	if v := delete.UpdatedTsBefore; v != nil {
+		where, args = append(where, "updated_ts < ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_session WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listUserSessions(ctx context.Context, tx *sql.Tx, find *FindUserSession) ([]*UserSession, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			updated_ts,
+			last_seen_ts,
+			user_agent,
+			ip
+		FROM user_session
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY last_seen_ts DESC, created_ts DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*UserSession, 0)
+	for rows.Next() {
+		userSession := &UserSession{}
+		if err := rows.Scan(
+			&userSession.ID,
+			&userSession.UserID,
+			&userSession.CreatedTs,
+			&userSession.UpdatedTs,
+			&userSession.LastSeenTs,
+			&userSession.UserAgent,
+			&userSession.IP,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, userSession)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumUserSession(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_session
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
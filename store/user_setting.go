@@ -17,6 +17,11 @@ type FindUserSetting struct {
 	Key    string
 }
 
+type DeleteUserSetting struct {
+	UserID int
+	Key    string
+}
+
 func (s *Store) UpsertUserSetting(ctx context.Context, upsert *UserSetting) (*UserSetting, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -69,7 +74,7 @@ func (s *Store) ListUserSettings(ctx context.Context, find *FindUserSetting) ([]
 
 func (s *Store) GetUserSetting(ctx context.Context, find *FindUserSetting) (*UserSetting, error) {
 	if find.UserID != nil {
-		if cache, ok := s.userSettingCache.Load(getUserSettingCacheKey(*find.UserID, find.Key)); ok {
+		if cache, ok := loadCache(&s.userSettingCache, "user_setting", getUserSettingCacheKey(*find.UserID, find.Key)); ok {
 			return cache.(*UserSetting), nil
 		}
 	}
@@ -98,6 +103,26 @@ func (s *Store) GetUserSetting(ctx context.Context, find *FindUserSetting) (*Use
 	return userSetting, nil
 }
 
+func (s *Store) DeleteUserSetting(ctx context.Context, delete *DeleteUserSetting) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `DELETE FROM user_setting WHERE user_id = ? AND key = ?`
+	if _, err := tx.ExecContext(ctx, stmt, delete.UserID, delete.Key); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.userSettingCache.Delete(getUserSettingCacheKey(delete.UserID, delete.Key))
+	return nil
+}
+
 func listUserSettings(ctx context.Context, tx *sql.Tx, find *FindUserSetting) ([]*UserSetting, error) {
 	where, args := []string{"1 = 1"}, []any{}
 
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// UserTwoFactor is the TOTP two-factor authentication of a user.
+type UserTwoFactor struct {
+	UserID    int
+	CreatedTs int64
+	UpdatedTs int64
+
+	Secret string
+	// Enabled is false until the user confirms the secret with a code.
+	Enabled bool
+	// RecoveryCodes are the hashes of the unused recovery codes.
+	RecoveryCodes []string
+	// LastUsedStep is the time step of the last accepted code, used to reject replayed codes.
+	LastUsedStep int64
+}
+
+type FindUserTwoFactor struct {
+	UserID  *int
+	Enabled *bool
+}
+
+type UpdateUserTwoFactor struct {
+	UserID        int
+	Enabled       *bool
+	RecoveryCodes *[]string
+	LastUsedStep  *int64
+}
+
+type DeleteUserTwoFactor struct {
+	UserID int
+}
+
+func (s *Store) UpsertUserTwoFactor(ctx context.Context, upsert *UserTwoFactor) (*UserTwoFactor, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	recoveryCodes, err := json.Marshal(upsert.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO user_two_factor (
+			user_id,
+			secret,
+			enabled,
+			recovery_codes,
+			last_used_step
+		)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE
+		SET
+			secret = EXCLUDED.secret,
+			enabled = EXCLUDED.enabled,
+			recovery_codes = EXCLUDED.recovery_codes,
+			last_used_step = EXCLUDED.last_used_step,
+			updated_ts = strftime('%s', 'now')
+		RETURNING created_ts, updated_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		upsert.UserID,
+		upsert.Secret,
+		upsert.Enabled,
+		string(recoveryCodes),
+		upsert.LastUsedStep,
+	).Scan(
+		&upsert.CreatedTs,
+		&upsert.UpdatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userTwoFactor := upsert
+	return userTwoFactor, nil
+}
+
+func (s *Store) ListUserTwoFactors(ctx context.Context, find *FindUserTwoFactor) ([]*UserTwoFactor, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserTwoFactors(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetUserTwoFactor(ctx context.Context, find *FindUserTwoFactor) (*UserTwoFactor, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listUserTwoFactors(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateUserTwoFactor(ctx context.Context, update *UpdateUserTwoFactor) (*UserTwoFactor, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{"updated_ts = strftime('%s', 'now')"}, []any{}
+	if v := update.Enabled; v != nil {
+		set, args = append(set, "enabled = ?"), append(args, *v)
+	}
+	if v := update.RecoveryCodes; v != nil {
+		recoveryCodes, err := json.Marshal(*v)
+		if err != nil {
+			return nil, err
+		}
+		set, args = append(set, "recovery_codes = ?"), append(args, string(recoveryCodes))
+	}
+	if v := update.LastUsedStep; v != nil {
+		set, args = append(set, "last_used_step = ?"), append(args, *v)
+	}
+	args = append(args, update.UserID)
+
+	query := `
+		UPDATE user_two_factor
+		SET ` + strings.Join(set, ", ") + `
+		WHERE user_id = ?
+		RETURNING user_id, created_ts, updated_ts, secret, enabled, recovery_codes, last_used_step
+	`
+	userTwoFactor := &UserTwoFactor{}
+	var recoveryCodes string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&userTwoFactor.UserID,
+		&userTwoFactor.CreatedTs,
+		&userTwoFactor.UpdatedTs,
+		&userTwoFactor.Secret,
+		&userTwoFactor.Enabled,
+		&recoveryCodes,
+		&userTwoFactor.LastUsedStep,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(recoveryCodes), &userTwoFactor.RecoveryCodes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userTwoFactor, nil
+}
+
+func (s *Store) DeleteUserTwoFactor(ctx context.Context, delete *DeleteUserTwoFactor) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_two_factor WHERE user_id = ?`, delete.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listUserTwoFactors(ctx context.Context, tx *sql.Tx, find *FindUserTwoFactor) ([]*UserTwoFactor, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.Enabled; v != nil {
+		where, args = append(where, "enabled = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			user_id,
+			created_ts,
+			updated_ts,
+			secret,
+			enabled,
+			recovery_codes,
+			last_used_step
+		FROM user_two_factor
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY user_id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*UserTwoFactor, 0)
+	for rows.Next() {
+		userTwoFactor := &UserTwoFactor{}
+		var recoveryCodes string
+		if err := rows.Scan(
+			&userTwoFactor.UserID,
+			&userTwoFactor.CreatedTs,
+			&userTwoFactor.UpdatedTs,
+			&userTwoFactor.Secret,
+			&userTwoFactor.Enabled,
+			&recoveryCodes,
+			&userTwoFactor.LastUsedStep,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(recoveryCodes), &userTwoFactor.RecoveryCodes); err != nil {
+			return nil, err
+		}
+		list = append(list, userTwoFactor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumUserTwoFactor(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_two_factor
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
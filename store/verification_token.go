@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// VerificationTokenKind is the purpose of a verification token.
+type VerificationTokenKind string
+
+const (
+	VerificationTokenEmailVerification VerificationTokenKind = "EMAIL_VERIFICATION"
+	VerificationTokenPasswordReset     VerificationTokenKind = "PASSWORD_RESET"
+)
+
+// VerificationToken is a one-time token sent to the email of a user.
+type VerificationToken struct {
+	ID        int
+	UserID    int
+	CreatedTs int64
+	ExpiredTs int64
+
+	Kind VerificationTokenKind
+	// TokenHash is the hash of the token, the token itself is only sent to the user.
+	TokenHash string
+	// Email is the email the token was sent to.
+	Email string
+}
+
+type FindVerificationToken struct {
+	ID        *int
+	UserID    *int
+	Kind      *VerificationTokenKind
+	TokenHash *string
+}
+
+type DeleteVerificationToken struct {
+	ID     *int
+	UserID *int
+	Kind   *VerificationTokenKind
+	// ExpiredTsBefore deletes the tokens expired before the time.
+	ExpiredTsBefore *int64
+}
+
+func (s *Store) CreateVerificationToken(ctx context.Context, create *VerificationToken) (*VerificationToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO verification_token (
+			user_id,
+			expired_ts,
+			kind,
+			token_hash,
+			email
+		)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.UserID,
+		create.ExpiredTs,
+		create.Kind,
+		create.TokenHash,
+		create.Email,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	verificationToken := create
+	return verificationToken, nil
+}
+
+func (s *Store) GetVerificationToken(ctx context.Context, find *FindVerificationToken) (*VerificationToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listVerificationTokens(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) DeleteVerificationToken(ctx context.Context, delete *DeleteVerificationToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := delete.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := delete.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := delete.Kind; v != nil {
+		where, args = append(where, "kind = ?"), append(args, *v)
+	}
+	if v := delete.ExpiredTsBefore; v != nil {
+		where, args = append(where, "expired_ts < ?"), append(args, *v)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM verification_token WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listVerificationTokens(ctx context.Context, tx *sql.Tx, find *FindVerificationToken) ([]*VerificationToken, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.Kind; v != nil {
+		where, args = append(where, "kind = ?"), append(args, *v)
+	}
+	if v := find.TokenHash; v != nil {
+		where, args = append(where, "token_hash = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			expired_ts,
+			kind,
+			token_hash,
+			email
+		FROM verification_token
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts DESC, id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*VerificationToken, 0)
+	for rows.Next() {
+		verificationToken := &VerificationToken{}
+		if err := rows.Scan(
+			&verificationToken.ID,
+			&verificationToken.UserID,
+			&verificationToken.CreatedTs,
+			&verificationToken.ExpiredTs,
+			&verificationToken.Kind,
+			&verificationToken.TokenHash,
+			&verificationToken.Email,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, verificationToken)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumVerificationToken(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		verification_token
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// WebAuthnCredential is a passkey registered by a user.
+type WebAuthnCredential struct {
+	ID        int
+	UserID    int
+	CreatedTs int64
+	UpdatedTs int64
+
+	Name string
+	// CredentialID is the base64url encoded credential id assigned by the authenticator.
+	CredentialID string
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey  []byte
+	SignCount  uint32
+	LastUsedTs int64
+}
+
+type FindWebAuthnCredential struct {
+	ID           *int
+	UserID       *int
+	CredentialID *string
+}
+
+type UpdateWebAuthnCredential struct {
+	ID         int
+	Name       *string
+	SignCount  *uint32
+	LastUsedTs *int64
+}
+
+type DeleteWebAuthnCredential struct {
+	ID int
+}
+
+func (s *Store) CreateWebAuthnCredential(ctx context.Context, create *WebAuthnCredential) (*WebAuthnCredential, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webauthn_credential (
+			user_id,
+			name,
+			credential_id,
+			public_key,
+			sign_count
+		)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_ts, updated_ts, last_used_ts
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		create.UserID,
+		create.Name,
+		create.CredentialID,
+		create.PublicKey,
+		create.SignCount,
+	).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.LastUsedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	webAuthnCredential := create
+	return webAuthnCredential, nil
+}
+
+func (s *Store) ListWebAuthnCredentials(ctx context.Context, find *FindWebAuthnCredential) ([]*WebAuthnCredential, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWebAuthnCredentials(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetWebAuthnCredential(ctx context.Context, find *FindWebAuthnCredential) (*WebAuthnCredential, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWebAuthnCredentials(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateWebAuthnCredential(ctx context.Context, update *UpdateWebAuthnCredential) (*WebAuthnCredential, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{"updated_ts = strftime('%s', 'now')"}, []any{}
+	if v := update.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := update.SignCount; v != nil {
+		set, args = append(set, "sign_count = ?"), append(args, *v)
+	}
+	if v := update.LastUsedTs; v != nil {
+		set, args = append(set, "last_used_ts = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE webauthn_credential
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, user_id, created_ts, updated_ts, name, credential_id, public_key, sign_count, last_used_ts
+	`
+	webAuthnCredential := &WebAuthnCredential{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&webAuthnCredential.ID,
+		&webAuthnCredential.UserID,
+		&webAuthnCredential.CreatedTs,
+		&webAuthnCredential.UpdatedTs,
+		&webAuthnCredential.Name,
+		&webAuthnCredential.CredentialID,
+		&webAuthnCredential.PublicKey,
+		&webAuthnCredential.SignCount,
+		&webAuthnCredential.LastUsedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return webAuthnCredential, nil
+}
+
+func (s *Store) DeleteWebAuthnCredential(ctx context.Context, delete *DeleteWebAuthnCredential) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webauthn_credential WHERE id = ?`, delete.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listWebAuthnCredentials(ctx context.Context, tx *sql.Tx, find *FindWebAuthnCredential) ([]*WebAuthnCredential, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.CredentialID; v != nil {
+		where, args = append(where, "credential_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			updated_ts,
+			name,
+			credential_id,
+			public_key,
+			sign_count,
+			last_used_ts
+		FROM webauthn_credential
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts ASC, id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*WebAuthnCredential, 0)
+	for rows.Next() {
+		webAuthnCredential := &WebAuthnCredential{}
+		if err := rows.Scan(
+			&webAuthnCredential.ID,
+			&webAuthnCredential.UserID,
+			&webAuthnCredential.CreatedTs,
+			&webAuthnCredential.UpdatedTs,
+			&webAuthnCredential.Name,
+			&webAuthnCredential.CredentialID,
+			&webAuthnCredential.PublicKey,
+			&webAuthnCredential.SignCount,
+			&webAuthnCredential.LastUsedTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, webAuthnCredential)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumWebAuthnCredential(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		webauthn_credential
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
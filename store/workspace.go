@@ -0,0 +1,626 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// DefaultWorkspaceID is the workspace of the content created without a workspace, shared by all users.
+const DefaultWorkspaceID = 0
+
+// WorkspaceRole is the role of a member in a workspace.
+type WorkspaceRole string
+
+const (
+	// WorkspaceRoleAdmin is the role of the members managing the workspace.
+	WorkspaceRoleAdmin WorkspaceRole = "ADMIN"
+	// WorkspaceRoleMember is the role of the other members.
+	WorkspaceRoleMember WorkspaceRole = "MEMBER"
+)
+
+func (r WorkspaceRole) String() string {
+	return string(r)
+}
+
+// Workspace is a team of users isolated from the others on the instance.
+type Workspace struct {
+	ID int
+
+	// Standard fields
+	RowStatus RowStatus
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name        string
+	Description string
+}
+
+type FindWorkspace struct {
+	ID   *int
+	Name *string
+	// MemberID finds the workspaces the user is a member of.
+	MemberID *int
+}
+
+type UpdateWorkspace struct {
+	ID          int
+	UpdatedTs   *int64
+	RowStatus   *RowStatus
+	Name        *string
+	Description *string
+}
+
+type DeleteWorkspace struct {
+	ID int
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int
+	UserID      int
+	Role        WorkspaceRole
+	CreatedTs   int64
+}
+
+type FindWorkspaceMember struct {
+	WorkspaceID *int
+	UserID      *int
+}
+
+type DeleteWorkspaceMember struct {
+	WorkspaceID int
+	UserID      int
+}
+
+// WorkspaceSetting overrides the system setting of the same name in the workspace.
+type WorkspaceSetting struct {
+	WorkspaceID int
+	Name        string
+	Value       string
+}
+
+type FindWorkspaceSetting struct {
+	WorkspaceID int
+	Name        *string
+}
+
+type DeleteWorkspaceSetting struct {
+	WorkspaceID int
+	Name        string
+}
+
+func (s *Store) CreateWorkspace(ctx context.Context, create *Workspace) (*Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workspace (
+			creator_id,
+			name,
+			description
+		)
+		VALUES (?, ?, ?)
+		RETURNING id, created_ts, updated_ts, row_status
+	`
+	if err := tx.QueryRowContext(ctx, query, create.CreatorID, create.Name, create.Description).Scan(
+		&create.ID,
+		&create.CreatedTs,
+		&create.UpdatedTs,
+		&create.RowStatus,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	workspace := create
+	return workspace, nil
+}
+
+func (s *Store) ListWorkspaces(ctx context.Context, find *FindWorkspace) ([]*Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaces(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetWorkspace(ctx context.Context, find *FindWorkspace) (*Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaces(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) UpdateWorkspace(ctx context.Context, update *UpdateWorkspace) (*Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := update.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := update.RowStatus; v != nil {
+		set, args = append(set, "row_status = ?"), append(args, *v)
+	}
+	if v := update.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := update.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	args = append(args, update.ID)
+
+	query := `
+		UPDATE workspace
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, row_status, name, description
+	`
+	workspace := &Workspace{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&workspace.ID,
+		&workspace.CreatorID,
+		&workspace.CreatedTs,
+		&workspace.UpdatedTs,
+		&workspace.RowStatus,
+		&workspace.Name,
+		&workspace.Description,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// DeleteWorkspace deletes the workspace with its members and settings.
+func (s *Store) DeleteWorkspace(ctx context.Context, delete *DeleteWorkspace) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace_member WHERE workspace_id = ?`, delete.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace_setting WHERE workspace_id = ?`, delete.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace WHERE id = ?`, delete.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WorkspaceHasContent returns whether any memo, resource or shortcut belongs to the workspace.
+func (s *Store) WorkspaceHasContent(ctx context.Context, workspaceID int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var hasContent bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM memo WHERE workspace_id = ?)
+			OR EXISTS (SELECT 1 FROM resource WHERE workspace_id = ?)
+			OR EXISTS (SELECT 1 FROM shortcut WHERE workspace_id = ?)`,
+		workspaceID, workspaceID, workspaceID,
+	).Scan(&hasContent); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return hasContent, nil
+}
+
+func (s *Store) UpsertWorkspaceMember(ctx context.Context, upsert *WorkspaceMember) (*WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workspace_member (
+			workspace_id, user_id, role
+		)
+		VALUES (?, ?, ?)
+		ON CONFLICT(workspace_id, user_id) DO UPDATE
+		SET
+			role = EXCLUDED.role
+		RETURNING created_ts
+	`
+	if err := tx.QueryRowContext(ctx, query, upsert.WorkspaceID, upsert.UserID, upsert.Role).Scan(&upsert.CreatedTs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	workspaceMember := upsert
+	return workspaceMember, nil
+}
+
+func (s *Store) ListWorkspaceMembers(ctx context.Context, find *FindWorkspaceMember) ([]*WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaceMembers(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetWorkspaceMember(ctx context.Context, find *FindWorkspaceMember) (*WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaceMembers(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) DeleteWorkspaceMember(ctx context.Context, delete *DeleteWorkspaceMember) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace_member WHERE workspace_id = ? AND user_id = ?`, delete.WorkspaceID, delete.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) UpsertWorkspaceSetting(ctx context.Context, upsert *WorkspaceSetting) (*WorkspaceSetting, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workspace_setting (
+			workspace_id, name, value
+		)
+		VALUES (?, ?, ?)
+		ON CONFLICT(workspace_id, name) DO UPDATE
+		SET
+			value = EXCLUDED.value
+	`
+	if _, err := tx.ExecContext(ctx, query, upsert.WorkspaceID, upsert.Name, upsert.Value); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	workspaceSetting := upsert
+	return workspaceSetting, nil
+}
+
+func (s *Store) ListWorkspaceSettings(ctx context.Context, find *FindWorkspaceSetting) ([]*WorkspaceSetting, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaceSettings(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *Store) GetWorkspaceSetting(ctx context.Context, find *FindWorkspaceSetting) (*WorkspaceSetting, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := listWorkspaceSettings(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+func (s *Store) DeleteWorkspaceSetting(ctx context.Context, delete *DeleteWorkspaceSetting) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace_setting WHERE workspace_id = ? AND name = ?`, delete.WorkspaceID, delete.Name); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func listWorkspaces(ctx context.Context, tx *sql.Tx, find *FindWorkspace) ([]*Workspace, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.Name; v != nil {
+		where, args = append(where, "name = ?"), append(args, *v)
+	}
+	if v := find.MemberID; v != nil {
+		where, args = append(where, "id IN (SELECT workspace_id FROM workspace_member WHERE user_id = ?)"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			row_status,
+			name,
+			description
+		FROM workspace
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*Workspace, 0)
+	for rows.Next() {
+		workspace := &Workspace{}
+		if err := rows.Scan(
+			&workspace.ID,
+			&workspace.CreatorID,
+			&workspace.CreatedTs,
+			&workspace.UpdatedTs,
+			&workspace.RowStatus,
+			&workspace.Name,
+			&workspace.Description,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, workspace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func listWorkspaceMembers(ctx context.Context, tx *sql.Tx, find *FindWorkspaceMember) ([]*WorkspaceMember, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.WorkspaceID; v != nil {
+		where, args = append(where, "workspace_id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			workspace_id,
+			user_id,
+			role,
+			created_ts
+		FROM workspace_member
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_ts ASC, user_id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*WorkspaceMember, 0)
+	for rows.Next() {
+		workspaceMember := &WorkspaceMember{}
+		if err := rows.Scan(
+			&workspaceMember.WorkspaceID,
+			&workspaceMember.UserID,
+			&workspaceMember.Role,
+			&workspaceMember.CreatedTs,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, workspaceMember)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func listWorkspaceSettings(ctx context.Context, tx *sql.Tx, find *FindWorkspaceSetting) ([]*WorkspaceSetting, error) {
+	where, args := []string{"workspace_id = ?"}, []any{find.WorkspaceID}
+	if v := find.Name; v != nil {
+		where, args = append(where, "name = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			workspace_id,
+			name,
+			value
+		FROM workspace_setting
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*WorkspaceSetting, 0)
+	for rows.Next() {
+		workspaceSetting := &WorkspaceSetting{}
+		if err := rows.Scan(
+			&workspaceSetting.WorkspaceID,
+			&workspaceSetting.Name,
+			&workspaceSetting.Value,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, workspaceSetting)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func vacuumWorkspaceMember(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		workspace_member
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR workspace_id NOT IN (
+			SELECT
+				id
+			FROM
+				workspace
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func vacuumWorkspaceSetting(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		workspace_setting
+	WHERE
+		workspace_id NOT IN (
+			SELECT
+				id
+			FROM
+				workspace
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP protocol operations and result codes used by the testing LDAP server.
+const (
+	ldapBindRequest      = 0
+	ldapBindResponse     = 1
+	ldapUnbindRequest    = 2
+	ldapSearchRequest    = 3
+	ldapSearchResultItem = 4
+	ldapSearchResultDone = 5
+
+	ldapResultSuccess            = 0
+	ldapResultInvalidCredentials = 49
+	ldapResultUnwillingToPerform = 53
+
+	ldapFilterAnd      = 0
+	ldapFilterOr       = 1
+	ldapFilterNot      = 2
+	ldapFilterEquality = 3
+	ldapFilterPresent  = 7
+)
+
+// LDAPEntry is an entry of the testing LDAP server.
+type LDAPEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// LDAPServer is a minimal in-process LDAP server supporting simple bind and search with
+// equality, presence, and, or and not filters.
+type LDAPServer struct {
+	URL string
+
+	listener net.Listener
+	entries  []*LDAPEntry
+}
+
+// NewLDAPServer starts a testing LDAP server with the entries, it is closed when the test finishes.
+func NewLDAPServer(t *testing.T, entries []*LDAPEntry) *LDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &LDAPServer{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return s
+}
+
+func (s *LDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *LDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldapBindRequest:
+			responses = append(responses, s.bind(op))
+		case ldapSearchRequest:
+			responses = s.search(op)
+		case ldapUnbindRequest:
+			return
+		default:
+			responses = append(responses, newLDAPResult(ldapBindResponse, ldapResultUnwillingToPerform))
+		}
+
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			envelope.AppendChild(response)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *LDAPServer) bind(op *ber.Packet) *ber.Packet {
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return newLDAPResult(ldapBindResponse, ldapResultSuccess)
+		}
+	}
+	return newLDAPResult(ldapBindResponse, ldapResultInvalidCredentials)
+}
+
+func (s *LDAPServer) search(op *ber.Packet) []*ber.Packet {
+	baseDN, _ := op.Children[0].Value.(string)
+	filter := op.Children[6]
+
+	responses := []*ber.Packet{}
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(baseDN)) || !entry.match(filter) {
+			continue
+		}
+
+		item := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultItem, nil, "Search Result Entry")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+		attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range entry.Attributes {
+			attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+			attribute.AppendChild(set)
+			attributes.AppendChild(attribute)
+		}
+		item.AppendChild(attributes)
+		responses = append(responses, item)
+	}
+	return append(responses, newLDAPResult(ldapSearchResultDone, ldapResultSuccess))
+}
+
+func (e *LDAPEntry) match(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldapFilterAnd:
+		for _, child := range filter.Children {
+			if !e.match(child) {
+				return false
+			}
+		}
+		return true
+	case ldapFilterOr:
+		for _, child := range filter.Children {
+			if e.match(child) {
+				return true
+			}
+		}
+		return false
+	case ldapFilterNot:
+		return len(filter.Children) == 1 && !e.match(filter.Children[0])
+	case ldapFilterEquality:
+		name, value := filter.Children[0].Data.String(), filter.Children[1].Data.String()
+		for _, v := range e.attributeValues(name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldapFilterPresent:
+		return len(e.attributeValues(filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+func (e *LDAPEntry) attributeValues(name string) []string {
+	for key, values := range e.Attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func newLDAPResult(tag ber.Tag, resultCode int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, resultCode, "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return result
+}
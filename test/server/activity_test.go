@@ -0,0 +1,99 @@
+package testserver
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestActivityServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	for i := 0; i < 3; i++ {
+		memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: fmt.Sprintf("memo %d", i)})
+		require.NoError(t, err)
+		content := "updated"
+		_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: memo.ID, Content: &content})
+		require.NoError(t, err)
+		require.NoError(t, s.deleteMemo(memo.ID))
+	}
+	user, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "alice",
+		Password: "alicepassword",
+	})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "alice memo"})
+	require.NoError(t, err)
+
+	// The users only find their own activities.
+	response := &apiv1.ListActivitiesResponse{}
+	require.NoError(t, s.getJSON("/api/v1/activity?type=memo.create,memo.delete", response))
+	require.Equal(t, 1, len(response.Activities))
+	require.Equal(t, user.ID, response.Activities[0].CreatorID)
+	require.ErrorContains(t, s.getJSON(fmt.Sprintf("/api/v1/activity?creatorId=%d", host.ID), response), "403")
+
+	// The admins find the activities of all users page by page.
+	s.cookie = hostCookie
+	require.NoError(t, s.getJSON("/api/v1/activity?type=memo.create&type=memo.update", response))
+	require.Equal(t, 7, len(response.Activities))
+	require.Equal(t, 0, response.NextCursor)
+	// The content of the memos of other users is left out, but the own one is kept.
+	require.Equal(t, user.ID, response.Activities[0].CreatorID)
+	require.NotContains(t, response.Activities[0].Payload, "alice memo")
+	require.JSONEq(t, `{"visibility":"PRIVATE"}`, response.Activities[0].Payload)
+	require.Contains(t, response.Activities[len(response.Activities)-1].Payload, "memo 0")
+	require.ErrorContains(t, s.getJSON("/api/v1/activity?level=DEBUG", response), "400")
+	activityList := []*apiv1.Activity{}
+	cursor := 0
+	for {
+		require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/activity?creatorId=%d&type=memo.delete&limit=2&cursor=%d", host.ID, cursor), response))
+		activityList = append(activityList, response.Activities...)
+		if cursor = response.NextCursor; cursor == 0 {
+			break
+		}
+	}
+	require.Equal(t, 3, len(activityList))
+	payload := &apiv1.ActivityMemoDeletePayload{}
+	require.NoError(t, json.Unmarshal([]byte(activityList[0].Payload), payload))
+	require.NotZero(t, payload.MemoID)
+
+	// The export contains all the activities matching the filters.
+	body, err := s.get("/api/v1/activity/export?format=csv&type=memo.update", nil)
+	require.NoError(t, err)
+	records, err := csv.NewReader(body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 4, len(records))
+	require.Equal(t, []string{"id", "creatorId", "createdTs", "type", "level", "payload"}, records[0])
+	require.Equal(t, "memo.update", records[1][3])
+	require.NotContains(t, records[1][5], "updated")
+	require.NoError(t, s.getJSON("/api/v1/activity/export?format=json&type=memo.delete", &activityList))
+	require.Equal(t, 3, len(activityList))
+	require.ErrorContains(t, s.getJSON("/api/v1/activity/export?format=xml", &activityList), "400")
+
+	// The activities older than the retention days are pruned.
+	deletedCount, err := apiv1.PruneActivities(ctx, s.server.Store, time.Now().Unix())
+	require.NoError(t, err)
+	require.Zero(t, deletedCount)
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingActivityRetentionDaysName, 30))
+	deletedCount, err = apiv1.PruneActivities(ctx, s.server.Store, time.Now().Add(31*24*time.Hour).Unix())
+	require.NoError(t, err)
+	require.NotZero(t, deletedCount)
+	require.NoError(t, s.getJSON("/api/v1/activity", response))
+	require.Equal(t, 0, len(response.Activities))
+}
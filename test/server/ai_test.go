@@ -0,0 +1,272 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestAIServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"llama2","message":{"role":"assistant","content":"Hello there"},"done":true,"prompt_eval_count":5,"eval_count":2}`)
+	}))
+	defer ollamaServer.Close()
+
+	signup := &apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	}
+	user, err := s.postAuthSignup(signup)
+	require.NoError(t, err)
+
+	_, err = s.postAIChatCompletion("Hi")
+	require.Error(t, err)
+
+	err = s.postSystemSetting(apiv1.SystemSettingAIConfigName, &apiv1.AIConfig{
+		Provider: apiv1.AIProviderOllama,
+	})
+	require.Error(t, err)
+	err = s.postSystemSetting(apiv1.SystemSettingAIConfigName, &apiv1.AIConfig{
+		Provider:         apiv1.AIProviderOllama,
+		Host:             ollamaServer.URL,
+		Model:            "llama2",
+		RateLimitPerHour: 2,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		content, err := s.postAIChatCompletion("Hi")
+		require.NoError(t, err)
+		require.Equal(t, "Hello there", content)
+	}
+	// The third request in the same hour exceeds the rate limit.
+	_, err = s.postAIChatCompletion("Hi")
+	require.ErrorContains(t, err, "429")
+
+	summary, err := s.getAIUsage()
+	require.NoError(t, err)
+	require.Equal(t, &apiv1.AIUsageSummary{
+		UserID:               user.ID,
+		RequestCount:         2,
+		PromptTokens:         10,
+		CompletionTokens:     4,
+		RequestCountInWindow: 2,
+		RateLimitPerHour:     2,
+	}, summary)
+}
+
+func (s *TestingServer) postSystemSetting(name apiv1.SystemSettingName, value any) error {
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal system setting value")
+	}
+	rawData, err := json.Marshal(&apiv1.UpsertSystemSettingRequest{
+		Name:  name,
+		Value: string(rawValue),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal system setting upsert")
+	}
+	_, err = s.post("/api/v1/system/setting", bytes.NewReader(rawData), nil)
+	return err
+}
+
+func (s *TestingServer) postAIChatCompletion(content string) (string, error) {
+	rawData, err := json.Marshal([]map[string]string{{"role": "user", "content": content}})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal chat completion messages")
+	}
+	body, err := s.post("/api/v1/ai/chat-completion", bytes.NewReader(rawData), nil)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return "", errors.Wrap(err, "fail to read response body")
+	}
+
+	result := ""
+	if err = json.Unmarshal(buf.Bytes(), &result); err != nil {
+		return "", errors.Wrap(err, "fail to unmarshal post chat completion response")
+	}
+	return result, nil
+}
+
+func (s *TestingServer) getAIUsage() (*apiv1.AIUsageSummary, error) {
+	body, err := s.get("/api/v1/ai/usage", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	summary := &apiv1.AIUsageSummary{}
+	if err = json.Unmarshal(buf.Bytes(), summary); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal get AI usage response")
+	}
+	return summary, nil
+}
+
+func TestAIAskServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	// The mock embeds texts by the keywords they contain, and answers with citing the first memo in the context.
+	embedRequestCount := atomic.Int32{}
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embed" {
+			embedRequestCount.Add(1)
+			request := struct {
+				Input []string `json:"input"`
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			embeddings := [][]float32{}
+			for _, input := range request.Input {
+				embedding := []float32{0, 0, 0.1}
+				if strings.Contains(input, "cat") {
+					embedding[0] = 1
+				}
+				if strings.Contains(input, "dog") {
+					embedding[1] = 1
+				}
+				embeddings = append(embeddings, embedding)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+			return
+		}
+
+		request := struct {
+			Messages []map[string]string `json:"messages"`
+		}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		citation := regexp.MustCompile(`\[memo:\d+\]`).FindString(request.Messages[0]["content"])
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]string{"role": "assistant", "content": "Your cat is Tom " + citation},
+			"done":    true,
+		})
+	}))
+	defer ollamaServer.Close()
+
+	signup := &apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	}
+	_, err = s.postAuthSignup(signup)
+	require.NoError(t, err)
+	err = s.postSystemSetting(apiv1.SystemSettingAIConfigName, &apiv1.AIConfig{
+		Provider: apiv1.AIProviderOllama,
+		Host:     ollamaServer.URL,
+		Model:    "llama2",
+	})
+	require.NoError(t, err)
+
+	catMemo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content: "My cat is called Tom",
+	})
+	require.NoError(t, err)
+	dogMemo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content: "Walk the dog at 7pm",
+	})
+	require.NoError(t, err)
+	// The memos are not sent to the provider until the user asks, as the user didn't opt in to embed them on save.
+	time.Sleep(100 * time.Millisecond)
+	require.Zero(t, embedRequestCount.Load())
+
+	response, err := s.postAIAsk(&apiv1.AIAskRequest{
+		Question: "What is my cat called?",
+		TopK:     1,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Sources, 1)
+	require.Equal(t, catMemo.ID, response.Sources[0].MemoID)
+	require.Equal(t, []int{catMemo.ID}, response.Citations)
+
+	// The embedding is refreshed once the memo is patched.
+	content := "My dog is called Tom"
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{
+		ID:      catMemo.ID,
+		Content: &content,
+	})
+	require.NoError(t, err)
+	response, err = s.postAIAsk(&apiv1.AIAskRequest{
+		Question: "What is my dog called?",
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Sources, 2)
+	require.ElementsMatch(t, []int{catMemo.ID, dogMemo.ID}, []int{response.Sources[0].MemoID, response.Sources[1].MemoID})
+	require.Equal(t, response.Sources[0].Score, response.Sources[1].Score)
+
+	// Once opted in, the memo is embedded as soon as it is saved, and the request is accounted.
+	err = s.postUserSetting(&apiv1.UpsertUserSettingRequest{
+		Key:   apiv1.UserSettingAIMemoEmbeddingKey,
+		Value: "true",
+	})
+	require.NoError(t, err)
+	content = "My cat is called Tom"
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{
+		ID:      catMemo.ID,
+		Content: &content,
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		summary, err := s.getAIUsage()
+		return err == nil && summary.RequestCount == 3
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (s *TestingServer) postUserSetting(request *apiv1.UpsertUserSettingRequest) error {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal user setting request")
+	}
+	_, err = s.post("/api/v1/user/setting", bytes.NewReader(rawData), nil)
+	return err
+}
+
+func (s *TestingServer) postAIAsk(request *apiv1.AIAskRequest) (*apiv1.AIAskResponse, error) {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal ask request")
+	}
+	body, err := s.post("/api/v1/ai/ask", bytes.NewReader(rawData), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	response := &apiv1.AIAskResponse{}
+	if err = json.Unmarshal(buf.Bytes(), response); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal post ask response")
+	}
+	return response, nil
+}
@@ -0,0 +1,158 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestCustomRoleServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	user := &apiv1.User{}
+	require.NoError(t, s.getJSON("/api/v1/user/me", user))
+	require.Contains(t, user.Permissions, apiv1.PermissionSystemManage)
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+
+	alice, err := s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	publicVisibility, protectedVisibility, privateVisibility := apiv1.Public, apiv1.Protected, apiv1.Private
+	publicMemo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "public", Visibility: publicVisibility})
+	require.NoError(t, err)
+	protectedMemo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "protected", Visibility: protectedVisibility})
+	require.NoError(t, err)
+	privateMemo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "private", Visibility: privateVisibility})
+	require.NoError(t, err)
+
+	// The users have no permission to moderate by default.
+	bob, err := s.postAuthSignup(&apiv1.SignUp{Username: "bob", Password: "password"})
+	require.NoError(t, err)
+	bobCookie := s.cookie
+	user = &apiv1.User{}
+	require.NoError(t, s.getJSON("/api/v1/user/me", user))
+	require.Empty(t, user.Permissions)
+	archived := apiv1.Archived
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: publicMemo.ID, RowStatus: &archived})
+	require.ErrorContains(t, err, "403")
+
+	// The custom roles are validated.
+	s.cookie = hostCookie
+	err = s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{Name: "admin"}, nil)
+	require.ErrorContains(t, err, "400")
+	err = s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{Name: "Owner", Permissions: []apiv1.Permission{"memo.own"}}, nil)
+	require.ErrorContains(t, err, "400")
+	moderator := &apiv1.CustomRole{}
+	require.NoError(t, s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{
+		Name:        "Moderator",
+		Permissions: []apiv1.Permission{apiv1.PermissionMemoModerate},
+	}, moderator))
+	err = s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{Name: "Moderator"}, nil)
+	require.ErrorContains(t, err, "409")
+	customRoleList := []*apiv1.CustomRole{}
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/user/%d/role", bob.ID), &apiv1.SetUserCustomRolesRequest{RoleIDList: []int{moderator.ID}}, &customRoleList))
+	require.Len(t, customRoleList, 1)
+
+	// The moderator archives the public memos of other users and gains nothing else.
+	s.cookie = bobCookie
+	require.NoError(t, s.getJSON("/api/v1/user/me", user))
+	require.Equal(t, []apiv1.Permission{apiv1.PermissionMemoModerate}, user.Permissions)
+	memo, err := s.patchMemo(&apiv1.PatchMemoRequest{ID: publicMemo.ID, RowStatus: &archived})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.Archived, memo.RowStatus)
+	content := "moderated"
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: publicMemo.ID, Content: &content})
+	require.ErrorContains(t, err, "403")
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: privateMemo.ID, RowStatus: &archived})
+	require.ErrorContains(t, err, "401")
+	require.ErrorContains(t, s.deleteMemo(privateMemo.ID), "401")
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: protectedMemo.ID, Visibility: &publicVisibility})
+	require.ErrorContains(t, err, "401")
+	normal := apiv1.Normal
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: publicMemo.ID, RowStatus: &normal})
+	require.ErrorContains(t, err, "403")
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: publicMemo.ID, Visibility: &publicVisibility})
+	require.ErrorContains(t, err, "403")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", alice.ID), nil)
+	require.ErrorContains(t, err, "403")
+	err = s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{Name: "Auditor"}, nil)
+	require.ErrorContains(t, err, "403")
+	require.ErrorContains(t, s.getJSON("/api/v1/storage", &[]*apiv1.Storage{}), "403")
+	require.NoError(t, s.deleteMemo(publicMemo.ID))
+
+	// The admins can not grant the permissions they are not granted, nor manage the host.
+	s.cookie = hostCookie
+	require.NoError(t, s.postJSON("/api/v1/user", &apiv1.CreateUserRequest{Username: "carol", Password: "password", Role: apiv1.RoleAdmin}, nil))
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "carol", Password: "password"})
+	require.NoError(t, err)
+	err = s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{Name: "Operator", Permissions: []apiv1.Permission{apiv1.PermissionStorageManage}}, nil)
+	require.ErrorContains(t, err, "403")
+	err = s.postJSON("/api/v1/user", &apiv1.CreateUserRequest{Username: "dave", Password: "password", Role: apiv1.RoleAdmin}, nil)
+	require.ErrorContains(t, err, "403")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", host.ID), nil)
+	require.ErrorContains(t, err, "403")
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/user/%d/role", bob.ID), &apiv1.SetUserCustomRolesRequest{RoleIDList: []int{}}, &customRoleList))
+	require.Empty(t, customRoleList)
+
+	// The user managers can not take over the users holding the permissions not granted to them.
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	userManager, systemManager := &apiv1.CustomRole{}, &apiv1.CustomRole{}
+	require.NoError(t, s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{
+		Name:        "User Manager",
+		Permissions: []apiv1.Permission{apiv1.PermissionUserManage},
+	}, userManager))
+	require.NoError(t, s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{
+		Name:        "System Manager",
+		Permissions: []apiv1.Permission{apiv1.PermissionSystemManage},
+	}, systemManager))
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/user/%d/role", alice.ID), &apiv1.SetUserCustomRolesRequest{RoleIDList: []int{userManager.ID}}, &customRoleList))
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/user/%d/role", bob.ID), &apiv1.SetUserCustomRolesRequest{RoleIDList: []int{systemManager.ID}}, &customRoleList))
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	password := "takeover"
+	err = s.patchJSON(fmt.Sprintf("/api/v1/user/%d", bob.ID), &apiv1.UpdateUserRequest{Password: &password}, &apiv1.User{})
+	require.ErrorContains(t, err, "403")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", bob.ID), nil)
+	require.ErrorContains(t, err, "403")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d/2fa", bob.ID), nil)
+	require.ErrorContains(t, err, "403")
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/user/%d/role", bob.ID), &apiv1.SetUserCustomRolesRequest{RoleIDList: []int{}}, &customRoleList))
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.NoError(t, s.patchJSON(fmt.Sprintf("/api/v1/user/%d", bob.ID), &apiv1.UpdateUserRequest{Password: &password}, &apiv1.User{}))
+}
+
+// putJSON puts the request as JSON to the uri and decodes the response into the response.
+func (s *TestingServer) putJSON(uri string, request, response any) error {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+	body, err := s.put(uri, bytes.NewReader(rawData), nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(response); err != nil {
+		return errors.Wrap(err, "fail to unmarshal response")
+	}
+	return nil
+}
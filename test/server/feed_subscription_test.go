@@ -0,0 +1,131 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestFeedSubscriptionServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rss.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><guid>post-2</guid><title>Second post</title><link>http://%s/posts/2</link><description>Second summary</description></item>
+<item><guid>post-1</guid><title>First post</title><link>http://%s/posts/1</link><description>First summary</description></item>
+</channel></rss>`, r.Host, r.Host)
+		case "/posts/1":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><head><meta property="og:image" content="/cover.png"></head><body></body></html>`)
+		case "/cover.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "fake png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer feedServer.Close()
+
+	signup := &apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	}
+	_, err = s.postAuthSignup(signup)
+	require.NoError(t, err)
+
+	feedSubscription, err := s.postFeedSubscriptionCreate(&apiv1.CreateFeedSubscriptionRequest{
+		URL:  feedServer.URL + "/rss.xml",
+		Tags: []string{"blog"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.Private, feedSubscription.Visibility)
+	require.Equal(t, 3600, feedSubscription.PollInterval)
+	_, err = s.postFeedSubscriptionCreate(&apiv1.CreateFeedSubscriptionRequest{
+		URL:          feedServer.URL + "/atom.xml",
+		PollInterval: 10,
+	})
+	require.Error(t, err)
+
+	feedSubscription, err = s.postFeedSubscriptionPoll(feedSubscription.ID)
+	require.NoError(t, err)
+	require.NotZero(t, feedSubscription.LastPolledTs)
+	memoList, err := s.getMemoList()
+	require.NoError(t, err)
+	require.Len(t, memoList, 2)
+	// The oldest entry is imported first.
+	require.True(t, strings.HasPrefix(memoList[1].Content, "# First post"))
+	require.Contains(t, memoList[1].Content, "#blog")
+	require.Len(t, memoList[1].ResourceList, 1)
+	require.Equal(t, "image/png", memoList[1].ResourceList[0].Type)
+	require.Len(t, memoList[0].ResourceList, 0)
+
+	// Entries are imported only once.
+	_, err = s.postFeedSubscriptionPoll(feedSubscription.ID)
+	require.NoError(t, err)
+	memoList, err = s.getMemoList()
+	require.NoError(t, err)
+	require.Len(t, memoList, 2)
+
+	_, err = s.delete(fmt.Sprintf("/api/v1/feed-subscription/%d", feedSubscription.ID), nil)
+	require.NoError(t, err)
+	_, err = s.postFeedSubscriptionPoll(feedSubscription.ID)
+	require.Error(t, err)
+}
+
+func (s *TestingServer) postFeedSubscriptionCreate(create *apiv1.CreateFeedSubscriptionRequest) (*apiv1.FeedSubscription, error) {
+	rawData, err := json.Marshal(create)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal feed subscription create")
+	}
+	body, err := s.post("/api/v1/feed-subscription", bytes.NewReader(rawData), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	feedSubscription := &apiv1.FeedSubscription{}
+	if err = json.Unmarshal(buf.Bytes(), feedSubscription); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal post feed subscription create response")
+	}
+	return feedSubscription, nil
+}
+
+func (s *TestingServer) postFeedSubscriptionPoll(feedSubscriptionID int) (*apiv1.FeedSubscription, error) {
+	body, err := s.post(fmt.Sprintf("/api/v1/feed-subscription/%d/poll", feedSubscriptionID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	feedSubscription := &apiv1.FeedSubscription{}
+	if err = json.Unmarshal(buf.Bytes(), feedSubscription); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal post feed subscription poll response")
+	}
+	return feedSubscription, nil
+}
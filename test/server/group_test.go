@@ -0,0 +1,160 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/test"
+)
+
+func TestGroupServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	aliceCookie := s.cookie
+	bob, err := s.postAuthSignup(&apiv1.SignUp{Username: "bob", Password: "password"})
+	require.NoError(t, err)
+	bobCookie := s.cookie
+
+	// The users can not create groups.
+	err = s.postJSON("/api/v1/group", &apiv1.CreateGroupRequest{Name: "Bob"}, nil)
+	require.ErrorContains(t, err, "403")
+
+	s.cookie = hostCookie
+	group := &apiv1.Group{}
+	require.NoError(t, s.postJSON("/api/v1/group", &apiv1.CreateGroupRequest{Name: "Reviewers"}, group))
+	err = s.postJSON("/api/v1/group", &apiv1.CreateGroupRequest{Name: "Reviewers"}, nil)
+	require.ErrorContains(t, err, "409")
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/group/%d/member", group.ID), &apiv1.CreateGroupMemberRequest{UserID: bob.ID}, &apiv1.GroupMember{}))
+	groupList := []*apiv1.Group{}
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/group?userId=%d", bob.ID), &groupList))
+	require.Len(t, groupList, 1)
+
+	// The private memos shared with a group are visible to its members.
+	s.cookie = aliceCookie
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "unknown group", Visibility: apiv1.Private, GroupIDList: []int{group.ID + 1}})
+	require.ErrorContains(t, err, "400")
+	memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "shared memo", Visibility: apiv1.Private, GroupIDList: []int{group.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []int{group.ID}, memo.GroupIDList)
+	s.cookie = bobCookie
+	memoList := []*apiv1.Memo{}
+	require.NoError(t, s.getJSON("/api/v1/memo/all", &memoList))
+	require.Len(t, memoList, 1)
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/memo/%d", memo.ID), &apiv1.Memo{}))
+
+	s.cookie = aliceCookie
+	privateVisibility := apiv1.Private
+	_, err = s.patchMemo(&apiv1.PatchMemoRequest{ID: memo.ID, Visibility: &privateVisibility, GroupIDList: []int{}})
+	require.NoError(t, err)
+	s.cookie = bobCookie
+	require.ErrorContains(t, s.getJSON(fmt.Sprintf("/api/v1/memo/%d", memo.ID), &apiv1.Memo{}), "403")
+
+	// The custom roles of a group are granted to its members.
+	s.cookie = hostCookie
+	moderator := &apiv1.CustomRole{}
+	require.NoError(t, s.postJSON("/api/v1/role", &apiv1.CreateCustomRoleRequest{
+		Name:        "Moderator",
+		Permissions: []apiv1.Permission{apiv1.PermissionMemoModerate},
+	}, moderator))
+	customRoleList := []*apiv1.CustomRole{}
+	require.NoError(t, s.putJSON(fmt.Sprintf("/api/v1/group/%d/role", group.ID), &apiv1.SetGroupCustomRolesRequest{RoleIDList: []int{moderator.ID}}, &customRoleList))
+	require.Len(t, customRoleList, 1)
+	s.cookie = bobCookie
+	user := &apiv1.User{}
+	require.NoError(t, s.getJSON("/api/v1/user/me", user))
+	require.Equal(t, []apiv1.Permission{apiv1.PermissionMemoModerate}, user.Permissions)
+
+	s.cookie = hostCookie
+	_, err = s.delete(fmt.Sprintf("/api/v1/group/%d/member/%d", group.ID, bob.ID), nil)
+	require.NoError(t, err)
+	s.cookie = bobCookie
+	user = &apiv1.User{}
+	require.NoError(t, s.getJSON("/api/v1/user/me", user))
+	require.Empty(t, user.Permissions)
+}
+
+func TestGroupIdentityProviderSyncServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+
+	ldapServer := test.NewLDAPServer(t, []*test.LDAPEntry{
+		{
+			DN:       "uid=john,ou=people,dc=example,dc=com",
+			Password: "john-password",
+			Attributes: map[string][]string{
+				"uid":      {"john"},
+				"memberOf": {"cn=engineering,ou=groups,dc=example,dc=com"},
+			},
+		},
+	})
+	_, err = s.postIdentityProviderCreate(&apiv1.CreateIdentityProviderRequest{
+		Name: "Directory",
+		Type: apiv1.IdentityProviderLDAPType,
+		Config: &apiv1.IdentityProviderConfig{
+			LDAPConfig: &apiv1.IdentityProviderLDAPConfig{
+				URL:        ldapServer.URL,
+				BaseDN:     "ou=people,dc=example,dc=com",
+				UserFilter: "(uid=%s)",
+			},
+		},
+	})
+	require.NoError(t, err)
+	engineering, sales := &apiv1.Group{}, &apiv1.Group{}
+	require.NoError(t, s.postJSON("/api/v1/group", &apiv1.CreateGroupRequest{Name: "Engineering", IdentityProviderGroup: "engineering"}, engineering))
+	require.NoError(t, s.postJSON("/api/v1/group", &apiv1.CreateGroupRequest{Name: "Sales", IdentityProviderGroup: "sales"}, sales))
+
+	// The directory users are synced into the groups mapped to their directory groups on sign-in.
+	user, err := s.postAuthSignin(&apiv1.SignIn{
+		Username: "john",
+		Password: "john-password",
+	})
+	require.NoError(t, err)
+	s.cookie = hostCookie
+	memberList := []*apiv1.GroupMember{}
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/group/%d/member", engineering.ID), &memberList))
+	require.Len(t, memberList, 1)
+	require.Equal(t, user.ID, memberList[0].UserID)
+	require.NotZero(t, memberList[0].IdentityProviderID)
+	memberList = []*apiv1.GroupMember{}
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/group/%d/member", sales.ID), &memberList))
+	require.Empty(t, memberList)
+
+	// The synced members are removed once the directory group is unmapped, the manual ones are kept.
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/group/%d/member", sales.ID), &apiv1.CreateGroupMemberRequest{UserID: user.ID}, &apiv1.GroupMember{}))
+	mapped := "marketing"
+	require.NoError(t, s.patchJSON(fmt.Sprintf("/api/v1/group/%d", engineering.ID), &apiv1.UpdateGroupRequest{IdentityProviderGroup: &mapped}, &apiv1.Group{}))
+	_, err = s.postAuthSignin(&apiv1.SignIn{
+		Username: "john",
+		Password: "john-password",
+	})
+	require.NoError(t, err)
+	s.cookie = hostCookie
+	groupList := []*apiv1.Group{}
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/group?userId=%d", user.ID), &groupList))
+	require.Len(t, groupList, 1)
+	require.Equal(t, sales.ID, groupList[0].ID)
+}
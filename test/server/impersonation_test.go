@@ -0,0 +1,102 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestImpersonationServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	alice, err := s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	aliceCookie := s.cookie
+	memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "private memo", Visibility: apiv1.Private})
+	require.NoError(t, err)
+
+	// Only the host can impersonate the other users.
+	err = s.postJSON("/api/v1/auth/impersonate", &apiv1.ImpersonateRequest{UserID: host.ID}, nil)
+	require.ErrorContains(t, err, "403")
+	s.cookie = hostCookie
+	err = s.postJSON("/api/v1/auth/impersonate", &apiv1.ImpersonateRequest{UserID: host.ID}, nil)
+	require.ErrorContains(t, err, "400")
+	err = s.postJSON("/api/v1/auth/impersonate", &apiv1.ImpersonateRequest{UserID: alice.ID, ExpiresIn: 7200}, nil)
+	require.ErrorContains(t, err, "400")
+	token := &apiv1.ImpersonationToken{}
+	require.NoError(t, s.postJSON("/api/v1/auth/impersonate", &apiv1.ImpersonateRequest{UserID: alice.ID}, token))
+	require.Equal(t, host.ID, token.ImpersonatorID)
+	require.False(t, token.AllowWrite)
+
+	// The host sees what the user sees, even with their own cookie, but can not write.
+	user := &apiv1.User{}
+	require.NoError(t, s.requestAsImpersonation("GET", "/api/v1/user/me", token.AccessToken, nil, user))
+	require.Equal(t, alice.ID, user.ID)
+	require.NoError(t, s.requestAsImpersonation("GET", fmt.Sprintf("/api/v1/memo/%d", memo.ID), token.AccessToken, nil, &apiv1.Memo{}))
+	err = s.requestAsImpersonation("POST", "/api/v1/memo", token.AccessToken, &apiv1.CreateMemoRequest{Content: "impersonated"}, nil)
+	require.ErrorContains(t, err, "403")
+
+	// The writes allowed by the host exclude the account changes.
+	writeToken := &apiv1.ImpersonationToken{}
+	require.NoError(t, s.postJSON("/api/v1/auth/impersonate", &apiv1.ImpersonateRequest{UserID: alice.ID, AllowWrite: true}, writeToken))
+	require.NoError(t, s.requestAsImpersonation("POST", "/api/v1/memo", writeToken.AccessToken, &apiv1.CreateMemoRequest{Content: "impersonated"}, &apiv1.Memo{}))
+	password := "hijacked"
+	err = s.requestAsImpersonation("PATCH", fmt.Sprintf("/api/v1/user/%d", alice.ID), writeToken.AccessToken, &apiv1.UpdateUserRequest{Password: &password}, nil)
+	require.ErrorContains(t, err, "403")
+
+	// The user sees every request made under impersonation.
+	s.cookie = aliceCookie
+	response := &apiv1.ListActivitiesResponse{}
+	require.NoError(t, s.getJSON("/api/v1/activity?type=user.auth.impersonation.start", response))
+	require.Len(t, response.Activities, 2)
+	response = &apiv1.ListActivitiesResponse{}
+	require.NoError(t, s.getJSON("/api/v1/activity?type=user.auth.impersonation.request", response))
+	require.Len(t, response.Activities, 5)
+
+	// Signing out the host ends the impersonation.
+	s.cookie = hostCookie
+	_, err = s.post("/api/v1/auth/signout", nil, nil)
+	require.NoError(t, err)
+	s.cookie = ""
+	err = s.requestAsImpersonation("GET", "/api/v1/user/me", token.AccessToken, nil, nil)
+	require.ErrorContains(t, err, "401")
+}
+
+// requestAsImpersonation sends a request with the impersonation token besides the cookie of the current user.
+func (s *TestingServer) requestAsImpersonation(method, uri, token string, request, response any) error {
+	var body io.Reader
+	if request != nil {
+		rawData, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(rawData)
+	}
+	respBody, err := s.request(method, uri, body, nil, map[string]string{
+		"Cookie":        s.cookie,
+		"Authorization": "Bearer " + token,
+	})
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(respBody).Decode(response)
+}
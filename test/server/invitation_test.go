@@ -0,0 +1,82 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestInvitationServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingSignUpModeName, apiv1.SignUpModeInviteOnly))
+	status, err := s.getSystemStatus()
+	require.NoError(t, err)
+	require.Equal(t, apiv1.SignUpModeInviteOnly, status.SignUpMode)
+
+	maxUses := 2
+	adminInvitation := &apiv1.Invitation{}
+	require.NoError(t, s.postJSON("/api/v1/invitation", &apiv1.CreateInvitationRequest{Role: apiv1.RoleAdmin, MaxUses: &maxUses}, adminInvitation))
+	require.Equal(t, apiv1.InvitationPending, adminInvitation.Status)
+	emailInvitation := &apiv1.Invitation{}
+	require.NoError(t, s.postJSON("/api/v1/invitation", &apiv1.CreateInvitationRequest{Email: "carol@example.com"}, emailInvitation))
+	require.Equal(t, apiv1.RoleUser, emailInvitation.Role)
+	require.Equal(t, 1, emailInvitation.MaxUses)
+	expiredInvitation := &apiv1.Invitation{}
+	require.NoError(t, s.postJSON("/api/v1/invitation", &apiv1.CreateInvitationRequest{ExpiredTs: time.Now().Unix() - 1}, expiredInvitation))
+	require.Equal(t, apiv1.InvitationExpired, expiredInvitation.Status)
+
+	// The invite only mode requires a valid invitation code.
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.ErrorContains(t, err, "401")
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password", InviteCode: expiredInvitation.Code})
+	require.ErrorContains(t, err, "401")
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password", InviteCode: emailInvitation.Code})
+	require.ErrorContains(t, err, "401")
+
+	// The invitation presets the role and is used up after max uses.
+	for _, username := range []string{"alice", "bob"} {
+		user, err := s.postAuthSignup(&apiv1.SignUp{Username: username, Password: "password", InviteCode: adminInvitation.Code})
+		require.NoError(t, err)
+		require.Equal(t, apiv1.RoleAdmin, user.Role)
+	}
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "dave", Password: "password", InviteCode: adminInvitation.Code})
+	require.ErrorContains(t, err, "401")
+	// The failed signups do not spend the invitation.
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password", Email: "carol@example.com", InviteCode: emailInvitation.Code})
+	require.ErrorContains(t, err, "409")
+	user, err := s.postAuthSignup(&apiv1.SignUp{Username: "carol", Password: "password", Email: "Carol@example.com", InviteCode: emailInvitation.Code})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.RoleUser, user.Role)
+
+	// Only the host can invite admins.
+	err = s.postJSON("/api/v1/invitation", &apiv1.CreateInvitationRequest{Role: apiv1.RoleAdmin}, nil)
+	require.ErrorContains(t, err, "403")
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.ErrorContains(t, s.postJSON("/api/v1/invitation", &apiv1.CreateInvitationRequest{Role: apiv1.RoleAdmin}, nil), "403")
+
+	s.cookie = hostCookie
+	invitationList := []*apiv1.Invitation{}
+	require.NoError(t, s.getJSON("/api/v1/invitation?status=USED", &invitationList))
+	require.Equal(t, 2, len(invitationList))
+	require.NoError(t, s.getJSON("/api/v1/invitation?status=EXPIRED", &invitationList))
+	require.Equal(t, 1, len(invitationList))
+	_, err = s.delete(fmt.Sprintf("/api/v1/invitation/%d", expiredInvitation.ID), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.getJSON("/api/v1/invitation", &invitationList))
+	require.Equal(t, 2, len(invitationList))
+}
@@ -0,0 +1,151 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/test"
+)
+
+func TestLDAPSignInServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.RoleHost, host.Role)
+
+	ldapServer := test.NewLDAPServer(t, []*test.LDAPEntry{
+		{
+			DN:       "uid=john,ou=people,dc=example,dc=com",
+			Password: "john-password",
+			Attributes: map[string][]string{
+				"uid":      {"john"},
+				"cn":       {"John Doe"},
+				"mail":     {"john.doe@example.com"},
+				"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			DN:       "uid=jane,ou=people,dc=example,dc=com",
+			Password: "jane-password",
+			Attributes: map[string][]string{
+				"uid": {"jane"},
+			},
+		},
+		{
+			DN:       "uid=testuser,ou=people,dc=example,dc=com",
+			Password: "directory-password",
+			Attributes: map[string][]string{
+				"uid": {"testuser"},
+			},
+		},
+	})
+	_, err = s.postIdentityProviderCreate(&apiv1.CreateIdentityProviderRequest{
+		Name: "Directory",
+		Type: apiv1.IdentityProviderLDAPType,
+		Config: &apiv1.IdentityProviderConfig{
+			LDAPConfig: &apiv1.IdentityProviderLDAPConfig{
+				URL:         ldapServer.URL,
+				BaseDN:      "ou=people,dc=example,dc=com",
+				UserFilter:  "(uid=%s)",
+				AdminGroups: []string{"admins"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// The directory users are provisioned on their first sign-in with roles from their groups.
+	user, err := s.postAuthSignin(&apiv1.SignIn{
+		Username: "john",
+		Password: "john-password",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "john", user.Username)
+	require.Equal(t, "John Doe", user.Nickname)
+	require.Equal(t, "john.doe@example.com", user.Email)
+	require.Equal(t, apiv1.RoleAdmin, user.Role)
+	user, err = s.postAuthSignin(&apiv1.SignIn{
+		Username: "jane",
+		Password: "jane-password",
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.RoleUser, user.Role)
+
+	_, err = s.postAuthSignin(&apiv1.SignIn{
+		Username: "john",
+		Password: "wrong-password",
+	})
+	require.ErrorContains(t, err, "401")
+
+	// The host keeps signing in with the local password and is never taken over by the directory user of the same username.
+	user, err = s.postAuthSignin(&apiv1.SignIn{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.RoleHost, user.Role)
+	_, err = s.postAuthSignin(&apiv1.SignIn{
+		Username: "testuser",
+		Password: "directory-password",
+	})
+	require.ErrorContains(t, err, "409")
+}
+
+func (s *TestingServer) postAuthSignin(signin *apiv1.SignIn) (*apiv1.User, error) {
+	rawData, err := json.Marshal(&signin)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal signin")
+	}
+	reader := bytes.NewReader(rawData)
+	body, err := s.post("/api/v1/auth/signin", reader, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	user := &apiv1.User{}
+	if err = json.Unmarshal(buf.Bytes(), user); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal post signin response")
+	}
+	return user, nil
+}
+
+func (s *TestingServer) postIdentityProviderCreate(create *apiv1.CreateIdentityProviderRequest) (*apiv1.IdentityProvider, error) {
+	rawData, err := json.Marshal(&create)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal identity provider create")
+	}
+	reader := bytes.NewReader(rawData)
+	body, err := s.post("/api/v1/idp", reader, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	identityProvider := &apiv1.IdentityProvider{}
+	if err = json.Unmarshal(buf.Bytes(), identityProvider); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal post identity provider response")
+	}
+	return identityProvider, nil
+}
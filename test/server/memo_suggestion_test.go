@@ -0,0 +1,151 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestMemoSuggestionServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			Messages []map[string]string `json:"messages"`
+		}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		reply := "NONE"
+		if strings.HasPrefix(request.Messages[0]["content"], "Summarize") {
+			reply = "A long note about work"
+		} else if strings.Contains(request.Messages[1]["content"], "meeting") {
+			reply = "work, unknown"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]string{"role": "assistant", "content": reply},
+			"done":    true,
+		})
+	}))
+	defer ollamaServer.Close()
+
+	signup := &apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	}
+	user, err := s.postAuthSignup(signup)
+	require.NoError(t, err)
+	err = s.postSystemSetting(apiv1.SystemSettingAIConfigName, &apiv1.AIConfig{
+		Provider: apiv1.AIProviderOllama,
+		Host:     ollamaServer.URL,
+		Model:    "llama2",
+	})
+	require.NoError(t, err)
+	for _, tag := range []string{"work", "life"} {
+		rawData, err := json.Marshal(&apiv1.UpsertTagRequest{Name: tag})
+		require.NoError(t, err)
+		_, err = s.post("/api/v1/tag", bytes.NewReader(rawData), nil)
+		require.NoError(t, err)
+	}
+
+	memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content:    "meeting notes " + strings.Repeat("blah ", 60),
+		Visibility: apiv1.Public,
+	})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content: "short memo",
+	})
+	require.NoError(t, err)
+
+	processedCount, err := apiv1.ProcessMemoSuggestions(ctx, s.server.Store, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, processedCount)
+	memoSuggestionList, err := s.getMemoSuggestionList(apiv1.MemoSuggestionPending)
+	require.NoError(t, err)
+	require.Len(t, memoSuggestionList, 2)
+	suggestions := map[apiv1.MemoSuggestionType]*apiv1.MemoSuggestion{}
+	for _, memoSuggestion := range memoSuggestionList {
+		require.Equal(t, memo.ID, memoSuggestion.MemoID)
+		suggestions[memoSuggestion.Type] = memoSuggestion
+	}
+	require.Equal(t, "work", suggestions[apiv1.MemoSuggestionTag].Content)
+	require.Equal(t, "A long note about work", suggestions[apiv1.MemoSuggestionSummary].Content)
+
+	_, err = s.patchMemoSuggestion(suggestions[apiv1.MemoSuggestionTag].ID, apiv1.MemoSuggestionAccepted)
+	require.NoError(t, err)
+	memo, err = s.getMemo(memo.ID)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(memo.Content, "#work "))
+	_, err = s.patchMemoSuggestion(suggestions[apiv1.MemoSuggestionSummary].ID, apiv1.MemoSuggestionAccepted)
+	require.NoError(t, err)
+
+	jsonFeed, err := s.getJSONFeed(fmt.Sprintf("/u/%d/feed.json", user.ID), nil)
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 1)
+	require.Equal(t, "A long note about work", jsonFeed.Items[0].Title)
+
+	// The memos are not processed again until they are changed.
+	processedCount, err = apiv1.ProcessMemoSuggestions(ctx, s.server.Store, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, processedCount)
+	memoSuggestionList, err = s.getMemoSuggestionList(apiv1.MemoSuggestionPending)
+	require.NoError(t, err)
+	require.Len(t, memoSuggestionList, 0)
+}
+
+func (s *TestingServer) getMemoSuggestionList(status apiv1.MemoSuggestionStatus) ([]*apiv1.MemoSuggestion, error) {
+	body, err := s.get("/api/v1/memo-suggestion", map[string]string{
+		"status": status.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	memoSuggestionList := []*apiv1.MemoSuggestion{}
+	if err = json.Unmarshal(buf.Bytes(), &memoSuggestionList); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal get memo suggestion list response")
+	}
+	return memoSuggestionList, nil
+}
+
+func (s *TestingServer) patchMemoSuggestion(suggestionID int, status apiv1.MemoSuggestionStatus) (*apiv1.MemoSuggestion, error) {
+	rawData, err := json.Marshal(&apiv1.UpdateMemoSuggestionRequest{
+		Status: status,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal memo suggestion patch")
+	}
+	body, err := s.patch(fmt.Sprintf("/api/v1/memo-suggestion/%d", suggestionID), bytes.NewReader(rawData), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	memoSuggestion := &apiv1.MemoSuggestion{}
+	if err = json.Unmarshal(buf.Bytes(), memoSuggestion); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal patch memo suggestion response")
+	}
+	return memoSuggestion, nil
+}
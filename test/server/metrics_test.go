@@ -0,0 +1,50 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/test"
+)
+
+func TestMetricsServer(t *testing.T) {
+	ctx := context.Background()
+	profile := test.GetTestingProfile(t)
+	profile.MetricsToken = "metrics-token"
+	s, err := newTestingServerWithProfile(ctx, profile)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	user, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "test memo"})
+	require.NoError(t, err)
+	require.ErrorContains(t, s.getJSON("/api/v1/memo/0", &apiv1.Memo{}), "404")
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/user/%d", user.ID), &apiv1.User{}))
+
+	// The metrics require the token.
+	_, err = s.get("/metrics", nil)
+	require.ErrorContains(t, err, "401")
+	_, err = s.request("GET", "/metrics", nil, nil, map[string]string{"Authorization": "Bearer wrong-token"})
+	require.ErrorContains(t, err, "401")
+	body, err := s.request("GET", "/metrics", nil, nil, map[string]string{"Authorization": "Bearer metrics-token"})
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	text := string(content)
+	require.Contains(t, text, `memos_http_requests_total{method="POST",route="/api/v1/memo",status="200"}`)
+	require.Contains(t, text, `memos_http_requests_total{method="GET",route="/api/v1/memo/:memoId",status="404"}`)
+	require.Contains(t, text, `memos_http_request_duration_seconds_count{method="POST",route="/api/v1/memo"}`)
+	require.Contains(t, text, `memos_db_query_duration_seconds_count{statement="insert"}`)
+	require.Contains(t, text, `memos_store_cache_lookups_total{cache="user",result="hit"}`)
+	require.Contains(t, text, "memos_thumbnail_generators_in_use 0")
+	require.Contains(t, text, "memos_telegram_poll_errors_total")
+	require.Contains(t, text, "memos_backup_last_success_timestamp_seconds 0")
+}
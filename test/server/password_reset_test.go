@@ -0,0 +1,92 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/plugin/mail"
+	"github.com/usememos/memos/test"
+)
+
+var mailTokenRegexp = regexp.MustCompile(`http://memos\.example\.com/auth/(verify-email|reset-password)\?token=([0-9a-f]+)`)
+
+func TestPasswordResetServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+	smtpServer := test.NewSMTPServer(t)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingCustomizedProfileName, &apiv1.CustomizedProfile{
+		Name:        "Test Memos",
+		ExternalURL: "http://memos.example.com",
+	}))
+	err = s.postSystemSetting(apiv1.SystemSettingSMTPConfigName, &apiv1.SMTPConfig{Host: smtpServer.Host, Port: smtpServer.Port, From: "noreply"})
+	require.ErrorContains(t, err, "400")
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingSMTPConfigName, &apiv1.SMTPConfig{
+		Host:     smtpServer.Host,
+		Port:     smtpServer.Port,
+		From:     "Test Memos <noreply@example.com>",
+		Security: mail.SMTPSecurityNone,
+	}))
+
+	// Signing up with an email sends the verification email.
+	user, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "alice",
+		Password: "alicepassword",
+		Email:    "alice@example.com",
+	})
+	require.NoError(t, err)
+	messages := smtpServer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, []string{"alice@example.com"}, messages[0].To)
+	require.Equal(t, "Verify your email for Test Memos", messages[0].Subject)
+	matches := mailTokenRegexp.FindStringSubmatch(messages[0].Body)
+	require.Equal(t, "verify-email", matches[1])
+	require.ErrorContains(t, s.postJSON("/api/v1/user/me/email-verification", nil, nil), "429")
+
+	// Only the token of the email verifies it.
+	verifiedUser := &apiv1.User{}
+	require.ErrorContains(t, s.postJSON("/api/v1/auth/email-verification", &apiv1.EmailVerificationRequest{Token: "invalid"}, verifiedUser), "400")
+	require.NoError(t, s.postJSON("/api/v1/auth/email-verification", &apiv1.EmailVerificationRequest{Token: matches[2]}, verifiedUser))
+	require.True(t, verifiedUser.EmailVerified)
+	require.ErrorContains(t, s.postJSON("/api/v1/auth/email-verification", &apiv1.EmailVerificationRequest{Token: matches[2]}, verifiedUser), "400")
+	aliceCookie := s.cookie
+
+	// The password reset does not reveal whether the account exists.
+	require.NoError(t, s.postJSON("/api/v1/auth/password-reset", &apiv1.PasswordResetRequest{Username: "bob"}, nil))
+	require.NoError(t, s.postJSON("/api/v1/auth/password-reset", &apiv1.PasswordResetRequest{Email: "alice@example.com"}, nil))
+	messages = smtpServer.Messages()
+	require.Len(t, messages, 2)
+	require.Equal(t, "Reset your password for Test Memos", messages[1].Subject)
+	matches = mailTokenRegexp.FindStringSubmatch(messages[1].Body)
+	require.Equal(t, "reset-password", matches[1])
+
+	// Resetting the password signs out everywhere.
+	err = s.postJSON("/api/v1/auth/password-reset/confirm", &apiv1.PasswordResetConfirmRequest{Token: "invalid", Password: "newpassword"}, nil)
+	require.ErrorContains(t, err, "400")
+	require.NoError(t, s.postJSON("/api/v1/auth/password-reset/confirm", &apiv1.PasswordResetConfirmRequest{Token: matches[2], Password: "newpassword"}, nil))
+	err = s.postJSON("/api/v1/auth/password-reset/confirm", &apiv1.PasswordResetConfirmRequest{Token: matches[2], Password: "otherpassword"}, nil)
+	require.ErrorContains(t, err, "400")
+	s.cookie = aliceCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/user/me", &apiv1.User{}), "401")
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "alicepassword"})
+	require.ErrorContains(t, err, "401")
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "newpassword"})
+	require.NoError(t, err)
+
+	// Changing the email requires verifying it again.
+	email := "alice@example.org"
+	require.NoError(t, s.patchJSON(fmt.Sprintf("/api/v1/user/%d", user.ID), &apiv1.UpdateUserRequest{Email: &email}, verifiedUser))
+	require.False(t, verifiedUser.EmailVerified)
+}
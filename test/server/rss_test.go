@@ -0,0 +1,140 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/feeds"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/store"
+)
+
+func TestRSSServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	signup := &apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	}
+	user, err := s.postAuthSignup(signup)
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content:    "public memo #hello ",
+		Visibility: apiv1.Public,
+	})
+	require.NoError(t, err)
+	memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content:    "public memo without tag",
+		Visibility: apiv1.Public,
+	})
+	require.NoError(t, err)
+	// The missing resources are left out of the feed.
+	_, err = s.server.Store.UpsertMemoResource(ctx, &store.UpsertMemoResource{MemoID: memo.ID, ResourceID: 999})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content:    "protected memo #hello ",
+		Visibility: apiv1.Protected,
+	})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{
+		Content:    "public memo #helloworld #hello/sub",
+		Visibility: apiv1.Public,
+	})
+	require.NoError(t, err)
+
+	jsonFeed, err := s.getJSONFeed(fmt.Sprintf("/u/%d/feed.json", user.ID), nil)
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 3)
+	jsonFeed, err = s.getJSONFeed(fmt.Sprintf("/u/%d/tag/hello/feed.json", user.ID), nil)
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 1)
+	require.Equal(t, []string{"hello"}, jsonFeed.Items[0].Tags)
+
+	feedToken, err := s.postUserFeedToken()
+	require.NoError(t, err)
+	jsonFeed, err = s.getJSONFeed(fmt.Sprintf("/u/%d/tag/hello/feed.json", user.ID), map[string]string{"token": feedToken})
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 2)
+	_, err = s.getJSONFeed(fmt.Sprintf("/u/%d/feed.json", user.ID), map[string]string{"token": "invalid"})
+	require.Error(t, err)
+
+	// The search terms narrow down any feed.
+	jsonFeed, err = s.getJSONFeed(fmt.Sprintf("/u/%d/feed.json", user.ID), map[string]string{"q": "without"})
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 1)
+	require.Contains(t, jsonFeed.Items[0].ContentHTML, "public memo without tag")
+	require.Empty(t, jsonFeed.Items[0].Attachments)
+	jsonFeed, err = s.getJSONFeed(fmt.Sprintf("/u/%d/tag/hello/feed.json", user.ID), map[string]string{"token": feedToken, "q": "protected"})
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 1)
+	jsonFeed, err = s.getJSONFeed("/explore/feed.json", map[string]string{"q": "public"})
+	require.NoError(t, err)
+	require.Len(t, jsonFeed.Items, 3)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/explore/atom.xml", s.profile.Port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%d/explore/atom.xml", s.profile.Port), nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+}
+
+func (s *TestingServer) getJSONFeed(uri string, params map[string]string) (*feeds.JSONFeed, error) {
+	body, err := s.request("GET", uri, nil, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to read response body")
+	}
+
+	jsonFeed := &feeds.JSONFeed{}
+	if err = json.Unmarshal(buf, jsonFeed); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal get json feed response")
+	}
+	return jsonFeed, nil
+}
+
+func (s *TestingServer) postUserFeedToken() (string, error) {
+	body, err := s.post("/api/v1/user/me/feed-token", nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return "", errors.Wrap(err, "fail to read response body")
+	}
+
+	userSetting := &apiv1.UserSetting{}
+	if err = json.Unmarshal(buf.Bytes(), userSetting); err != nil {
+		return "", errors.Wrap(err, "fail to unmarshal post user feed token response")
+	}
+	feedToken := ""
+	if err = json.Unmarshal([]byte(userSetting.Value), &feedToken); err != nil {
+		return "", errors.Wrap(err, "fail to unmarshal feed token")
+	}
+	return feedToken, nil
+}
@@ -0,0 +1,171 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestSCIMServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	scimToken := &apiv1.SCIMToken{}
+	require.NoError(t, s.postJSON("/api/v1/scim/token", nil, scimToken))
+
+	// The endpoints require the issued bearer token.
+	code, err := s.scimRequest(http.MethodGet, "/scim/v2/Users", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, err = s.scimRequest(http.MethodGet, "/scim/v2/Users", "invalid", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	scimUser := &apiv1.SCIMUser{}
+	code, err = s.scimRequest(http.MethodPost, "/scim/v2/Users", scimToken.Token, &apiv1.SCIMUser{
+		UserName:    "alice",
+		DisplayName: "Alice",
+		Emails:      []*apiv1.SCIMEmail{{Value: "alice@example.com", Primary: true}},
+		Password:    "alicepassword",
+	}, scimUser)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, code)
+	require.Equal(t, "Alice", scimUser.DisplayName)
+	require.True(t, *scimUser.Active)
+	require.Equal(t, "USER", scimUser.Groups[0].Value)
+	code, err = s.scimRequest(http.MethodPost, "/scim/v2/Users", scimToken.Token, &apiv1.SCIMUser{UserName: "alice"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, code)
+
+	listResponse := &apiv1.SCIMListResponse{}
+	code, err = s.scimRequest(http.MethodGet, `/scim/v2/Users?filter=userName%20eq%20%22alice%22`, scimToken.Token, nil, listResponse)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 1, listResponse.TotalResults)
+	code, err = s.scimRequest(http.MethodGet, `/scim/v2/Users?filter=title%20eq%20%22CEO%22`, scimToken.Token, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// Deactivating the user kills its access.
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "alicepassword"})
+	require.NoError(t, err)
+	aliceCookie := s.cookie
+	feedToken, err := s.postUserFeedToken()
+	require.NoError(t, err)
+	_, err = s.getJSONFeed("/explore/feed.json", map[string]string{"token": feedToken})
+	require.NoError(t, err)
+	userURI := "/scim/v2/Users/" + scimUser.ID
+	code, err = s.scimRequest(http.MethodPatch, userURI, scimToken.Token, &apiv1.SCIMPatchRequest{
+		Operations: []*apiv1.SCIMPatchOperation{{Op: "replace", Value: json.RawMessage(`{"active":false}`)}},
+	}, scimUser)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, *scimUser.Active)
+	s.cookie = aliceCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/user/me", &apiv1.User{}), "401")
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "alicepassword"})
+	require.ErrorContains(t, err, "403")
+	_, err = s.getJSONFeed("/explore/feed.json", map[string]string{"token": feedToken})
+	require.ErrorContains(t, err, "401")
+
+	code, err = s.scimRequest(http.MethodPatch, userURI, scimToken.Token, &apiv1.SCIMPatchRequest{
+		Operations: []*apiv1.SCIMPatchOperation{
+			{Op: "Replace", Path: "active", Value: json.RawMessage(`"True"`)},
+			{Op: "Replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"alice@example.org"`)},
+		},
+	}, scimUser)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, *scimUser.Active)
+	require.Equal(t, "alice@example.org", scimUser.Emails[0].Value)
+
+	// The groups are the roles.
+	group := &apiv1.SCIMGroup{}
+	code, err = s.scimRequest(http.MethodPatch, "/scim/v2/Groups/ADMIN", scimToken.Token, &apiv1.SCIMPatchRequest{
+		Operations: []*apiv1.SCIMPatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(fmt.Sprintf(`[{"value":"%s"}]`, scimUser.ID))}},
+	}, group)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 1, len(group.Members))
+	require.Equal(t, scimUser.ID, group.Members[0].Value)
+	code, err = s.scimRequest(http.MethodPatch, "/scim/v2/Groups/ADMIN", scimToken.Token, &apiv1.SCIMPatchRequest{
+		Operations: []*apiv1.SCIMPatchOperation{{Op: "remove", Path: fmt.Sprintf(`members[value eq "%s"]`, scimUser.ID)}},
+	}, group)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 0, len(group.Members))
+	code, err = s.scimRequest(http.MethodGet, userURI, scimToken.Token, nil, scimUser)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "USER", scimUser.Groups[0].Value)
+
+	// The host can not be provisioned.
+	code, err = s.scimRequest(http.MethodDelete, fmt.Sprintf("/scim/v2/Users/%d", host.ID), scimToken.Token, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, code)
+
+	// Deleting archives the user.
+	code, err = s.scimRequest(http.MethodDelete, userURI, scimToken.Token, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, code)
+	code, err = s.scimRequest(http.MethodGet, userURI, scimToken.Token, nil, scimUser)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, *scimUser.Active)
+
+	// Revoking the token disables the provisioning.
+	s.cookie = hostCookie
+	_, err = s.delete("/api/v1/scim/token", nil)
+	require.NoError(t, err)
+	code, err = s.scimRequest(http.MethodGet, "/scim/v2/Users", scimToken.Token, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, code)
+}
+
+// scimRequest sends the request with the bearer token, decoding the successful response into the response if not nil.
+func (s *TestingServer) scimRequest(method, uri, token string, request, response any) (int, error) {
+	var body io.Reader
+	if request != nil {
+		rawData, err := json.Marshal(request)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to marshal request")
+		}
+		body = bytes.NewReader(rawData)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d%s", s.profile.Port, uri), body)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/scim+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if response != nil && resp.StatusCode < http.StatusMultipleChoices {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return 0, errors.Wrap(err, "fail to unmarshal response")
+		}
+	}
+	return resp.StatusCode, nil
+}
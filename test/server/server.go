@@ -30,7 +30,11 @@ type TestingServer struct {
 }
 
 func NewTestingServer(ctx context.Context, t *testing.T) (*TestingServer, error) {
-	profile := test.GetTestingProfile(t)
+	return newTestingServerWithProfile(ctx, test.GetTestingProfile(t))
+}
+
+// newTestingServerWithProfile starts a testing server with the profile, which may be adjusted by the test beforehand.
+func newTestingServerWithProfile(ctx context.Context, profile *profile.Profile) (*TestingServer, error) {
 	db := db.NewDB(profile)
 	if err := db.Open(ctx); err != nil {
 		return nil, errors.Wrap(err, "failed to open db")
@@ -127,21 +131,20 @@ func (s *TestingServer) request(method, uri string, body io.Reader, params, head
 		return nil, errors.Errorf("http response error code %v body %q", resp.StatusCode, string(body))
 	}
 
-	if method == "POST" {
-		if strings.Contains(uri, "/api/v1/auth/login") || strings.Contains(uri, "/api/v1/auth/signup") {
-			cookie := ""
-			h := resp.Header.Get("Set-Cookie")
-			parts := strings.Split(h, "; ")
-			for _, p := range parts {
-				if strings.HasPrefix(p, fmt.Sprintf("%s=", auth.AccessTokenCookieName)) {
-					cookie = p
-					break
-				}
-			}
-			if cookie == "" {
-				return nil, errors.Errorf("unable to find access token in the login response headers")
+	// Keep the access token issued by the sign-in, the token refresh or the password change.
+	hasAccessToken := false
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == auth.AccessTokenCookieName {
+			hasAccessToken = true
+			s.cookie = ""
+			if cookie.Value != "" {
+				s.cookie = fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)
 			}
-			s.cookie = cookie
+		}
+	}
+	if method == "POST" {
+		if strings.Contains(uri, "/api/v1/auth/signup") && !hasAccessToken {
+			return nil, errors.Errorf("unable to find access token in the login response headers")
 		} else if strings.Contains(uri, "/api/v1/auth/logout") {
 			s.cookie = ""
 		}
@@ -170,6 +173,13 @@ func (s *TestingServer) patch(url string, body io.Reader, params map[string]stri
 	})
 }
 
+// put sends a PUT client request.
+func (s *TestingServer) put(url string, body io.Reader, params map[string]string) (io.ReadCloser, error) {
+	return s.request("PUT", url, body, params, map[string]string{
+		"Cookie": s.cookie,
+	})
+}
+
 // delete sends a DELETE client request.
 func (s *TestingServer) delete(url string, params map[string]string) (io.ReadCloser, error) {
 	return s.request("DELETE", url, nil, params, map[string]string{
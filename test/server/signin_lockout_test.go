@@ -0,0 +1,73 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/store"
+)
+
+func TestSignInLockoutServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingSignInLockoutName, &apiv1.SignInLockoutConfig{
+		UsernameMaxAttempts: 2,
+		IPMaxAttempts:       4,
+		LockoutSeconds:      60,
+		MaxLockoutSeconds:   3600,
+	}))
+	err = s.postSystemSetting(apiv1.SystemSettingSignInLockoutName, &apiv1.SignInLockoutConfig{LockoutSeconds: 0})
+	require.ErrorContains(t, err, "400")
+
+	// The username is locked after the max attempts, even for the right password.
+	for i := 0; i < 2; i++ {
+		_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "wrongpassword"})
+		require.ErrorContains(t, err, "401")
+	}
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.ErrorContains(t, err, "429")
+
+	s.cookie = hostCookie
+	signInLockoutList := []*apiv1.SignInLockout{}
+	require.NoError(t, s.getJSON("/api/v1/signin-lockout", &signInLockoutList))
+	require.Equal(t, 2, len(signInLockoutList))
+	var usernameLockout *apiv1.SignInLockout
+	for _, signInLockout := range signInLockoutList {
+		require.Equal(t, 2, signInLockout.FailedCount)
+		if signInLockout.Kind == store.SignInLockoutUsername {
+			usernameLockout = signInLockout
+		}
+	}
+	require.NotNil(t, usernameLockout)
+	require.Equal(t, "testuser", usernameLockout.Key)
+	require.True(t, usernameLockout.Locked)
+	require.Equal(t, usernameLockout.LastFailedTs+60, usernameLockout.LockedUntilTs)
+
+	// Clearing the lockout allows the sign-in again.
+	_, err = s.delete(fmt.Sprintf("/api/v1/signin-lockout/%d", usernameLockout.ID), nil)
+	require.NoError(t, err)
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+
+	// The IP is locked after the max attempts over any usernames.
+	for _, username := range []string{"alice", "bob"} {
+		_, err = s.postAuthSignin(&apiv1.SignIn{Username: username, Password: "password"})
+		require.ErrorContains(t, err, "401")
+	}
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.ErrorContains(t, err, "429")
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "carol", Password: "password"})
+	require.ErrorContains(t, err, "429")
+}
@@ -0,0 +1,62 @@
+package testserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestSystemStatsServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "public memo", Visibility: apiv1.Public})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "private memo", Visibility: apiv1.Private})
+	require.NoError(t, err)
+	_, err = s.postResourceBlob("stats.txt", "statistics")
+	require.NoError(t, err)
+
+	// The users can not view the statistics.
+	require.ErrorContains(t, s.getJSON("/api/v1/system/stats", &apiv1.SystemStats{}), "403")
+	s.cookie = hostCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/system/stats?days=0", &apiv1.SystemStats{}), "400")
+
+	stats := &apiv1.SystemStats{}
+	require.NoError(t, s.getJSON("/api/v1/system/stats", stats))
+	require.ElementsMatch(t, []*apiv1.UserCount{
+		{Role: apiv1.RoleHost, RowStatus: apiv1.Normal, Count: 1},
+		{Role: apiv1.RoleUser, RowStatus: apiv1.Normal, Count: 1},
+	}, stats.User.CountList)
+	require.Equal(t, 1, stats.User.ActiveCount7Days)
+	require.Equal(t, 1, stats.User.ActiveCount30Days)
+	require.ElementsMatch(t, []*apiv1.MemoVisibilityCount{
+		{Visibility: apiv1.Private, Count: 1},
+		{Visibility: apiv1.Public, Count: 1},
+	}, stats.Memo.VisibilityCountList)
+	require.Equal(t, []*apiv1.MemoDailyCount{
+		{Date: time.Now().UTC().Format("2006-01-02"), Count: 2},
+	}, stats.Memo.DailyCountList)
+	require.Equal(t, []*apiv1.ResourceStorageStats{
+		{Storage: "DATABASE", Count: 1, Size: int64(len("statistics"))},
+	}, stats.Resource)
+	require.Positive(t, stats.Database.Size)
+	require.False(t, stats.TelegramBot.Healthy)
+	require.Nil(t, stats.LastBackup)
+}
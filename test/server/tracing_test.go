@@ -0,0 +1,52 @@
+package testserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/plugin/tracing"
+	"github.com/usememos/memos/test"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	shutdown := tracing.Setup(recorder, &tracing.Config{SampleRatio: 1})
+	defer shutdown(ctx)
+	s, err := newTestingServerWithProfile(ctx, test.GetTestingProfile(t))
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	_, err = s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	_, err = s.request("POST", "/api/v1/memo", strings.NewReader(`{"content":"test memo"}`), nil, map[string]string{
+		"Cookie":      s.cookie,
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	require.NoError(t, err)
+
+	// The spans of the request continue the incoming trace, down to the queries of the store.
+	var serverSpan tracetest.SpanStub
+	storeSpanNames := []string{}
+	for _, span := range tracetest.SpanStubsFromReadOnlySpans(recorder.Ended()) {
+		if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			continue
+		}
+		if span.SpanKind == trace.SpanKindServer {
+			serverSpan = span
+		} else if strings.HasPrefix(span.Name, "store.") {
+			storeSpanNames = append(storeSpanNames, span.Name)
+		}
+	}
+	require.Equal(t, "POST /api/v1/memo", serverSpan.Name)
+	require.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+	require.Contains(t, storeSpanNames, "store.CreateMemo")
+}
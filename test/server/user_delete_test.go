@@ -0,0 +1,157 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/store"
+)
+
+func TestUserDeleteServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingStorageServiceIDName, apiv1.LocalStorage))
+
+	alice, err := s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "#work alice memo"})
+	require.NoError(t, err)
+	require.NoError(t, s.postJSON("/api/v1/tag", &apiv1.UpsertTagRequest{Name: "work"}, nil))
+	aliceResource, err := s.postResourceBlob("alice.txt", "alice")
+	require.NoError(t, err)
+	bob, err := s.postAuthSignup(&apiv1.SignUp{Username: "bob", Password: "password"})
+	require.NoError(t, err)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "bob memo"})
+	require.NoError(t, err)
+	bobResource, err := s.postResourceBlob("bob.txt", "bob")
+	require.NoError(t, err)
+	bobStoreResource, err := s.server.Store.GetResource(ctx, &store.FindResource{ID: &bobResource.ID})
+	require.NoError(t, err)
+	require.FileExists(t, bobStoreResource.InternalPath)
+	// The resources only linking to an external object have no file of the server.
+	bobLinkResource := &apiv1.Resource{}
+	require.NoError(t, s.postJSON("/api/v1/resource", &apiv1.CreateResourceRequest{
+		Filename:     "bob.png",
+		ExternalLink: "https://memos.s3.amazonaws.com/assets/host.png",
+		Type:         "image/png",
+	}, bobLinkResource))
+
+	s.cookie = hostCookie
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", host.ID), nil)
+	require.ErrorContains(t, err, "403")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", alice.ID), map[string]string{"mode": "archive"})
+	require.ErrorContains(t, err, "400")
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d", alice.ID), map[string]string{"mode": "transfer", "transferTo": fmt.Sprint(alice.ID)})
+	require.ErrorContains(t, err, "400")
+
+	// The dry run reports without deleting.
+	report, err := s.deleteUser(alice.ID, map[string]string{"dryRun": "true"})
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, 1, report.MemoCount)
+	require.Equal(t, 1, report.ResourceCount)
+	require.Equal(t, 1, report.TagCount)
+	require.Equal(t, 1, report.FileCount)
+	user, err := s.server.Store.GetUser(ctx, &store.FindUser{ID: &alice.ID})
+	require.NoError(t, err)
+	require.NotNil(t, user)
+
+	// The transfer keeps the content and files for the other user.
+	report, err = s.deleteUser(alice.ID, map[string]string{"mode": "transfer", "transferTo": fmt.Sprint(host.ID)})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.UserDeleteTransfer, report.Mode)
+	require.Equal(t, 0, report.FileCount)
+	memoList, err := s.server.Store.ListMemos(ctx, &store.FindMemo{CreatorID: &host.ID})
+	require.NoError(t, err)
+	require.Len(t, memoList, 1)
+	require.Equal(t, "#work alice memo", memoList[0].Content)
+	resource, err := s.server.Store.GetResource(ctx, &store.FindResource{ID: &aliceResource.ID})
+	require.NoError(t, err)
+	require.Equal(t, host.ID, resource.CreatorID)
+	require.FileExists(t, resource.InternalPath)
+	tagList, err := s.server.Store.ListTags(ctx, &store.FindTag{CreatorID: host.ID})
+	require.NoError(t, err)
+	require.Len(t, tagList, 1)
+
+	// The cascade deletes the content and files.
+	report, err = s.deleteUser(bob.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, apiv1.UserDeleteCascade, report.Mode)
+	require.Equal(t, 1, report.FileCount)
+	memoList, err = s.server.Store.ListMemos(ctx, &store.FindMemo{CreatorID: &bob.ID})
+	require.NoError(t, err)
+	require.Empty(t, memoList)
+	resource, err = s.server.Store.GetResource(ctx, &store.FindResource{ID: &bobResource.ID})
+	require.NoError(t, err)
+	require.Nil(t, resource)
+	resource, err = s.server.Store.GetResource(ctx, &store.FindResource{ID: &bobLinkResource.ID})
+	require.NoError(t, err)
+	require.Nil(t, resource)
+	require.NoFileExists(t, bobStoreResource.InternalPath)
+
+	// The deletions are audited.
+	response := &apiv1.ListActivitiesResponse{}
+	require.NoError(t, s.getJSON("/api/v1/activity?type=user.delete", response))
+	require.Len(t, response.Activities, 2)
+}
+
+func (s *TestingServer) deleteUser(userID int, params map[string]string) (*apiv1.DeleteUserReport, error) {
+	body, err := s.delete(fmt.Sprintf("/api/v1/user/%d", userID), params)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	report := &apiv1.DeleteUserReport{}
+	if err := json.NewDecoder(body).Decode(report); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal delete user report")
+	}
+	return report, nil
+}
+
+// postResourceBlob uploads the file content as a resource.
+func (s *TestingServer) postResourceBlob(filename, content string) (*apiv1.Resource, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create form file")
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		return nil, errors.Wrap(err, "failed to write form file")
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close multipart writer")
+	}
+	body, err := s.request("POST", "/api/v1/resource/blob", buffer, nil, map[string]string{
+		"Cookie":       s.cookie,
+		"Content-Type": writer.FormDataContentType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	resource := &apiv1.Resource{}
+	if err := json.NewDecoder(body).Decode(resource); err != nil {
+		return nil, errors.Wrap(err, "fail to unmarshal resource")
+	}
+	return resource, nil
+}
@@ -0,0 +1,153 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/plugin/mail"
+	"github.com/usememos/memos/store"
+	"github.com/usememos/memos/test"
+)
+
+func TestUserIdentityServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+	smtpServer := test.NewSMTPServer(t)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingCustomizedProfileName, &apiv1.CustomizedProfile{
+		Name:        "Test Memos",
+		ExternalURL: "http://memos.example.com",
+	}))
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingSMTPConfigName, &apiv1.SMTPConfig{
+		Host:     smtpServer.Host,
+		Port:     smtpServer.Port,
+		From:     "noreply@example.com",
+		Security: mail.SMTPSecurityNone,
+	}))
+	require.ErrorContains(t, s.postSystemSetting(apiv1.SystemSettingIdentityLinkPolicyName, "ALWAYS"), "400")
+	require.ErrorContains(t, s.postSystemSetting(apiv1.SystemSettingIdentityLinkPolicyName, "USERNAME"), "400")
+
+	ldapServer := test.NewLDAPServer(t, []*test.LDAPEntry{
+		{
+			DN:       "uid=john,ou=people,dc=example,dc=com",
+			Password: "john-password",
+			Attributes: map[string][]string{
+				"uid": {"john"},
+			},
+		},
+		{
+			DN:       "uid=asmith,ou=people,dc=example,dc=com",
+			Password: "asmith-password",
+			Attributes: map[string][]string{
+				"uid":  {"asmith"},
+				"mail": {"alice@example.com"},
+			},
+		},
+		{
+			DN:       "uid=cjones,ou=people,dc=example,dc=com",
+			Password: "cjones-password",
+			Attributes: map[string][]string{
+				"uid":  {"cjones"},
+				"mail": {"carol@example.com"},
+			},
+		},
+		{
+			DN:       "uid=hsmith,ou=people,dc=example,dc=com",
+			Password: "hsmith-password",
+			Attributes: map[string][]string{
+				"uid":  {"hsmith"},
+				"mail": {"host@example.com"},
+			},
+		},
+	})
+	identityProvider, err := s.postIdentityProviderCreate(&apiv1.CreateIdentityProviderRequest{
+		Name: "Directory",
+		Type: apiv1.IdentityProviderLDAPType,
+		Config: &apiv1.IdentityProviderConfig{
+			LDAPConfig: &apiv1.IdentityProviderLDAPConfig{
+				URL:        ldapServer.URL,
+				BaseDN:     "ou=people,dc=example,dc=com",
+				UserFilter: "(uid=%s)",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// The identity does not take over the local user of the same username by default.
+	john, err := s.postAuthSignup(&apiv1.SignUp{Username: "john", Password: "local-password"})
+	require.NoError(t, err)
+	johnCookie := s.cookie
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "john", Password: "john-password"})
+	require.ErrorContains(t, err, "409")
+
+	// The user links the identity from the settings.
+	s.cookie = johnCookie
+	err = s.postJSON("/api/v1/user/me/identity", &apiv1.LinkUserIdentityRequest{IdentityProviderID: identityProvider.ID, Username: "john", Password: "wrong-password"}, nil)
+	require.ErrorContains(t, err, "401")
+	userIdentity := &apiv1.UserIdentity{}
+	require.NoError(t, s.postJSON("/api/v1/user/me/identity", &apiv1.LinkUserIdentityRequest{IdentityProviderID: identityProvider.ID, Username: "john", Password: "john-password"}, userIdentity))
+	require.Equal(t, "john", userIdentity.ExternalID)
+	require.Equal(t, "Directory", userIdentity.IdentityProviderName)
+	user, err := s.postAuthSignin(&apiv1.SignIn{Username: "john", Password: "john-password"})
+	require.NoError(t, err)
+	require.Equal(t, john.ID, user.ID)
+
+	// An identity is linked to one user only.
+	_, err = s.postAuthSignup(&apiv1.SignUp{Username: "bob", Password: "bob-password"})
+	require.NoError(t, err)
+	err = s.postJSON("/api/v1/user/me/identity", &apiv1.LinkUserIdentityRequest{IdentityProviderID: identityProvider.ID, Username: "john", Password: "john-password"}, nil)
+	require.ErrorContains(t, err, "409")
+	userIdentityList := []*apiv1.UserIdentity{}
+	require.NoError(t, s.getJSON("/api/v1/user/me/identity", &userIdentityList))
+	require.Empty(t, userIdentityList)
+
+	// The unlinked identity can not sign in again.
+	s.cookie = johnCookie
+	require.NoError(t, s.getJSON("/api/v1/user/me/identity", &userIdentityList))
+	require.Len(t, userIdentityList, 1)
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/me/identity/%d", userIdentityList[0].ID), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.getJSON("/api/v1/user/me/identity", &userIdentityList))
+	require.Empty(t, userIdentityList)
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "john", Password: "john-password"})
+	require.ErrorContains(t, err, "409")
+
+	// The identities are linked by the verified emails if allowed.
+	alice, err := s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "alice-password", Email: "alice@example.com"})
+	require.NoError(t, err)
+	matches := mailTokenRegexp.FindStringSubmatch(smtpServer.Messages()[0].Body)
+	require.NoError(t, s.postJSON("/api/v1/auth/email-verification", &apiv1.EmailVerificationRequest{Token: matches[2]}, nil))
+	carol, err := s.postAuthSignup(&apiv1.SignUp{Username: "carol", Password: "carol-password", Email: "carol@example.com"})
+	require.NoError(t, err)
+	s.cookie = hostCookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingIdentityLinkPolicyName, apiv1.IdentityLinkPolicyVerifiedEmail))
+	user, err = s.postAuthSignin(&apiv1.SignIn{Username: "asmith", Password: "asmith-password"})
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, user.ID)
+	user, err = s.postAuthSignin(&apiv1.SignIn{Username: "cjones", Password: "cjones-password"})
+	require.NoError(t, err)
+	require.NotEqual(t, carol.ID, user.ID)
+	require.Equal(t, "cjones", user.Username)
+	require.True(t, user.EmailVerified)
+
+	// The host and admins are never linked automatically.
+	hostEmail, emailVerified := "host@example.com", true
+	_, err = s.server.Store.UpdateUser(ctx, &store.UpdateUser{ID: host.ID, Email: &hostEmail, EmailVerified: &emailVerified})
+	require.NoError(t, err)
+	user, err = s.postAuthSignin(&apiv1.SignIn{Username: "hsmith", Password: "hsmith-password"})
+	require.NoError(t, err)
+	require.NotEqual(t, host.ID, user.ID)
+	require.Equal(t, apiv1.RoleUser, user.Role)
+}
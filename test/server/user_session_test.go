@@ -0,0 +1,84 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestUserSessionServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	firstCookie := s.cookie
+	userSessionList := []*apiv1.UserSession{}
+	require.NoError(t, s.getJSON("/api/v1/user/me/session", &userSessionList))
+	require.Equal(t, 1, len(userSessionList))
+	require.True(t, userSessionList[0].Current)
+	firstSessionID := userSessionList[0].ID
+
+	// Every sign-in is a new session.
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	secondCookie := s.cookie
+	require.NoError(t, s.getJSON("/api/v1/user/me/session", &userSessionList))
+	require.Equal(t, 2, len(userSessionList))
+	for _, userSession := range userSessionList {
+		require.Equal(t, userSession.ID != firstSessionID, userSession.Current)
+	}
+
+	// A revoked session is rejected.
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/me/session/%s", firstSessionID), nil)
+	require.NoError(t, err)
+	s.cookie = firstCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/user/me/session", &userSessionList), "401")
+	s.cookie = secondCookie
+	require.NoError(t, s.getJSON("/api/v1/user/me/session", &userSessionList))
+	require.Equal(t, 1, len(userSessionList))
+
+	// Revoke all the sessions except the current one.
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	thirdCookie := s.cookie
+	_, err = s.delete("/api/v1/user/me/session", nil)
+	require.NoError(t, err)
+	s.cookie = secondCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/user/me/session", &userSessionList), "401")
+	s.cookie = thirdCookie
+	require.NoError(t, s.getJSON("/api/v1/user/me/session", &userSessionList))
+	require.Equal(t, 1, len(userSessionList))
+
+	// Signing out revokes the session.
+	_, err = s.post("/api/v1/auth/signout", nil, nil)
+	require.NoError(t, err)
+	s.cookie = thirdCookie
+	require.ErrorContains(t, s.getJSON("/api/v1/user/me/session", &userSessionList), "401")
+
+	// Changing the password revokes all the sessions, the current request continues with a new one.
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	otherCookie := s.cookie
+	_, err = s.postAuthSignin(&apiv1.SignIn{Username: "testuser", Password: "testpassword"})
+	require.NoError(t, err)
+	currentCookie := s.cookie
+	password := "newpassword"
+	user := &apiv1.User{}
+	require.NoError(t, s.patchJSON(fmt.Sprintf("/api/v1/user/%d", host.ID), &apiv1.UpdateUserRequest{Password: &password}, user))
+	require.NotEqual(t, currentCookie, s.cookie)
+	require.NoError(t, s.getJSON("/api/v1/user/me/session", &userSessionList))
+	require.Equal(t, 1, len(userSessionList))
+	for _, cookie := range []string{otherCookie, currentCookie} {
+		s.cookie = cookie
+		require.ErrorContains(t, s.getJSON("/api/v1/user/me/session", &userSessionList), "401")
+	}
+}
@@ -0,0 +1,123 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/plugin/totp"
+)
+
+func TestUserTwoFactorServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+
+	// Enroll and confirm with a code to receive the recovery codes.
+	enrollment := &apiv1.TwoFactorEnrollment{}
+	require.NoError(t, s.postJSON("/api/v1/user/me/2fa/enroll", nil, enrollment))
+	require.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/memos:testuser?")
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	recoveryCodes := &apiv1.TwoFactorRecoveryCodes{}
+	require.NoError(t, s.postJSON("/api/v1/user/me/2fa/confirm", &apiv1.TwoFactorCodeRequest{Code: code}, recoveryCodes))
+	require.Equal(t, 10, len(recoveryCodes.RecoveryCodes))
+	status := &apiv1.UserTwoFactorStatus{}
+	require.NoError(t, s.getJSON("/api/v1/user/me/2fa", status))
+	require.Equal(t, &apiv1.UserTwoFactorStatus{Enabled: true, RecoveryCodesRemaining: 10}, status)
+
+	// The password sign-in returns a challenge instead of the tokens.
+	challenge := &apiv1.SignInTwoFactorChallenge{}
+	require.NoError(t, s.postJSON("/api/v1/auth/signin", &apiv1.SignIn{Username: "testuser", Password: "testpassword"}, challenge))
+	require.NotEmpty(t, challenge.ChallengeToken)
+	require.False(t, challenge.EnrollmentRequired)
+
+	// The code used to confirm cannot be replayed.
+	err = s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken, Code: code}, nil)
+	require.ErrorContains(t, err, "401")
+	err = s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: "invalid", Code: recoveryCodes.RecoveryCodes[0]}, nil)
+	require.ErrorContains(t, err, "401")
+	user := &apiv1.User{}
+	require.NoError(t, s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken, Code: recoveryCodes.RecoveryCodes[0]}, user))
+	require.Equal(t, host.ID, user.ID)
+	// The recovery codes are one-time.
+	err = s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken, Code: recoveryCodes.RecoveryCodes[0]}, nil)
+	require.ErrorContains(t, err, "401")
+	require.NoError(t, s.getJSON("/api/v1/user/me/2fa", status))
+	require.Equal(t, 9, status.RecoveryCodesRemaining)
+
+	// Users with a required role enroll during the sign-in.
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingTwoFactorRequiredRolesName, []apiv1.Role{apiv1.RoleHost, apiv1.RoleUser}))
+	member, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "member",
+		Password: "memberpassword",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.postJSON("/api/v1/auth/signin", &apiv1.SignIn{Username: "member", Password: "memberpassword"}, challenge))
+	require.True(t, challenge.EnrollmentRequired)
+	require.NoError(t, s.postJSON("/api/v1/auth/signin/2fa/enroll", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken}, enrollment))
+	require.Equal(t, 10, len(enrollment.RecoveryCodes))
+	code, err = totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken, Code: code}, user))
+	require.Equal(t, member.ID, user.ID)
+	err = s.postJSON("/api/v1/user/me/2fa/disable", &apiv1.TwoFactorCodeRequest{Code: enrollment.RecoveryCodes[0]}, nil)
+	require.ErrorContains(t, err, "403")
+
+	// The host resets the two-factor authentication of the member.
+	require.NoError(t, s.postJSON("/api/v1/auth/signin", &apiv1.SignIn{Username: "testuser", Password: "testpassword"}, challenge))
+	require.NoError(t, s.postJSON("/api/v1/auth/signin/2fa", &apiv1.SignInTwoFactor{ChallengeToken: challenge.ChallengeToken, Code: recoveryCodes.RecoveryCodes[1]}, user))
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/%d/2fa", member.ID), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.postJSON("/api/v1/auth/signin", &apiv1.SignIn{Username: "member", Password: "memberpassword"}, challenge))
+	require.True(t, challenge.EnrollmentRequired)
+}
+
+// postJSON posts the request as JSON and decodes the response into the response if not nil.
+func (s *TestingServer) postJSON(uri string, request, response any) error {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+	body, err := s.post(uri, bytes.NewReader(rawData), nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(body).Decode(response); err != nil {
+		return errors.Wrap(err, "fail to unmarshal response")
+	}
+	return nil
+}
+
+// getJSON gets the uri and decodes the JSON response into the response.
+func (s *TestingServer) getJSON(uri string, response any) error {
+	body, err := s.get(uri, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(response); err != nil {
+		return errors.Wrap(err, "fail to unmarshal response")
+	}
+	return nil
+}
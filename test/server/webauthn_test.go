@@ -0,0 +1,139 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+	"github.com/usememos/memos/plugin/webauthn"
+	"github.com/usememos/memos/test"
+)
+
+func TestWebAuthnServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+
+	// Passkeys are bound to the external URL.
+	creationOptions := &webauthn.CreationOptions{}
+	err = s.postJSON("/api/v1/auth/webauthn/registration/begin", nil, creationOptions)
+	require.ErrorContains(t, err, "External URL is required")
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingCustomizedProfileName, &apiv1.CustomizedProfile{
+		Name:        "memos",
+		Locale:      "en",
+		Appearance:  "system",
+		ExternalURL: "https://memos.example.com",
+	}))
+
+	authenticator := test.NewWebAuthnAuthenticator(t, "https://memos.example.com", "memos.example.com")
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/registration/begin", nil, creationOptions))
+	require.Equal(t, "memos.example.com", creationOptions.RelyingParty.ID)
+	require.Equal(t, "testuser", creationOptions.User.Name)
+	passkey := &apiv1.Passkey{}
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/registration/finish", map[string]any{
+		"name":       "Laptop",
+		"credential": authenticator.Register(t, creationOptions.Challenge.String(), creationOptions.User.ID),
+	}, passkey))
+	require.Equal(t, "Laptop", passkey.Name)
+	require.Equal(t, authenticator.CredentialID(), passkey.CredentialID)
+
+	// The challenge is consumed by the registration.
+	err = s.postJSON("/api/v1/auth/webauthn/registration/finish", map[string]any{
+		"credential": authenticator.Register(t, creationOptions.Challenge.String(), creationOptions.User.ID),
+	}, nil)
+	require.ErrorContains(t, err, "Invalid or expired passkey challenge")
+
+	// Sign in with the passkey without being signed in.
+	s.cookie = ""
+	err = s.postJSON("/api/v1/auth/webauthn/registration/begin", nil, creationOptions)
+	require.ErrorContains(t, err, "401")
+	requestOptions := &webauthn.RequestOptions{}
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/begin", &apiv1.PasskeySignInBegin{Username: "testuser"}, requestOptions))
+	require.Equal(t, 1, len(requestOptions.AllowCredentials))
+	require.Equal(t, authenticator.CredentialID(), requestOptions.AllowCredentials[0].ID.String())
+	user := &apiv1.User{}
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{
+		"credential": authenticator.Assert(t, requestOptions.Challenge.String()),
+	}, user))
+	require.Equal(t, host.ID, user.ID)
+
+	// A replayed assertion is rejected.
+	assertion := authenticator.Assert(t, requestOptions.Challenge.String())
+	err = s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{"credential": assertion}, nil)
+	require.ErrorContains(t, err, "Invalid or expired passkey challenge")
+
+	// Discoverable credentials sign in without a username.
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/begin", &apiv1.PasskeySignInBegin{}, requestOptions))
+	require.Equal(t, 0, len(requestOptions.AllowCredentials))
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{
+		"credential": authenticator.Assert(t, requestOptions.Challenge.String()),
+	}, user))
+	require.Equal(t, host.ID, user.ID)
+
+	// A passkey without user verification is a single factor, so the two-factor step applies where it is required.
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingTwoFactorRequiredRolesName, []apiv1.Role{apiv1.RoleHost}))
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/begin", &apiv1.PasskeySignInBegin{}, requestOptions))
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{
+		"credential": authenticator.Assert(t, requestOptions.Challenge.String()),
+	}, user))
+	require.Equal(t, host.ID, user.ID)
+	authenticator.SkipUserVerification = true
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/begin", &apiv1.PasskeySignInBegin{}, requestOptions))
+	challenge := &apiv1.SignInTwoFactorChallenge{}
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{
+		"credential": authenticator.Assert(t, requestOptions.Challenge.String()),
+	}, challenge))
+	require.NotEmpty(t, challenge.ChallengeToken)
+	require.True(t, challenge.EnrollmentRequired)
+	authenticator.SkipUserVerification = false
+
+	// List, rename and delete the passkey.
+	passkeyList := []*apiv1.Passkey{}
+	require.NoError(t, s.getJSON("/api/v1/user/me/passkey", &passkeyList))
+	require.Equal(t, 1, len(passkeyList))
+	require.NotZero(t, passkeyList[0].LastUsedTs)
+	name := "Phone"
+	require.NoError(t, s.patchJSON(fmt.Sprintf("/api/v1/user/me/passkey/%d", passkey.ID), &apiv1.PatchPasskeyRequest{Name: &name}, passkey))
+	require.Equal(t, "Phone", passkey.Name)
+	_, err = s.delete(fmt.Sprintf("/api/v1/user/me/passkey/%d", passkey.ID), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.getJSON("/api/v1/user/me/passkey", &passkeyList))
+	require.Equal(t, 0, len(passkeyList))
+
+	// The deleted passkey cannot sign in anymore.
+	require.NoError(t, s.postJSON("/api/v1/auth/webauthn/signin/begin", &apiv1.PasskeySignInBegin{}, requestOptions))
+	err = s.postJSON("/api/v1/auth/webauthn/signin/finish", map[string]any{
+		"credential": authenticator.Assert(t, requestOptions.Challenge.String()),
+	}, nil)
+	require.ErrorContains(t, err, "401")
+}
+
+// patchJSON patches the uri with the request as JSON and decodes the response into the response.
+func (s *TestingServer) patchJSON(uri string, request, response any) error {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+	body, err := s.patch(uri, bytes.NewReader(rawData), nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(response); err != nil {
+		return errors.Wrap(err, "fail to unmarshal response")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package testserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "github.com/usememos/memos/api/v1"
+)
+
+func TestWorkspaceServer(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewTestingServer(ctx, t)
+	require.NoError(t, err)
+	defer s.Shutdown(ctx)
+
+	host, err := s.postAuthSignup(&apiv1.SignUp{
+		Username: "testuser",
+		Password: "testpassword",
+	})
+	require.NoError(t, err)
+	hostCookie := s.cookie
+	require.NoError(t, s.postSystemSetting(apiv1.SystemSettingAllowSignUpName, true))
+	alice, err := s.postAuthSignup(&apiv1.SignUp{Username: "alice", Password: "password"})
+	require.NoError(t, err)
+	aliceCookie := s.cookie
+	bob, err := s.postAuthSignup(&apiv1.SignUp{Username: "bob", Password: "password"})
+	require.NoError(t, err)
+	bobCookie := s.cookie
+
+	// The users can not create workspaces.
+	err = s.postJSON("/api/v1/workspace", &apiv1.CreateWorkspaceRequest{Name: "Bob"}, nil)
+	require.ErrorContains(t, err, "403")
+
+	s.cookie = hostCookie
+	teamA, teamB := &apiv1.Workspace{}, &apiv1.Workspace{}
+	require.NoError(t, s.postJSON("/api/v1/workspace", &apiv1.CreateWorkspaceRequest{Name: "Team A"}, teamA))
+	require.NoError(t, s.postJSON("/api/v1/workspace", &apiv1.CreateWorkspaceRequest{Name: "Team B"}, teamB))
+	err = s.postJSON("/api/v1/workspace", &apiv1.CreateWorkspaceRequest{Name: "Team A"}, nil)
+	require.ErrorContains(t, err, "409")
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamA.ID), &apiv1.UpsertWorkspaceMemberRequest{UserID: alice.ID, Role: apiv1.WorkspaceRoleAdmin}, &apiv1.WorkspaceMember{}))
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamB.ID), &apiv1.UpsertWorkspaceMemberRequest{UserID: bob.ID}, &apiv1.WorkspaceMember{}))
+
+	// The protected memos are visible to the members of the workspace only.
+	s.cookie = aliceCookie
+	workspaceList := []*apiv1.Workspace{}
+	require.NoError(t, s.getJSON("/api/v1/workspace", &workspaceList))
+	require.Len(t, workspaceList, 1)
+	require.Equal(t, teamA.ID, workspaceList[0].ID)
+	memo, err := s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "team a memo", Visibility: apiv1.Protected, WorkspaceID: teamA.ID})
+	require.NoError(t, err)
+	require.Equal(t, teamA.ID, memo.WorkspaceID)
+	_, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "team b memo", WorkspaceID: teamB.ID})
+	require.ErrorContains(t, err, "403")
+	memoList := []*apiv1.Memo{}
+	require.NoError(t, s.getJSON(fmt.Sprintf("/api/v1/memo/all?workspaceId=%d", teamA.ID), &memoList))
+	require.Len(t, memoList, 1)
+
+	s.cookie = bobCookie
+	memoList = []*apiv1.Memo{}
+	require.NoError(t, s.getJSON("/api/v1/memo/all", &memoList))
+	require.Empty(t, memoList)
+	require.ErrorContains(t, s.getJSON(fmt.Sprintf("/api/v1/memo/%d", memo.ID), &apiv1.Memo{}), "403")
+	require.ErrorContains(t, s.getJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamA.ID), &[]*apiv1.WorkspaceMember{}), "403")
+
+	// The settings are overridden per workspace by its admins.
+	err = s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/setting", teamB.ID), &apiv1.UpsertWorkspaceSettingRequest{Name: apiv1.SystemSettingDisablePublicMemosName, Value: "true"}, nil)
+	require.ErrorContains(t, err, "403")
+	s.cookie = aliceCookie
+	err = s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/setting", teamA.ID), &apiv1.UpsertWorkspaceSettingRequest{Name: apiv1.SystemSettingAllowSignUpName, Value: "true"}, nil)
+	require.ErrorContains(t, err, "400")
+	err = s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/setting", teamA.ID), &apiv1.UpsertWorkspaceSettingRequest{Name: apiv1.SystemSettingStorageServiceIDName, Value: "-1"}, nil)
+	require.ErrorContains(t, err, "403")
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/setting", teamA.ID), &apiv1.UpsertWorkspaceSettingRequest{Name: apiv1.SystemSettingDisablePublicMemosName, Value: "true"}, &apiv1.WorkspaceSetting{}))
+	memo, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "public team a memo", Visibility: apiv1.Public, WorkspaceID: teamA.ID})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.Private, memo.Visibility)
+	memo, err = s.postMemoCreate(&apiv1.CreateMemoRequest{Content: "public instance memo", Visibility: apiv1.Public})
+	require.NoError(t, err)
+	require.Equal(t, apiv1.Public, memo.Visibility)
+
+	// The last admin of the workspace can not be removed or demoted.
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamA.ID), &apiv1.UpsertWorkspaceMemberRequest{UserID: host.ID}, &apiv1.WorkspaceMember{}))
+	err = s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamA.ID), &apiv1.UpsertWorkspaceMemberRequest{UserID: alice.ID}, nil)
+	require.ErrorContains(t, err, "409")
+	_, err = s.delete(fmt.Sprintf("/api/v1/workspace/%d/member/%d", teamA.ID, alice.ID), nil)
+	require.ErrorContains(t, err, "409")
+	require.NoError(t, s.postJSON(fmt.Sprintf("/api/v1/workspace/%d/member", teamA.ID), &apiv1.UpsertWorkspaceMemberRequest{UserID: host.ID, Role: apiv1.WorkspaceRoleAdmin}, &apiv1.WorkspaceMember{}))
+	_, err = s.delete(fmt.Sprintf("/api/v1/workspace/%d/member/%d", teamA.ID, alice.ID), nil)
+	require.NoError(t, err)
+
+	// The workspaces with content can not be deleted.
+	s.cookie = hostCookie
+	_, err = s.delete(fmt.Sprintf("/api/v1/workspace/%d", teamA.ID), nil)
+	require.ErrorContains(t, err, "409")
+	_, err = s.delete(fmt.Sprintf("/api/v1/workspace/%d", teamB.ID), nil)
+	require.NoError(t, err)
+}
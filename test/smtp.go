@@ -0,0 +1,174 @@
+package test
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SMTPMessage is a message received by the testing SMTP server.
+type SMTPMessage struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// SMTPServer is a minimal in-process SMTP server capturing the received messages, with optional
+// AUTH PLAIN if a username is set.
+type SMTPServer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	messages []*SMTPMessage
+}
+
+// NewSMTPServer starts a testing SMTP server, it is closed when the test finishes.
+func NewSMTPServer(t *testing.T) *SMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &SMTPServer{
+		Host:     addr.IP.String(),
+		Port:     addr.Port,
+		listener: listener,
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return s
+}
+
+// Messages returns the messages received so far.
+func (s *SMTPServer) Messages() []*SMTPMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*SMTPMessage{}, s.messages...)
+}
+
+func (s *SMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer text.Close()
+
+	reply := func(code int, message string) bool {
+		return text.PrintfLine("%d %s", code, message) == nil
+	}
+	if !reply(220, "localhost ESMTP") {
+		return
+	}
+
+	authenticated := s.Username == ""
+	message := &SMTPMessage{}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			if s.Username != "" {
+				_ = text.PrintfLine("250-localhost")
+				reply(250, "AUTH PLAIN")
+			} else {
+				reply(250, "localhost")
+			}
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			credentials, err := base64.StdEncoding.DecodeString(initial)
+			if !strings.EqualFold(mechanism, "PLAIN") || err != nil {
+				reply(504, "unsupported authentication")
+				continue
+			}
+			fields := strings.Split(string(credentials), "\x00")
+			if len(fields) != 3 || fields[1] != s.Username || fields[2] != s.Password {
+				reply(535, "authentication failed")
+				continue
+			}
+			authenticated = true
+			reply(235, "authenticated")
+		case "MAIL":
+			if !authenticated {
+				reply(530, "authentication required")
+				continue
+			}
+			message = &SMTPMessage{From: parseSMTPPath(arg)}
+			reply(250, "OK")
+		case "RCPT":
+			message.To = append(message.To, parseSMTPPath(arg))
+			reply(250, "OK")
+		case "DATA":
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if err := parseSMTPData(message, data); err != nil {
+				reply(554, "malformed message")
+				continue
+			}
+			s.mutex.Lock()
+			s.messages = append(s.messages, message)
+			s.mutex.Unlock()
+			reply(250, "OK")
+		case "RSET", "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+// parseSMTPPath returns the address of `FROM:<address>` and `TO:<address>`.
+func parseSMTPPath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
+
+func parseSMTPData(message *SMTPMessage, data []byte) error {
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return err
+	}
+	message.Subject = subject
+	message.Body = string(body)
+	return nil
+}
@@ -0,0 +1,72 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestActivityStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	for _, activityType := range []string{"memo.create", "memo.update", "memo.delete", "memo.create"} {
+		_, err := ts.CreateActivity(ctx, &store.Activity{
+			CreatorID: user.ID,
+			Type:      activityType,
+			Level:     "INFO",
+			Payload:   "{}",
+		})
+		require.NoError(t, err)
+	}
+	warnActivity, err := ts.CreateActivity(ctx, &store.Activity{
+		CreatorID: -1,
+		Type:      "server.start",
+		Level:     "WARN",
+		Payload:   "{}",
+	})
+	require.NoError(t, err)
+
+	list, err := ts.ListActivities(ctx, &store.FindActivity{
+		CreatorID: &user.ID,
+		TypeList:  []string{"memo.create", "memo.delete"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(list))
+	level := "WARN"
+	list, err = ts.ListActivities(ctx, &store.FindActivity{
+		Level: &level,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []*store.Activity{warnActivity}, list)
+
+	// The activities are paginated by descending ID.
+	limit := 2
+	list, err = ts.ListActivities(ctx, &store.FindActivity{
+		Limit: &limit,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	require.Equal(t, warnActivity.ID, list[0].ID)
+	list, err = ts.ListActivities(ctx, &store.FindActivity{
+		IDBefore: &list[1].ID,
+		Limit:    &limit,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	require.Equal(t, "memo.update", list[1].Type)
+
+	createdTsBefore := warnActivity.CreatedTs + 1
+	deletedCount, err := ts.DeleteActivities(ctx, &store.DeleteActivity{
+		CreatedTsBefore: &createdTsBefore,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), deletedCount)
+	list, err = ts.ListActivities(ctx, &store.FindActivity{})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(list))
+}
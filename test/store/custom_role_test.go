@@ -0,0 +1,70 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestCustomRoleStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	moderator, err := ts.CreateCustomRole(ctx, &store.CustomRole{
+		CreatorID:   user.ID,
+		Name:        "Moderator",
+		Permissions: []string{"memo.moderate"},
+	})
+	require.NoError(t, err)
+	auditor, err := ts.CreateCustomRole(ctx, &store.CustomRole{
+		CreatorID:   user.ID,
+		Name:        "Auditor",
+		Permissions: []string{"activity.view"},
+	})
+	require.NoError(t, err)
+	description := "Moderates the public memos"
+	updated, err := ts.UpdateCustomRole(ctx, &store.UpdateCustomRole{
+		ID:          moderator.ID,
+		Description: &description,
+		Permissions: []string{"memo.moderate", "activity.view"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, description, updated.Description)
+	require.Equal(t, []string{"memo.moderate", "activity.view"}, updated.Permissions)
+	customRole, err := ts.GetCustomRole(ctx, &store.FindCustomRole{
+		ID: &moderator.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, updated, customRole)
+
+	require.NoError(t, ts.SetUserCustomRoles(ctx, user.ID, []int{moderator.ID, auditor.ID}))
+	list, err := ts.ListCustomRoles(ctx, &store.FindCustomRole{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	require.NoError(t, ts.SetUserCustomRoles(ctx, user.ID, []int{auditor.ID}))
+	list, err = ts.ListCustomRoles(ctx, &store.FindCustomRole{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	require.Equal(t, auditor.ID, list[0].ID)
+
+	// Deleting the role unassigns it.
+	require.NoError(t, ts.DeleteCustomRole(ctx, &store.DeleteCustomRole{
+		ID: auditor.ID,
+	}))
+	list, err = ts.ListCustomRoles(ctx, &store.FindCustomRole{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(list))
+	list, err = ts.ListCustomRoles(ctx, &store.FindCustomRole{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+}
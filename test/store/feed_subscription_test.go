@@ -0,0 +1,67 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestFeedSubscriptionStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+	feedSubscription, err := ts.CreateFeedSubscription(ctx, &store.FeedSubscription{
+		CreatorID:    user.ID,
+		URL:          "https://example.com/rss.xml",
+		PollInterval: 3600,
+		Visibility:   store.Private,
+		Tags:         []string{"news", "tech"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, store.Normal, feedSubscription.RowStatus)
+	require.Equal(t, int64(0), feedSubscription.LastPolledTs)
+
+	list, err := ts.ListFeedSubscriptions(ctx, &store.FindFeedSubscription{
+		CreatorID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	require.Equal(t, feedSubscription, list[0])
+
+	tags := []string{}
+	lastPolledTs := int64(1000)
+	updatedFeedSubscription, err := ts.UpdateFeedSubscription(ctx, &store.UpdateFeedSubscription{
+		ID:           feedSubscription.ID,
+		Tags:         &tags,
+		LastPolledTs: &lastPolledTs,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{}, updatedFeedSubscription.Tags)
+	require.Equal(t, lastPolledTs, updatedFeedSubscription.LastPolledTs)
+
+	_, err = ts.CreateFeedSubscriptionEntry(ctx, &store.FeedSubscriptionEntry{
+		SubscriptionID: feedSubscription.ID,
+		GUID:           "guid-1",
+		MemoID:         1,
+	})
+	require.NoError(t, err)
+	_, err = ts.CreateFeedSubscriptionEntry(ctx, &store.FeedSubscriptionEntry{
+		SubscriptionID: feedSubscription.ID,
+		GUID:           "guid-1",
+		MemoID:         2,
+	})
+	require.Error(t, err)
+
+	err = ts.DeleteFeedSubscription(ctx, &store.DeleteFeedSubscription{
+		ID: feedSubscription.ID,
+	})
+	require.NoError(t, err)
+	entryList, err := ts.ListFeedSubscriptionEntries(ctx, &store.FindFeedSubscriptionEntry{
+		SubscriptionID: &feedSubscription.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(entryList))
+}
@@ -54,3 +54,46 @@ func TestIdentityProviderStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, len(idpList))
 }
+
+func TestOIDCIdentityProviderStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	createdIDP, err := ts.CreateIdentityProvider(ctx, &store.IdentityProvider{
+		Name: "Keycloak",
+		Type: store.IdentityProviderOIDCType,
+		Config: &store.IdentityProviderConfig{
+			OIDCConfig: &store.IdentityProviderOIDCConfig{
+				Issuer:       "https://keycloak.example.com/realms/memos",
+				ClientID:     "client_id",
+				ClientSecret: "client_secret",
+				GroupsClaim:  "groups",
+			},
+		},
+	})
+	require.NoError(t, err)
+	list, err := ts.ListIdentityProviders(ctx, &store.FindIdentityProvider{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	require.Equal(t, createdIDP.Config.OIDCConfig, list[0].Config.OIDCConfig)
+	require.Nil(t, list[0].Config.OAuth2Config)
+
+	newIssuer := "https://keycloak.example.com/realms/team"
+	updatedIdp, err := ts.UpdateIdentityProvider(ctx, &store.UpdateIdentityProvider{
+		ID:   createdIDP.ID,
+		Type: store.IdentityProviderOIDCType,
+		Config: &store.IdentityProviderConfig{
+			OIDCConfig: &store.IdentityProviderOIDCConfig{
+				Issuer:       newIssuer,
+				ClientID:     "client_id",
+				ClientSecret: "client_secret",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newIssuer, updatedIdp.Config.OIDCConfig.Issuer)
+	idp, err := ts.GetIdentityProvider(ctx, &store.FindIdentityProvider{
+		ID: &createdIDP.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, newIssuer, idp.Config.OIDCConfig.Issuer)
+}
@@ -0,0 +1,52 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestMemoEmbeddingStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+	memo, err := ts.CreateMemo(ctx, &store.Memo{
+		CreatorID:  user.ID,
+		Content:    "test_content",
+		Visibility: store.Public,
+	})
+	require.NoError(t, err)
+
+	_, err = ts.UpsertMemoEmbedding(ctx, &store.MemoEmbedding{
+		MemoID:      memo.ID,
+		Model:       "test_model",
+		ContentHash: "hash",
+		Embedding:   []float32{0.5, -1, 2},
+	})
+	require.NoError(t, err)
+	_, err = ts.UpsertMemoEmbedding(ctx, &store.MemoEmbedding{
+		MemoID:      memo.ID,
+		Model:       "test_model",
+		ContentHash: "hash_2",
+		Embedding:   []float32{1, 0.25},
+	})
+	require.NoError(t, err)
+	list, err := ts.ListMemoEmbeddings(ctx, &store.FindMemoEmbedding{
+		CreatorID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	require.Equal(t, "hash_2", list[0].ContentHash)
+	require.Equal(t, []float32{1, 0.25}, list[0].Embedding)
+
+	err = ts.DeleteMemo(ctx, &store.DeleteMemo{
+		ID: memo.ID,
+	})
+	require.NoError(t, err)
+	list, err = ts.ListMemoEmbeddings(ctx, &store.FindMemoEmbedding{})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(list))
+}
@@ -48,6 +48,26 @@ func TestResourceStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, notFoundResource)
 
+	storageID := 1
+	objectKey := "assets/test.png"
+	_, err = ts.CreateResource(ctx, &store.Resource{
+		CreatorID:    101,
+		Filename:     "test.png",
+		ExternalLink: "https://memos.s3.amazonaws.com/assets/test.png",
+		Type:         "image/png",
+		StorageID:    storageID,
+		ObjectKey:    objectKey,
+	})
+	require.NoError(t, err)
+	res, err = ts.GetResource(ctx, &store.FindResource{
+		StorageID: &storageID,
+		ObjectKey: &objectKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "test.png", res.Filename)
+	require.Equal(t, storageID, res.StorageID)
+	require.Equal(t, objectKey, res.ObjectKey)
+
 	err = ts.DeleteResource(ctx, &store.DeleteResource{
 		ID: 1,
 	})
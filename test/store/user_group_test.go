@@ -0,0 +1,98 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestUserGroupStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+	member, err := ts.CreateUser(ctx, &store.User{
+		Username: "member",
+		Role:     store.RoleUser,
+		Email:    "member@test.com",
+	})
+	require.NoError(t, err)
+
+	userGroup, err := ts.CreateUserGroup(ctx, &store.UserGroup{
+		CreatorID:             user.ID,
+		Name:                  "Reviewers",
+		IdentityProviderGroup: "reviewers",
+	})
+	require.NoError(t, err)
+	_, err = ts.UpsertUserGroupMember(ctx, &store.UserGroupMember{
+		GroupID: userGroup.ID,
+		UserID:  member.ID,
+	})
+	require.NoError(t, err)
+	userGroupMember, err := ts.UpsertUserGroupMember(ctx, &store.UserGroupMember{
+		GroupID:            userGroup.ID,
+		UserID:             member.ID,
+		IdentityProviderID: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, userGroupMember.IdentityProviderID)
+	list, err := ts.ListUserGroups(ctx, &store.FindUserGroup{
+		MemberID: &member.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+
+	// The private memos shared with the group are visible to its members.
+	memo, err := ts.CreateMemo(ctx, &store.Memo{
+		CreatorID:  user.ID,
+		Content:    "shared memo",
+		Visibility: store.Private,
+	})
+	require.NoError(t, err)
+	require.NoError(t, ts.SetMemoGroupShares(ctx, memo.ID, []int{userGroup.ID}))
+	isShared, err := ts.IsMemoSharedWithUser(ctx, memo.ID, member.ID)
+	require.NoError(t, err)
+	require.True(t, isShared)
+	memoList, err := ts.ListMemos(ctx, &store.FindMemo{
+		VisibilityList: []store.Visibility{store.Public, store.Protected},
+		MemberID:       &member.ID,
+		SharedUserID:   &member.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(memoList))
+	require.Equal(t, memo.ID, memoList[0].ID)
+
+	// The custom roles of the group are found by its members.
+	customRole, err := ts.CreateCustomRole(ctx, &store.CustomRole{
+		CreatorID:   user.ID,
+		Name:        "Moderator",
+		Permissions: []string{"memo.moderate"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ts.SetUserGroupCustomRoles(ctx, userGroup.ID, []int{customRole.ID}))
+	customRoleList, err := ts.ListCustomRoles(ctx, &store.FindCustomRole{
+		GroupMemberID: &member.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(customRoleList))
+
+	// Deleting the group deletes its members, roles and shares.
+	require.NoError(t, ts.DeleteUserGroup(ctx, &store.DeleteUserGroup{
+		ID: userGroup.ID,
+	}))
+	memberList, err := ts.ListUserGroupMembers(ctx, &store.FindUserGroupMember{
+		UserID: &member.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(memberList))
+	groupIDList, err := ts.ListMemoGroupShares(ctx, memo.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(groupIDList))
+	customRoleList, err = ts.ListCustomRoles(ctx, &store.FindCustomRole{
+		GroupMemberID: &member.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(customRoleList))
+}
@@ -0,0 +1,65 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestUserSessionStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	for _, id := range []string{"session-1", "session-2", "session-3"} {
+		_, err := ts.CreateUserSession(ctx, &store.UserSession{
+			ID:        id,
+			UserID:    user.ID,
+			UserAgent: "Mozilla/5.0",
+			IP:        "127.0.0.1",
+		})
+		require.NoError(t, err)
+	}
+	ip := "10.0.0.1"
+	updatedTs := int64(1)
+	updated, err := ts.UpdateUserSession(ctx, &store.UpdateUserSession{
+		ID:        "session-1",
+		UpdatedTs: &updatedTs,
+		IP:        &ip,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", updated.IP)
+	userSession, err := ts.GetUserSession(ctx, &store.FindUserSession{
+		ID: &updated.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, updated, userSession)
+
+	// Delete the expired sessions, then all the others except the current one.
+	expiredTs := int64(2)
+	err = ts.DeleteUserSession(ctx, &store.DeleteUserSession{
+		UserID:          &user.ID,
+		UpdatedTsBefore: &expiredTs,
+	})
+	require.NoError(t, err)
+	list, err := ts.ListUserSessions(ctx, &store.FindUserSession{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	currentID := "session-3"
+	err = ts.DeleteUserSession(ctx, &store.DeleteUserSession{
+		UserID:    &user.ID,
+		ExcludeID: &currentID,
+	})
+	require.NoError(t, err)
+	list, err = ts.ListUserSessions(ctx, &store.FindUserSession{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	require.Equal(t, currentID, list[0].ID)
+}
@@ -30,4 +30,19 @@ func TestUserSettingStore(t *testing.T) {
 	require.Equal(t, 2, len(list))
 	require.Equal(t, testSetting, list[0])
 	require.Equal(t, localeSetting, list[1])
+
+	err = ts.DeleteUserSetting(ctx, &store.DeleteUserSetting{
+		UserID: user.ID,
+		Key:    "test_key",
+	})
+	require.NoError(t, err)
+	testSetting, err = ts.GetUserSetting(ctx, &store.FindUserSetting{
+		UserID: &user.ID,
+		Key:    "test_key",
+	})
+	require.NoError(t, err)
+	require.Nil(t, testSetting)
+	list, err = ts.ListUserSettings(ctx, &store.FindUserSetting{})
+	require.NoError(t, err)
+	require.Equal(t, []*store.UserSetting{localeSetting}, list)
 }
@@ -0,0 +1,63 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestUserTwoFactorStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	_, err = ts.UpsertUserTwoFactor(ctx, &store.UserTwoFactor{
+		UserID:        user.ID,
+		Secret:        "JBSWY3DPEHPK3PXP",
+		RecoveryCodes: []string{},
+	})
+	require.NoError(t, err)
+	enabled := true
+	recoveryCodes := []string{"hash1", "hash2"}
+	lastUsedStep := int64(56666666)
+	updated, err := ts.UpdateUserTwoFactor(ctx, &store.UpdateUserTwoFactor{
+		UserID:        user.ID,
+		Enabled:       &enabled,
+		RecoveryCodes: &recoveryCodes,
+		LastUsedStep:  &lastUsedStep,
+	})
+	require.NoError(t, err)
+	require.True(t, updated.Enabled)
+	userTwoFactor, err := ts.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, updated, userTwoFactor)
+	require.Equal(t, recoveryCodes, userTwoFactor.RecoveryCodes)
+
+	// Enrolling again replaces the pending secret.
+	_, err = ts.UpsertUserTwoFactor(ctx, &store.UserTwoFactor{
+		UserID:        user.ID,
+		Secret:        "KRSXG5CTMVRXEZLU",
+		RecoveryCodes: []string{},
+	})
+	require.NoError(t, err)
+	list, err := ts.ListUserTwoFactors(ctx, &store.FindUserTwoFactor{
+		Enabled: &enabled,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(list))
+
+	err = ts.DeleteUserTwoFactor(ctx, &store.DeleteUserTwoFactor{
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+	userTwoFactor, err = ts.GetUserTwoFactor(ctx, &store.FindUserTwoFactor{
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Nil(t, userTwoFactor)
+}
@@ -0,0 +1,132 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/usememos/memos/store"
+)
+
+func TestWorkspaceStore(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+	outsider, err := ts.CreateUser(ctx, &store.User{
+		Username: "outsider",
+		Role:     store.RoleUser,
+		Email:    "outsider@test.com",
+	})
+	require.NoError(t, err)
+
+	workspace, err := ts.CreateWorkspace(ctx, &store.Workspace{
+		CreatorID: user.ID,
+		Name:      "Engineering",
+	})
+	require.NoError(t, err)
+	require.Equal(t, store.Normal, workspace.RowStatus)
+	_, err = ts.UpsertWorkspaceMember(ctx, &store.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+		Role:        store.WorkspaceRoleMember,
+	})
+	require.NoError(t, err)
+	workspaceMember, err := ts.UpsertWorkspaceMember(ctx, &store.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+		Role:        store.WorkspaceRoleAdmin,
+	})
+	require.NoError(t, err)
+	require.Equal(t, store.WorkspaceRoleAdmin, workspaceMember.Role)
+	list, err := ts.ListWorkspaces(ctx, &store.FindWorkspace{
+		MemberID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	list, err = ts.ListWorkspaces(ctx, &store.FindWorkspace{
+		MemberID: &outsider.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(list))
+
+	// The protected memos of the workspace are visible to its members only.
+	memo, err := ts.CreateMemo(ctx, &store.Memo{
+		CreatorID:   user.ID,
+		Content:     "workspace memo",
+		Visibility:  store.Protected,
+		WorkspaceID: workspace.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, workspace.ID, memo.WorkspaceID)
+	_, err = ts.CreateMemo(ctx, &store.Memo{
+		CreatorID:  user.ID,
+		Content:    "instance memo",
+		Visibility: store.Protected,
+	})
+	require.NoError(t, err)
+	memoList, err := ts.ListMemos(ctx, &store.FindMemo{
+		MemberID: &outsider.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(memoList))
+	require.Equal(t, "instance memo", memoList[0].Content)
+	memoList, err = ts.ListMemos(ctx, &store.FindMemo{
+		WorkspaceID: &workspace.ID,
+		MemberID:    &user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(memoList))
+	require.Equal(t, memo.ID, memoList[0].ID)
+
+	// The tags of the same name are kept per workspace.
+	_, err = ts.UpsertTag(ctx, &store.Tag{Name: "work", CreatorID: user.ID})
+	require.NoError(t, err)
+	_, err = ts.UpsertTag(ctx, &store.Tag{Name: "work", CreatorID: user.ID, WorkspaceID: workspace.ID})
+	require.NoError(t, err)
+	tagList, err := ts.ListTags(ctx, &store.FindTag{CreatorID: user.ID})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tagList))
+	tagList, err = ts.ListTags(ctx, &store.FindTag{CreatorID: user.ID, WorkspaceID: &workspace.ID})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tagList))
+
+	_, err = ts.UpsertWorkspaceSetting(ctx, &store.WorkspaceSetting{
+		WorkspaceID: workspace.ID,
+		Name:        "disable-public-memos",
+		Value:       "true",
+	})
+	require.NoError(t, err)
+	settingName := "disable-public-memos"
+	workspaceSetting, err := ts.GetWorkspaceSetting(ctx, &store.FindWorkspaceSetting{
+		WorkspaceID: workspace.ID,
+		Name:        &settingName,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "true", workspaceSetting.Value)
+
+	hasContent, err := ts.WorkspaceHasContent(ctx, workspace.ID)
+	require.NoError(t, err)
+	require.True(t, hasContent)
+	require.NoError(t, ts.DeleteMemo(ctx, &store.DeleteMemo{
+		ID: memo.ID,
+	}))
+	hasContent, err = ts.WorkspaceHasContent(ctx, workspace.ID)
+	require.NoError(t, err)
+	require.False(t, hasContent)
+
+	// Deleting the workspace deletes its members and settings.
+	require.NoError(t, ts.DeleteWorkspace(ctx, &store.DeleteWorkspace{
+		ID: workspace.ID,
+	}))
+	memberList, err := ts.ListWorkspaceMembers(ctx, &store.FindWorkspaceMember{
+		WorkspaceID: &workspace.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(memberList))
+	settingList, err := ts.ListWorkspaceSettings(ctx, &store.FindWorkspaceSetting{
+		WorkspaceID: workspace.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(settingList))
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// WebAuthnAuthenticator is a software authenticator with a single ES256 credential,
+// producing the JSON serialization of the responses the browsers send to the relying party.
+type WebAuthnAuthenticator struct {
+	Origin string
+	RPID   string
+	// SignCount is incremented by every assertion.
+	SignCount uint32
+	// SkipUserVerification makes the assertions only prove the presence of the user, like a security key without PIN.
+	SkipUserVerification bool
+
+	credentialID []byte
+	userHandle   []byte
+	key          *ecdsa.PrivateKey
+}
+
+// NewWebAuthnAuthenticator creates an authenticator of the relying party at the origin.
+func NewWebAuthnAuthenticator(t *testing.T, origin, rpID string) *WebAuthnAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("failed to generate credential id: %v", err)
+	}
+	return &WebAuthnAuthenticator{
+		Origin:       origin,
+		RPID:         rpID,
+		credentialID: credentialID,
+		key:          key,
+	}
+}
+
+// CredentialID returns the base64url encoded id of the credential.
+func (a *WebAuthnAuthenticator) CredentialID() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialID)
+}
+
+// Register creates the credential for the base64url encoded challenge and user handle, with a "none" attestation.
+func (a *WebAuthnAuthenticator) Register(t *testing.T, challenge string, userHandle []byte) json.RawMessage {
+	a.userHandle = userHandle
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	// AAGUID, the length of the credential id, the credential id and the public key.
+	attestedCredentialData := make([]byte, 16, 16+2+len(a.credentialID)+len(publicKey))
+	attestedCredentialData = binary.BigEndian.AppendUint16(attestedCredentialData, uint16(len(a.credentialID)))
+	attestedCredentialData = append(attestedCredentialData, a.credentialID...)
+	attestedCredentialData = append(attestedCredentialData, publicKey...)
+	authData := a.authenticatorData(0x01|0x04|0x40, attestedCredentialData)
+
+	attestationObject, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attestation object: %v", err)
+	}
+	return a.marshalCredential(t, map[string]any{
+		"clientDataJSON":    a.clientDataJSON(t, "webauthn.create", challenge),
+		"attestationObject": attestationObject,
+	})
+}
+
+// Assert signs the base64url encoded challenge with the credential.
+func (a *WebAuthnAuthenticator) Assert(t *testing.T, challenge string) json.RawMessage {
+	a.SignCount++
+	flags := byte(0x01 | 0x04)
+	if a.SkipUserVerification {
+		flags = 0x01
+	}
+	authData := a.authenticatorData(flags, nil)
+	clientDataJSON := a.clientDataJSON(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	return a.marshalCredential(t, map[string]any{
+		"clientDataJSON":    clientDataJSON,
+		"authenticatorData": authData,
+		"signature":         signature,
+		"userHandle":        a.userHandle,
+	})
+}
+
+func (a *WebAuthnAuthenticator) authenticatorData(flags byte, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	authData := append([]byte{}, rpIDHash[:]...)
+	authData = append(authData, flags)
+	authData = binary.BigEndian.AppendUint32(authData, a.SignCount)
+	return append(authData, attestedCredentialData...)
+}
+
+func (a *WebAuthnAuthenticator) clientDataJSON(t *testing.T, ceremonyType, challenge string) []byte {
+	clientDataJSON, err := json.Marshal(map[string]any{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    a.Origin,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal client data: %v", err)
+	}
+	return clientDataJSON
+}
+
+func (a *WebAuthnAuthenticator) marshalCredential(t *testing.T, response map[string]any) json.RawMessage {
+	encoded := map[string]string{}
+	for key, value := range response {
+		encoded[key] = base64.RawURLEncoding.EncodeToString(value.([]byte))
+	}
+	credential, err := json.Marshal(map[string]any{
+		"id":       a.CredentialID(),
+		"rawId":    a.CredentialID(),
+		"type":     "public-key",
+		"response": encoded,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal credential: %v", err)
+	}
+	return credential
+}